@@ -4,11 +4,16 @@ import (
 	"auth-service/internal/api/handler"
 	"auth-service/internal/api/middleware"
 	"auth-service/internal/api/routes"
+	"auth-service/internal/client"
 	"auth-service/internal/config"
 	"auth-service/internal/infra"
 	"auth-service/internal/jwt"
+	"auth-service/internal/model"
+	"auth-service/internal/oidc"
+	"auth-service/internal/password"
 	"auth-service/internal/repository"
 	"auth-service/internal/service"
+	"auth-service/internal/webauthn"
 	"context"
 	"errors"
 	"fmt"
@@ -58,30 +63,239 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	tokenRepo := repository.NewRefreshTokenRepository(redisClient)
+	// set up minio
+	minioClient, err := infra.NewMinioClient(appConfig.Minio)
+	if err != nil {
+		zapLogger.Fatal("failed to connect to minio", zap.Error(err))
+	} else {
+		zapLogger.Info("connected to minio successfully")
+	}
+
+	sessionRepo := repository.NewSessionRepository(redisClient)
+	revocationRepo := repository.NewTokenRevocationRepository(redisClient)
+	actionTokenRepo := repository.NewActionTokenRepository(redisClient)
+	passwordResetRepo := repository.NewPasswordResetRepository(redisClient)
+	rateLimitRepo := repository.NewRateLimitRepository(redisClient)
 	userRepo := repository.NewUserRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(redisClient)
+	loginLockoutRepo := repository.NewLoginLockoutRepository(db)
+	patRepo := repository.NewPersonalAccessTokenRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	oidcStateRepo := repository.NewOIDCStateRepository(redisClient)
+	passkeyRepo := repository.NewPasskeyRepository(db)
+	passkeyChallengeRepo := repository.NewPasskeyChallengeRepository(redisClient)
+	deviceAuthorizationRepo := repository.NewDeviceAuthorizationRepository(redisClient)
+	oauthClientRepo := repository.NewOAuthClientRepository(db)
+	oauthCodeRepo := repository.NewOAuthCodeRepository(redisClient)
+	securityEventRepo := repository.NewSecurityEventRepository(db)
+	inviteRepo := repository.NewInviteRepository(db)
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
 
-	jwtUtils := jwt.NewJwtUtils(appConfig.JWT.SecretKey, appConfig.JWT.AccessTokenTTL, appConfig.JWT.RefreshTokenTTL)
+	keySet, err := jwt.LoadKeySet(appConfig.JWT.KeysDir, appConfig.JWT.ActiveKeyID, appConfig.JWT.SigningAlgorithm)
+	if err != nil {
+		zapLogger.Fatal("failed to load jwt signing keys", zap.Error(err))
+	}
+	zapLogger.Info("loaded jwt signing keys", zap.String("active_key_id", keySet.Active().ID))
+	jwtUtils := jwt.NewJwtUtils(keySet, appConfig.OAuth.Issuer, appConfig.JWT.AccessTokenTTL, appConfig.JWT.RefreshTokenTTL)
 
-	userService := service.NewUserService(userRepo)
-	authService := service.NewAuthService(userService, jwtUtils, tokenRepo, appConfig.Server.UserSessionTTL)
+	mailSender, err := infra.NewMailer(appConfig.Mail, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("failed to create mailer", zap.Error(err))
+	}
 
-	m := middleware.NewAuthMiddleware(jwtUtils)
+	revocationService := service.NewRevocationService(revocationRepo, sessionRepo, appConfig.JWT.AccessTokenTTL)
+	passwordHasher, err := password.NewHasher(password.HasherConfig{
+		Algorithm:         appConfig.PasswordPolicy.HashAlgorithm,
+		BcryptCost:        appConfig.PasswordPolicy.BcryptCost,
+		Argon2Memory:      appConfig.PasswordPolicy.Argon2Memory,
+		Argon2Iterations:  appConfig.PasswordPolicy.Argon2Iterations,
+		Argon2Parallelism: appConfig.PasswordPolicy.Argon2Parallelism,
+		Argon2KeyLength:   32,
+	})
+	if err != nil {
+		zapLogger.Fatal("failed to create password hasher", zap.Error(err))
+	}
+	passwordPolicy := password.NewPolicy(password.PolicyConfig{
+		MinLength:           appConfig.PasswordPolicy.MinLength,
+		MaxLength:           appConfig.PasswordPolicy.MaxLength,
+		MinCharacterClasses: appConfig.PasswordPolicy.MinCharacterClasses,
+	})
+	userService := service.NewUserService(userRepo, passwordHistoryRepo, revocationService, passwordHasher, passwordPolicy, appConfig.PasswordPolicy.HistorySize)
+	profileService := service.NewProfileService(userRepo, revocationService, minioClient, appConfig.Minio.AvatarBucket, appConfig.Profile.UsernameChangeCooldown, appConfig.Profile.AvatarMaxSize, appConfig.Profile.AvatarURLTTL)
+	emailVerificationService := service.NewEmailVerificationService(userService, jwtUtils, actionTokenRepo, mailSender, appConfig.EmailVerification.TokenTTL, appConfig.Server.FrontendURL)
+	rateLimiter := service.NewRateLimiter(rateLimitRepo)
+	passwordResetService := service.NewPasswordResetService(userService, passwordResetRepo, rateLimiter, mailSender, zapLogger, service.PasswordResetConfig{
+		TokenTTL:    appConfig.PasswordReset.TokenTTL,
+		EmailLimit:  appConfig.PasswordReset.EmailLimit,
+		IPLimit:     appConfig.PasswordReset.IPLimit,
+		LimitWindow: appConfig.PasswordReset.LimitWindow,
+		FrontendURL: appConfig.Server.FrontendURL,
+	})
+	// users without a password sign in again instead of typing it before sensitive changes
+	reauthService := service.NewReauthenticationService(userService, sessionRepo, appConfig.Server.ReauthenticationMaxAge)
+	emailChangeService := service.NewEmailChangeService(userService, reauthService, emailChangeRepo, actionTokenRepo, revocationService, rateLimiter, jwtUtils, mailSender, service.EmailChangeConfig{
+		TokenTTL:    appConfig.EmailChange.TokenTTL,
+		CancelTTL:   appConfig.EmailChange.CancelTTL,
+		Limit:       appConfig.EmailChange.Limit,
+		LimitWindow: appConfig.EmailChange.LimitWindow,
+		FrontendURL: appConfig.Server.FrontendURL,
+	})
+	mfaService := service.NewMFAService(userService, mfaRepo, rateLimiter, service.MFAConfig{
+		Issuer:            appConfig.MFA.Issuer,
+		MaxAttempts:       appConfig.MFA.MaxAttempts,
+		AttemptWindow:     appConfig.MFA.AttemptWindow,
+		RecoveryCodeCount: appConfig.MFA.RecoveryCodeCount,
+	})
+	loginGuard := service.NewLoginGuard(userService, loginAttemptRepo, loginLockoutRepo, service.LoginGuardConfig{
+		FailureWindow:    appConfig.LoginProtection.FailureWindow,
+		DelayThreshold:   appConfig.LoginProtection.DelayThreshold,
+		BaseDelay:        appConfig.LoginProtection.BaseDelay,
+		MaxDelay:         appConfig.LoginProtection.MaxDelay,
+		LockoutThreshold: appConfig.LoginProtection.LockoutThreshold,
+		LockoutDuration:  appConfig.LoginProtection.LockoutDuration,
+		IPFailureLimit:   appConfig.LoginProtection.IPFailureLimit,
+	})
+	// auth-service signs its own service token to export and erase the data the other services keep about users
+	serviceTokenSource := client.NewServiceTokenSource(jwtUtils, "auth-service", []string{model.ScopeUserDataRead, model.ScopeUserDataDelete, model.ScopeChannelsTransfer}, appConfig.OAuth.ServiceTokenTTL)
+	channelClient := client.NewChannelClient(appConfig.Services.ChannelServiceURL, serviceTokenSource)
+	chatClient := client.NewChatClient(appConfig.Services.ChatServiceURL, serviceTokenSource)
+	identityService := service.NewIdentityService(userService, identityRepo, passkeyRepo, channelClient)
+	relyingParty := webauthn.NewRelyingParty(webauthn.Config{
+		RPID:    appConfig.Passkey.RPID,
+		RPName:  appConfig.Passkey.RPName,
+		Origins: appConfig.Passkey.Origins,
+		Timeout: appConfig.Passkey.ChallengeTTL,
+	})
+	passkeyService := service.NewPasskeyService(userService, identityService, passkeyRepo, passkeyChallengeRepo, relyingParty, service.PasskeyConfig{
+		ChallengeTTL: appConfig.Passkey.ChallengeTTL,
+	})
+	securityEventService := service.NewSecurityEventService(securityEventRepo)
+	accountDataService := service.NewAccountDataService(userService, reauthService, profileService, revocationService, securityEventService, rateLimiter, userRepo, dataExportRepo, channelClient, chatClient, minioClient, mailSender, zapLogger, service.AccountDataConfig{
+		ExportBucket:        appConfig.Minio.ExportBucket,
+		ExportTTL:           appConfig.AccountData.ExportTTL,
+		ExportURLTTL:        appConfig.AccountData.ExportURLTTL,
+		ExportLimit:         appConfig.AccountData.ExportLimit,
+		ExportLimitWindow:   appConfig.AccountData.ExportLimitWindow,
+		DeletionGracePeriod: appConfig.AccountData.DeletionGracePeriod,
+		SweepInterval:       appConfig.AccountData.SweepInterval,
+	})
+	if !model.IsValidRegistrationMode(appConfig.Registration.Mode) {
+		zapLogger.Fatal("invalid registration mode", zap.String("mode", appConfig.Registration.Mode))
+	}
+	inviteService := service.NewInviteService(inviteRepo, appConfig.Registration.Mode)
+	authService := service.NewAuthService(userService, revocationService, emailVerificationService, mfaService, passkeyService, loginGuard, securityEventService, inviteService, jwtUtils, sessionRepo, actionTokenRepo, zapLogger, appConfig.Server.UserSessionTTL, appConfig.MFA.ChallengeTTL)
+	magicLinkService := service.NewMagicLinkService(userService, authService, jwtUtils, actionTokenRepo, rateLimiter, mailSender, zapLogger, service.MagicLinkConfig{
+		TokenTTL:    appConfig.MagicLink.TokenTTL,
+		EmailLimit:  appConfig.MagicLink.EmailLimit,
+		IPLimit:     appConfig.MagicLink.IPLimit,
+		LimitWindow: appConfig.MagicLink.LimitWindow,
+		FrontendURL: appConfig.Server.FrontendURL,
+	})
+	deviceAuthorizationService := service.NewDeviceAuthorizationService(userService, authService, deviceAuthorizationRepo, rateLimiter, service.DeviceAuthorizationConfig{
+		ClientIDs:       appConfig.DeviceAuthorization.ClientIDs,
+		CodeTTL:         appConfig.DeviceAuthorization.CodeTTL,
+		PollInterval:    appConfig.DeviceAuthorization.PollInterval,
+		VerificationURL: appConfig.DeviceAuthorization.VerificationURL,
+		IPLimit:         appConfig.DeviceAuthorization.IPLimit,
+		UserLimit:       appConfig.DeviceAuthorization.UserLimit,
+		LimitWindow:     appConfig.DeviceAuthorization.LimitWindow,
+	})
+
+	patService := service.NewPersonalAccessTokenService(userService, patRepo, service.PersonalAccessTokenConfig{
+		MaxPerUser:       appConfig.PersonalAccessToken.MaxPerUser,
+		LastUsedInterval: appConfig.PersonalAccessToken.LastUsedInterval,
+	})
+	oauthService := service.NewOAuthService(userService, authService, revocationService, patService, jwtUtils, oauthClientRepo, oauthCodeRepo, service.OAuthConfig{
+		Issuer:           appConfig.OAuth.Issuer,
+		ConsentURL:       appConfig.OAuth.ConsentURL,
+		CodeTTL:          appConfig.OAuth.CodeTTL,
+		SigningAlgorithm: keySet.Active().Method.Alg(),
+		ServiceTokenTTL:  appConfig.OAuth.ServiceTokenTTL,
+	})
+
+	m := middleware.NewAuthMiddleware(jwtUtils, revocationService, patService)
 
 	handlerLogger := handler.NewLogger(zapLogger)
-	userHandler := handler.NewUserHandler(userService, handlerLogger)
-	authHandler := handler.NewAuthHandler(authService, handlerLogger)
+	securityAuditor := handler.NewSecurityAuditor(securityEventService, handlerLogger)
+	sessionCookies, err := handler.NewSessionCookies(handler.SessionCookieConfig{
+		Enabled:  appConfig.SessionCookie.Enabled,
+		Secure:   appConfig.SessionCookie.Secure,
+		Domain:   appConfig.SessionCookie.Domain,
+		SameSite: appConfig.SessionCookie.SameSite,
+	}, handlerLogger)
+	if err != nil {
+		zapLogger.Fatal("failed to configure session cookies", zap.Error(err))
+	}
+	userHandler := handler.NewUserHandler(userService, profileService, sessionCookies, securityAuditor, handlerLogger)
+	authHandler := handler.NewAuthHandler(authService, emailVerificationService, sessionCookies, securityAuditor, handlerLogger)
+	sessionHandler := handler.NewSessionHandler(authService, sessionCookies, securityAuditor, handlerLogger)
+	jwksHandler := handler.NewJWKSHandler(jwtUtils)
+	passwordHandler := handler.NewPasswordHandler(passwordResetService, securityAuditor, handlerLogger)
+	mfaHandler := handler.NewMFAHandler(mfaService, handlerLogger)
+	loginLockoutHandler := handler.NewLoginLockoutHandler(loginGuard, securityAuditor, handlerLogger)
+	patHandler := handler.NewPersonalAccessTokenHandler(patService, handlerLogger)
+	identityHandler := handler.NewIdentityHandler(identityService, handlerLogger)
+	passkeyHandler := handler.NewPasskeyHandler(passkeyService, authService, sessionCookies, handlerLogger)
+	magicLinkHandler := handler.NewMagicLinkHandler(magicLinkService, sessionCookies, handlerLogger)
+	deviceAuthorizationHandler := handler.NewDeviceAuthorizationHandler(deviceAuthorizationService, handlerLogger)
+	oauthHandler := handler.NewOAuthHandler(oauthService, handlerLogger)
+	oauthClientHandler := handler.NewOAuthClientHandler(oauthService, handlerLogger)
+	securityEventHandler := handler.NewSecurityEventHandler(securityEventService, handlerLogger)
+	inviteHandler := handler.NewInviteHandler(inviteService, securityAuditor, handlerLogger)
+	profileHandler := handler.NewProfileHandler(profileService, securityAuditor, handlerLogger)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeService, securityAuditor, handlerLogger)
+	accountDataHandler := handler.NewAccountDataHandler(accountDataService, securityAuditor, handlerLogger)
 
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
 	routes.SetUpUserRoutes(r, userHandler, m)
 	routes.SetUpAuthRoutes(r, authHandler, m)
+	routes.SetUpSessionRoutes(r, sessionHandler, m)
+	routes.SetUpJWKSRoutes(r, jwksHandler)
+	routes.SetUpPasswordRoutes(r, passwordHandler)
+	routes.SetUpMFARoutes(r, mfaHandler, m)
+	routes.SetUpLoginLockoutRoutes(r, loginLockoutHandler, m)
+	routes.SetUpPersonalAccessTokenRoutes(r, patHandler, m)
+	routes.SetUpIdentityRoutes(r, identityHandler, m)
+	routes.SetUpPasskeyRoutes(r, passkeyHandler, m)
+	routes.SetUpMagicLinkRoutes(r, magicLinkHandler)
+	routes.SetUpDeviceAuthorizationRoutes(r, deviceAuthorizationHandler, m)
+	routes.SetUpOAuthRoutes(r, oauthHandler, m)
+	routes.SetUpOAuthClientRoutes(r, oauthClientHandler, m)
+	routes.SetUpSecurityEventRoutes(r, securityEventHandler, m)
+	routes.SetUpInviteRoutes(r, inviteHandler, m)
+	routes.SetUpProfileRoutes(r, profileHandler, m)
+	routes.SetUpEmailChangeRoutes(r, emailChangeHandler, m)
+	routes.SetUpAccountDataRoutes(r, accountDataHandler, m)
+	if appConfig.OIDC.ClientID != "" {
+		oidcProvider := oidc.NewProvider(oidc.Config{
+			Issuer:         appConfig.OIDC.Issuer,
+			BackchannelURL: appConfig.OIDC.BackchannelURL,
+			ClientID:       appConfig.OIDC.ClientID,
+			ClientSecret:   appConfig.OIDC.ClientSecret,
+			RedirectURL:    appConfig.OIDC.RedirectURL,
+			Scopes:         appConfig.OIDC.Scopes,
+		})
+		oidcService := service.NewOIDCService(authService, userService, inviteService, oidcProvider, identityRepo, oidcStateRepo, service.OIDCConfig{
+			ProviderName: appConfig.OIDC.ProviderName,
+			StateTTL:     appConfig.OIDC.StateTTL,
+		})
+		routes.SetUpOIDCRoutes(r, handler.NewOIDCHandler(oidcService, sessionCookies, handlerLogger), m)
+		zapLogger.Info("oidc login enabled", zap.String("issuer", appConfig.OIDC.Issuer))
+	}
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", appConfig.Server.Port),
 		Handler: r,
 	}
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go accountDataService.Run(sweepCtx)
+
 	go func() {
 		zapLogger.Info(fmt.Sprintf("starting server on %s", srv.Addr))
 		if e := srv.ListenAndServe(); e != nil && !errors.Is(e, http.ErrServerClosed) {
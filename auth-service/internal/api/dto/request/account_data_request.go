@@ -0,0 +1,6 @@
+package request
+
+type DeleteAccountRequest struct {
+	// CurrentPassword is required unless the user has no password, they must then have signed in recently
+	CurrentPassword string `json:"current_password"`
+}
@@ -0,0 +1,16 @@
+package request
+
+import "time"
+
+type SuspendUserRequest struct {
+	Until  time.Time `json:"until" binding:"required"`
+	Reason string    `json:"reason" binding:"required,max=500"`
+}
+
+type BanUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type ChangeUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
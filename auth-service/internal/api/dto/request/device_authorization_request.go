@@ -0,0 +1,18 @@
+package request
+
+// DeviceCodeRequest and DeviceTokenRequest follow RFC 8628, they are accepted as form or JSON
+type DeviceCodeRequest struct {
+	ClientID string `form:"client_id" json:"client_id" binding:"required"`
+	// DeviceName is shown to the user approving the device and used as the session name
+	DeviceName string `form:"device_name" json:"device_name" binding:"max=100"`
+}
+
+type DeviceTokenRequest struct {
+	GrantType  string `form:"grant_type" json:"grant_type" binding:"required"`
+	DeviceCode string `form:"device_code" json:"device_code" binding:"required"`
+	ClientID   string `form:"client_id" json:"client_id" binding:"required"`
+}
+
+type DeviceVerificationRequest struct {
+	UserCode string `json:"user_code" binding:"required"`
+}
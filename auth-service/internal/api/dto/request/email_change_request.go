@@ -0,0 +1,12 @@
+package request
+
+type RequestEmailChangeRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	// CurrentPassword is required unless the user has no password, they must then have signed in recently
+	CurrentPassword string `json:"current_password"`
+}
+
+// EmailChangeTokenRequest carries the token of a confirmation or cancel link
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,11 @@
+package request
+
+type MergeUsersRequest struct {
+	// SourceUserID is the duplicate account, it is deleted once merged
+	SourceUserID string `json:"source_user_id" binding:"required,uuid"`
+}
+
+type OIDCLinkRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
@@ -0,0 +1,9 @@
+package request
+
+type CreateInviteRequest struct {
+	// Role is given to the users who sign up with the invite, user when empty
+	Role          string `json:"role"`
+	MaxUses       int    `json:"max_uses" binding:"required,min=1,max=10000"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"required,min=1,max=365"`
+	Note          string `json:"note" binding:"max=200"`
+}
@@ -3,4 +3,10 @@ package request
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// DeviceName is an optional human readable label for the created session
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
@@ -0,0 +1,13 @@
+package request
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	// DeviceName is an optional human readable label for the session created when the link is used
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
+
+type ConsumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+	// DeviceName overrides the one given when the link was requested
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
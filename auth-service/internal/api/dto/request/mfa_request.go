@@ -0,0 +1,11 @@
+package request
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is either a TOTP code or a recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
@@ -0,0 +1,47 @@
+package request
+
+// AuthorizationRequest is read from the query of the authorization endpoint and of the consent page
+type AuthorizationRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Nonce               string `form:"nonce" json:"nonce"`
+}
+
+// ConsentRequest carry the parameters of the authorization request along with the decision of the user
+type ConsentRequest struct {
+	AuthorizationRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthTokenRequest follows RFC 6749 sections 4.1.3, 4.4.2 and 6, the client credentials can also be sent with basic auth
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// IntrospectionRequest follows RFC 7662 section 2.1, the caller authenticates like on the token endpoint
+type IntrospectionRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	// Public clients (single page and mobile apps) get no secret
+	Public bool `json:"public"`
+}
@@ -0,0 +1,8 @@
+package request
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+	// DeviceName overrides the one given when the login was started
+	DeviceName string `json:"device_name" binding:"max=100"`
+}
@@ -0,0 +1,25 @@
+package request
+
+import "auth-service/internal/webauthn"
+
+type RegisterPasskeyRequest struct {
+	Name string `json:"name" binding:"max=64"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.create
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type PasskeyLoginRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required,uuid"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.get
+	Credential webauthn.AssertionResponse `json:"credential"`
+	DeviceName string                     `json:"device_name" binding:"max=100"`
+}
+
+type MFAPasskeyOptionsRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type LoginMFAPasskeyRequest struct {
+	MFAToken   string                     `json:"mfa_token" binding:"required"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
@@ -0,0 +1,7 @@
+package request
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"required,min=1,max=365"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type ChangeUsernameRequest struct {
+	Username string `json:"username" binding:"required"`
+}
@@ -5,4 +5,8 @@ type RegisterRequest struct {
 	Password  string `json:"password" binding:"required"`
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
+	// Username is optional, the user can pick one later
+	Username string `json:"username"`
+	// InviteCode is required when the registration is invite-only
+	InviteCode string `json:"invite_code"`
 }
@@ -4,4 +4,6 @@ type UpdateUserInfoRequest struct {
 	Email     string `json:"email" binding:"omitempty,email"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	// Bio is left unchanged when omitted and cleared when empty
+	Bio *string `json:"bio" binding:"omitempty,max=300"`
 }
@@ -0,0 +1,18 @@
+package response
+
+import "time"
+
+// DataExportResponse is an export of the data of the user, DownloadURL is only set once the archive is ready
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type AccountDeletionResponse struct {
+	Message             string    `json:"message"`
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
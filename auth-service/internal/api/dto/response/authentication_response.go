@@ -1,7 +1,22 @@
 package response
 
 type AuthenticationResponse struct {
+	// AccessToken is empty in the cookie session mode, the token is in an HttpOnly cookie
+	AccessToken string `json:"access_token,omitempty"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	// CSRFToken has to be sent back in the X-CSRF-Token header in the cookie session mode
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+// TokenResponse return the refresh token in the body, for clients that do not keep cookies
+type TokenResponse struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
 	ExpiresIn   int    `json:"expires_in"`
+	// RefreshToken is not issued to backend services
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// IDToken and Scope are only returned to third-party apps
+	IDToken string `json:"id_token,omitempty"`
+	Scope   string `json:"scope,omitempty"`
 }
@@ -0,0 +1,26 @@
+package response
+
+import "time"
+
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorizationResponse describe the device a user code belongs to on the verification page
+type DeviceAuthorizationResponse struct {
+	ClientID   string    `json:"client_id"`
+	DeviceName string    `json:"device_name"`
+	UserCode   string    `json:"user_code"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// OAuthErrorResponse is the error format of the OAuth 2.0 endpoints, see RFC 6749 section 5.2
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,10 @@
+package response
+
+import "time"
+
+type EmailChangeResponse struct {
+	ID        string    `json:"id"`
+	NewEmail  string    `json:"new_email"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
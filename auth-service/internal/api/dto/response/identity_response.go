@@ -0,0 +1,18 @@
+package response
+
+import "time"
+
+type IdentityResponse struct {
+	ID         string     `json:"id"`
+	Provider   string     `json:"provider"`
+	Email      string     `json:"email"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type LoginMethodsResponse struct {
+	Password   bool               `json:"password"`
+	Identities []IdentityResponse `json:"identities"`
+	// Passkeys is the number of passkeys, they are listed by GET /users/me/passkeys
+	Passkeys int `json:"passkeys"`
+}
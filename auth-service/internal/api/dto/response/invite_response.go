@@ -0,0 +1,25 @@
+package response
+
+import "time"
+
+type InviteResponse struct {
+	ID        string     `json:"id"`
+	Role      string     `json:"role"`
+	Note      string     `json:"note"`
+	MaxUses   int        `json:"max_uses"`
+	UseCount  int        `json:"use_count"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	// Code is only returned when the invite is created
+	Code string `json:"code,omitempty"`
+}
+
+type InvitedUserResponse struct {
+	UserID     string    `json:"user_id"`
+	Email      string    `json:"email"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
@@ -0,0 +1,15 @@
+package response
+
+import "time"
+
+type LoginLockoutResponse struct {
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	IP             string     `json:"ip"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LockedAt       time.Time  `json:"locked_at"`
+	LockedUntil    time.Time  `json:"locked_until"`
+	Active         bool       `json:"active"`
+	ClearedAt      *time.Time `json:"cleared_at,omitempty"`
+	ClearedBy      *string    `json:"cleared_by,omitempty"`
+}
@@ -0,0 +1,26 @@
+package response
+
+import (
+	"auth-service/internal/webauthn"
+	"time"
+)
+
+type PasskeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// PasskeyCreationOptionsResponse hold the options of navigator.credentials.create
+type PasskeyCreationOptionsResponse struct {
+	PublicKey webauthn.CreationOptions `json:"public_key"`
+}
+
+// PasskeyRequestOptionsResponse hold the options of navigator.credentials.get,
+// ChallengeID is only set for a passkey login and must be sent back with the credential
+type PasskeyRequestOptionsResponse struct {
+	ChallengeID string                  `json:"challenge_id,omitempty"`
+	PublicKey   webauthn.RequestOptions `json:"public_key"`
+}
@@ -0,0 +1,14 @@
+package response
+
+import "time"
+
+type PersonalAccessTokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Token is only returned when the token is created
+	Token string `json:"token,omitempty"`
+}
@@ -0,0 +1,14 @@
+package response
+
+import "time"
+
+type SecurityEventResponse struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	UserID    *string           `json:"user_id,omitempty"`
+	ActorID   *string           `json:"actor_id,omitempty"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package response
+
+import "time"
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+	// ClientID is set when the session has been granted to a third-party app
+	ClientID string `json:"client_id,omitempty"`
+}
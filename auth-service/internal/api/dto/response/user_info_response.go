@@ -1,9 +1,38 @@
 package response
 
+import "time"
+
 type UserInfoResponse struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
-	Role      string `json:"role"`
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	Username       string     `json:"username,omitempty"`
+	FirstName      string     `json:"first_name,omitempty"`
+	LastName       string     `json:"last_name,omitempty"`
+	Bio            string     `json:"bio,omitempty"`
+	AvatarURL      string     `json:"avatar_url,omitempty"`
+	Role           string     `json:"role"`
+	EmailVerified  bool       `json:"email_verified"`
+	Status         string     `json:"status,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	StatusReason   string     `json:"status_reason,omitempty"`
+	// DeletionScheduledAt is set when the user asked for their account to be deleted
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// UserListResponse is a page of users, NextCursor is omitted on the last page
+type UserListResponse struct {
+	Users      []UserInfoResponse `json:"users"`
+	Total      int64              `json:"total"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// PublicUserResponse is the profile anyone can see, it has nothing private such as the email address
+type PublicUserResponse struct {
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	Bio       string    `json:"bio,omitempty"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// AccountDataHandler let users download the data every service keeps about them and delete their account
+type AccountDataHandler interface {
+	RequestExport() gin.HandlerFunc
+	GetExport() gin.HandlerFunc
+	ScheduleDeletion() gin.HandlerFunc
+	CancelDeletion() gin.HandlerFunc
+}
+
+type accountDataHandler struct {
+	accountDataService service.AccountDataService
+	auditor            SecurityAuditor
+	logger             Logger
+}
+
+func (*accountDataHandler) toResponse(export model.DataExport, downloadURL string) response.DataExportResponse {
+	return response.DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		DownloadURL: downloadURL,
+		ExpiresAt:   export.ExpiresAt,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+	}
+}
+
+func (a *accountDataHandler) RequestExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		export, err := a.accountDataService.RequestExport(c, userID)
+		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+				return
+			}
+			err = fmt.Errorf("accountDataHandler.RequestExport: %w", err)
+			a.logger.LoggingError(c, err, "failed to request data export", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		a.auditor.Record(c, model.SecurityEventDataExportRequested, userID, map[string]string{
+			"export_id": export.ID,
+		})
+		c.JSON(http.StatusAccepted, a.toResponse(export, ""))
+	}
+}
+
+func (a *accountDataHandler) GetExport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		export, downloadURL, err := a.accountDataService.GetExport(c, userID, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, apperrors.ErrDataExportNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Data export not found",
+				})
+				return
+			}
+			err = fmt.Errorf("accountDataHandler.GetExport: %w", err)
+			a.logger.LoggingError(c, err, "failed to get data export", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, a.toResponse(export, downloadURL))
+	}
+}
+
+func (a *accountDataHandler) ScheduleDeletion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.DeleteAccountRequest
+		// users without a password send no body
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "Invalid request body",
+			})
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		sessionID, _ := claims["sid"].(string)
+		deleteAt, err := a.accountDataService.ScheduleDeletion(c, userID, sessionID, req.CurrentPassword)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidPassword):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid password",
+				})
+			case errors.Is(err, apperrors.ErrReauthenticationRequired):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Please sign in again to delete your account",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("accountDataHandler.ScheduleDeletion: %w", err)
+				a.logger.LoggingError(c, err, "failed to schedule account deletion", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		a.auditor.Record(c, model.SecurityEventAccountDeletionScheduled, userID, map[string]string{
+			"deletion_scheduled_at": deleteAt.Format(time.RFC3339),
+		})
+		c.JSON(http.StatusAccepted, response.AccountDeletionResponse{
+			Message:             "Account deletion scheduled, it can be cancelled until then",
+			DeletionScheduledAt: deleteAt,
+		})
+	}
+}
+
+func (a *accountDataHandler) CancelDeletion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := a.accountDataService.CancelDeletion(c, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrAccountDeletionNotScheduled):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "No account deletion scheduled",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("accountDataHandler.CancelDeletion: %w", err)
+				a.logger.LoggingError(c, err, "failed to cancel account deletion", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		a.auditor.Record(c, model.SecurityEventAccountDeletionCancelled, userID, nil)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Account deletion cancelled",
+		})
+	}
+}
+
+func NewAccountDataHandler(accountDataService service.AccountDataService, auditor SecurityAuditor, logger Logger) AccountDataHandler {
+	return &accountDataHandler{
+		accountDataService: accountDataService,
+		auditor:            auditor,
+		logger:             logger,
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -20,14 +21,20 @@ import (
 type AuthHandler interface {
 	Register() gin.HandlerFunc
 	Login() gin.HandlerFunc
+	LoginMFA() gin.HandlerFunc
 	Logout() gin.HandlerFunc
 	Refresh() gin.HandlerFunc
 	VerifyToken() gin.HandlerFunc
+	VerifyEmail() gin.HandlerFunc
+	ResendVerificationEmail() gin.HandlerFunc
 }
 
 type authHandler struct {
-	authService service.AuthService
-	logger      Logger
+	authService              service.AuthService
+	emailVerificationService service.EmailVerificationService
+	cookies                  SessionCookies
+	auditor                  SecurityAuditor
+	logger                   Logger
 }
 
 func (*authHandler) formatValidationError(err validator.FieldError) string {
@@ -64,14 +71,36 @@ func (a *authHandler) Register() gin.HandlerFunc {
 			Password:  req.Password,
 			FirstName: req.FirstName,
 			LastName:  req.LastName,
+			Username:  req.Username,
+		}
+		res, err := a.authService.Register(c, newUser, req.InviteCode)
+		if err != nil && errors.Is(err, apperrors.ErrMailDeliveryFailed) {
+			// the account exists, the user can ask for a new verification email later
+			a.logger.LoggingError(c, fmt.Errorf("AuthHandler.Register: %w", err), "failed to send verification email", zap.WarnLevel)
+			err = nil
 		}
-		res, err := a.authService.Register(c, newUser)
 		if err != nil {
-			if errors.Is(err, apperrors.ErrUserMailAlreadyExists) {
+			if respondPasswordRejected(c, err) || respondUsernameRejected(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, apperrors.ErrUserMailAlreadyExists):
 				c.JSON(http.StatusConflict, response.Response{
 					Message: "Email already exists",
 				})
-			} else {
+			case errors.Is(err, apperrors.ErrRegistrationClosed):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Registration is closed",
+				})
+			case errors.Is(err, apperrors.ErrInviteRequired):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "An invite code is required to register",
+				})
+			case errors.Is(err, apperrors.ErrInvalidInvite):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired invite code",
+				})
+			default:
 				err = fmt.Errorf("AuthHandler.Register: %w", err)
 				a.logger.LoggingError(c, err, "failed to register an user", zap.ErrorLevel)
 				c.JSON(http.StatusInternalServerError, response.Response{
@@ -81,11 +110,14 @@ func (a *authHandler) Register() gin.HandlerFunc {
 			return
 		}
 		c.JSON(http.StatusOK, response.UserInfoResponse{
-			ID:        res.ID,
-			Email:     res.Email,
-			FirstName: res.FirstName,
-			LastName:  res.LastName,
-			Role:      res.Role,
+			ID:            res.ID,
+			Email:         res.Email,
+			Username:      res.Username,
+			FirstName:     res.FirstName,
+			LastName:      res.LastName,
+			Role:          res.Role,
+			EmailVerified: res.EmailVerified,
+			CreatedAt:     res.CreatedAt,
 		})
 	}
 }
@@ -106,8 +138,16 @@ func (a *authHandler) Login() gin.HandlerFunc {
 			}
 			return
 		}
-		auth, err := a.authService.Login(c, req.Email, req.Password)
+		client := service.ClientInfo{
+			DeviceName: req.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IP:         c.ClientIP(),
+		}
+		auth, challenge, err := a.authService.Login(c, req.Email, req.Password, client)
 		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
 			switch {
 			case errors.Is(err, apperrors.ErrUserNotFound):
 				c.JSON(http.StatusNotFound, response.Response{
@@ -117,6 +157,14 @@ func (a *authHandler) Login() gin.HandlerFunc {
 				c.JSON(http.StatusBadRequest, response.Response{
 					Message: "Invalid password",
 				})
+			case errors.Is(err, apperrors.ErrUserSuspended):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account suspended",
+				})
+			case errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account banned",
+				})
 			default:
 				err = fmt.Errorf("AuthHandler.Login: %w", err)
 				a.logger.LoggingError(c, err, "failed to login", zap.ErrorLevel)
@@ -126,12 +174,84 @@ func (a *authHandler) Login() gin.HandlerFunc {
 			}
 			return
 		}
-		c.SetCookie("refresh_token", auth.RefreshToken, int(auth.RefreshTokenTTL.Seconds()), "/auth/refresh", "", false, true)
-		c.JSON(http.StatusOK, response.AuthenticationResponse{
-			AccessToken: auth.AccessToken,
-			TokenType:   "Bearer",
-			ExpiresIn:   int(auth.AccessTokenTTL.Seconds()),
+		respondLogin(c, a.cookies, auth, challenge)
+	}
+}
+
+// respondLogin write the tokens of a new session, or the MFA challenge that has to be completed first
+func respondLogin(c *gin.Context, cookies SessionCookies, auth service.AuthenticationResponse, challenge *service.MFAChallenge) {
+	if challenge != nil {
+		c.JSON(http.StatusOK, response.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    challenge.Token,
+			ExpiresIn:   int(challenge.TTL.Seconds()),
+			Methods:     challenge.Methods,
 		})
+		return
+	}
+	cookies.RespondSession(c, auth)
+}
+
+func (a *authHandler) LoginMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.LoginMFARequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: a.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		client := service.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		}
+		auth, err := a.authService.CompleteMFALogin(c, req.MFAToken, req.Code, client)
+		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusUnauthorized, response.Response{
+					Message: "Invalid or expired MFA token",
+				})
+			case errors.Is(err, apperrors.ErrInvalidMFACode):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid MFA code",
+				})
+			case errors.Is(err, apperrors.ErrMFANotEnabled):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "MFA is not enabled",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			case errors.Is(err, apperrors.ErrUserSuspended):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account suspended",
+				})
+			case errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account banned",
+				})
+			default:
+				err = fmt.Errorf("AuthHandler.LoginMFA: %w", err)
+				a.logger.LoggingError(c, err, "failed to complete mfa login", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		a.cookies.RespondSession(c, auth)
 	}
 }
 
@@ -139,7 +259,9 @@ func (a *authHandler) Logout() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
 		userID := claims["user_id"].(string)
-		err := a.authService.Logout(c, userID)
+		sessionID, _ := claims["sid"].(string)
+		accessTokenID, _ := claims["jti"].(string)
+		err := a.authService.Logout(c, userID, sessionID, accessTokenID)
 		if err != nil {
 			err = fmt.Errorf("AuthHandler.Logout: %w", err)
 			a.logger.LoggingError(c, err, "failed to logout", zap.ErrorLevel)
@@ -148,7 +270,10 @@ func (a *authHandler) Logout() gin.HandlerFunc {
 			})
 			return
 		}
-		c.SetCookie("refresh_token", "", -1, "/auth/refresh", "", false, true)
+		a.auditor.Record(c, model.SecurityEventLogout, userID, map[string]string{
+			"session_id": sessionID,
+		})
+		a.cookies.ClearSession(c)
 		c.JSON(http.StatusOK, response.Response{
 			Message: "Logout successfully",
 		})
@@ -157,21 +282,37 @@ func (a *authHandler) Logout() gin.HandlerFunc {
 
 func (a *authHandler) Refresh() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		refreshToken, err := c.Cookie("refresh_token")
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, response.Response{
-				Message: "Cookie not found",
+		refreshToken, err := c.Cookie(refreshTokenCookie)
+		fromCookie := err == nil
+		if fromCookie && a.cookies.Enabled() && !middleware.CheckCSRF(c, c.Request.Method) {
+			c.JSON(http.StatusForbidden, response.Response{
+				Message: "Invalid CSRF token",
 			})
 			return
 		}
-		auth, err := a.authService.Refresh(c, refreshToken)
+		if !fromCookie {
+			// clients without cookies, like devices signed in with the device flow, send it in the body
+			var req request.RefreshRequest
+			if c.ShouldBindJSON(&req) != nil || req.RefreshToken == "" {
+				c.JSON(http.StatusUnauthorized, response.Response{
+					Message: "Cookie not found",
+				})
+				return
+			}
+			refreshToken = req.RefreshToken
+		}
+		client := service.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		}
+		auth, err := a.authService.Refresh(c, refreshToken, client)
 		if err != nil {
 			switch {
 			case errors.Is(err, apperrors.ErrInvalidToken):
 				c.JSON(http.StatusUnauthorized, response.Response{
 					Message: "Invalid refresh token",
 				})
-			case errors.Is(err, apperrors.ErrRefreshTokenNotFound):
+			case errors.Is(err, apperrors.ErrSessionNotFound):
 				c.JSON(http.StatusUnauthorized, response.Response{
 					Message: "Invalid refresh token",
 				})
@@ -179,6 +320,14 @@ func (a *authHandler) Refresh() gin.HandlerFunc {
 				c.JSON(http.StatusNotFound, response.Response{
 					Message: "User not found",
 				})
+			case errors.Is(err, apperrors.ErrUserSuspended):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account suspended",
+				})
+			case errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account banned",
+				})
 			default:
 				err = fmt.Errorf("AuthHandler.Refresh: %w", err)
 				a.logger.LoggingError(c, err, "failed to refresh token", zap.ErrorLevel)
@@ -188,29 +337,126 @@ func (a *authHandler) Refresh() gin.HandlerFunc {
 			}
 			return
 		}
-		c.SetCookie("refresh_token", auth.RefreshToken, int(auth.RefreshTokenTTL.Seconds()), "/auth/refresh", "", false, true)
-		c.JSON(http.StatusOK, response.AuthenticationResponse{
-			AccessToken: auth.AccessToken,
-			TokenType:   "Bearer",
-			ExpiresIn:   int(auth.AccessTokenTTL.Seconds()),
-		})
+		if !fromCookie {
+			c.JSON(http.StatusOK, response.TokenResponse{
+				AccessToken:  auth.AccessToken,
+				TokenType:    "Bearer",
+				ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+				RefreshToken: auth.RefreshToken,
+			})
+			return
+		}
+		a.cookies.RespondSession(c, auth)
 	}
 }
 
 func (a *authHandler) VerifyToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// behind the forward authentication of the proxy the method of the request being authorized is forwarded,
+		// the CSRF token of cookie sessions is checked against it
+		if c.GetBool(middleware.TokenFromCookieContextKey) {
+			method := c.GetHeader("X-Forwarded-Method")
+			if method != "" && !middleware.CheckCSRF(c, method) {
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Invalid CSRF token",
+				})
+				return
+			}
+		}
 		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
 		userID := claims["user_id"].(string)
 		role := claims["role"].(string)
+		scope, _ := claims["scope"].(string)
+		emailVerified, _ := claims["email_verified"].(bool)
 		c.Header("X-User-Id", userID)
 		c.Header("X-User-Role", role)
+		c.Header("X-User-Scopes", scope)
+		c.Header("X-User-Email-Verified", strconv.FormatBool(emailVerified))
+		if username, _ := claims["username"].(string); username != "" {
+			c.Header("X-Username", username)
+		}
 		c.Status(http.StatusNoContent)
 	}
 }
 
-func NewAuthHandler(authService service.AuthService, logger Logger) AuthHandler {
+func (a *authHandler) VerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: a.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		err := a.emailVerificationService.VerifyEmail(c, req.Token)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired verification token",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("AuthHandler.VerifyEmail: %w", err)
+				a.logger.LoggingError(c, err, "failed to verify email", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Email verified successfully",
+		})
+	}
+}
+
+func (a *authHandler) ResendVerificationEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := a.emailVerificationService.ResendVerificationEmail(c, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrEmailAlreadyVerified):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "Email already verified",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("AuthHandler.ResendVerificationEmail: %w", err)
+				a.logger.LoggingError(c, err, "failed to resend verification email", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusAccepted, response.Response{
+			Message: "Verification email sent",
+		})
+	}
+}
+
+func NewAuthHandler(authService service.AuthService, emailVerificationService service.EmailVerificationService, cookies SessionCookies, auditor SecurityAuditor, logger Logger) AuthHandler {
 	return &authHandler{
-		authService: authService,
-		logger:      logger,
+		authService:              authService,
+		emailVerificationService: emailVerificationService,
+		cookies:                  cookies,
+		auditor:                  auditor,
+		logger:                   logger,
 	}
 }
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// DeviceCodeGrantType is the grant_type a device polls the token endpoint with
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+type DeviceAuthorizationHandler interface {
+	RequestDeviceCode() gin.HandlerFunc
+	PollToken() gin.HandlerFunc
+	GetAuthorization() gin.HandlerFunc
+	Approve() gin.HandlerFunc
+	Deny() gin.HandlerFunc
+}
+
+type deviceAuthorizationHandler struct {
+	deviceService service.DeviceAuthorizationService
+	logger        Logger
+}
+
+func (*deviceAuthorizationHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s characters", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+// bindOAuthRequest write an invalid_request error and return false if the body is not a valid request
+func (d *deviceAuthorizationHandler) bindOAuthRequest(c *gin.Context, req any) bool {
+	if err := c.ShouldBind(req); err != nil {
+		description := "Invalid request body"
+		var validatorError validator.ValidationErrors
+		if errors.As(err, &validatorError) {
+			description = d.formatValidationError(validatorError[0])
+		}
+		c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: description,
+		})
+		return false
+	}
+	return true
+}
+
+func (d *deviceAuthorizationHandler) RequestDeviceCode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.DeviceCodeRequest
+		if !d.bindOAuthRequest(c, &req) {
+			return
+		}
+		code, err := d.deviceService.StartAuthorization(c, req.ClientID, req.DeviceName, c.ClientIP())
+		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
+			if errors.Is(err, apperrors.ErrInvalidClient) {
+				c.JSON(http.StatusUnauthorized, response.OAuthErrorResponse{
+					Error:            "invalid_client",
+					ErrorDescription: "Unknown client",
+				})
+				return
+			}
+			err = fmt.Errorf("deviceAuthorizationHandler.RequestDeviceCode: %w", err)
+			d.logger.LoggingError(c, err, "failed to start device authorization", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.OAuthErrorResponse{
+				Error: "server_error",
+			})
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, response.DeviceCodeResponse{
+			DeviceCode:              code.DeviceCode,
+			UserCode:                code.UserCode,
+			VerificationURI:         code.VerificationURI,
+			VerificationURIComplete: code.VerificationURIComplete,
+			ExpiresIn:               int(code.ExpiresIn.Seconds()),
+			Interval:                int(code.Interval.Seconds()),
+		})
+	}
+}
+
+func (d *deviceAuthorizationHandler) PollToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.DeviceTokenRequest
+		if !d.bindOAuthRequest(c, &req) {
+			return
+		}
+		if req.GrantType != DeviceCodeGrantType {
+			c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+				Error: "unsupported_grant_type",
+			})
+			return
+		}
+		client := service.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		}
+		auth, err := d.deviceService.PollToken(c, req.ClientID, req.DeviceCode, client)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrAuthorizationPending):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error: "authorization_pending",
+				})
+			case errors.Is(err, apperrors.ErrSlowDown):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error: "slow_down",
+				})
+			case errors.Is(err, apperrors.ErrDeviceAccessDenied):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "access_denied",
+					ErrorDescription: "The user denied the device",
+				})
+			case errors.Is(err, apperrors.ErrInvalidDeviceCode):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "expired_token",
+					ErrorDescription: "The device code is invalid or has expired",
+				})
+			case errors.Is(err, apperrors.ErrInvalidClient):
+				c.JSON(http.StatusUnauthorized, response.OAuthErrorResponse{
+					Error: "invalid_client",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound),
+				errors.Is(err, apperrors.ErrUserSuspended),
+				errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "access_denied",
+					ErrorDescription: "The account can not sign in",
+				})
+			default:
+				err = fmt.Errorf("deviceAuthorizationHandler.PollToken: %w", err)
+				d.logger.LoggingError(c, err, "failed to issue device token", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.OAuthErrorResponse{
+					Error: "server_error",
+				})
+			}
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, response.TokenResponse{
+			AccessToken:  auth.AccessToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+			RefreshToken: auth.RefreshToken,
+		})
+	}
+}
+
+// respondUserCodeError write the response of a failed user code lookup, return false if err is unexpected
+func (d *deviceAuthorizationHandler) respondUserCodeError(c *gin.Context, err error) bool {
+	if respondRateLimited(c, err) {
+		return true
+	}
+	if errors.Is(err, apperrors.ErrInvalidUserCode) {
+		c.JSON(http.StatusNotFound, response.Response{
+			Message: "Invalid or expired code",
+		})
+		return true
+	}
+	return false
+}
+
+func (d *deviceAuthorizationHandler) GetAuthorization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userCode := c.Query("user_code")
+		if userCode == "" {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "The user_code parameter is required",
+			})
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		authorization, err := d.deviceService.GetAuthorization(c, userID, userCode)
+		if err != nil {
+			if d.respondUserCodeError(c, err) {
+				return
+			}
+			err = fmt.Errorf("deviceAuthorizationHandler.GetAuthorization: %w", err)
+			d.logger.LoggingError(c, err, "failed to get device authorization", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.DeviceAuthorizationResponse{
+			ClientID:   authorization.ClientID,
+			DeviceName: authorization.DeviceName,
+			UserCode:   authorization.UserCode,
+			ExpiresAt:  authorization.ExpiresAt,
+		})
+	}
+}
+
+func (d *deviceAuthorizationHandler) Approve() gin.HandlerFunc {
+	return d.decide(true)
+}
+
+func (d *deviceAuthorizationHandler) Deny() gin.HandlerFunc {
+	return d.decide(false)
+}
+
+func (d *deviceAuthorizationHandler) decide(approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.DeviceVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: d.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		var err error
+		message := "Device approved"
+		if approve {
+			err = d.deviceService.Approve(c, userID, req.UserCode)
+		} else {
+			err = d.deviceService.Deny(c, userID, req.UserCode)
+			message = "Device denied"
+		}
+		if err != nil {
+			if d.respondUserCodeError(c, err) {
+				return
+			}
+			err = fmt.Errorf("deviceAuthorizationHandler.decide: %w", err)
+			d.logger.LoggingError(c, err, "failed to decide device authorization", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: message,
+		})
+	}
+}
+
+func NewDeviceAuthorizationHandler(deviceService service.DeviceAuthorizationService, logger Logger) DeviceAuthorizationHandler {
+	return &deviceAuthorizationHandler{
+		deviceService: deviceService,
+		logger:        logger,
+	}
+}
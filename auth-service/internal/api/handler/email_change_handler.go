@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// EmailChangeHandler let users change their email, the new address must be confirmed and the old one can cancel
+type EmailChangeHandler interface {
+	RequestEmailChange() gin.HandlerFunc
+	GetPendingEmailChange() gin.HandlerFunc
+	CancelPendingEmailChange() gin.HandlerFunc
+	ConfirmEmailChange() gin.HandlerFunc
+	CancelEmailChange() gin.HandlerFunc
+}
+
+type emailChangeHandler struct {
+	emailChangeService service.EmailChangeService
+	auditor            SecurityAuditor
+	logger             Logger
+}
+
+func (*emailChangeHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "email":
+		return fmt.Sprintf("The %s field is not a valid email", err.Field())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (*emailChangeHandler) toResponse(change model.EmailChange) response.EmailChangeResponse {
+	return response.EmailChangeResponse{
+		ID:        change.ID,
+		NewEmail:  change.NewEmail,
+		ExpiresAt: change.ExpiresAt,
+		CreatedAt: change.CreatedAt,
+	}
+}
+
+// bindToken bind the token of a confirmation or cancel link, a 400 response is written if it is missing
+func (e *emailChangeHandler) bindToken(c *gin.Context) (string, bool) {
+	var req request.EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validatorError validator.ValidationErrors
+		if errors.As(err, &validatorError) {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: e.formatValidationError(validatorError[0]),
+			})
+		} else {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "Invalid request body",
+			})
+		}
+		return "", false
+	}
+	return req.Token, true
+}
+
+func (e *emailChangeHandler) RequestEmailChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.RequestEmailChangeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: e.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		sessionID, _ := claims["sid"].(string)
+		change, err := e.emailChangeService.RequestEmailChange(c, userID, sessionID, req.CurrentPassword, req.NewEmail)
+		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidPassword):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid password",
+				})
+			case errors.Is(err, apperrors.ErrReauthenticationRequired):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Please sign in again to change your email",
+				})
+			case errors.Is(err, apperrors.ErrEmailUnchanged):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "The new email must differ from the current one",
+				})
+			case errors.Is(err, apperrors.ErrUserMailAlreadyExists):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "Email already exists",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			case errors.Is(err, apperrors.ErrMailDeliveryFailed):
+				e.logger.LoggingError(c, fmt.Errorf("emailChangeHandler.RequestEmailChange: %w", err), "failed to send email change emails", zap.WarnLevel)
+				c.JSON(http.StatusServiceUnavailable, response.Response{
+					Message: "The emails could not be sent, please try again later",
+				})
+			default:
+				err = fmt.Errorf("emailChangeHandler.RequestEmailChange: %w", err)
+				e.logger.LoggingError(c, err, "failed to request email change", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		e.auditor.Record(c, model.SecurityEventEmailChangeRequested, userID, map[string]string{
+			"change_id":             change.ID,
+			"new_email_fingerprint": model.EmailFingerprint(change.NewEmail),
+		})
+		c.JSON(http.StatusAccepted, e.toResponse(change))
+	}
+}
+
+func (e *emailChangeHandler) GetPendingEmailChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		change, err := e.emailChangeService.GetPendingEmailChange(c, userID)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrEmailChangeNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "No pending email change",
+				})
+				return
+			}
+			err = fmt.Errorf("emailChangeHandler.GetPendingEmailChange: %w", err)
+			e.logger.LoggingError(c, err, "failed to get pending email change", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, e.toResponse(change))
+	}
+}
+
+func (e *emailChangeHandler) CancelPendingEmailChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		change, err := e.emailChangeService.CancelPendingEmailChange(c, userID)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrEmailChangeNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "No pending email change",
+				})
+				return
+			}
+			err = fmt.Errorf("emailChangeHandler.CancelPendingEmailChange: %w", err)
+			e.logger.LoggingError(c, err, "failed to cancel pending email change", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		e.auditor.Record(c, model.SecurityEventEmailChangeCancelled, userID, map[string]string{
+			"change_id":             change.ID,
+			"new_email_fingerprint": model.EmailFingerprint(change.NewEmail),
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Email change cancelled",
+		})
+	}
+}
+
+func (e *emailChangeHandler) ConfirmEmailChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := e.bindToken(c)
+		if !ok {
+			return
+		}
+		change, err := e.emailChangeService.ConfirmEmailChange(c, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired confirmation token",
+				})
+			case errors.Is(err, apperrors.ErrUserMailAlreadyExists):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "Email already exists",
+				})
+			default:
+				err = fmt.Errorf("emailChangeHandler.ConfirmEmailChange: %w", err)
+				e.logger.LoggingError(c, err, "failed to confirm email change", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		e.auditor.Record(c, model.SecurityEventEmailChanged, change.UserID, map[string]string{
+			"change_id":             change.ID,
+			"old_email_fingerprint": model.EmailFingerprint(change.OldEmail),
+			"new_email_fingerprint": model.EmailFingerprint(change.NewEmail),
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Email changed successfully",
+		})
+	}
+}
+
+func (e *emailChangeHandler) CancelEmailChange() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := e.bindToken(c)
+		if !ok {
+			return
+		}
+		change, err := e.emailChangeService.CancelEmailChange(c, token)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired cancel token",
+				})
+			case errors.Is(err, apperrors.ErrUserMailAlreadyExists):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "The previous email is now used by another account",
+				})
+			default:
+				err = fmt.Errorf("emailChangeHandler.CancelEmailChange: %w", err)
+				e.logger.LoggingError(c, err, "failed to cancel email change", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		details := map[string]string{
+			"change_id":             change.ID,
+			"old_email_fingerprint": model.EmailFingerprint(change.OldEmail),
+			"new_email_fingerprint": model.EmailFingerprint(change.NewEmail),
+		}
+		if change.Status == model.EmailChangeStatusReverted {
+			e.auditor.Record(c, model.SecurityEventEmailChangeReverted, change.UserID, details)
+			c.JSON(http.StatusOK, response.Response{
+				Message: "Email change reverted, every session has been signed out",
+			})
+			return
+		}
+		e.auditor.Record(c, model.SecurityEventEmailChangeCancelled, change.UserID, details)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Email change cancelled",
+		})
+	}
+}
+
+func NewEmailChangeHandler(emailChangeService service.EmailChangeService, auditor SecurityAuditor, logger Logger) EmailChangeHandler {
+	return &emailChangeHandler{
+		emailChangeService: emailChangeService,
+		auditor:            auditor,
+		logger:             logger,
+	}
+}
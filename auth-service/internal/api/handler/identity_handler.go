@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type IdentityHandler interface {
+	GetLoginMethods() gin.HandlerFunc
+	UnlinkIdentity() gin.HandlerFunc
+	MergeUsers() gin.HandlerFunc
+}
+
+type identityHandler struct {
+	identityService service.IdentityService
+	logger          Logger
+}
+
+func (*identityHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "uuid":
+		return fmt.Sprintf("The %s field is not a valid uuid", err.Field())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func toIdentityResponse(identity model.Identity) response.IdentityResponse {
+	return response.IdentityResponse{
+		ID:         identity.ID,
+		Provider:   identity.Provider,
+		Email:      identity.Email,
+		CreatedAt:  identity.CreatedAt,
+		LastUsedAt: identity.LastUsedAt,
+	}
+}
+
+func (i *identityHandler) GetLoginMethods() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		methods, err := i.identityService.GetLoginMethods(c, userID)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+				return
+			}
+			err = fmt.Errorf("identityHandler.GetLoginMethods: %w", err)
+			i.logger.LoggingError(c, err, "failed to get login methods", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := response.LoginMethodsResponse{
+			Password:   methods.HasPassword,
+			Identities: make([]response.IdentityResponse, len(methods.Identities)),
+			Passkeys:   methods.Passkeys,
+		}
+		for idx, identity := range methods.Identities {
+			res.Identities[idx] = toIdentityResponse(identity)
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (i *identityHandler) UnlinkIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := i.identityService.UnlinkIdentity(c, userID, c.Param("id"))
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrIdentityNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Login method not found",
+				})
+			case errors.Is(err, apperrors.ErrLastLoginMethod):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "You can not remove your last login method",
+				})
+			default:
+				err = fmt.Errorf("identityHandler.UnlinkIdentity: %w", err)
+				i.logger.LoggingError(c, err, "failed to unlink identity", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Login method removed successfully",
+		})
+	}
+}
+
+func (i *identityHandler) MergeUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.MergeUsersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: i.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		if claims["user_id"].(string) == req.SourceUserID {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "You can not perform this action on your own account",
+			})
+			return
+		}
+		err := i.identityService.MergeUsers(c, c.Param("id"), req.SourceUserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			case errors.Is(err, apperrors.ErrMergeSameUser):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "The source and target users must be different",
+				})
+			case errors.Is(err, apperrors.ErrChannelConflict):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "Both users own a channel",
+				})
+			default:
+				err = fmt.Errorf("identityHandler.MergeUsers: %w", err)
+				i.logger.LoggingError(c, err, "failed to merge users", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Users merged successfully",
+		})
+	}
+}
+
+func NewIdentityHandler(identityService service.IdentityService, logger Logger) IdentityHandler {
+	return &identityHandler{
+		identityService: identityService,
+		logger:          logger,
+	}
+}
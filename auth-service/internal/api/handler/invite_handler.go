@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// InviteHandler let admins manage the invites needed to sign up while the registration is invite-only,
+// and report which users came from which invite
+type InviteHandler interface {
+	CreateInvite() gin.HandlerFunc
+	GetInvites() gin.HandlerFunc
+	GetInvite() gin.HandlerFunc
+	RevokeInvite() gin.HandlerFunc
+	GetInvitedUsers() gin.HandlerFunc
+	GetUserInvite() gin.HandlerFunc
+}
+
+type inviteHandler struct {
+	inviteService service.InviteService
+	auditor       SecurityAuditor
+	logger        Logger
+}
+
+func (*inviteHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "min":
+		return fmt.Sprintf("The %s field must be at least %s", err.Field(), err.Param())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+// bindPagination read the limit and offset query parameters, the response is written if they are invalid
+func (*inviteHandler) bindPagination(c *gin.Context) (int, int, bool) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Offset must be an integer",
+		})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Limit must be an integer",
+		})
+		return 0, 0, false
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return limit, offset, true
+}
+
+func (*inviteHandler) toResponse(invite model.Invite) response.InviteResponse {
+	return response.InviteResponse{
+		ID:        invite.ID,
+		Role:      invite.Role,
+		Note:      invite.Note,
+		MaxUses:   invite.MaxUses,
+		UseCount:  invite.UseCount,
+		ExpiresAt: invite.ExpiresAt,
+		RevokedAt: invite.RevokedAt,
+		CreatedBy: invite.CreatedBy,
+		CreatedAt: invite.CreatedAt,
+	}
+}
+
+// respondInviteNotFound write a 404 response if err is apperrors.ErrInviteNotFound
+func (*inviteHandler) respondInviteNotFound(c *gin.Context, err error) bool {
+	if !errors.Is(err, apperrors.ErrInviteNotFound) {
+		return false
+	}
+	c.JSON(http.StatusNotFound, response.Response{
+		Message: "Invite not found",
+	})
+	return true
+}
+
+func (i *inviteHandler) CreateInvite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.CreateInviteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: i.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		invite, code, err := i.inviteService.CreateInvite(c, userID, req.Role, req.MaxUses, ttl, req.Note)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrInvalidRoles) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid role",
+				})
+				return
+			}
+			err = fmt.Errorf("inviteHandler.CreateInvite: %w", err)
+			i.logger.LoggingError(c, err, "failed to create invite", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		i.auditor.Record(c, model.SecurityEventInviteCreated, "", map[string]string{
+			"invite_id": invite.ID,
+			"role":      invite.Role,
+			"max_uses":  strconv.Itoa(invite.MaxUses),
+		})
+		res := i.toResponse(invite)
+		res.Code = code
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+func (i *inviteHandler) GetInvites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := i.bindPagination(c)
+		if !ok {
+			return
+		}
+		invites, err := i.inviteService.GetInvites(c, limit, offset)
+		if err != nil {
+			err = fmt.Errorf("inviteHandler.GetInvites: %w", err)
+			i.logger.LoggingError(c, err, "failed to get invites", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := make([]response.InviteResponse, len(invites))
+		for j, invite := range invites {
+			res[j] = i.toResponse(invite)
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (i *inviteHandler) GetInvite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invite, err := i.inviteService.GetInvite(c, c.Param("id"))
+		if err != nil {
+			if i.respondInviteNotFound(c, err) {
+				return
+			}
+			err = fmt.Errorf("inviteHandler.GetInvite: %w", err)
+			i.logger.LoggingError(c, err, "failed to get invite", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, i.toResponse(invite))
+	}
+}
+
+func (i *inviteHandler) RevokeInvite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		err := i.inviteService.RevokeInvite(c, id)
+		if err != nil {
+			if i.respondInviteNotFound(c, err) {
+				return
+			}
+			err = fmt.Errorf("inviteHandler.RevokeInvite: %w", err)
+			i.logger.LoggingError(c, err, "failed to revoke invite", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		i.auditor.Record(c, model.SecurityEventInviteRevoked, "", map[string]string{
+			"invite_id": id,
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Invite revoked successfully",
+		})
+	}
+}
+
+func (i *inviteHandler) GetInvitedUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := i.bindPagination(c)
+		if !ok {
+			return
+		}
+		users, err := i.inviteService.GetInvitedUsers(c, c.Param("id"), limit, offset)
+		if err != nil {
+			if i.respondInviteNotFound(c, err) {
+				return
+			}
+			err = fmt.Errorf("inviteHandler.GetInvitedUsers: %w", err)
+			i.logger.LoggingError(c, err, "failed to get invited users", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := make([]response.InvitedUserResponse, len(users))
+		for j, user := range users {
+			res[j] = response.InvitedUserResponse{
+				UserID:     user.UserID,
+				Email:      user.Email,
+				FirstName:  user.FirstName,
+				LastName:   user.LastName,
+				RedeemedAt: user.RedeemedAt,
+			}
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (i *inviteHandler) GetUserInvite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invite, err := i.inviteService.GetUserInvite(c, c.Param("id"))
+		if err != nil {
+			if i.respondInviteNotFound(c, err) {
+				return
+			}
+			err = fmt.Errorf("inviteHandler.GetUserInvite: %w", err)
+			i.logger.LoggingError(c, err, "failed to get user invite", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, i.toResponse(invite))
+	}
+}
+
+func NewInviteHandler(inviteService service.InviteService, auditor SecurityAuditor, logger Logger) InviteHandler {
+	return &inviteHandler{
+		inviteService: inviteService,
+		auditor:       auditor,
+		logger:        logger,
+	}
+}
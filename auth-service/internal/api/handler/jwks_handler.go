@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"auth-service/internal/jwt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler interface {
+	GetJWKS() gin.HandlerFunc
+}
+
+type jwksHandler struct {
+	jwt jwt.Utils
+}
+
+func (j *jwksHandler) GetJWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, j.jwt.JWKS())
+	}
+}
+
+func NewJWKSHandler(jwt jwt.Utils) JWKSHandler {
+	return &jwksHandler{
+		jwt: jwt,
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type LoginLockoutHandler interface {
+	GetUserLockouts() gin.HandlerFunc
+	ClearUserLockout() gin.HandlerFunc
+}
+
+type loginLockoutHandler struct {
+	loginGuard service.LoginGuard
+	auditor    SecurityAuditor
+	logger     Logger
+}
+
+func (l *loginLockoutHandler) GetUserLockouts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offset := c.DefaultQuery("offset", "0")
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "Offset must be an integer",
+			})
+			return
+		}
+		limit := c.DefaultQuery("limit", "10")
+		lim, err := strconv.Atoi(limit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "Limit must be an integer",
+			})
+			return
+		}
+		if o < 0 {
+			o = 0
+		}
+		if lim <= 0 {
+			lim = 10
+		}
+		lockouts, err := l.loginGuard.GetLockouts(c, c.Param("id"), lim, o)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+				return
+			}
+			err = fmt.Errorf("loginLockoutHandler.GetUserLockouts: %w", err)
+			l.logger.LoggingError(c, err, "failed to get user lockouts", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		now := time.Now()
+		res := make([]response.LoginLockoutResponse, len(lockouts))
+		for i, lockout := range lockouts {
+			res[i] = response.LoginLockoutResponse{
+				ID:             lockout.ID,
+				Email:          lockout.Email,
+				IP:             lockout.IP,
+				FailedAttempts: lockout.FailedAttempts,
+				LockedAt:       lockout.LockedAt,
+				LockedUntil:    lockout.LockedUntil,
+				Active:         lockout.ClearedAt == nil && lockout.LockedUntil.After(now),
+				ClearedAt:      lockout.ClearedAt,
+				ClearedBy:      lockout.ClearedBy,
+			}
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (l *loginLockoutHandler) ClearUserLockout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		adminID := claims["user_id"].(string)
+		err := l.loginGuard.ClearLockout(c, c.Param("id"), adminID)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+				return
+			}
+			err = fmt.Errorf("loginLockoutHandler.ClearUserLockout: %w", err)
+			l.logger.LoggingError(c, err, "failed to clear user lockout", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		l.auditor.Record(c, model.SecurityEventLockoutCleared, c.Param("id"), nil)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Lockout cleared successfully",
+		})
+	}
+}
+
+func NewLoginLockoutHandler(loginGuard service.LoginGuard, auditor SecurityAuditor, logger Logger) LoginLockoutHandler {
+	return &loginLockoutHandler{
+		loginGuard: loginGuard,
+		auditor:    auditor,
+		logger:     logger,
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type MagicLinkHandler interface {
+	RequestMagicLink() gin.HandlerFunc
+	ConsumeMagicLink() gin.HandlerFunc
+}
+
+type magicLinkHandler struct {
+	magicLinkService service.MagicLinkService
+	cookies          SessionCookies
+	logger           Logger
+}
+
+func (*magicLinkHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "email":
+		return fmt.Sprintf("The %s field is not a valid email", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s characters", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (m *magicLinkHandler) RequestMagicLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.MagicLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: m.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		err := m.magicLinkService.RequestMagicLink(c, req.Email, req.DeviceName, c.ClientIP())
+		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
+			err = fmt.Errorf("magicLinkHandler.RequestMagicLink: %w", err)
+			m.logger.LoggingError(c, err, "failed to request magic link", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		// same response whether the email exists or not
+		c.JSON(http.StatusAccepted, response.Response{
+			Message: "If an account exists for this email, a sign in link has been sent",
+		})
+	}
+}
+
+func (m *magicLinkHandler) ConsumeMagicLink() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.ConsumeMagicLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: m.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		client := service.ClientInfo{
+			DeviceName: req.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IP:         c.ClientIP(),
+		}
+		auth, challenge, err := m.magicLinkService.ConsumeMagicLink(c, req.Token, client)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired sign in link",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			case errors.Is(err, apperrors.ErrUserSuspended):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account suspended",
+				})
+			case errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account banned",
+				})
+			default:
+				err = fmt.Errorf("magicLinkHandler.ConsumeMagicLink: %w", err)
+				m.logger.LoggingError(c, err, "failed to consume magic link", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		respondLogin(c, m.cookies, auth, challenge)
+	}
+}
+
+func NewMagicLinkHandler(magicLinkService service.MagicLinkService, cookies SessionCookies, logger Logger) MagicLinkHandler {
+	return &magicLinkHandler{
+		magicLinkService: magicLinkService,
+		cookies:          cookies,
+		logger:           logger,
+	}
+}
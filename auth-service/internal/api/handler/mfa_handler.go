@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type MFAHandler interface {
+	GetStatus() gin.HandlerFunc
+	EnrollTOTP() gin.HandlerFunc
+	ConfirmTOTP() gin.HandlerFunc
+	RegenerateRecoveryCodes() gin.HandlerFunc
+	Disable() gin.HandlerFunc
+	ResetUserMFA() gin.HandlerFunc
+}
+
+type mfaHandler struct {
+	mfaService service.MFAService
+	logger     Logger
+}
+
+func (*mfaHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+// bindCode bind the request body and write the error response if it is invalid
+func (m *mfaHandler) bindCode(c *gin.Context) (string, bool) {
+	var req request.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validatorError validator.ValidationErrors
+		if errors.As(err, &validatorError) {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: m.formatValidationError(validatorError[0]),
+			})
+		} else {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "Invalid request body",
+			})
+		}
+		return "", false
+	}
+	return req.Code, true
+}
+
+// handleError write the response matching the MFA service error
+func (m *mfaHandler) handleError(c *gin.Context, err error, msg string) {
+	if respondRateLimited(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidMFACode):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid MFA code",
+		})
+	case errors.Is(err, apperrors.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, response.Response{
+			Message: "MFA is already enabled",
+		})
+	case errors.Is(err, apperrors.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "No pending TOTP enrollment",
+		})
+	case errors.Is(err, apperrors.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "MFA is not enabled",
+		})
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, response.Response{
+			Message: "User not found",
+		})
+	default:
+		m.logger.LoggingError(c, err, msg, zap.ErrorLevel)
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Message: "Internal server error",
+		})
+	}
+}
+
+func (m *mfaHandler) GetStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		status, err := m.mfaService.GetStatus(c, userID)
+		if err != nil {
+			m.handleError(c, fmt.Errorf("mfaHandler.GetStatus: %w", err), "failed to get mfa status")
+			return
+		}
+		c.JSON(http.StatusOK, response.MFAStatusResponse{
+			Enabled:                status.Enabled,
+			RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+		})
+	}
+}
+
+func (m *mfaHandler) EnrollTOTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		enrollment, err := m.mfaService.EnrollTOTP(c, userID)
+		if err != nil {
+			m.handleError(c, fmt.Errorf("mfaHandler.EnrollTOTP: %w", err), "failed to enroll totp")
+			return
+		}
+		c.JSON(http.StatusOK, response.TOTPEnrollmentResponse{
+			Secret:     enrollment.Secret,
+			OTPAuthURI: enrollment.URI,
+		})
+	}
+}
+
+func (m *mfaHandler) ConfirmTOTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code, ok := m.bindCode(c)
+		if !ok {
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		recoveryCodes, err := m.mfaService.ConfirmTOTP(c, userID, code)
+		if err != nil {
+			m.handleError(c, fmt.Errorf("mfaHandler.ConfirmTOTP: %w", err), "failed to confirm totp")
+			return
+		}
+		c.JSON(http.StatusOK, response.RecoveryCodesResponse{
+			RecoveryCodes: recoveryCodes,
+		})
+	}
+}
+
+func (m *mfaHandler) RegenerateRecoveryCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code, ok := m.bindCode(c)
+		if !ok {
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		recoveryCodes, err := m.mfaService.RegenerateRecoveryCodes(c, userID, code)
+		if err != nil {
+			m.handleError(c, fmt.Errorf("mfaHandler.RegenerateRecoveryCodes: %w", err), "failed to regenerate recovery codes")
+			return
+		}
+		c.JSON(http.StatusOK, response.RecoveryCodesResponse{
+			RecoveryCodes: recoveryCodes,
+		})
+	}
+}
+
+func (m *mfaHandler) Disable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code, ok := m.bindCode(c)
+		if !ok {
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := m.mfaService.Disable(c, userID, code)
+		if err != nil {
+			m.handleError(c, fmt.Errorf("mfaHandler.Disable: %w", err), "failed to disable mfa")
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "MFA disabled successfully",
+		})
+	}
+}
+
+func (m *mfaHandler) ResetUserMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := m.mfaService.Reset(c, c.Param("id"))
+		if err != nil {
+			m.handleError(c, fmt.Errorf("mfaHandler.ResetUserMFA: %w", err), "failed to reset user mfa")
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "MFA reset successfully",
+		})
+	}
+}
+
+func NewMFAHandler(mfaService service.MFAService, logger Logger) MFAHandler {
+	return &mfaHandler{
+		mfaService: mfaService,
+		logger:     logger,
+	}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// OAuthClientHandler manage the third-party apps registered by admins and the consents users gave them
+type OAuthClientHandler interface {
+	CreateClient() gin.HandlerFunc
+	GetClients() gin.HandlerFunc
+	GetClient() gin.HandlerFunc
+	DeleteClient() gin.HandlerFunc
+	GetConsents() gin.HandlerFunc
+	RevokeConsent() gin.HandlerFunc
+}
+
+type oauthClientHandler struct {
+	oauthService service.OAuthService
+	logger       Logger
+}
+
+func (*oauthClientHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s characters", err.Field(), err.Param())
+	case "min":
+		return fmt.Sprintf("The %s field must have at least %s items", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (*oauthClientHandler) toResponse(client model.OAuthClient) response.OAuthClientResponse {
+	return response.OAuthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		Scopes:       strings.Fields(client.Scopes),
+		Public:       client.IsPublic(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func (o *oauthClientHandler) CreateClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.CreateOAuthClientRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: o.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		client, secret, err := o.oauthService.CreateClient(c, service.ClientRegistration{
+			Name:         req.Name,
+			RedirectURIs: req.RedirectURIs,
+			Scopes:       req.Scopes,
+			Public:       req.Public,
+			CreatedBy:    userID,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidRedirectURI):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Redirect uris must be absolute uris without fragment",
+				})
+			case errors.Is(err, apperrors.ErrInvalidScopes):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Unknown scope",
+				})
+			default:
+				err = fmt.Errorf("oauthClientHandler.CreateClient: %w", err)
+				o.logger.LoggingError(c, err, "failed to create oauth client", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		res := o.toResponse(client)
+		res.ClientSecret = secret
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+func (o *oauthClientHandler) GetClients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clients, err := o.oauthService.GetClients(c)
+		if err != nil {
+			err = fmt.Errorf("oauthClientHandler.GetClients: %w", err)
+			o.logger.LoggingError(c, err, "failed to get oauth clients", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := make([]response.OAuthClientResponse, len(clients))
+		for i, client := range clients {
+			res[i] = o.toResponse(client)
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (o *oauthClientHandler) GetClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, err := o.oauthService.GetClient(c, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, apperrors.ErrOAuthClientNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Client not found",
+				})
+				return
+			}
+			err = fmt.Errorf("oauthClientHandler.GetClient: %w", err)
+			o.logger.LoggingError(c, err, "failed to get oauth client", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, o.toResponse(client))
+	}
+}
+
+func (o *oauthClientHandler) DeleteClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := o.oauthService.DeleteClient(c, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, apperrors.ErrOAuthClientNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Client not found",
+				})
+				return
+			}
+			err = fmt.Errorf("oauthClientHandler.DeleteClient: %w", err)
+			o.logger.LoggingError(c, err, "failed to delete oauth client", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Client deleted successfully",
+		})
+	}
+}
+
+func (o *oauthClientHandler) GetConsents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		consents, err := o.oauthService.GetUserConsents(c, userID)
+		if err != nil {
+			err = fmt.Errorf("oauthClientHandler.GetConsents: %w", err)
+			o.logger.LoggingError(c, err, "failed to get oauth consents", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := make([]response.OAuthConsentResponse, len(consents))
+		for i, consent := range consents {
+			res[i] = response.OAuthConsentResponse{
+				ClientID:   consent.ClientID,
+				ClientName: consent.Client.Name,
+				Scopes:     strings.Fields(consent.Scopes),
+				CreatedAt:  consent.CreatedAt,
+				UpdatedAt:  consent.UpdatedAt,
+			}
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (o *oauthClientHandler) RevokeConsent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := o.oauthService.RevokeConsent(c, userID, c.Param("client_id"))
+		if err != nil {
+			if errors.Is(err, apperrors.ErrConsentNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Consent not found",
+				})
+				return
+			}
+			err = fmt.Errorf("oauthClientHandler.RevokeConsent: %w", err)
+			o.logger.LoggingError(c, err, "failed to revoke oauth consent", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Access revoked successfully",
+		})
+	}
+}
+
+func NewOAuthClientHandler(oauthService service.OAuthService, logger Logger) OAuthClientHandler {
+	return &oauthClientHandler{
+		oauthService: oauthService,
+		logger:       logger,
+	}
+}
@@ -0,0 +1,446 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type OAuthHandler interface {
+	Authorize() gin.HandlerFunc
+	GetAuthorizationPrompt() gin.HandlerFunc
+	Consent() gin.HandlerFunc
+	Token() gin.HandlerFunc
+	Introspect() gin.HandlerFunc
+	UserInfo() gin.HandlerFunc
+	GetProviderMetadata() gin.HandlerFunc
+}
+
+type oauthHandler struct {
+	oauthService service.OAuthService
+	logger       Logger
+}
+
+func (*oauthHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (*oauthHandler) toServiceRequest(req request.AuthorizationRequest) service.AuthorizationRequest {
+	return service.AuthorizationRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+	}
+}
+
+// authorizationError return the OAuth error code and description of an invalid authorization request,
+// ok is false if err is unexpected
+func (*oauthHandler) authorizationError(err error) (code string, description string, ok bool) {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidClient):
+		return "invalid_client", "Unknown client", true
+	case errors.Is(err, apperrors.ErrInvalidRedirectURI):
+		return "invalid_request", "The redirect_uri is not registered for the client", true
+	case errors.Is(err, apperrors.ErrUnsupportedResponseType):
+		return "unsupported_response_type", "Only the code response type is supported", true
+	case errors.Is(err, apperrors.ErrInvalidAuthorizationRequest):
+		return "invalid_request", "A code_challenge using the S256 method is required", true
+	case errors.Is(err, apperrors.ErrInvalidScopes):
+		return "invalid_scope", "The requested scopes are not allowed for the client", true
+	}
+	return "", "", false
+}
+
+func (o *oauthHandler) Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.AuthorizationRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			description := "Invalid request"
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				description = o.formatValidationError(validatorError[0])
+			}
+			c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+				Error:            "invalid_request",
+				ErrorDescription: description,
+			})
+			return
+		}
+		consentURL, err := o.oauthService.StartAuthorization(c, o.toServiceRequest(req))
+		if err != nil {
+			code, description, ok := o.authorizationError(err)
+			if !ok {
+				err = fmt.Errorf("oauthHandler.Authorize: %w", err)
+				o.logger.LoggingError(c, err, "failed to start authorization", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.OAuthErrorResponse{
+					Error: "server_error",
+				})
+				return
+			}
+			// the user is only sent back to the client once the redirect uri is known to belong to it
+			if errors.Is(err, apperrors.ErrInvalidClient) || errors.Is(err, apperrors.ErrInvalidRedirectURI) {
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            code,
+					ErrorDescription: description,
+				})
+				return
+			}
+			redirect, parseErr := url.Parse(req.RedirectURI)
+			if parseErr != nil {
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "invalid_request",
+					ErrorDescription: "Invalid redirect_uri",
+				})
+				return
+			}
+			query := redirect.Query()
+			query.Set("error", code)
+			query.Set("error_description", description)
+			if req.State != "" {
+				query.Set("state", req.State)
+			}
+			redirect.RawQuery = query.Encode()
+			c.Redirect(http.StatusFound, redirect.String())
+			return
+		}
+		c.Redirect(http.StatusFound, consentURL)
+	}
+}
+
+// respondAuthorizationError write the response of an invalid request made by the consent page, return false if err is unexpected
+func (o *oauthHandler) respondAuthorizationError(c *gin.Context, err error) bool {
+	if _, description, ok := o.authorizationError(err); ok {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: description,
+		})
+		return true
+	}
+	switch {
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, response.Response{
+			Message: "User not found",
+		})
+	case errors.Is(err, apperrors.ErrUserSuspended):
+		c.JSON(http.StatusForbidden, response.Response{
+			Message: "Account suspended",
+		})
+	case errors.Is(err, apperrors.ErrUserBanned):
+		c.JSON(http.StatusForbidden, response.Response{
+			Message: "Account banned",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+func (o *oauthHandler) GetAuthorizationPrompt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.AuthorizationRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: o.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		prompt, err := o.oauthService.GetAuthorizationPrompt(c, userID, o.toServiceRequest(req))
+		if err != nil {
+			if o.respondAuthorizationError(c, err) {
+				return
+			}
+			err = fmt.Errorf("oauthHandler.GetAuthorizationPrompt: %w", err)
+			o.logger.LoggingError(c, err, "failed to get authorization prompt", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.AuthorizationPromptResponse{
+			ClientID:   prompt.Client.ID,
+			ClientName: prompt.Client.Name,
+			Scopes:     prompt.Scopes,
+			Consented:  prompt.Consented,
+		})
+	}
+}
+
+func (o *oauthHandler) Consent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.ConsentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: o.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		redirectURI, err := o.oauthService.Authorize(c, userID, o.toServiceRequest(req.AuthorizationRequest), req.Approve)
+		if err != nil {
+			if o.respondAuthorizationError(c, err) {
+				return
+			}
+			err = fmt.Errorf("oauthHandler.Consent: %w", err)
+			o.logger.LoggingError(c, err, "failed to authorize client", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.AuthorizationRedirectResponse{
+			RedirectURI: redirectURI,
+		})
+	}
+}
+
+// clientCredentials return the credentials of the client from basic auth, or the ones sent in the body
+func (*oauthHandler) clientCredentials(c *gin.Context, bodyID string, bodySecret string) (string, string) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return bodyID, bodySecret
+	}
+	// the credentials are form-urlencoded before being put in the header, see RFC 6749 section 2.3.1
+	clientID, _ := url.QueryUnescape(id)
+	clientSecret, _ := url.QueryUnescape(secret)
+	return clientID, clientSecret
+}
+
+func (*oauthHandler) respondInvalidClient(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	c.JSON(http.StatusUnauthorized, response.OAuthErrorResponse{
+		Error: "invalid_client",
+	})
+}
+
+func (o *oauthHandler) Token() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.OAuthTokenRequest
+		if err := c.ShouldBind(&req); err != nil {
+			description := "Invalid request body"
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				description = o.formatValidationError(validatorError[0])
+			}
+			c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+				Error:            "invalid_request",
+				ErrorDescription: description,
+			})
+			return
+		}
+		clientID, clientSecret := o.clientCredentials(c, req.ClientID, req.ClientSecret)
+		tokens, err := o.oauthService.Token(c, service.TokenRequest{
+			GrantType:    req.GrantType,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Code:         req.Code,
+			RedirectURI:  req.RedirectURI,
+			CodeVerifier: req.CodeVerifier,
+			RefreshToken: req.RefreshToken,
+			Scope:        req.Scope,
+		}, service.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrUnsupportedGrantType):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error: "unsupported_grant_type",
+				})
+			case errors.Is(err, apperrors.ErrInvalidClient):
+				o.respondInvalidClient(c)
+			case errors.Is(err, apperrors.ErrUnauthorizedClient):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "unauthorized_client",
+					ErrorDescription: "The client is not allowed to use this grant",
+				})
+			case errors.Is(err, apperrors.ErrInvalidScopes):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "invalid_scope",
+					ErrorDescription: "The requested scopes are not allowed for the client",
+				})
+			case errors.Is(err, apperrors.ErrInvalidGrant):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "invalid_grant",
+					ErrorDescription: "The code or refresh token is invalid, expired or was issued to another client",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound),
+				errors.Is(err, apperrors.ErrUserSuspended),
+				errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+					Error:            "invalid_grant",
+					ErrorDescription: "The account can not sign in",
+				})
+			default:
+				err = fmt.Errorf("oauthHandler.Token: %w", err)
+				o.logger.LoggingError(c, err, "failed to issue oauth token", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.OAuthErrorResponse{
+					Error: "server_error",
+				})
+			}
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, response.TokenResponse{
+			AccessToken:  tokens.AccessToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    int(tokens.AccessTokenTTL.Seconds()),
+			RefreshToken: tokens.RefreshToken,
+			IDToken:      tokens.IDToken,
+			Scope:        strings.Join(tokens.Scopes, " "),
+		})
+	}
+}
+
+func (o *oauthHandler) Introspect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.IntrospectionRequest
+		if err := c.ShouldBind(&req); err != nil {
+			description := "Invalid request body"
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				description = o.formatValidationError(validatorError[0])
+			}
+			c.JSON(http.StatusBadRequest, response.OAuthErrorResponse{
+				Error:            "invalid_request",
+				ErrorDescription: description,
+			})
+			return
+		}
+		clientID, clientSecret := o.clientCredentials(c, req.ClientID, req.ClientSecret)
+		introspection, err := o.oauthService.Introspect(c, clientID, clientSecret, req.Token)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidClient):
+				o.respondInvalidClient(c)
+			case errors.Is(err, apperrors.ErrUnauthorizedClient):
+				c.JSON(http.StatusForbidden, response.OAuthErrorResponse{
+					Error:            "unauthorized_client",
+					ErrorDescription: "Only confidential clients can introspect tokens",
+				})
+			default:
+				err = fmt.Errorf("oauthHandler.Introspect: %w", err)
+				o.logger.LoggingError(c, err, "failed to introspect token", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.OAuthErrorResponse{
+					Error: "server_error",
+				})
+			}
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, response.IntrospectionResponse{
+			Active:    introspection.Active,
+			Scope:     strings.Join(introspection.Scopes, " "),
+			ClientID:  introspection.ClientID,
+			Subject:   introspection.Subject,
+			TokenType: introspection.TokenType,
+			JTI:       introspection.JTI,
+			IssuedAt:  introspection.IssuedAt,
+			ExpiresAt: introspection.ExpiresAt,
+		})
+	}
+}
+
+func (o *oauthHandler) UserInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := c.Value(middleware.AuthUserInfoContextKey).(service.AuthUserInfo)
+		user, err := o.oauthService.UserInfo(c, info.UserID)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusUnauthorized, response.OAuthErrorResponse{
+					Error: "invalid_token",
+				})
+				return
+			}
+			err = fmt.Errorf("oauthHandler.UserInfo: %w", err)
+			o.logger.LoggingError(c, err, "failed to get user info", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.OAuthErrorResponse{
+				Error: "server_error",
+			})
+			return
+		}
+		res := response.OIDCUserInfoResponse{
+			Subject: user.ID,
+		}
+		if info.HasScope(model.ScopeEmail) {
+			res.Email = user.Email
+			res.EmailVerified = &user.EmailVerified
+		}
+		if info.HasScope(model.ScopeProfile) {
+			res.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+			res.GivenName = user.FirstName
+			res.FamilyName = user.LastName
+			res.PreferredUsername = user.Username
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (o *oauthHandler) GetProviderMetadata() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		metadata := o.oauthService.Metadata()
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, response.ProviderMetadataResponse{
+			Issuer:                            metadata.Issuer,
+			AuthorizationEndpoint:             metadata.AuthorizationEndpoint,
+			TokenEndpoint:                     metadata.TokenEndpoint,
+			UserInfoEndpoint:                  metadata.UserInfoEndpoint,
+			IntrospectionEndpoint:             metadata.IntrospectionEndpoint,
+			JWKSURI:                           metadata.JWKSURI,
+			ScopesSupported:                   metadata.ScopesSupported,
+			ResponseTypesSupported:            metadata.ResponseTypesSupported,
+			GrantTypesSupported:               metadata.GrantTypesSupported,
+			SubjectTypesSupported:             metadata.SubjectTypesSupported,
+			IDTokenSigningAlgValuesSupported:  metadata.IDTokenSigningAlgValuesSupported,
+			TokenEndpointAuthMethodsSupported: metadata.TokenEndpointAuthMethodsSupported,
+			CodeChallengeMethodsSupported:     metadata.CodeChallengeMethodsSupported,
+			ClaimsSupported:                   metadata.ClaimsSupported,
+		})
+	}
+}
+
+func NewOAuthHandler(oauthService service.OAuthService, logger Logger) OAuthHandler {
+	return &oauthHandler{
+		oauthService: oauthService,
+		logger:       logger,
+	}
+}
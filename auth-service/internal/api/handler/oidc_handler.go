@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/oidc"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type OIDCHandler interface {
+	Authorize() gin.HandlerFunc
+	Callback() gin.HandlerFunc
+	LinkAuthorize() gin.HandlerFunc
+	LinkCallback() gin.HandlerFunc
+}
+
+type oidcHandler struct {
+	oidcService service.OIDCService
+	cookies     SessionCookies
+	logger      Logger
+}
+
+func (*oidcHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s characters", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (o *oidcHandler) Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deviceName := c.Query("device_name")
+		if len(deviceName) > 100 {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "The device_name parameter must be at most 100 characters",
+			})
+			return
+		}
+		authURL, err := o.oidcService.AuthorizationURL(c, deviceName)
+		if err != nil {
+			if errors.Is(err, oidc.ErrProvider) {
+				o.logger.LoggingError(c, fmt.Errorf("oidcHandler.Authorize: %w", err), "identity provider unavailable", zap.WarnLevel)
+				c.JSON(http.StatusBadGateway, response.Response{
+					Message: "Identity provider unavailable",
+				})
+				return
+			}
+			err = fmt.Errorf("oidcHandler.Authorize: %w", err)
+			o.logger.LoggingError(c, err, "failed to start oidc login", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.OIDCAuthorizationResponse{
+			AuthorizationURL: authURL,
+		})
+	}
+}
+
+func (o *oidcHandler) Callback() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.OIDCCallbackRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: o.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		client := service.ClientInfo{
+			DeviceName: req.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IP:         c.ClientIP(),
+		}
+		auth, challenge, err := o.oidcService.Callback(c, req.Code, req.State, client)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidOIDCState):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired login state",
+				})
+			case errors.Is(err, oidc.ErrInvalidIDToken):
+				o.logger.LoggingError(c, fmt.Errorf("oidcHandler.Callback: %w", err), "rejected id token", zap.WarnLevel)
+				c.JSON(http.StatusUnauthorized, response.Response{
+					Message: "Invalid identity token",
+				})
+			case errors.Is(err, oidc.ErrProvider):
+				o.logger.LoggingError(c, fmt.Errorf("oidcHandler.Callback: %w", err), "identity provider error", zap.WarnLevel)
+				c.JSON(http.StatusBadGateway, response.Response{
+					Message: "Identity provider rejected the login",
+				})
+			case errors.Is(err, apperrors.ErrOIDCEmailNotVerified):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "The identity provider did not verify your email",
+				})
+			case errors.Is(err, apperrors.ErrRegistrationClosed), errors.Is(err, apperrors.ErrInviteRequired):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Registration is not open, ask for an invite to create an account",
+				})
+			case errors.Is(err, apperrors.ErrOIDCAccountConflict), errors.Is(err, apperrors.ErrUserMailAlreadyExists),
+				errors.Is(err, apperrors.ErrIdentityAlreadyLinked):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "An account already uses this email, sign in with your password to continue",
+				})
+			case errors.Is(err, apperrors.ErrUserSuspended):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account suspended",
+				})
+			case errors.Is(err, apperrors.ErrUserBanned):
+				c.JSON(http.StatusForbidden, response.Response{
+					Message: "Account banned",
+				})
+			default:
+				err = fmt.Errorf("oidcHandler.Callback: %w", err)
+				o.logger.LoggingError(c, err, "failed to complete oidc login", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		respondLogin(c, o.cookies, auth, challenge)
+	}
+}
+
+func (o *oidcHandler) LinkAuthorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		authURL, err := o.oidcService.LinkAuthorizationURL(c, userID)
+		if err != nil {
+			if errors.Is(err, oidc.ErrProvider) {
+				o.logger.LoggingError(c, fmt.Errorf("oidcHandler.LinkAuthorize: %w", err), "identity provider unavailable", zap.WarnLevel)
+				c.JSON(http.StatusBadGateway, response.Response{
+					Message: "Identity provider unavailable",
+				})
+				return
+			}
+			err = fmt.Errorf("oidcHandler.LinkAuthorize: %w", err)
+			o.logger.LoggingError(c, err, "failed to start oidc link", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.OIDCAuthorizationResponse{
+			AuthorizationURL: authURL,
+		})
+	}
+}
+
+func (o *oidcHandler) LinkCallback() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.OIDCLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: o.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		identity, err := o.oidcService.CompleteLink(c, userID, req.Code, req.State)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidOIDCState):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired login state",
+				})
+			case errors.Is(err, oidc.ErrInvalidIDToken):
+				o.logger.LoggingError(c, fmt.Errorf("oidcHandler.LinkCallback: %w", err), "rejected id token", zap.WarnLevel)
+				c.JSON(http.StatusUnauthorized, response.Response{
+					Message: "Invalid identity token",
+				})
+			case errors.Is(err, oidc.ErrProvider):
+				o.logger.LoggingError(c, fmt.Errorf("oidcHandler.LinkCallback: %w", err), "identity provider error", zap.WarnLevel)
+				c.JSON(http.StatusBadGateway, response.Response{
+					Message: "Identity provider rejected the login",
+				})
+			case errors.Is(err, apperrors.ErrIdentityAlreadyLinked):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "This identity is already linked to another account",
+				})
+			default:
+				err = fmt.Errorf("oidcHandler.LinkCallback: %w", err)
+				o.logger.LoggingError(c, err, "failed to link oidc identity", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, toIdentityResponse(identity))
+	}
+}
+
+func NewOIDCHandler(oidcService service.OIDCService, cookies SessionCookies, logger Logger) OIDCHandler {
+	return &oidcHandler{
+		oidcService: oidcService,
+		cookies:     cookies,
+		logger:      logger,
+	}
+}
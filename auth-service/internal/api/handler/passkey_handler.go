@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type PasskeyHandler interface {
+	GetPasskeys() gin.HandlerFunc
+	RegistrationOptions() gin.HandlerFunc
+	RegisterPasskey() gin.HandlerFunc
+	DeletePasskey() gin.HandlerFunc
+	LoginOptions() gin.HandlerFunc
+	Login() gin.HandlerFunc
+	MFAOptions() gin.HandlerFunc
+	LoginMFA() gin.HandlerFunc
+}
+
+type passkeyHandler struct {
+	passkeyService service.PasskeyService
+	authService    service.AuthService
+	cookies        SessionCookies
+	logger         Logger
+}
+
+func (*passkeyHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s characters", err.Field(), err.Param())
+	case "uuid":
+		return fmt.Sprintf("The %s field is not a valid uuid", err.Field())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+// bindJSON write a 400 response and return false if the body is not a valid request
+func (p *passkeyHandler) bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		var validatorError validator.ValidationErrors
+		if errors.As(err, &validatorError) {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: p.formatValidationError(validatorError[0]),
+			})
+		} else {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "Invalid request body",
+			})
+		}
+		return false
+	}
+	return true
+}
+
+// respondLoginError write the response of a failed passkey login or MFA completion
+func (p *passkeyHandler) respondLoginError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidPasskeyChallenge):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid or expired passkey challenge",
+		})
+	case errors.Is(err, apperrors.ErrInvalidPasskey), errors.Is(err, apperrors.ErrUserNotFound):
+		c.JSON(http.StatusUnauthorized, response.Response{
+			Message: "Invalid passkey",
+		})
+	case errors.Is(err, apperrors.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, response.Response{
+			Message: "Invalid or expired MFA token",
+		})
+	case errors.Is(err, apperrors.ErrUserSuspended):
+		c.JSON(http.StatusForbidden, response.Response{
+			Message: "Account suspended",
+		})
+	case errors.Is(err, apperrors.ErrUserBanned):
+		c.JSON(http.StatusForbidden, response.Response{
+			Message: "Account banned",
+		})
+	default:
+		p.logger.LoggingError(c, err, message, zap.ErrorLevel)
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Message: "Internal server error",
+		})
+	}
+}
+
+func toPasskeyResponse(passkey model.Passkey) response.PasskeyResponse {
+	transports := []string{}
+	if passkey.Transports != "" {
+		transports = strings.Split(passkey.Transports, ",")
+	}
+	return response.PasskeyResponse{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		Transports: transports,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
+
+func (p *passkeyHandler) GetPasskeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		passkeys, err := p.passkeyService.GetPasskeys(c, userID)
+		if err != nil {
+			err = fmt.Errorf("passkeyHandler.GetPasskeys: %w", err)
+			p.logger.LoggingError(c, err, "failed to get passkeys", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := make([]response.PasskeyResponse, len(passkeys))
+		for i, passkey := range passkeys {
+			res[i] = toPasskeyResponse(passkey)
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (p *passkeyHandler) RegistrationOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		options, err := p.passkeyService.BeginRegistration(c, userID)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+				return
+			}
+			err = fmt.Errorf("passkeyHandler.RegistrationOptions: %w", err)
+			p.logger.LoggingError(c, err, "failed to start passkey registration", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.PasskeyCreationOptionsResponse{
+			PublicKey: options,
+		})
+	}
+}
+
+func (p *passkeyHandler) RegisterPasskey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.RegisterPasskeyRequest
+		if !p.bindJSON(c, &req) {
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		passkey, err := p.passkeyService.FinishRegistration(c, userID, req.Name, req.Credential)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidPasskeyChallenge):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired passkey challenge",
+				})
+			case errors.Is(err, apperrors.ErrInvalidPasskey):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid passkey",
+				})
+			case errors.Is(err, apperrors.ErrPasskeyAlreadyExists):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "Passkey already registered",
+				})
+			default:
+				err = fmt.Errorf("passkeyHandler.RegisterPasskey: %w", err)
+				p.logger.LoggingError(c, err, "failed to register passkey", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, toPasskeyResponse(passkey))
+	}
+}
+
+func (p *passkeyHandler) DeletePasskey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := p.passkeyService.DeletePasskey(c, userID, c.Param("id"))
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrPasskeyNotFound), errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Passkey not found",
+				})
+			case errors.Is(err, apperrors.ErrLastLoginMethod):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "You can not remove your last login method",
+				})
+			default:
+				err = fmt.Errorf("passkeyHandler.DeletePasskey: %w", err)
+				p.logger.LoggingError(c, err, "failed to delete passkey", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Passkey removed successfully",
+		})
+	}
+}
+
+func (p *passkeyHandler) LoginOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		challengeID, options, err := p.passkeyService.BeginLogin(c)
+		if err != nil {
+			err = fmt.Errorf("passkeyHandler.LoginOptions: %w", err)
+			p.logger.LoggingError(c, err, "failed to start passkey login", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.PasskeyRequestOptionsResponse{
+			ChallengeID: challengeID,
+			PublicKey:   options,
+		})
+	}
+}
+
+func (p *passkeyHandler) Login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.PasskeyLoginRequest
+		if !p.bindJSON(c, &req) {
+			return
+		}
+		client := service.ClientInfo{
+			DeviceName: req.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IP:         c.ClientIP(),
+		}
+		auth, err := p.authService.LoginWithPasskey(c, req.ChallengeID, req.Credential, client)
+		if err != nil {
+			p.respondLoginError(c, fmt.Errorf("passkeyHandler.Login: %w", err), "failed to login with passkey")
+			return
+		}
+		respondLogin(c, p.cookies, auth, nil)
+	}
+}
+
+func (p *passkeyHandler) MFAOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.MFAPasskeyOptionsRequest
+		if !p.bindJSON(c, &req) {
+			return
+		}
+		options, err := p.authService.BeginMFAPasskey(c, req.MFAToken)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusUnauthorized, response.Response{
+					Message: "Invalid or expired MFA token",
+				})
+			case errors.Is(err, apperrors.ErrPasskeyNotFound):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "No passkey registered",
+				})
+			default:
+				err = fmt.Errorf("passkeyHandler.MFAOptions: %w", err)
+				p.logger.LoggingError(c, err, "failed to start passkey verification", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, response.PasskeyRequestOptionsResponse{
+			PublicKey: options,
+		})
+	}
+}
+
+func (p *passkeyHandler) LoginMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.LoginMFAPasskeyRequest
+		if !p.bindJSON(c, &req) {
+			return
+		}
+		client := service.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		}
+		auth, err := p.authService.CompleteMFALoginWithPasskey(c, req.MFAToken, req.Credential, client)
+		if err != nil {
+			p.respondLoginError(c, fmt.Errorf("passkeyHandler.LoginMFA: %w", err), "failed to complete mfa login with passkey")
+			return
+		}
+		respondLogin(c, p.cookies, auth, nil)
+	}
+}
+
+func NewPasskeyHandler(passkeyService service.PasskeyService, authService service.AuthService, cookies SessionCookies, logger Logger) PasskeyHandler {
+	return &passkeyHandler{
+		passkeyService: passkeyService,
+		authService:    authService,
+		cookies:        cookies,
+		logger:         logger,
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type PasswordHandler interface {
+	ForgotPassword() gin.HandlerFunc
+	ResetPassword() gin.HandlerFunc
+}
+
+type passwordHandler struct {
+	passwordResetService service.PasswordResetService
+	auditor              SecurityAuditor
+	logger               Logger
+}
+
+func (*passwordHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "email":
+		return fmt.Sprintf("The %s field is not a valid email", err.Field())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (p *passwordHandler) ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: p.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		err := p.passwordResetService.RequestPasswordReset(c, req.Email, c.ClientIP())
+		if err != nil {
+			if respondRateLimited(c, err) {
+				return
+			}
+			err = fmt.Errorf("passwordHandler.ForgotPassword: %w", err)
+			p.logger.LoggingError(c, err, "failed to request password reset", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		// same response whether the email exists or not
+		c.JSON(http.StatusAccepted, response.Response{
+			Message: "If an account exists for this email, a reset link has been sent",
+		})
+	}
+}
+
+func (p *passwordHandler) ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: p.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		userID, err := p.passwordResetService.ResetPassword(c, req.Token, req.NewPassword)
+		if err != nil {
+			if respondPasswordRejected(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidToken):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid or expired reset token",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("passwordHandler.ResetPassword: %w", err)
+				p.logger.LoggingError(c, err, "failed to reset password", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		p.auditor.Record(c, model.SecurityEventPasswordReset, userID, nil)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Password reset successfully",
+		})
+	}
+}
+
+func NewPasswordHandler(passwordResetService service.PasswordResetService, auditor SecurityAuditor, logger Logger) PasswordHandler {
+	return &passwordHandler{
+		passwordResetService: passwordResetService,
+		auditor:              auditor,
+		logger:               logger,
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/response"
+	apperrors "auth-service/internal/error"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondPasswordRejected write a 400 response explaining why the new password was rejected if err is a password
+// policy or reuse error
+func respondPasswordRejected(c *gin.Context, err error) bool {
+	var policyErr *apperrors.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: policyErr.Reason,
+		})
+	case errors.Is(err, apperrors.ErrPasswordReused):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "The new password must differ from your last passwords",
+		})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type PersonalAccessTokenHandler interface {
+	CreateToken() gin.HandlerFunc
+	GetTokens() gin.HandlerFunc
+	DeleteToken() gin.HandlerFunc
+}
+
+type personalAccessTokenHandler struct {
+	patService service.PersonalAccessTokenService
+	logger     Logger
+}
+
+func (*personalAccessTokenHandler) formatValidationError(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return fmt.Sprintf("The %s field is required", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s", err.Field(), err.Param())
+	case "min":
+		return fmt.Sprintf("The %s field must be at least %s", err.Field(), err.Param())
+	default:
+		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
+	}
+}
+
+func (*personalAccessTokenHandler) toResponse(token model.PersonalAccessToken) response.PersonalAccessTokenResponse {
+	return response.PersonalAccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     strings.Fields(token.Scopes),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func (p *personalAccessTokenHandler) CreateToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.CreatePersonalAccessTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: p.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		token, plain, err := p.patService.CreateToken(c, userID, req.Name, req.Scopes, ttl)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrInvalidScopes):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Scopes must be granted by your role",
+				})
+			case errors.Is(err, apperrors.ErrTooManyTokens):
+				c.JSON(http.StatusConflict, response.Response{
+					Message: "Maximum number of tokens reached",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("personalAccessTokenHandler.CreateToken: %w", err)
+				p.logger.LoggingError(c, err, "failed to create personal access token", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		res := p.toResponse(token)
+		res.Token = plain
+		c.JSON(http.StatusCreated, res)
+	}
+}
+
+func (p *personalAccessTokenHandler) GetTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		tokens, err := p.patService.GetUserTokens(c, userID)
+		if err != nil {
+			err = fmt.Errorf("personalAccessTokenHandler.GetTokens: %w", err)
+			p.logger.LoggingError(c, err, "failed to get personal access tokens", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		res := make([]response.PersonalAccessTokenResponse, len(tokens))
+		for i, token := range tokens {
+			res[i] = p.toResponse(token)
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (p *personalAccessTokenHandler) DeleteToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := p.patService.DeleteUserToken(c, userID, c.Param("id"))
+		if err != nil {
+			if errors.Is(err, apperrors.ErrPersonalAccessTokenNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Token not found",
+				})
+				return
+			}
+			err = fmt.Errorf("personalAccessTokenHandler.DeleteToken: %w", err)
+			p.logger.LoggingError(c, err, "failed to delete personal access token", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Token revoked successfully",
+		})
+	}
+}
+
+func NewPersonalAccessTokenHandler(patService service.PersonalAccessTokenService, logger Logger) PersonalAccessTokenHandler {
+	return &personalAccessTokenHandler{
+		patService: patService,
+		logger:     logger,
+	}
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/request"
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// ProfileHandler let users pick their username and avatar, and anyone see the public profile of a user
+type ProfileHandler interface {
+	ChangeUsername() gin.HandlerFunc
+	SetAvatar() gin.HandlerFunc
+	DeleteAvatar() gin.HandlerFunc
+	GetPublicProfile() gin.HandlerFunc
+}
+
+type profileHandler struct {
+	profileService service.ProfileService
+	auditor        SecurityAuditor
+	logger         Logger
+}
+
+// respondUsernameRejected write a 400 or 409 response if err tells why a username could not be picked
+func respondUsernameRejected(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidUsername):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "The username must be 3 to 30 letters, digits or underscores and not be reserved",
+		})
+	case errors.Is(err, apperrors.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, response.Response{
+			Message: "Username already taken",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *profileHandler) ChangeUsername() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.ChangeUsernameRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "The username field is required",
+			})
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := p.profileService.ChangeUsername(c, userID, req.Username)
+		if err != nil {
+			if respondUsernameRejected(c, err) {
+				return
+			}
+			var rateLimitErr *apperrors.RateLimitError
+			switch {
+			case errors.As(err, &rateLimitErr):
+				retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+				c.Header("Retry-After", strconv.Itoa(retryAfter))
+				c.JSON(http.StatusTooManyRequests, response.Response{
+					Message: "The username was changed recently, please try again later",
+				})
+			case errors.Is(err, apperrors.ErrUserNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+			default:
+				err = fmt.Errorf("profileHandler.ChangeUsername: %w", err)
+				p.logger.LoggingError(c, err, "failed to change username", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		oldUsername, _ := claims["username"].(string)
+		p.auditor.Record(c, model.SecurityEventUsernameChanged, userID, map[string]string{
+			"old_username": oldUsername,
+			"new_username": req.Username,
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Username changed successfully",
+		})
+	}
+}
+
+func (p *profileHandler) SetAvatar() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fileHeader, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "The image field is required",
+			})
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err = p.profileService.SetAvatar(c, userID, fileHeader)
+		if err != nil {
+			if errors.Is(err, apperrors.ErrInvalidAvatar) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "The avatar must be a JPEG, PNG, GIF or WebP image and not be too large",
+				})
+				return
+			}
+			err = fmt.Errorf("profileHandler.SetAvatar: %w", err)
+			p.logger.LoggingError(c, err, "failed to set avatar", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Avatar updated successfully",
+		})
+	}
+}
+
+func (p *profileHandler) DeleteAvatar() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := p.profileService.DeleteAvatar(c, userID)
+		if err != nil {
+			err = fmt.Errorf("profileHandler.DeleteAvatar: %w", err)
+			p.logger.LoggingError(c, err, "failed to delete avatar", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Avatar deleted successfully",
+		})
+	}
+}
+
+func (p *profileHandler) GetPublicProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, avatarURL, err := p.profileService.GetPublicProfile(c, c.Param("username"))
+		if err != nil {
+			if errors.Is(err, apperrors.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "User not found",
+				})
+				return
+			}
+			err = fmt.Errorf("profileHandler.GetPublicProfile: %w", err)
+			p.logger.LoggingError(c, err, "failed to get public profile", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.PublicUserResponse{
+			Username:  user.Username,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Bio:       user.Bio,
+			AvatarURL: avatarURL,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+}
+
+func NewProfileHandler(profileService service.ProfileService, auditor SecurityAuditor, logger Logger) ProfileHandler {
+	return &profileHandler{
+		profileService: profileService,
+		auditor:        auditor,
+		logger:         logger,
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/response"
+	apperrors "auth-service/internal/error"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondRateLimited write a 429 response with the Retry-After header if err is a rate limit error
+func respondRateLimited(c *gin.Context, err error) bool {
+	var rateLimitErr *apperrors.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return false
+	}
+	retryAfter := int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, response.Response{
+		Message: "Too many requests, please try again later",
+	})
+	return true
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// SecurityAuditor record in the audit log the events performed by the authenticated user of the request,
+// a failure is only logged so the action itself is not reported as failed
+type SecurityAuditor interface {
+	Record(c *gin.Context, eventType string, userID string, details map[string]string)
+}
+
+type securityAuditor struct {
+	securityEventService service.SecurityEventService
+	logger               Logger
+}
+
+func (s *securityAuditor) Record(c *gin.Context, eventType string, userID string, details map[string]string) {
+	event := model.SecurityEvent{
+		Type:      eventType,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   details,
+	}
+	if userID != "" {
+		event.UserID = &userID
+	}
+	if claims, ok := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims); ok {
+		if actorID, _ := claims["user_id"].(string); actorID != "" && actorID != userID {
+			event.ActorID = &actorID
+		}
+	}
+	err := s.securityEventService.Record(c, event)
+	if err != nil {
+		err = fmt.Errorf("securityAuditor.Record: %w", err)
+		s.logger.LoggingError(c, err, "failed to record security event "+eventType, zap.ErrorLevel)
+	}
+}
+
+func NewSecurityAuditor(securityEventService service.SecurityEventService, logger Logger) SecurityAuditor {
+	return &securityAuditor{
+		securityEventService: securityEventService,
+		logger:               logger,
+	}
+}
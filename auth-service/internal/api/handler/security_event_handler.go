@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+// SecurityEventHandler expose the audit log to admins, and to each user for their own account
+type SecurityEventHandler interface {
+	GetEvents() gin.HandlerFunc
+	GetMyEvents() gin.HandlerFunc
+}
+
+type securityEventHandler struct {
+	securityEventService service.SecurityEventService
+	logger               Logger
+}
+
+// bindPagination read the limit and offset query parameters, the response is written if they are invalid
+func (*securityEventHandler) bindPagination(c *gin.Context) (int, int, bool) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Offset must be an integer",
+		})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Limit must be an integer",
+		})
+		return 0, 0, false
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return limit, offset, true
+}
+
+// bindTime read the RFC 3339 time of the query parameter key, the response is written if it is invalid
+func (*securityEventHandler) bindTime(c *gin.Context, key string) (time.Time, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: fmt.Sprintf("The %s parameter must be a RFC 3339 time", key),
+		})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (*securityEventHandler) toResponse(events []model.SecurityEvent) []response.SecurityEventResponse {
+	res := make([]response.SecurityEventResponse, len(events))
+	for i, event := range events {
+		res[i] = response.SecurityEventResponse{
+			ID:        event.ID,
+			Type:      event.Type,
+			UserID:    event.UserID,
+			ActorID:   event.ActorID,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Details:   event.Details,
+			CreatedAt: event.CreatedAt,
+		}
+	}
+	return res
+}
+
+func (s *securityEventHandler) GetEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := s.bindPagination(c)
+		if !ok {
+			return
+		}
+		from, ok := s.bindTime(c, "from")
+		if !ok {
+			return
+		}
+		to, ok := s.bindTime(c, "to")
+		if !ok {
+			return
+		}
+		events, err := s.securityEventService.GetEvents(c, model.SecurityEventFilter{
+			UserID: c.Query("user_id"),
+			Type:   c.Query("type"),
+			From:   from,
+			To:     to,
+		}, limit, offset)
+		if err != nil {
+			err = fmt.Errorf("securityEventHandler.GetEvents: %w", err)
+			s.logger.LoggingError(c, err, "failed to get security events", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, s.toResponse(events))
+	}
+}
+
+func (s *securityEventHandler) GetMyEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, offset, ok := s.bindPagination(c)
+		if !ok {
+			return
+		}
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		events, err := s.securityEventService.GetUserEvents(c, userID, limit, offset)
+		if err != nil {
+			err = fmt.Errorf("securityEventHandler.GetMyEvents: %w", err)
+			s.logger.LoggingError(c, err, "failed to get security events", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, s.toResponse(events))
+	}
+}
+
+func NewSecurityEventHandler(securityEventService service.SecurityEventService, logger Logger) SecurityEventHandler {
+	return &securityEventHandler{
+		securityEventService: securityEventService,
+		logger:               logger,
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/service"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	refreshTokenCookie = "refresh_token"
+	// refreshTokenCookiePath scope the refresh token to the only route that needs it
+	refreshTokenCookiePath = "/auth/refresh"
+)
+
+type SessionCookieConfig struct {
+	// Enabled issue the access token in an HttpOnly cookie instead of the response body, with a CSRF token
+	Enabled bool
+	Secure  bool
+	Domain  string
+	// SameSite is one of strict, lax or none
+	SameSite string
+}
+
+// SessionCookies write the tokens of the sessions of browsers in cookies. The refresh token is always a cookie,
+// the access token only in the cookie session mode
+type SessionCookies interface {
+	// Enabled report whether the cookie session mode is enabled
+	Enabled() bool
+	// RespondSession set the cookies of a new or refreshed session and write the response
+	RespondSession(c *gin.Context, auth service.AuthenticationResponse)
+	// ClearSession delete the cookies of the session
+	ClearSession(c *gin.Context)
+}
+
+type sessionCookies struct {
+	cfg      SessionCookieConfig
+	sameSite http.SameSite
+	logger   Logger
+}
+
+func (s *sessionCookies) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+func (s *sessionCookies) setCookie(c *gin.Context, name string, value string, path string, ttl time.Duration, httpOnly bool) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.cfg.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: s.sameSite,
+	})
+}
+
+func (*sessionCookies) generateCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *sessionCookies) RespondSession(c *gin.Context, auth service.AuthenticationResponse) {
+	if !s.cfg.Enabled {
+		s.setCookie(c, refreshTokenCookie, auth.RefreshToken, refreshTokenCookiePath, auth.RefreshTokenTTL, true)
+		c.JSON(http.StatusOK, response.AuthenticationResponse{
+			AccessToken: auth.AccessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(auth.AccessTokenTTL.Seconds()),
+		})
+		return
+	}
+	csrfToken, err := s.generateCSRFToken()
+	if err != nil {
+		err = fmt.Errorf("sessionCookies.RespondSession: %w", err)
+		s.logger.LoggingError(c, err, "failed to generate csrf token", zap.ErrorLevel)
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Message: "Internal server error",
+		})
+		return
+	}
+	s.setCookie(c, refreshTokenCookie, auth.RefreshToken, refreshTokenCookiePath, auth.RefreshTokenTTL, true)
+	s.setCookie(c, middleware.AccessTokenCookie, auth.AccessToken, "/", auth.AccessTokenTTL, true)
+	// the CSRF token lives as long as the session so it is still there when the access token is refreshed
+	s.setCookie(c, middleware.CSRFTokenCookie, csrfToken, "/", auth.RefreshTokenTTL, false)
+	c.JSON(http.StatusOK, response.AuthenticationResponse{
+		TokenType: "Cookie",
+		ExpiresIn: int(auth.AccessTokenTTL.Seconds()),
+		CSRFToken: csrfToken,
+	})
+}
+
+func (s *sessionCookies) ClearSession(c *gin.Context) {
+	// the cookies of both modes are deleted, a session may have started before the mode changed
+	s.setCookie(c, refreshTokenCookie, "", refreshTokenCookiePath, -1, true)
+	s.setCookie(c, middleware.AccessTokenCookie, "", "/", -1, true)
+	s.setCookie(c, middleware.CSRFTokenCookie, "", "/", -1, false)
+}
+
+// NewSessionCookies return an error for an unknown SameSite mode
+func NewSessionCookies(cfg SessionCookieConfig, logger Logger) (SessionCookies, error) {
+	var sameSite http.SameSite
+	switch cfg.SameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "lax":
+		sameSite = http.SameSiteLaxMode
+	case "none":
+		// browsers reject SameSite=None cookies that are not Secure
+		if !cfg.Secure {
+			return nil, fmt.Errorf("handler.NewSessionCookies: SameSite none requires secure cookies")
+		}
+		sameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("handler.NewSessionCookies: unknown SameSite mode %q", cfg.SameSite)
+	}
+	return &sessionCookies{cfg: cfg, sameSite: sameSite, logger: logger}, nil
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"auth-service/internal/api/dto/response"
+	"auth-service/internal/api/middleware"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+type SessionHandler interface {
+	GetSessions() gin.HandlerFunc
+	RevokeSession() gin.HandlerFunc
+	RevokeAllSessions() gin.HandlerFunc
+}
+
+type sessionHandler struct {
+	authService service.AuthService
+	cookies     SessionCookies
+	auditor     SecurityAuditor
+	logger      Logger
+}
+
+func (s *sessionHandler) GetSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		currentSessionID, _ := claims["sid"].(string)
+		sessions, err := s.authService.GetSessions(c, userID)
+		if err != nil {
+			err = fmt.Errorf("sessionHandler.GetSessions: %w", err)
+			s.logger.LoggingError(c, err, "failed to get user sessions", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		})
+		sessionsRes := make([]response.SessionResponse, len(sessions))
+		for i, session := range sessions {
+			sessionsRes[i] = response.SessionResponse{
+				ID:         session.ID,
+				DeviceName: session.DeviceName,
+				UserAgent:  session.UserAgent,
+				IP:         session.IP,
+				CreatedAt:  session.CreatedAt,
+				LastUsedAt: session.LastUsedAt,
+				Current:    session.ID == currentSessionID,
+				ClientID:   session.ClientID,
+			}
+		}
+		c.JSON(http.StatusOK, sessionsRes)
+	}
+}
+
+func (s *sessionHandler) RevokeSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		sessionID := c.Param("id")
+		err := s.authService.RevokeSession(c, userID, sessionID)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrSessionNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Message: "Session not found",
+				})
+			default:
+				err = fmt.Errorf("sessionHandler.RevokeSession: %w", err)
+				s.logger.LoggingError(c, err, "failed to revoke session", zap.ErrorLevel)
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Message: "Internal server error",
+				})
+			}
+			return
+		}
+		s.auditor.Record(c, model.SecurityEventSessionRevoked, userID, map[string]string{
+			"session_id": sessionID,
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Session revoked successfully",
+		})
+	}
+}
+
+func (s *sessionHandler) RevokeAllSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+		userID := claims["user_id"].(string)
+		err := s.authService.LogoutAll(c, userID)
+		if err != nil {
+			err = fmt.Errorf("sessionHandler.RevokeAllSessions: %w", err)
+			s.logger.LoggingError(c, err, "failed to revoke all sessions", zap.ErrorLevel)
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Message: "Internal server error",
+			})
+			return
+		}
+		s.auditor.Record(c, model.SecurityEventLogoutAll, userID, nil)
+		s.cookies.ClearSession(c)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "Signed out of all sessions successfully",
+		})
+	}
+}
+
+func NewSessionHandler(authService service.AuthService, cookies SessionCookies, auditor SecurityAuditor, logger Logger) SessionHandler {
+	return &sessionHandler{
+		authService: authService,
+		cookies:     cookies,
+		auditor:     auditor,
+		logger:      logger,
+	}
+}
@@ -7,10 +7,13 @@ import (
 	apperrors "auth-service/internal/error"
 	"auth-service/internal/model"
 	"auth-service/internal/service"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -24,11 +27,38 @@ type UserHandler interface {
 	UpdateUserPassword() gin.HandlerFunc
 	UpdateUserInfo() gin.HandlerFunc
 	GetUsers() gin.HandlerFunc
+	SuspendUser() gin.HandlerFunc
+	BanUser() gin.HandlerFunc
+	UnbanUser() gin.HandlerFunc
+	ChangeUserRole() gin.HandlerFunc
+	DeleteUser() gin.HandlerFunc
+	ExportUsers() gin.HandlerFunc
 }
 
 type userHandler struct {
-	userService service.UserService
-	logger      Logger
+	userService    service.UserService
+	profileService service.ProfileService
+	cookies        SessionCookies
+	auditor        SecurityAuditor
+	logger         Logger
+}
+
+func (*userHandler) toResponse(user model.User, now time.Time) response.UserInfoResponse {
+	return response.UserInfoResponse{
+		ID:                  user.ID,
+		Email:               user.Email,
+		Username:            user.Username,
+		FirstName:           user.FirstName,
+		LastName:            user.LastName,
+		Bio:                 user.Bio,
+		Role:                user.Role,
+		EmailVerified:       user.EmailVerified,
+		Status:              user.EffectiveStatus(now),
+		SuspendedUntil:      user.SuspendedUntil,
+		StatusReason:        user.StatusReason,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		CreatedAt:           user.CreatedAt,
+	}
 }
 
 func (u *userHandler) GetMe() gin.HandlerFunc {
@@ -50,12 +80,11 @@ func (u *userHandler) GetMe() gin.HandlerFunc {
 			}
 			return
 		}
-		userRes := response.UserInfoResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Role:      user.Role,
+		userRes := u.toResponse(user, time.Now())
+		userRes.AvatarURL, err = u.profileService.GetAvatarURL(c, userID)
+		if err != nil && !errors.Is(err, apperrors.ErrAvatarNotFound) {
+			// the profile is still useful without the avatar
+			u.logger.LoggingError(c, err, "failed to get avatar url", zap.WarnLevel)
 		}
 		c.JSON(http.StatusOK, userRes)
 	}
@@ -79,13 +108,7 @@ func (u *userHandler) GetUserByID() gin.HandlerFunc {
 			}
 			return
 		}
-		userRes := response.UserInfoResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Role:      user.Role,
-		}
+		userRes := u.toResponse(user, time.Now())
 		c.JSON(http.StatusOK, userRes)
 	}
 }
@@ -110,6 +133,9 @@ func (u *userHandler) UpdateUserPassword() gin.HandlerFunc {
 		userId := claims["user_id"].(string)
 		err := u.userService.UpdateUserPassword(c, userId, req.CurrentPassword, req.NewPassword)
 		if err != nil {
+			if respondPasswordRejected(c, err) {
+				return
+			}
 			switch {
 			case errors.Is(err, apperrors.ErrUserNotFound):
 				c.JSON(http.StatusNotFound, response.Response{
@@ -128,6 +154,9 @@ func (u *userHandler) UpdateUserPassword() gin.HandlerFunc {
 			}
 			return
 		}
+		u.auditor.Record(c, model.SecurityEventPasswordChanged, userId, nil)
+		// every token has been revoked, the user has to log in again
+		u.cookies.ClearSession(c)
 		c.JSON(http.StatusOK, response.Response{
 			Message: "Password updated successfully",
 		})
@@ -159,15 +188,18 @@ func (u *userHandler) UpdateUserInfo() gin.HandlerFunc {
 			LastName:  req.LastName,
 		}
 		err := u.userService.UpdateUserByID(c, updatedData)
+		if err == nil && req.Bio != nil {
+			err = u.profileService.UpdateBio(c, userId, *req.Bio)
+		}
 		if err != nil {
 			switch {
 			case errors.Is(err, apperrors.ErrUserNotFound):
 				c.JSON(http.StatusNotFound, response.Response{
 					Message: "User not found",
 				})
-			case errors.Is(err, apperrors.ErrUserMailAlreadyExists):
-				c.JSON(http.StatusConflict, response.Response{
-					Message: "User mail already exists",
+			case errors.Is(err, apperrors.ErrEmailChangeRequiresConfirmation):
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "The email address must be changed with POST /users/me/email, which requires a confirmation",
 				})
 			default:
 				err = fmt.Errorf("userHandler.UpdateUserInfo: %w", err)
@@ -184,40 +216,85 @@ func (u *userHandler) UpdateUserInfo() gin.HandlerFunc {
 	}
 }
 
-func (u *userHandler) GetUsers() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		email := c.Query("email")
-		offset := c.DefaultQuery("offset", "0")
-		o, err := strconv.Atoi(offset)
+// bindUserQuery read the filters and sort of the user search from the query string, the response is written if they are invalid
+func (u *userHandler) bindUserQuery(c *gin.Context) (model.UserQuery, bool) {
+	query := model.UserQuery{
+		Email:  c.Query("email"),
+		Name:   c.Query("name"),
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+		SortBy: c.DefaultQuery("sort_by", model.UserSortCreatedAt),
+	}
+	if query.Role != "" && !model.IsValidRole(query.Role) {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid role",
+		})
+		return model.UserQuery{}, false
+	}
+	if query.Status != "" && !model.IsValidUserStatus(query.Status) {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid status",
+		})
+		return model.UserQuery{}, false
+	}
+	if !model.IsUserSortField(query.SortBy) {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid sort field",
+		})
+		return model.UserQuery{}, false
+	}
+	switch c.DefaultQuery("sort_order", "asc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid sort order",
+		})
+		return model.UserQuery{}, false
+	}
+	for key, t := range map[string]*time.Time{"created_from": &query.CreatedFrom, "created_to": &query.CreatedTo} {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, response.Response{
-				Message: "Offset must be an integer",
+				Message: fmt.Sprintf("The %s parameter must be a RFC 3339 time", key),
 			})
+			return model.UserQuery{}, false
+		}
+		*t = parsed
+	}
+	return query, true
+}
+
+func (u *userHandler) GetUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := u.bindUserQuery(c)
+		if !ok {
 			return
 		}
-		limit := c.DefaultQuery("limit", "10")
-		l, err := strconv.Atoi(limit)
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, response.Response{
 				Message: "Limit must be an integer",
 			})
 			return
 		}
-		if o < 0 {
-			o = 0
-		}
-		if l <= 0 {
-			l = 10
+		if limit <= 0 || limit > 100 {
+			limit = 20
 		}
-		sortOrder := c.DefaultQuery("sort_order", "asc")
-		if sortOrder != "asc" && sortOrder != "desc" {
-			c.JSON(http.StatusBadRequest, response.Response{
-				Message: "Invalid sort order",
-			})
-			return
-		}
-		users, err := u.userService.GetUsers(c, email, sortOrder, l, o)
+		query.Limit = limit
+		page, err := u.userService.SearchUsers(c, query, c.Query("cursor"))
 		if err != nil {
+			if errors.Is(err, apperrors.ErrInvalidCursor) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid cursor",
+				})
+				return
+			}
 			err = fmt.Errorf("userHandler.GetUsers: %w", err)
 			u.logger.LoggingError(c, err, "failed to get users", zap.ErrorLevel)
 			c.JSON(http.StatusInternalServerError, response.Response{
@@ -225,17 +302,67 @@ func (u *userHandler) GetUsers() gin.HandlerFunc {
 			})
 			return
 		}
-		usersRes := make([]response.UserInfoResponse, len(users))
-		for i, user := range users {
-			usersRes[i] = response.UserInfoResponse{
-				ID:        user.ID,
-				FirstName: user.FirstName,
-				LastName:  user.LastName,
-				Email:     user.Email,
-			}
+		now := time.Now()
+		usersRes := make([]response.UserInfoResponse, len(page.Users))
+		for i, user := range page.Users {
+			usersRes[i] = u.toResponse(user, now)
 		}
-		c.JSON(http.StatusOK, usersRes)
+		c.JSON(http.StatusOK, response.UserListResponse{
+			Users:      usersRes,
+			Total:      page.Total,
+			NextCursor: page.NextCursor,
+		})
+	}
+}
+
+// ExportUsers stream as CSV every user matching the same filters as GetUsers
+func (u *userHandler) ExportUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, ok := u.bindUserQuery(c)
+		if !ok {
+			return
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="users.csv"`)
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		header := []string{"id", "email", "username", "first_name", "last_name", "role", "status", "email_verified", "created_at"}
+		if err := w.Write(header); err != nil {
+			u.logger.LoggingError(c, fmt.Errorf("userHandler.ExportUsers: %w", err), "failed to export users", zap.ErrorLevel)
+			return
+		}
+		now := time.Now()
+		err := u.userService.ExportUsers(c, query, func(user model.User) error {
+			return w.Write([]string{
+				user.ID,
+				csvSafe(user.Email),
+				user.Username,
+				csvSafe(user.FirstName),
+				csvSafe(user.LastName),
+				user.Role,
+				user.EffectiveStatus(now),
+				strconv.FormatBool(user.EmailVerified),
+				user.CreatedAt.UTC().Format(time.RFC3339),
+			})
+		})
+		w.Flush()
+		if err == nil {
+			err = w.Error()
+		}
+		if err != nil {
+			// the status has already been sent, the truncated file is the only sign of the failure
+			err = fmt.Errorf("userHandler.ExportUsers: %w", err)
+			u.logger.LoggingError(c, err, "failed to export users", zap.ErrorLevel)
+		}
+	}
+}
+
+// csvSafe prevent a value typed by a user from being run as a formula by spreadsheet software
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
 	}
+	return value
 }
 
 func (u *userHandler) formatValidationError(err validator.FieldError) string {
@@ -244,14 +371,169 @@ func (u *userHandler) formatValidationError(err validator.FieldError) string {
 		return fmt.Sprintf("The %s field is required", err.Field())
 	case "email":
 		return fmt.Sprintf("The %s field is not a valid email", err.Field())
+	case "max":
+		return fmt.Sprintf("The %s field must be at most %s characters long", err.Field(), err.Param())
+	case "oneof":
+		return fmt.Sprintf("The %s field must be one of %s", err.Field(), err.Param())
 	default:
 		return fmt.Sprintf("Validation failed for %s with tag %s.", err.Field(), err.Tag())
 	}
 }
 
-func NewUserHandler(userService service.UserService, logger Logger) UserHandler {
+// bindAdminRequest bind the request body and reject admins acting on their own account,
+// it writes the error response and returns false if the request can not proceed
+func (u *userHandler) bindAdminRequest(c *gin.Context, req any) bool {
+	if req != nil {
+		if err := c.ShouldBindJSON(req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: u.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "Invalid request body",
+				})
+			}
+			return false
+		}
+	}
+	claims := c.Value(middleware.JWTClaimsContextKey).(jwt.MapClaims)
+	if claims["user_id"].(string) == c.Param("id") {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "You can not perform this action on your own account",
+		})
+		return false
+	}
+	return true
+}
+
+// handleAdminError write the response matching the error of an admin action
+func (u *userHandler) handleAdminError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, response.Response{
+			Message: "User not found",
+		})
+	case errors.Is(err, apperrors.ErrInvalidRoles):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Message: "Invalid role",
+		})
+	default:
+		u.logger.LoggingError(c, err, msg, zap.ErrorLevel)
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Message: "Internal server error",
+		})
+	}
+}
+
+func (u *userHandler) SuspendUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.SuspendUserRequest
+		if !u.bindAdminRequest(c, &req) {
+			return
+		}
+		if !req.Until.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Message: "The Until field must be in the future",
+			})
+			return
+		}
+		err := u.userService.SuspendUser(c, c.Param("id"), req.Until, req.Reason)
+		if err != nil {
+			u.handleAdminError(c, fmt.Errorf("userHandler.SuspendUser: %w", err), "failed to suspend user")
+			return
+		}
+		u.auditor.Record(c, model.SecurityEventUserSuspended, c.Param("id"), map[string]string{
+			"until":  req.Until.Format(time.RFC3339),
+			"reason": req.Reason,
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "User suspended successfully",
+		})
+	}
+}
+
+func (u *userHandler) BanUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.BanUserRequest
+		if !u.bindAdminRequest(c, &req) {
+			return
+		}
+		err := u.userService.BanUser(c, c.Param("id"), req.Reason)
+		if err != nil {
+			u.handleAdminError(c, fmt.Errorf("userHandler.BanUser: %w", err), "failed to ban user")
+			return
+		}
+		u.auditor.Record(c, model.SecurityEventUserBanned, c.Param("id"), map[string]string{
+			"reason": req.Reason,
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "User banned successfully",
+		})
+	}
+}
+
+func (u *userHandler) UnbanUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !u.bindAdminRequest(c, nil) {
+			return
+		}
+		err := u.userService.UnbanUser(c, c.Param("id"))
+		if err != nil {
+			u.handleAdminError(c, fmt.Errorf("userHandler.UnbanUser: %w", err), "failed to unban user")
+			return
+		}
+		u.auditor.Record(c, model.SecurityEventUserUnbanned, c.Param("id"), nil)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "User unbanned successfully",
+		})
+	}
+}
+
+func (u *userHandler) ChangeUserRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.ChangeUserRoleRequest
+		if !u.bindAdminRequest(c, &req) {
+			return
+		}
+		err := u.userService.ChangeUserRole(c, c.Param("id"), req.Role)
+		if err != nil {
+			u.handleAdminError(c, fmt.Errorf("userHandler.ChangeUserRole: %w", err), "failed to change user role")
+			return
+		}
+		u.auditor.Record(c, model.SecurityEventRoleChanged, c.Param("id"), map[string]string{
+			"role": req.Role,
+		})
+		c.JSON(http.StatusOK, response.Response{
+			Message: "User role updated successfully",
+		})
+	}
+}
+
+func (u *userHandler) DeleteUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !u.bindAdminRequest(c, nil) {
+			return
+		}
+		err := u.userService.DeleteUser(c, c.Param("id"))
+		if err != nil {
+			u.handleAdminError(c, fmt.Errorf("userHandler.DeleteUser: %w", err), "failed to delete user")
+			return
+		}
+		u.auditor.Record(c, model.SecurityEventUserDeleted, c.Param("id"), nil)
+		c.JSON(http.StatusOK, response.Response{
+			Message: "User deleted successfully",
+		})
+	}
+}
+
+func NewUserHandler(userService service.UserService, profileService service.ProfileService, cookies SessionCookies, auditor SecurityAuditor, logger Logger) UserHandler {
 	return &userHandler{
-		userService: userService,
-		logger:      logger,
+		userService:    userService,
+		profileService: profileService,
+		cookies:        cookies,
+		auditor:        auditor,
+		logger:         logger,
 	}
 }
@@ -2,7 +2,11 @@ package middleware
 
 import (
 	"auth-service/internal/api/dto/response"
+	apperrors "auth-service/internal/error"
 	"auth-service/internal/jwt"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -11,57 +15,186 @@ import (
 )
 
 type AuthMiddleware interface {
+	// ValidateAndExtractJwt authenticate the bearer token of the Authorization header, or the access token cookie
+	// of the cookie session mode whose state-changing requests must carry the CSRF token
 	ValidateAndExtractJwt() gin.HandlerFunc
-	CheckUserRole(requiredRole string) gin.HandlerFunc
+	// RequireScopes reject tokens that do not grant every one of the scopes
+	RequireScopes(scopes ...string) gin.HandlerFunc
+	// RequireSessionToken reject personal access tokens and tokens granted to third-party apps,
+	// for the actions that need an interactive login
+	RequireSessionToken() gin.HandlerFunc
+	// RejectClientToken reject tokens granted to third-party apps, which only authenticate to the APIs they were
+	// granted scopes of and not through the forward authentication of the proxy
+	RejectClientToken() gin.HandlerFunc
 }
 
 const (
-	JWTClaimsContextKey = "JWTClaimsContextKey"
+	JWTClaimsContextKey    = "JWTClaimsContextKey"
+	AuthUserInfoContextKey = "AuthUserInfoContextKey"
+	// AccessTokenContextKey holds the raw token the request was authenticated with, whether from the header or the cookie
+	AccessTokenContextKey = "AccessTokenContextKey"
+	// TokenFromCookieContextKey is set to true when the access token was read from the session cookie
+	TokenFromCookieContextKey = "TokenFromCookieContextKey"
 )
 
 type authMiddleware struct {
-	jwt jwt.Utils
+	jwt               jwt.Utils
+	revocationService service.RevocationService
+	patService        service.PersonalAccessTokenService
+}
+
+// extractToken return the bearer token of the Authorization header, or the access token cookie of the cookie session
+// mode when there is no header. It aborts the request and return false if there is no valid token
+func (*authMiddleware) extractToken(c *gin.Context) (token string, fromCookie bool, ok bool) {
+	authHeader := c.GetHeader("Authorization")
+	if len(authHeader) == 0 {
+		cookie, err := c.Cookie(AccessTokenCookie)
+		if err != nil || cookie == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Authorization header is empty"})
+			return "", false, false
+		}
+		return cookie, true, true
+	}
+	header := strings.Fields(authHeader)
+	if len(header) != 2 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Authorization header is invalid"})
+		return "", false, false
+	}
+	if header[0] != "Bearer" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Authorization header is invalid"})
+		return "", false, false
+	}
+	return header[1], false, true
 }
 
 func (a *authMiddleware) ValidateAndExtractJwt() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if len(authHeader) == 0 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Authorization header is empty"})
+		accessToken, fromCookie, ok := a.extractToken(c)
+		if !ok {
 			return
 		}
-		header := strings.Fields(authHeader)
-		if len(header) != 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Authorization header is invalid"})
-			return
+		if fromCookie {
+			// the browser sends the cookie with any cross-site request, only the frontend can read the CSRF token
+			if !CheckCSRF(c, c.Request.Method) {
+				c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Invalid CSRF token"})
+				return
+			}
+			c.Set(TokenFromCookieContextKey, true)
 		}
-		if header[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Authorization header is invalid"})
+		c.Set(AccessTokenContextKey, accessToken)
+		if strings.HasPrefix(accessToken, model.PersonalAccessTokenPrefix) {
+			a.authenticatePersonalAccessToken(c, accessToken)
 			return
 		}
-		accessToken := header[1]
-		claims, err := a.jwt.VerifyToken(accessToken)
+		claims, err := a.jwt.VerifyToken(accessToken, jwt.TokenTypeAccess)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Invalid access token"})
 			return
 		}
+		err = a.revocationService.CheckAccessToken(c, claims)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrTokenRevoked):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Access token has been revoked"})
+			case errors.Is(err, apperrors.ErrUserSuspended):
+				c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Account suspended"})
+			case errors.Is(err, apperrors.ErrUserBanned):
+				c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Account banned"})
+			default:
+				c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{Message: "Internal server error"})
+			}
+			return
+		}
+		userID, _ := claims["user_id"].(string)
+		scope, _ := claims["scope"].(string)
 		c.Set(JWTClaimsContextKey, claims)
+		c.Set(AuthUserInfoContextKey, service.AuthUserInfo{
+			UserID:     userID,
+			UserScopes: strings.Fields(scope),
+		})
 		c.Next()
 	}
 }
 
-func (a *authMiddleware) CheckUserRole(requiredRole string) gin.HandlerFunc {
+// authenticatePersonalAccessToken set the same context values as an access token would, with claims built from the user
+func (a *authMiddleware) authenticatePersonalAccessToken(c *gin.Context, token string) {
+	user, scopes, err := a.patService.Authenticate(c, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidToken), errors.Is(err, apperrors.ErrUserNotFound):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Invalid access token"})
+		case errors.Is(err, apperrors.ErrUserSuspended):
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Account suspended"})
+		case errors.Is(err, apperrors.ErrUserBanned):
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Account banned"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, response.Response{Message: "Internal server error"})
+		}
+		return
+	}
+	claims := jwt2.MapClaims{
+		"typ":            jwt.TokenTypePersonalAccess,
+		"user_id":        user.ID,
+		"role":           user.Role,
+		"scope":          strings.Join(scopes, " "),
+		"email_verified": user.EmailVerified,
+	}
+	if user.Username != "" {
+		claims["username"] = user.Username
+	}
+	c.Set(JWTClaimsContextKey, claims)
+	c.Set(AuthUserInfoContextKey, service.AuthUserInfo{
+		UserID:     user.ID,
+		UserScopes: scopes,
+	})
+	c.Next()
+}
+
+func (a *authMiddleware) RequireSessionToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims := c.Value(JWTClaimsContextKey).(jwt2.MapClaims)
-		role := claims["role"].(string)
-		if role != requiredRole {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{Message: "Permission denied"})
+		if claims["typ"] == jwt.TokenTypePersonalAccess {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Personal access tokens can not be used for this action"})
 			return
 		}
+		if isClientToken(claims) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Tokens granted to third-party apps can not be used for this action"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (a *authMiddleware) RejectClientToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.Value(JWTClaimsContextKey).(jwt2.MapClaims)
+		if isClientToken(claims) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Tokens granted to third-party apps can not be used for this action"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// isClientToken report whether the access token was granted to a third-party app rather than issued to a session of the user
+func isClientToken(claims jwt2.MapClaims) bool {
+	_, ok := claims["client_id"]
+	return ok
+}
+
+func (a *authMiddleware) RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := c.Value(AuthUserInfoContextKey).(service.AuthUserInfo)
+		for _, scope := range scopes {
+			if !info.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, response.Response{Message: "Permission denied"})
+				return
+			}
+		}
 		c.Next()
 	}
 }
 
-func NewAuthMiddleware(jwt jwt.Utils) AuthMiddleware {
-	return &authMiddleware{jwt: jwt}
+func NewAuthMiddleware(jwt jwt.Utils, revocationService service.RevocationService, patService service.PersonalAccessTokenService) AuthMiddleware {
+	return &authMiddleware{jwt: jwt, revocationService: revocationService, patService: patService}
 }
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookies of the cookie session mode, the CSRF token is readable by the frontend which sends it back in CSRFHeader
+const (
+	AccessTokenCookie = "access_token"
+	CSRFTokenCookie   = "csrf_token"
+	CSRFHeader        = "X-CSRF-Token"
+)
+
+// CheckCSRF report whether a request with method authenticated by cookie carries the double-submit CSRF token.
+// Safe methods do not change state and are always allowed
+func CheckCSRF(c *gin.Context, method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	cookie, err := c.Cookie(CSRFTokenCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(c.GetHeader(CSRFHeader))) == 1
+}
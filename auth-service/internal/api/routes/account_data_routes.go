@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpAccountDataRoutes(r *gin.Engine, h handler.AccountDataHandler, m middleware.AuthMiddleware) {
+	accountRoutes := r.Group("/users/me", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	accountRoutes.POST("/export", h.RequestExport())
+	accountRoutes.GET("/export/:id", h.GetExport())
+	accountRoutes.DELETE("", h.ScheduleDeletion())
+	accountRoutes.POST("/deletion/cancel", h.CancelDeletion())
+}
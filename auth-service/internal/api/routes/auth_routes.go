@@ -11,7 +11,10 @@ func SetUpAuthRoutes(r *gin.Engine, handler handler.AuthHandler, m middleware.Au
 	authRoutes := r.Group("/auth")
 	authRoutes.POST("/register", handler.Register())
 	authRoutes.POST("/login", handler.Login())
+	authRoutes.POST("/login/mfa", handler.LoginMFA())
 	authRoutes.POST("/logout", m.ValidateAndExtractJwt(), handler.Logout())
 	authRoutes.POST("/refresh", handler.Refresh())
-	authRoutes.GET("/verify", m.ValidateAndExtractJwt(), handler.VerifyToken())
+	authRoutes.GET("/verify", m.ValidateAndExtractJwt(), m.RejectClientToken(), handler.VerifyToken())
+	authRoutes.POST("/verify-email", handler.VerifyEmail())
+	authRoutes.POST("/verify-email/resend", m.ValidateAndExtractJwt(), handler.ResendVerificationEmail())
 }
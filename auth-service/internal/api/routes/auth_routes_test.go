@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/jwt"
+	"auth-service/internal/model"
+	"auth-service/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt2 "github.com/golang-jwt/jwt"
+)
+
+// fakeRevocationService revoke no token
+type fakeRevocationService struct {
+	service.RevocationService
+}
+
+func (f *fakeRevocationService) CheckAccessToken(context.Context, jwt2.MapClaims) error {
+	return nil
+}
+
+func TestVerifyRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys, err := jwt.LoadKeySet(t.TempDir(), "", jwt.AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils := jwt.NewJwtUtils(keys, "https://auth.example.com", time.Minute, time.Hour)
+	user := model.User{ID: "alice", Role: model.RoleUser, Username: "alice", EmailVerified: true}
+	sessionToken, err := utils.CreateAccessToken(user, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientToken, err := utils.CreateClientAccessToken(user, "session", "third-party-app", []string{model.ScopeProfile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	SetUpAuthRoutes(r, handler.NewAuthHandler(nil, nil, nil, nil, nil), middleware.NewAuthMiddleware(utils, &fakeRevocationService{}, nil))
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "session token", token: sessionToken.Token, wantStatus: http.StatusNoContent},
+		{name: "token granted to a third-party app", token: clientToken.Token, wantStatus: http.StatusForbidden},
+		{name: "invalid token", token: "invalid", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/auth/verify", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET /auth/verify status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusNoContent && w.Header().Get("X-User-Id") != "alice" {
+				t.Errorf("GET /auth/verify X-User-Id = %q, want %q", w.Header().Get("X-User-Id"), "alice")
+			}
+			if tt.wantStatus != http.StatusNoContent && w.Header().Get("X-User-Id") != "" {
+				t.Errorf("GET /auth/verify X-User-Id = %q, want none", w.Header().Get("X-User-Id"))
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpDeviceAuthorizationRoutes(r *gin.Engine, h handler.DeviceAuthorizationHandler, m middleware.AuthMiddleware) {
+	deviceRoutes := r.Group("/auth/device")
+	deviceRoutes.POST("/code", h.RequestDeviceCode())
+	deviceRoutes.POST("/token", h.PollToken())
+
+	// the verification page is used by a signed in user to approve the user code displayed by the device
+	verificationRoutes := deviceRoutes.Group("", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	verificationRoutes.GET("/verification", h.GetAuthorization())
+	verificationRoutes.POST("/approve", h.Approve())
+	verificationRoutes.POST("/deny", h.Deny())
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpEmailChangeRoutes(r *gin.Engine, h handler.EmailChangeHandler, m middleware.AuthMiddleware) {
+	emailRoutes := r.Group("/users/me/email", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	emailRoutes.POST("", h.RequestEmailChange())
+	emailRoutes.GET("", h.GetPendingEmailChange())
+	emailRoutes.DELETE("", h.CancelPendingEmailChange())
+
+	// the links are opened from the emails, so they are not authenticated
+	emailChangeRoutes := r.Group("/auth/email-change")
+	emailChangeRoutes.POST("/confirm", h.ConfirmEmailChange())
+	emailChangeRoutes.POST("/cancel", h.CancelEmailChange())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpIdentityRoutes(r *gin.Engine, h handler.IdentityHandler, m middleware.AuthMiddleware) {
+	identityRoutes := r.Group("/users/me/identities", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	identityRoutes.GET("", h.GetLoginMethods())
+	identityRoutes.DELETE("/:id", h.UnlinkIdentity())
+
+	r.POST("/users/:id/merge", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.MergeUsers())
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpInviteRoutes(r *gin.Engine, h handler.InviteHandler, m middleware.AuthMiddleware) {
+	inviteRoutes := r.Group("/users/invites", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeInvitesWrite))
+	inviteRoutes.POST("", h.CreateInvite())
+	inviteRoutes.GET("", h.GetInvites())
+	inviteRoutes.GET("/:id", h.GetInvite())
+	inviteRoutes.DELETE("/:id", h.RevokeInvite())
+	inviteRoutes.GET("/:id/users", h.GetInvitedUsers())
+
+	r.GET("/users/:id/invite", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeInvitesWrite), h.GetUserInvite())
+}
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpJWKSRoutes(r *gin.Engine, h handler.JWKSHandler) {
+	r.GET("/.well-known/jwks.json", h.GetJWKS())
+}
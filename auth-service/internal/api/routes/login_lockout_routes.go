@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpLoginLockoutRoutes(r *gin.Engine, h handler.LoginLockoutHandler, m middleware.AuthMiddleware) {
+	lockoutRoutes := r.Group("/users/:id/lockouts", m.ValidateAndExtractJwt())
+	lockoutRoutes.GET("", m.RequireScopes(model.ScopeUsersRead), h.GetUserLockouts())
+	lockoutRoutes.DELETE("", m.RequireScopes(model.ScopeUsersWrite), h.ClearUserLockout())
+}
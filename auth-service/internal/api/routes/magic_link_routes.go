@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpMagicLinkRoutes(r *gin.Engine, h handler.MagicLinkHandler) {
+	magicLinkRoutes := r.Group("/auth/magic-link")
+	magicLinkRoutes.POST("", h.RequestMagicLink())
+	magicLinkRoutes.POST("/consume", h.ConsumeMagicLink())
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpMFARoutes(r *gin.Engine, h handler.MFAHandler, m middleware.AuthMiddleware) {
+	mfaRoutes := r.Group("/users/me/mfa", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	mfaRoutes.GET("", h.GetStatus())
+	mfaRoutes.POST("/totp", h.EnrollTOTP())
+	mfaRoutes.POST("/totp/confirm", h.ConfirmTOTP())
+	mfaRoutes.POST("/recovery-codes", h.RegenerateRecoveryCodes())
+	mfaRoutes.POST("/disable", h.Disable())
+	r.DELETE("/users/:id/mfa", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.ResetUserMFA())
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpOAuthRoutes(r *gin.Engine, h handler.OAuthHandler, m middleware.AuthMiddleware) {
+	r.GET("/.well-known/openid-configuration", h.GetProviderMetadata())
+
+	oauthRoutes := r.Group("/oauth")
+	oauthRoutes.GET("/authorize", h.Authorize())
+	oauthRoutes.POST("/token", h.Token())
+	oauthRoutes.POST("/introspect", h.Introspect())
+	oauthRoutes.GET("/userinfo", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeOpenID), h.UserInfo())
+	oauthRoutes.POST("/userinfo", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeOpenID), h.UserInfo())
+
+	// the consent page is used by a signed in user to approve the authorization request of a third-party app
+	consentRoutes := oauthRoutes.Group("/consent", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	consentRoutes.GET("", h.GetAuthorizationPrompt())
+	consentRoutes.POST("", h.Consent())
+}
+
+func SetUpOAuthClientRoutes(r *gin.Engine, h handler.OAuthClientHandler, m middleware.AuthMiddleware) {
+	clientRoutes := r.Group("/oauth/clients", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeClientsWrite))
+	clientRoutes.POST("", h.CreateClient())
+	clientRoutes.GET("", h.GetClients())
+	clientRoutes.GET("/:id", h.GetClient())
+	clientRoutes.DELETE("/:id", h.DeleteClient())
+
+	consentRoutes := r.Group("/users/me/consents", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	consentRoutes.GET("", h.GetConsents())
+	consentRoutes.DELETE("/:client_id", h.RevokeConsent())
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpOIDCRoutes(r *gin.Engine, h handler.OIDCHandler, m middleware.AuthMiddleware) {
+	oidcRoutes := r.Group("/auth/oidc")
+	oidcRoutes.GET("/authorize", h.Authorize())
+	oidcRoutes.POST("/callback", h.Callback())
+
+	linkRoutes := r.Group("/users/me/identities/oidc", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	linkRoutes.GET("/authorize", h.LinkAuthorize())
+	linkRoutes.POST("", h.LinkCallback())
+}
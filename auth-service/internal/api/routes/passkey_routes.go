@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpPasskeyRoutes(r *gin.Engine, h handler.PasskeyHandler, m middleware.AuthMiddleware) {
+	passkeyRoutes := r.Group("/users/me/passkeys", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	passkeyRoutes.GET("", h.GetPasskeys())
+	passkeyRoutes.POST("/options", h.RegistrationOptions())
+	passkeyRoutes.POST("", h.RegisterPasskey())
+	passkeyRoutes.DELETE("/:id", h.DeletePasskey())
+
+	authRoutes := r.Group("/auth")
+	authRoutes.POST("/passkey/options", h.LoginOptions())
+	authRoutes.POST("/passkey/login", h.Login())
+	authRoutes.POST("/login/mfa/passkey/options", h.MFAOptions())
+	authRoutes.POST("/login/mfa/passkey", h.LoginMFA())
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpPasswordRoutes(r *gin.Engine, h handler.PasswordHandler) {
+	passwordRoutes := r.Group("/auth/password")
+	passwordRoutes.POST("/forgot", h.ForgotPassword())
+	passwordRoutes.POST("/reset", h.ResetPassword())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpPersonalAccessTokenRoutes(r *gin.Engine, h handler.PersonalAccessTokenHandler, m middleware.AuthMiddleware) {
+	tokenRoutes := r.Group("/users/me/tokens", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	tokenRoutes.POST("", h.CreateToken())
+	tokenRoutes.GET("", h.GetTokens())
+	tokenRoutes.DELETE("/:id", h.DeleteToken())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpProfileRoutes(r *gin.Engine, h handler.ProfileHandler, m middleware.AuthMiddleware) {
+	profileRoutes := r.Group("/users/me", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	profileRoutes.PUT("/username", h.ChangeUsername())
+	profileRoutes.PUT("/avatar", h.SetAvatar())
+	profileRoutes.DELETE("/avatar", h.DeleteAvatar())
+
+	r.GET("/public/users/:username", h.GetPublicProfile())
+}
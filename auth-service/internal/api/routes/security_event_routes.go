@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+	"auth-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpSecurityEventRoutes(r *gin.Engine, h handler.SecurityEventHandler, m middleware.AuthMiddleware) {
+	r.GET("/users/security-events", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeAuditRead), h.GetEvents())
+	r.GET("/users/me/security-events", m.ValidateAndExtractJwt(), m.RequireSessionToken(), h.GetMyEvents())
+}
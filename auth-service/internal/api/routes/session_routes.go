@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"auth-service/internal/api/handler"
+	"auth-service/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetUpSessionRoutes(r *gin.Engine, h handler.SessionHandler, m middleware.AuthMiddleware) {
+	sessionRoutes := r.Group("/users/me/sessions", m.ValidateAndExtractJwt(), m.RequireSessionToken())
+	sessionRoutes.GET("", h.GetSessions())
+	sessionRoutes.DELETE("", h.RevokeAllSessions())
+	sessionRoutes.DELETE("/:id", h.RevokeSession())
+}
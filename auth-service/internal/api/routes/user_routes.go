@@ -10,9 +10,15 @@ import (
 
 func SetUpUserRoutes(r *gin.Engine, h handler.UserHandler, m middleware.AuthMiddleware) {
 	userRoutes := r.Group("/users")
-	userRoutes.GET("/:id", m.ValidateAndExtractJwt(), m.CheckUserRole(model.RoleAdmin), h.GetUserByID())
+	userRoutes.GET("/:id", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersRead), h.GetUserByID())
 	userRoutes.GET("/me", m.ValidateAndExtractJwt(), h.GetMe())
-	userRoutes.PUT("/me/password", m.ValidateAndExtractJwt(), h.UpdateUserPassword())
-	userRoutes.PATCH("/me", m.ValidateAndExtractJwt(), h.UpdateUserInfo())
-	userRoutes.GET("", m.ValidateAndExtractJwt(), m.CheckUserRole(model.RoleAdmin), h.GetUsers())
+	userRoutes.PUT("/me/password", m.ValidateAndExtractJwt(), m.RequireSessionToken(), h.UpdateUserPassword())
+	userRoutes.PATCH("/me", m.ValidateAndExtractJwt(), m.RequireSessionToken(), h.UpdateUserInfo())
+	userRoutes.GET("", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersRead), h.GetUsers())
+	userRoutes.GET("/export", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersRead), h.ExportUsers())
+	userRoutes.POST("/:id/suspend", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.SuspendUser())
+	userRoutes.POST("/:id/ban", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.BanUser())
+	userRoutes.POST("/:id/unban", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.UnbanUser())
+	userRoutes.PUT("/:id/role", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.ChangeUserRole())
+	userRoutes.DELETE("/:id", m.ValidateAndExtractJwt(), m.RequireScopes(model.ScopeUsersWrite), h.DeleteUser())
 }
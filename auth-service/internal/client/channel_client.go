@@ -0,0 +1,117 @@
+package client
+
+import (
+	apperrors "auth-service/internal/error"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type ChannelClient interface {
+	// TransferChannel give the channel of fromUserID to toUserID with the service token. apperrors.ErrChannelNotFound
+	// is returned if fromUserID has no channel and apperrors.ErrChannelConflict if toUserID already has one
+	TransferChannel(ctx context.Context, fromUserID string, toUserID string) error
+	// ExportUserData return the channel and streams of the user as the JSON document of channel-service
+	ExportUserData(ctx context.Context, userID string) (json.RawMessage, error)
+	// DeleteUserData delete the channel of the user, its avatar and streams. Nothing is done if the user has no channel
+	DeleteUserData(ctx context.Context, userID string) error
+}
+
+type channelClient struct {
+	client           *http.Client
+	channelServerURL string
+	tokenSource      ServiceTokenSource
+}
+
+func (c *channelClient) TransferChannel(ctx context.Context, fromUserID string, toUserID string) error {
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("channelClient.TransferChannel: %w", err)
+	}
+	requestURL := fmt.Sprintf("%s/channels/%s/transfer", c.channelServerURL, url.PathEscape(fromUserID))
+	body := struct {
+		ToUserID string `json:"to_user_id"`
+	}{
+		ToUserID: toUserID,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return fmt.Errorf("channelClient.TransferChannel: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, &buf)
+	if err != nil {
+		return fmt.Errorf("channelClient.TransferChannel: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("channelClient.TransferChannel: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("channelClient.TransferChannel: %w", apperrors.ErrChannelNotFound)
+	case http.StatusConflict:
+		return fmt.Errorf("channelClient.TransferChannel: %w", apperrors.ErrChannelConflict)
+	default:
+		return fmt.Errorf("channelClient.TransferChannel: channel service status %d", resp.StatusCode)
+	}
+}
+
+// doUserData call the user data endpoint of channel-service for userID with the service token
+func (c *channelClient) doUserData(ctx context.Context, method string, userID string) (*http.Response, error) {
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	requestURL := fmt.Sprintf("%s/users/%s/data", c.channelServerURL, url.PathEscape(userID))
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.client.Do(req)
+}
+
+func (c *channelClient) ExportUserData(ctx context.Context, userID string) (json.RawMessage, error) {
+	resp, err := c.doUserData(ctx, http.MethodGet, userID)
+	if err != nil {
+		return nil, fmt.Errorf("channelClient.ExportUserData: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("channelClient.ExportUserData: channel service status %d", resp.StatusCode)
+	}
+	var data json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("channelClient.ExportUserData: %w", err)
+	}
+	return data, nil
+}
+
+func (c *channelClient) DeleteUserData(ctx context.Context, userID string) error {
+	resp, err := c.doUserData(ctx, http.MethodDelete, userID)
+	if err != nil {
+		return fmt.Errorf("channelClient.DeleteUserData: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("channelClient.DeleteUserData: channel service status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func NewChannelClient(channelServerURL string, tokenSource ServiceTokenSource) ChannelClient {
+	return &channelClient{
+		client:           &http.Client{Timeout: 10 * time.Second},
+		channelServerURL: channelServerURL,
+		tokenSource:      tokenSource,
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type ChatClient interface {
+	// ExportUserMessages return the chat messages the user sent as the JSON document of chat-service
+	ExportUserMessages(ctx context.Context, userID string) (json.RawMessage, error)
+	// AnonymizeUserMessages detach the chat messages of the user from their account
+	AnonymizeUserMessages(ctx context.Context, userID string) error
+}
+
+type chatClient struct {
+	client        *http.Client
+	chatServerURL string
+	tokenSource   ServiceTokenSource
+}
+
+// doUserMessages call the user messages endpoint of chat-service for userID with the service token
+func (c *chatClient) doUserMessages(ctx context.Context, method string, userID string) (*http.Response, error) {
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	requestURL := fmt.Sprintf("%s/api/chat/users/%s/messages", c.chatServerURL, url.PathEscape(userID))
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.client.Do(req)
+}
+
+func (c *chatClient) ExportUserMessages(ctx context.Context, userID string) (json.RawMessage, error) {
+	resp, err := c.doUserMessages(ctx, http.MethodGet, userID)
+	if err != nil {
+		return nil, fmt.Errorf("chatClient.ExportUserMessages: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chatClient.ExportUserMessages: chat service status %d", resp.StatusCode)
+	}
+	var messages json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&messages); err != nil {
+		return nil, fmt.Errorf("chatClient.ExportUserMessages: %w", err)
+	}
+	return messages, nil
+}
+
+func (c *chatClient) AnonymizeUserMessages(ctx context.Context, userID string) error {
+	resp, err := c.doUserMessages(ctx, http.MethodDelete, userID)
+	if err != nil {
+		return fmt.Errorf("chatClient.AnonymizeUserMessages: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("chatClient.AnonymizeUserMessages: chat service status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func NewChatClient(chatServerURL string, tokenSource ServiceTokenSource) ChatClient {
+	return &chatClient{
+		// exporting every message of a user can take a while
+		client:        &http.Client{Timeout: time.Minute},
+		chatServerURL: chatServerURL,
+		tokenSource:   tokenSource,
+	}
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"auth-service/internal/jwt"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ServiceTokenSource return the service token auth-service calls the other backend services with.
+// auth-service signs it itself, the token is cached until shortly before it expires
+type ServiceTokenSource interface {
+	Token() (string, error)
+}
+
+type serviceTokenSource struct {
+	jwt      jwt.Utils
+	clientID string
+	scopes   []string
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// refreshMargin is how long before its expiry a cached token is replaced
+const refreshMargin = 30 * time.Second
+
+func (s *serviceTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.token != "" && now.Add(refreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+	token, err := s.jwt.CreateServiceToken(s.clientID, s.scopes, s.ttl)
+	if err != nil {
+		return "", fmt.Errorf("serviceTokenSource.Token: %w", err)
+	}
+	s.token = token.Token
+	s.expiresAt = now.Add(token.TTL)
+	return s.token, nil
+}
+
+func NewServiceTokenSource(jwt jwt.Utils, clientID string, scopes []string, ttl time.Duration) ServiceTokenSource {
+	return &serviceTokenSource{
+		jwt:      jwt,
+		clientID: clientID,
+		scopes:   scopes,
+		ttl:      ttl,
+	}
+}
@@ -8,15 +8,48 @@ import (
 )
 
 type AppConfig struct {
-	Server   ServerConfig
-	Postgres PostgresConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
+	Server              ServerConfig
+	SessionCookie       SessionCookieConfig
+	Postgres            PostgresConfig
+	Redis               RedisConfig
+	Minio               MinioConfig
+	JWT                 JWTConfig
+	Mail                MailConfig
+	EmailVerification   EmailVerificationConfig
+	PasswordReset       PasswordResetConfig
+	EmailChange         EmailChangeConfig
+	AccountData         AccountDataConfig
+	PasswordPolicy      PasswordPolicyConfig
+	Registration        RegistrationConfig
+	Profile             ProfileConfig
+	MagicLink           MagicLinkConfig
+	MFA                 MFAConfig
+	LoginProtection     LoginProtectionConfig
+	PersonalAccessToken PersonalAccessTokenConfig
+	OIDC                OIDCConfig
+	Passkey             PasskeyConfig
+	DeviceAuthorization DeviceAuthorizationConfig
+	OAuth               OAuthConfig
+	Services            ServicesConfig
 }
 
 type ServerConfig struct {
 	Port           string        `envconfig:"SERVER_PORT" default:"8080"`
 	UserSessionTTL time.Duration `envconfig:"USER_SESSION_TTL" default:"720h"`
+	// ReauthenticationMaxAge is how recently users without a password must have signed in to change their email
+	// or delete their account, users with a password type it again instead
+	ReauthenticationMaxAge time.Duration `envconfig:"REAUTHENTICATION_MAX_AGE" default:"10m"`
+	// FrontendURL is used to build the links sent by email
+	FrontendURL string `envconfig:"SERVER_FRONTEND_URL" default:"http://localhost:3000"`
+}
+
+// SessionCookieConfig is the cookie session mode, where browsers get the access token in an HttpOnly cookie
+type SessionCookieConfig struct {
+	Enabled bool   `envconfig:"SESSION_COOKIE_ENABLED" default:"false"`
+	Secure  bool   `envconfig:"SESSION_COOKIE_SECURE" default:"true"`
+	Domain  string `envconfig:"SESSION_COOKIE_DOMAIN"`
+	// SameSite is one of strict, lax or none
+	SameSite string `envconfig:"SESSION_COOKIE_SAME_SITE" default:"lax"`
 }
 
 type PostgresConfig struct {
@@ -32,10 +65,184 @@ type RedisConfig struct {
 	Port int    `envconfig:"REDIS_PORT" required:"true"`
 }
 
+// MinioConfig is the object storage the avatars of the users and their data exports are kept in
+type MinioConfig struct {
+	Endpoint     string `envconfig:"MINIO_ENDPOINT" required:"true"`
+	AccessKey    string `envconfig:"MINIO_ACCESS_KEY" required:"true"`
+	SecretKey    string `envconfig:"MINIO_SECRET_KEY" required:"true"`
+	AvatarBucket string `envconfig:"MINIO_AVATAR_BUCKET" default:"avatars"`
+	ExportBucket string `envconfig:"MINIO_EXPORT_BUCKET" default:"exports"`
+}
+
 type JWTConfig struct {
-	SecretKey       string        `envconfig:"JWT_SECRET_KEY" required:"true"`
-	AccessTokenTTL  time.Duration `envconfig:"JWT_ACCESS_TOKEN_TTL" default:"15m"`
-	RefreshTokenTTL time.Duration `envconfig:"JWT_REFRESH_TOKEN_TTL" default:"168h"`
+	// KeysDir contains the "<kid>.pem" PKCS#8 signing keys, a key is generated if it is empty
+	KeysDir          string        `envconfig:"JWT_KEYS_DIR" default:"./keys"`
+	ActiveKeyID      string        `envconfig:"JWT_ACTIVE_KEY_ID"`
+	SigningAlgorithm string        `envconfig:"JWT_SIGNING_ALGORITHM" default:"RS256"`
+	AccessTokenTTL   time.Duration `envconfig:"JWT_ACCESS_TOKEN_TTL" default:"15m"`
+	RefreshTokenTTL  time.Duration `envconfig:"JWT_REFRESH_TOKEN_TTL" default:"168h"`
+}
+
+type MailConfig struct {
+	// Driver is one of smtp, file or log
+	Driver       string `envconfig:"MAIL_DRIVER" default:"log"`
+	From         string `envconfig:"MAIL_FROM" default:"no-reply@livestream.local"`
+	SMTPHost     string `envconfig:"MAIL_SMTP_HOST" default:"localhost"`
+	SMTPPort     int    `envconfig:"MAIL_SMTP_PORT" default:"1025"`
+	SMTPUsername string `envconfig:"MAIL_SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"MAIL_SMTP_PASSWORD"`
+	FilePath     string `envconfig:"MAIL_FILE_PATH" default:"./mails.log"`
+}
+
+type EmailVerificationConfig struct {
+	TokenTTL time.Duration `envconfig:"EMAIL_VERIFICATION_TOKEN_TTL" default:"24h"`
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `envconfig:"PASSWORD_RESET_TOKEN_TTL" default:"30m"`
+	// EmailLimit and IPLimit are the number of reset requests allowed per LimitWindow
+	EmailLimit  int           `envconfig:"PASSWORD_RESET_EMAIL_LIMIT" default:"3"`
+	IPLimit     int           `envconfig:"PASSWORD_RESET_IP_LIMIT" default:"10"`
+	LimitWindow time.Duration `envconfig:"PASSWORD_RESET_LIMIT_WINDOW" default:"1h"`
+}
+
+type EmailChangeConfig struct {
+	TokenTTL time.Duration `envconfig:"EMAIL_CHANGE_TOKEN_TTL" default:"24h"`
+	// CancelTTL is how long the old address can cancel the change, which is reverted if it was already confirmed
+	CancelTTL time.Duration `envconfig:"EMAIL_CHANGE_CANCEL_TTL" default:"168h"`
+	// Limit is the number of changes a user can request per LimitWindow
+	Limit       int           `envconfig:"EMAIL_CHANGE_LIMIT" default:"3"`
+	LimitWindow time.Duration `envconfig:"EMAIL_CHANGE_LIMIT_WINDOW" default:"24h"`
+}
+
+// AccountDataConfig is how users download and delete their data
+type AccountDataConfig struct {
+	// ExportTTL is how long an export archive can be downloaded once it is built
+	ExportTTL time.Duration `envconfig:"DATA_EXPORT_TTL" default:"168h"`
+	// ExportURLTTL is the lifetime of the presigned download URLs given to clients
+	ExportURLTTL time.Duration `envconfig:"DATA_EXPORT_URL_TTL" default:"15m"`
+	// ExportLimit is the number of exports a user can request per ExportLimitWindow
+	ExportLimit       int           `envconfig:"DATA_EXPORT_LIMIT" default:"3"`
+	ExportLimitWindow time.Duration `envconfig:"DATA_EXPORT_LIMIT_WINDOW" default:"24h"`
+	// DeletionGracePeriod is the time a user has to cancel the deletion of their account
+	DeletionGracePeriod time.Duration `envconfig:"ACCOUNT_DELETION_GRACE_PERIOD" default:"720h"`
+	// SweepInterval is how often the accounts due for deletion and the expired exports are removed
+	SweepInterval time.Duration `envconfig:"ACCOUNT_DATA_SWEEP_INTERVAL" default:"1h"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength int `envconfig:"PASSWORD_MIN_LENGTH" default:"10"`
+	// MaxLength must stay under 72 bytes with bcrypt, which ignores the rest of longer passwords
+	MaxLength           int `envconfig:"PASSWORD_MAX_LENGTH" default:"128"`
+	MinCharacterClasses int `envconfig:"PASSWORD_MIN_CHARACTER_CLASSES" default:"3"`
+	// HistorySize is the number of last passwords a new password must differ from
+	HistorySize int `envconfig:"PASSWORD_HISTORY_SIZE" default:"5"`
+	// HashAlgorithm is bcrypt or argon2id, the passwords hashed otherwise are upgraded on the next login
+	HashAlgorithm     string `envconfig:"PASSWORD_HASH_ALGORITHM" default:"argon2id"`
+	BcryptCost        int    `envconfig:"PASSWORD_BCRYPT_COST" default:"12"`
+	Argon2Memory      uint32 `envconfig:"PASSWORD_ARGON2_MEMORY_KIB" default:"65536"`
+	Argon2Iterations  uint32 `envconfig:"PASSWORD_ARGON2_ITERATIONS" default:"3"`
+	Argon2Parallelism uint8  `envconfig:"PASSWORD_ARGON2_PARALLELISM" default:"2"`
+}
+
+type RegistrationConfig struct {
+	// Mode is open, invite_only or closed
+	Mode string `envconfig:"REGISTRATION_MODE" default:"open"`
+}
+
+type ProfileConfig struct {
+	// UsernameChangeCooldown is the time a user must wait before changing their username again
+	UsernameChangeCooldown time.Duration `envconfig:"USERNAME_CHANGE_COOLDOWN" default:"720h"`
+	// AvatarMaxSize is in bytes
+	AvatarMaxSize int64 `envconfig:"AVATAR_MAX_SIZE" default:"2097152"`
+	// AvatarURLTTL is the lifetime of the presigned avatar URLs given to clients
+	AvatarURLTTL time.Duration `envconfig:"AVATAR_URL_TTL" default:"15m"`
+}
+
+type MagicLinkConfig struct {
+	TokenTTL time.Duration `envconfig:"MAGIC_LINK_TOKEN_TTL" default:"10m"`
+	// EmailLimit and IPLimit are the number of links that can be requested per LimitWindow
+	EmailLimit  int           `envconfig:"MAGIC_LINK_EMAIL_LIMIT" default:"3"`
+	IPLimit     int           `envconfig:"MAGIC_LINK_IP_LIMIT" default:"10"`
+	LimitWindow time.Duration `envconfig:"MAGIC_LINK_LIMIT_WINDOW" default:"1h"`
+}
+
+type MFAConfig struct {
+	Issuer       string        `envconfig:"MFA_ISSUER" default:"LiveStreamPlatform"`
+	ChallengeTTL time.Duration `envconfig:"MFA_CHALLENGE_TTL" default:"5m"`
+	// MaxAttempts is the number of wrong codes allowed per AttemptWindow
+	MaxAttempts       int           `envconfig:"MFA_MAX_ATTEMPTS" default:"5"`
+	AttemptWindow     time.Duration `envconfig:"MFA_ATTEMPT_WINDOW" default:"5m"`
+	RecoveryCodeCount int           `envconfig:"MFA_RECOVERY_CODE_COUNT" default:"10"`
+}
+
+type LoginProtectionConfig struct {
+	FailureWindow    time.Duration `envconfig:"LOGIN_FAILURE_WINDOW" default:"15m"`
+	DelayThreshold   int           `envconfig:"LOGIN_DELAY_THRESHOLD" default:"3"`
+	BaseDelay        time.Duration `envconfig:"LOGIN_BASE_DELAY" default:"1s"`
+	MaxDelay         time.Duration `envconfig:"LOGIN_MAX_DELAY" default:"30s"`
+	LockoutThreshold int           `envconfig:"LOGIN_LOCKOUT_THRESHOLD" default:"10"`
+	LockoutDuration  time.Duration `envconfig:"LOGIN_LOCKOUT_DURATION" default:"15m"`
+	IPFailureLimit   int           `envconfig:"LOGIN_IP_FAILURE_LIMIT" default:"50"`
+}
+
+type PersonalAccessTokenConfig struct {
+	MaxPerUser       int           `envconfig:"PAT_MAX_PER_USER" default:"20"`
+	LastUsedInterval time.Duration `envconfig:"PAT_LAST_USED_INTERVAL" default:"1m"`
+}
+
+// OIDCConfig is the identity provider users can sign in with, the login is disabled when ClientID is empty
+type OIDCConfig struct {
+	ProviderName string `envconfig:"OIDC_PROVIDER_NAME" default:"casdoor"`
+	Issuer       string `envconfig:"OIDC_ISSUER" default:"http://localhost:8000"`
+	// BackchannelURL is the address of the provider from auth-service, when it differs from the issuer
+	BackchannelURL string        `envconfig:"OIDC_BACKCHANNEL_URL"`
+	ClientID       string        `envconfig:"OIDC_CLIENT_ID"`
+	ClientSecret   string        `envconfig:"OIDC_CLIENT_SECRET"`
+	RedirectURL    string        `envconfig:"OIDC_REDIRECT_URL" default:"http://localhost:3000/auth/oidc/callback"`
+	Scopes         []string      `envconfig:"OIDC_SCOPES" default:"openid,email,profile"`
+	StateTTL       time.Duration `envconfig:"OIDC_STATE_TTL" default:"10m"`
+}
+
+// PasskeyConfig is the WebAuthn relying party, Origins must list every origin the frontend is served from
+type PasskeyConfig struct {
+	RPID         string        `envconfig:"WEBAUTHN_RP_ID" default:"localhost"`
+	RPName       string        `envconfig:"WEBAUTHN_RP_NAME" default:"LiveStreamPlatform"`
+	Origins      []string      `envconfig:"WEBAUTHN_ORIGINS" default:"http://localhost:3000"`
+	ChallengeTTL time.Duration `envconfig:"WEBAUTHN_CHALLENGE_TTL" default:"5m"`
+}
+
+// DeviceAuthorizationConfig is the OAuth device flow used by TV and console apps
+type DeviceAuthorizationConfig struct {
+	// ClientIDs are the apps allowed to start a device authorization
+	ClientIDs []string      `envconfig:"DEVICE_CLIENT_IDS" default:"tv-player"`
+	CodeTTL   time.Duration `envconfig:"DEVICE_CODE_TTL" default:"10m"`
+	// PollInterval is the minimum time between two token requests of a device
+	PollInterval time.Duration `envconfig:"DEVICE_POLL_INTERVAL" default:"5s"`
+	// VerificationURL is the frontend page where users type the user code
+	VerificationURL string `envconfig:"DEVICE_VERIFICATION_URL" default:"http://localhost:3000/device"`
+	// IPLimit is the number of device codes an ip can request per LimitWindow,
+	// UserLimit the number of user codes a user can look up per LimitWindow
+	IPLimit     int           `envconfig:"DEVICE_CODE_IP_LIMIT" default:"30"`
+	UserLimit   int           `envconfig:"DEVICE_USER_CODE_LIMIT" default:"10"`
+	LimitWindow time.Duration `envconfig:"DEVICE_LIMIT_WINDOW" default:"15m"`
+}
+
+// OAuthConfig is the OpenID Connect provider third-party apps sign users in with
+type OAuthConfig struct {
+	// Issuer is the public URL auth-service is reached at, it is the iss claim of ID tokens
+	Issuer string `envconfig:"OAUTH_ISSUER" default:"http://localhost:8081"`
+	// ConsentURL is the frontend page where users approve the apps
+	ConsentURL string        `envconfig:"OAUTH_CONSENT_URL" default:"http://localhost:3000/oauth/consent"`
+	CodeTTL    time.Duration `envconfig:"OAUTH_CODE_TTL" default:"1m"`
+	// ServiceTokenTTL is the lifetime of the tokens backend services get with the client credentials grant
+	ServiceTokenTTL time.Duration `envconfig:"OAUTH_SERVICE_TOKEN_TTL" default:"5m"`
+}
+
+// ServicesConfig are the addresses of the other services of the platform
+type ServicesConfig struct {
+	ChannelServiceURL string `envconfig:"CHANNEL_SERVICE_URL" default:"http://channel-service:8080"`
+	ChatServiceURL    string `envconfig:"CHAT_SERVICE_URL" default:"http://chat-service:8001"`
 }
 
 func LoadConfig(path string) (AppConfig, error) {
@@ -1,12 +1,129 @@
 package apperrors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrUserNotFound          = errors.New("user not found")
 	ErrUserMailAlreadyExists = errors.New("user mail already exists")
 	ErrInvalidToken          = errors.New("invalid token")
-	ErrRefreshTokenNotFound  = errors.New("refresh token not found")
+	ErrTokenRevoked          = errors.New("token revoked")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrRefreshTokenReused    = errors.New("refresh token reused")
 	ErrInvalidPassword       = errors.New("invalid password")
 	ErrInvalidRoles          = errors.New("invalid roles")
+	ErrEmailAlreadyVerified  = errors.New("email already verified")
+	ErrMailDeliveryFailed    = errors.New("mail delivery failed")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrMFANotEnrolled        = errors.New("mfa not enrolled")
+	ErrMFAAlreadyEnabled     = errors.New("mfa already enabled")
+	ErrMFANotEnabled         = errors.New("mfa not enabled")
+	ErrInvalidMFACode        = errors.New("invalid mfa code")
+	ErrUserSuspended         = errors.New("user suspended")
+	ErrUserBanned            = errors.New("user banned")
+	ErrInvalidScopes         = errors.New("invalid scopes")
+	ErrTooManyTokens         = errors.New("too many tokens")
+
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+	ErrIdentityNotFound            = errors.New("identity not found")
+	ErrIdentityAlreadyLinked       = errors.New("identity already linked")
+	ErrInvalidOIDCState            = errors.New("invalid oidc state")
+	ErrOIDCEmailNotVerified        = errors.New("oidc email not verified")
+	// ErrOIDCAccountConflict is returned when an account with the same email exists but its email is not verified,
+	// linking it could hand it over to whoever registered the address first
+	ErrOIDCAccountConflict = errors.New("oidc account conflict")
+	ErrLastLoginMethod     = errors.New("last login method")
+	ErrMergeSameUser       = errors.New("cannot merge a user into the same user")
+	ErrChannelNotFound     = errors.New("channel not found")
+	// ErrChannelConflict is returned when both merged users own a channel
+	ErrChannelConflict         = errors.New("channel conflict")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists    = errors.New("passkey already registered")
+	ErrInvalidPasskey          = errors.New("invalid passkey")
+	ErrInvalidPasskeyChallenge = errors.New("invalid passkey challenge")
+	ErrInvalidClient           = errors.New("invalid client")
+	// ErrAuthorizationPending and ErrSlowDown are returned to a device polling for a user code not approved yet
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrDeviceAccessDenied   = errors.New("device access denied")
+	ErrInvalidDeviceCode    = errors.New("invalid device code")
+	ErrInvalidUserCode      = errors.New("invalid user code")
+	ErrUserCodeTaken        = errors.New("user code already in use")
+	// ErrDeviceAuthorizationChanged is returned when a device authorization has been approved or denied while being updated
+	ErrDeviceAuthorizationChanged = errors.New("device authorization changed")
+	ErrOAuthClientNotFound        = errors.New("oauth client not found")
+	// ErrInvalidRedirectURI is returned when the redirect uri is not registered for the client,
+	// the user must then not be redirected to it
+	ErrInvalidRedirectURI          = errors.New("invalid redirect uri")
+	ErrInvalidAuthorizationRequest = errors.New("invalid authorization request")
+	ErrUnsupportedResponseType     = errors.New("unsupported response type")
+	ErrUnsupportedGrantType        = errors.New("unsupported grant type")
+	// ErrInvalidGrant is returned when an authorization code or refresh token is invalid, expired or issued to another client
+	ErrInvalidGrant = errors.New("invalid grant")
+	// ErrUnauthorizedClient is returned when a public client uses a grant or endpoint reserved to confidential clients
+	ErrUnauthorizedClient = errors.New("unauthorized client")
+	ErrConsentNotFound    = errors.New("consent not found")
+
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrWeakPassword is matched by the *PasswordPolicyError returned when a new password breaks the password policy
+	ErrWeakPassword = errors.New("weak password")
+	// ErrPasswordReused is returned when a new password is one of the last passwords of the user
+	ErrPasswordReused = errors.New("password reused")
+
+	// ErrRegistrationClosed is returned when an account is created while the registration is closed
+	ErrRegistrationClosed = errors.New("registration closed")
+	// ErrInviteRequired is returned when an account is created without invite code while the registration is invite-only
+	ErrInviteRequired = errors.New("invite required")
+	// ErrInvalidInvite is returned when an invite code is unknown, expired, revoked or used up
+	ErrInvalidInvite  = errors.New("invalid invite")
+	ErrInviteNotFound = errors.New("invite not found")
+
+	ErrInvalidUsername = errors.New("invalid username")
+	// ErrUsernameTaken is returned when another user has the username, in any case
+	ErrUsernameTaken = errors.New("username already taken")
+	// ErrInvalidAvatar is returned when an avatar is not a supported image or is too large
+	ErrInvalidAvatar  = errors.New("invalid avatar")
+	ErrAvatarNotFound = errors.New("avatar not found")
+
+	// ErrEmailChangeRequiresConfirmation is returned when the email is changed without the email change flow
+	ErrEmailChangeRequiresConfirmation = errors.New("email change requires confirmation")
+	ErrEmailUnchanged                  = errors.New("email unchanged")
+	// ErrReauthenticationRequired is returned to users without a password who must sign in again before a sensitive change
+	ErrReauthenticationRequired = errors.New("reauthentication required")
+	// ErrEmailChangeNotFound is returned when there is no email change in the expected status
+	ErrEmailChangeNotFound = errors.New("email change not found")
+
+	ErrDataExportNotFound = errors.New("data export not found")
+	// ErrAccountDeletionNotScheduled is returned when cancelling the deletion of an account that is not to be deleted
+	ErrAccountDeletionNotScheduled = errors.New("account deletion not scheduled")
 )
+
+// RateLimitError is returned when too many requests have been made, it matches ErrRateLimited with errors.Is
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// PasswordPolicyError tells why a new password was rejected, it matches ErrWeakPassword with errors.Is
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, e.Reason)
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
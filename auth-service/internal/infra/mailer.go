@@ -0,0 +1,22 @@
+package infra
+
+import (
+	"auth-service/internal/config"
+	"auth-service/internal/mailer"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+func NewMailer(cfg config.MailConfig, log *zap.Logger) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.FilePath), nil
+	case "log":
+		return mailer.NewLogMailer(log), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,36 @@
+package infra
+
+import (
+	"auth-service/internal/config"
+	"context"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// NewMinioClient connect to the object storage and create the avatar and export buckets if they do not exist
+func NewMinioClient(cfg config.MinioConfig) (*minio.Client, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, bucket := range []string{cfg.AvatarBucket, cfg.ExportBucket} {
+		exists, err := client.BucketExists(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
@@ -0,0 +1,189 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is a private key identified by the kid header of the tokens it signs
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+}
+
+// JWK is the public part of a SigningKey, as described in RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet holds every key accepted for verification, one of them is the active signing key.
+//
+// Keys are rotated by adding a new key, making it active and removing the old one
+// once the tokens it signed have expired.
+type KeySet struct {
+	keys   map[string]SigningKey
+	active string
+}
+
+// LoadKeySet load every "<kid>.pem" PKCS#8 private key in dir.
+//
+// If activeKeyID is empty the last key in lexical order is used for signing, so date prefixed
+// kids rotate naturally. If dir has no key, a new one using algorithm is generated and written to dir.
+func LoadKeySet(dir string, activeKeyID string, algorithm string) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("jwt.LoadKeySet: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("jwt.LoadKeySet: %w", err)
+	}
+	if len(paths) == 0 {
+		path, err := generateKeyFile(dir, algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.LoadKeySet: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	ks := &KeySet{keys: make(map[string]SigningKey, len(paths))}
+	for _, path := range paths {
+		key, err := readKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.LoadKeySet: %w", err)
+		}
+		ks.keys[key.ID] = key
+		ks.active = key.ID
+	}
+	if activeKeyID != "" {
+		if _, ok := ks.keys[activeKeyID]; !ok {
+			return nil, fmt.Errorf("jwt.LoadKeySet: active key %q not found in %s", activeKeyID, dir)
+		}
+		ks.active = activeKeyID
+	}
+	return ks, nil
+}
+
+// Active return the key new tokens are signed with
+func (k *KeySet) Active() SigningKey {
+	return k.keys[k.active]
+}
+
+// Get return the key identified by kid
+func (k *KeySet) Get(kid string) (SigningKey, bool) {
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// JWKS return the public keys of the set
+func (k *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	res := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+		switch pub := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+	return res
+}
+
+func readKeyFile(path string) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, fmt.Errorf("%s: no PEM block found", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("%s: %w", path, err)
+	}
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return SigningKey{ID: kid, Method: jwt.SigningMethodRS256, PrivateKey: key}, nil
+	case ed25519.PrivateKey:
+		return SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, PrivateKey: key}, nil
+	default:
+		return SigningKey{}, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+}
+
+func generateKeyFile(dir string, algorithm string) (string, error) {
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = errors.New("unsupported signing algorithm " + algorithm)
+	}
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102150405")+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -2,7 +2,10 @@ package jwt
 
 import (
 	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -12,6 +15,7 @@ import (
 type AccessToken struct {
 	Token string
 	TTL   time.Duration
+	JTI   string
 }
 
 type RefreshToken struct {
@@ -20,47 +24,187 @@ type RefreshToken struct {
 	JTI   string
 }
 
+// ActionToken is a short-lived token sent out of band (e.g. by email) to perform a single action
+type ActionToken struct {
+	Token string
+	TTL   time.Duration
+	JTI   string
+}
+
+// Token types, carried in the typ claim so a token can only be used for what it was issued for
+const (
+	TokenTypeAccess            = "access"
+	TokenTypeRefresh           = "refresh"
+	TokenTypeEmailVerification = "email_verification"
+	TokenTypeMFAChallenge      = "mfa_challenge"
+	TokenTypeMagicLink         = "magic_link"
+	// TokenTypeEmailChange is sent to the new address of an email change, TokenTypeEmailChangeCancel to the old one
+	TokenTypeEmailChange       = "email_change"
+	TokenTypeEmailChangeCancel = "email_change_cancel"
+	// TokenTypeService is issued to a backend service, it identifies the client_id instead of a user
+	TokenTypeService = "service"
+	// TokenTypePersonalAccess is never signed, it marks the claims built from a personal access token
+	TokenTypePersonalAccess = "personal_access"
+)
+
 type Utils interface {
-	CreateAccessToken(userID string, role string) (AccessToken, error)
-	CreateRefreshToken(userID string) (RefreshToken, error)
-	VerifyToken(tokenString string) (jwt.MapClaims, error)
+	CreateAccessToken(user model.User, sessionID string) (AccessToken, error)
+	// CreateClientAccessToken create an access token of a session granted to the third-party app clientID,
+	// it only carries scopes instead of the scopes of the user role
+	CreateClientAccessToken(user model.User, sessionID string, clientID string, scopes []string) (AccessToken, error)
+	// CreateIDToken create an OpenID Connect ID token for clientID, the profile and email claims are
+	// only added if the matching scope has been granted
+	CreateIDToken(user model.User, clientID string, nonce string, scopes []string) (string, error)
+	// CreateRefreshToken create a refresh token belonging to the session (refresh token family) sessionID
+	CreateRefreshToken(userID string, sessionID string) (RefreshToken, error)
+	// CreateActionToken create a token of type tokenType, extra claims are added to the token as is
+	CreateActionToken(userID string, tokenType string, ttl time.Duration, extra map[string]string) (ActionToken, error)
+	// CreateServiceToken create a token identifying the backend service clientID, granting scopes
+	CreateServiceToken(clientID string, scopes []string, ttl time.Duration) (AccessToken, error)
+	// VerifyToken verify the token signature, expiration and that it is of type tokenType
+	VerifyToken(tokenString string, tokenType string) (jwt.MapClaims, error)
+	// JWKS return the public keys tokens can be verified with
+	JWKS() JWKS
 }
 
 type utils struct {
+	// issuer is the iss claim of ID tokens
+	issuer          string
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
-	secretKey       string
+	keys            *KeySet
 }
 
-func (u *utils) CreateAccessToken(userId string, role string) (AccessToken, error) {
-	expireTime := time.Now().Add(u.accessTokenTTL).Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userId,
-		"role":    role,
-		"exp":     expireTime,
+func (u *utils) sign(claims jwt.MapClaims) (string, error) {
+	key := u.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+func (u *utils) CreateAccessToken(user model.User, sessionID string) (AccessToken, error) {
+	token, err := u.createAccessToken(user, sessionID, model.ScopesForRole(user.Role), nil)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("jwt.utils.CreateAccessToken: %w", err)
+	}
+	return token, nil
+}
+
+func (u *utils) CreateClientAccessToken(user model.User, sessionID string, clientID string, scopes []string) (AccessToken, error) {
+	token, err := u.createAccessToken(user, sessionID, scopes, jwt.MapClaims{
+		"client_id": clientID,
 	})
-	tokenString, err := token.SignedString([]byte(u.secretKey))
 	if err != nil {
-		return AccessToken{}, fmt.Errorf("jwt.utils.CreateToken signing token: %w", err)
+		return AccessToken{}, fmt.Errorf("jwt.utils.CreateClientAccessToken: %w", err)
+	}
+	return token, nil
+}
+
+func (u *utils) createAccessToken(user model.User, sessionID string, scopes []string, extra jwt.MapClaims) (AccessToken, error) {
+	now := time.Now()
+	jti, err := uuid.NewRandom()
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("jwt.utils.createAccessToken: %w", err)
+	}
+	claims := jwt.MapClaims{}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["typ"] = TokenTypeAccess
+	claims["jti"] = jti.String()
+	claims["user_id"] = user.ID
+	claims["role"] = user.Role
+	// the username is shown by the other services without looking up the user, it is omitted until the user picks one
+	if user.Username != "" {
+		claims["username"] = user.Username
+	}
+	claims["scope"] = strings.Join(scopes, " ")
+	claims["email_verified"] = user.EmailVerified
+	claims["sid"] = sessionID
+	// iat is a fractional NumericDate, a user wide revocation must tell apart the tokens issued in the same second
+	claims["iat"] = float64(now.UnixMicro()) / 1e6
+	claims["exp"] = now.Add(u.accessTokenTTL).Unix()
+	tokenString, err := u.sign(claims)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("jwt.utils.createAccessToken signing token: %w", err)
 	}
 	return AccessToken{
 		Token: tokenString,
 		TTL:   u.accessTokenTTL,
+		JTI:   jti.String(),
 	}, nil
 }
 
-func (u *utils) CreateRefreshToken(userId string) (RefreshToken, error) {
+func (u *utils) CreateServiceToken(clientID string, scopes []string, ttl time.Duration) (AccessToken, error) {
+	now := time.Now()
+	jti, err := uuid.NewRandom()
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("jwt.utils.CreateServiceToken: %w", err)
+	}
+	tokenString, err := u.sign(jwt.MapClaims{
+		"typ":       TokenTypeService,
+		"jti":       jti.String(),
+		"sub":       clientID,
+		"client_id": clientID,
+		"scope":     strings.Join(scopes, " "),
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("jwt.utils.CreateServiceToken signing token: %w", err)
+	}
+	return AccessToken{
+		Token: tokenString,
+		TTL:   ttl,
+		JTI:   jti.String(),
+	}, nil
+}
+
+func (u *utils) CreateIDToken(user model.User, clientID string, nonce string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": u.issuer,
+		"sub": user.ID,
+		"aud": clientID,
+		"azp": clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(u.accessTokenTTL).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if slices.Contains(scopes, model.ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerified
+	}
+	if slices.Contains(scopes, model.ScopeProfile) {
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		if user.Username != "" {
+			claims["preferred_username"] = user.Username
+		}
+	}
+	tokenString, err := u.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt.utils.CreateIDToken signing token: %w", err)
+	}
+	return tokenString, nil
+}
+
+func (u *utils) CreateRefreshToken(userId string, sessionID string) (RefreshToken, error) {
 	expireTime := time.Now().Add(u.refreshTokenTTL).Unix()
 	jti, err := uuid.NewRandom()
 	if err != nil {
 		return RefreshToken{}, fmt.Errorf("jwt.utils.CreateRefreshToken: %w", err)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	tokenString, err := u.sign(jwt.MapClaims{
+		"typ":     TokenTypeRefresh,
 		"jti":     jti.String(),
 		"user_id": userId,
+		"sid":     sessionID,
 		"exp":     expireTime,
 	})
-	tokenString, err := token.SignedString([]byte(u.secretKey))
 	if err != nil {
 		return RefreshToken{}, fmt.Errorf("jwt.utils.CreateToken signing token: %w", err)
 	}
@@ -71,13 +215,39 @@ func (u *utils) CreateRefreshToken(userId string) (RefreshToken, error) {
 	}, nil
 }
 
-func (u *utils) VerifyToken(tokenString string) (jwt.MapClaims, error) {
+func (u *utils) CreateActionToken(userID string, tokenType string, ttl time.Duration, extra map[string]string) (ActionToken, error) {
+	jti, err := uuid.NewRandom()
+	if err != nil {
+		return ActionToken{}, fmt.Errorf("jwt.utils.CreateActionToken: %w", err)
+	}
+	claims := jwt.MapClaims{}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["typ"] = tokenType
+	claims["jti"] = jti.String()
+	claims["user_id"] = userID
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	tokenString, err := u.sign(claims)
+	if err != nil {
+		return ActionToken{}, fmt.Errorf("jwt.utils.CreateActionToken signing token: %w", err)
+	}
+	return ActionToken{
+		Token: tokenString,
+		TTL:   ttl,
+		JTI:   jti.String(),
+	}, nil
+}
+
+func (u *utils) VerifyToken(tokenString string, tokenType string) (jwt.MapClaims, error) {
 	claims := jwt.MapClaims{}
 	parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := u.keys.Get(kid)
+		if !ok || token.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("jwt.Utils.VerifyToken: %w", apperrors.ErrInvalidToken)
 		}
-		return []byte(u.secretKey), nil
+		return key.PrivateKey.Public(), nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("jwt.Utils.VerifyToken: %w", apperrors.ErrInvalidToken)
@@ -85,12 +255,28 @@ func (u *utils) VerifyToken(tokenString string) (jwt.MapClaims, error) {
 	if !parsedToken.Valid {
 		return nil, fmt.Errorf("jwt.Utils.VerifyToken: %w", apperrors.ErrInvalidToken)
 	}
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return nil, fmt.Errorf("jwt.Utils.VerifyToken: %w", apperrors.ErrInvalidToken)
+	}
+	// service tokens identify a client instead of a user
+	subjectClaim := "user_id"
+	if tokenType == TokenTypeService {
+		subjectClaim = "client_id"
+	}
+	if _, ok := claims[subjectClaim].(string); !ok {
+		return nil, fmt.Errorf("jwt.Utils.VerifyToken: %w", apperrors.ErrInvalidToken)
+	}
 	return claims, nil
 }
 
-func NewJwtUtils(secretKey string, accessTokenTTL, refreshTokenTTL time.Duration) Utils {
+func (u *utils) JWKS() JWKS {
+	return u.keys.JWKS()
+}
+
+func NewJwtUtils(keys *KeySet, issuer string, accessTokenTTL, refreshTokenTTL time.Duration) Utils {
 	return &utils{
-		secretKey:       secretKey,
+		keys:            keys,
+		issuer:          issuer,
 		accessTokenTTL:  accessTokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
 	}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type logMailer struct {
+	log *zap.Logger
+}
+
+func (l *logMailer) Send(_ context.Context, msg Message) error {
+	l.log.Info("mail sent", zap.String("to", msg.To), zap.String("subject", msg.Subject), zap.String("body", msg.Body))
+	return nil
+}
+
+// NewLogMailer create a mailer writing emails to the application log, for local development
+func NewLogMailer(log *zap.Logger) Mailer {
+	return &logMailer{log: log}
+}
+
+type fileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (f *fileMailer) Send(_ context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("fileMailer.Send: %w", err)
+	}
+	defer file.Close()
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("fileMailer.Send: %w", err)
+	}
+	return nil
+}
+
+// NewFileMailer create a mailer appending emails to a file, for local development and tests
+func NewFileMailer(path string) Mailer {
+	return &fileMailer{path: path}
+}
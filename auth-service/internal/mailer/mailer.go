@@ -0,0 +1,14 @@
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer deliver transactional emails (verification, password reset...)
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+)
+
+// DataExport is an archive of the data every service keeps about a user, built in the background on their request
+type DataExport struct {
+	ID     string `gorm:"default:(-)"`
+	UserID string
+	Status string
+	// ExpiresAt is when the archive is removed, it is set once the archive is ready
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// ObjectKey is the name of the archive in the export bucket, the archives of a user share their id as prefix
+func (d DataExport) ObjectKey() string {
+	return d.UserID + "/" + d.ID + ".json"
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+)
+
+// DeviceAuthorization is a pending OAuth device authorization grant, it lives until the device
+// redeems it or it expires
+type DeviceAuthorization struct {
+	ClientID   string `json:"client_id"`
+	DeviceName string `json:"device_name"`
+	// UserCode is the short code the user types on the verification page
+	UserCode string `json:"user_code"`
+	Status   string `json:"status"`
+	// UserID is the user who approved or denied the device
+	UserID string `json:"user_id,omitempty"`
+	// Interval is the minimum number of seconds between two polls, it grows when the device polls too fast
+	Interval     int        `json:"interval"`
+	LastPolledAt *time.Time `json:"last_polled_at,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Statuses of an email change
+const (
+	EmailChangeStatusPending   = "pending"
+	EmailChangeStatusConfirmed = "confirmed"
+	EmailChangeStatusCancelled = "cancelled"
+	// EmailChangeStatusReverted is a confirmed change cancelled from the old address, which is restored
+	EmailChangeStatusReverted = "reverted"
+	// EmailChangeStatusFailed is a change whose new address was taken by another account before it was confirmed
+	EmailChangeStatusFailed = "failed"
+)
+
+// EmailChange is a request to change the email of a user. The email is only swapped once the new address is
+// confirmed, the old address can cancel the change, and revert it once it has been applied
+type EmailChange struct {
+	ID          string `gorm:"default:(-)"`
+	UserID      string
+	OldEmail    string
+	NewEmail    string
+	Status      string
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	ConfirmedAt *time.Time
+	CancelledAt *time.Time
+}
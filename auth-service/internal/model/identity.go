@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Identity links a credential of an external identity provider to a user
+type Identity struct {
+	ID     string `gorm:"default:(-)"`
+	UserID string
+	// Provider is the name of the identity provider, Subject the id of the user at that provider
+	Provider   string
+	Subject    string
+	Email      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// OIDCLoginState is kept between the redirection to the provider and the callback
+type OIDCLoginState struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	DeviceName   string `json:"device_name"`
+	// LinkUserID is set when the identity is linked to a signed in user instead of signing in
+	LinkUserID string `json:"link_user_id,omitempty"`
+}
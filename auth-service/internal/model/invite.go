@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// Registration modes, new accounts need an invite code in the invite-only mode and can not be created when closed
+const (
+	RegistrationModeOpen       = "open"
+	RegistrationModeInviteOnly = "invite_only"
+	RegistrationModeClosed     = "closed"
+)
+
+// IsValidRegistrationMode report whether mode is one of the known registration modes
+func IsValidRegistrationMode(mode string) bool {
+	return mode == RegistrationModeOpen || mode == RegistrationModeInviteOnly || mode == RegistrationModeClosed
+}
+
+// Invite lets up to MaxUses people sign up until it expires, only the SHA-256 hash of its code is stored
+type Invite struct {
+	ID       string `gorm:"default:(-)"`
+	CodeHash string
+	// Role is given to the users who sign up with the invite
+	Role      string
+	Note      string
+	MaxUses   int
+	UseCount  int
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// IsUsable report whether the invite can still be redeemed at t
+func (i Invite) IsUsable(t time.Time) bool {
+	return i.RevokedAt == nil && t.Before(i.ExpiresAt) && i.UseCount < i.MaxUses
+}
+
+// InviteRedemption records the invite a user signed up with
+type InviteRedemption struct {
+	ID        string `gorm:"default:(-)"`
+	InviteID  string
+	UserID    string
+	CreatedAt time.Time
+}
+
+// InvitedUser is a user who signed up with an invite
+type InvitedUser struct {
+	UserID     string
+	Email      string
+	FirstName  string
+	LastName   string
+	RedeemedAt time.Time
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// LoginLockout records an account being temporarily locked after too many failed login attempts
+type LoginLockout struct {
+	ID             string `gorm:"default:(-)"`
+	UserID         string
+	Email          string
+	IP             string
+	FailedAttempts int
+	LockedAt       time.Time
+	LockedUntil    time.Time
+	// ClearedAt and ClearedBy are set when an admin lifted the lockout before it expired
+	ClearedAt *time.Time
+	ClearedBy *string
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// UserMFA holds the TOTP second factor of a user. It is pending until the user confirms it with a first code
+type UserMFA struct {
+	UserID     string `gorm:"primaryKey"`
+	TOTPSecret string `gorm:"column:totp_secret"`
+	Enabled    bool
+	// LastUsedStep is the last accepted TOTP time step, codes of older or equal steps are rejected
+	LastUsedStep int64
+	ConfirmedAt  *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// RecoveryCode is a single-use code that can replace a TOTP code, only its SHA-256 hash is stored
+type RecoveryCode struct {
+	ID        string `gorm:"default:(-)"`
+	UserID    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (RecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+// OpenID Connect scopes a third-party app can request besides the permission scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// IsOpenIDScope report whether scope is one of the OpenID Connect scopes
+func IsOpenIDScope(scope string) bool {
+	return scope == ScopeOpenID || scope == ScopeProfile || scope == ScopeEmail
+}
+
+// Service scopes are never granted to users, backend services get them with the client credentials grant
+const (
+	ScopeChatThreadsWrite = "chat:threads:write"
+	// ScopeUserDataRead and ScopeUserDataDelete let auth-service export and erase the data the other services keep
+	// about a user
+	ScopeUserDataRead   = "user_data:read"
+	ScopeUserDataDelete = "user_data:delete"
+	// ScopeChannelsTransfer lets auth-service move the channel of a user to another when an admin merges them
+	ScopeChannelsTransfer = "channels:transfer"
+)
+
+// IsServiceScope report whether scope can only be granted to backend services
+func IsServiceScope(scope string) bool {
+	return scope == ScopeChatThreadsWrite || scope == ScopeUserDataRead || scope == ScopeUserDataDelete || scope == ScopeChannelsTransfer
+}
+
+// OAuthClient is a third-party app users can sign in to with their account,
+// or a backend service of the platform authenticating as itself
+type OAuthClient struct {
+	ID   string `gorm:"default:(-)"`
+	Name string
+	// SecretHash is the SHA-256 hash of the client secret,
+	// it is empty for public clients (single page and mobile apps) which can not keep a secret
+	SecretHash string
+	// RedirectURIs and Scopes are space separated lists, the scopes are the ones the client is allowed to request
+	RedirectURIs string
+	Scopes       string
+	CreatedBy    string
+	CreatedAt    time.Time
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// IsPublic report whether the client authenticates without a secret
+func (c OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+// OAuthConsent records the scopes a user granted to a client, the user is not asked again for them
+type OAuthConsent struct {
+	UserID   string `gorm:"primaryKey"`
+	ClientID string `gorm:"primaryKey"`
+	// Scopes is the space separated list of granted scopes
+	Scopes    string
+	Client    OAuthClient `gorm:"foreignKey:ClientID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// OAuthAuthorizationCode is kept between the consent of the user and the token request of the client
+type OAuthAuthorizationCode struct {
+	ClientID      string   `json:"client_id"`
+	UserID        string   `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"code_challenge"`
+	Nonce         string   `json:"nonce"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Passkey is a WebAuthn credential a user can sign in with instead of a password
+type Passkey struct {
+	ID     string `gorm:"default:(-)"`
+	UserID string
+	// CredentialID is the base64url encoded id chosen by the authenticator, PublicKey its COSE encoded key
+	CredentialID string
+	PublicKey    []byte
+	SignCount    int64
+	// Transports is the comma separated list of transports the authenticator reported, e.g. usb,internal
+	Transports string
+	Name       string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+// PasskeyChallenge is kept between the options sent to the browser and the credential it returns
+type PasskeyChallenge struct {
+	Challenge string `json:"challenge"`
+	// UserID is the user the ceremony is for, empty when the authenticator picks the credential
+	UserID string `json:"user_id,omitempty"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// PasswordHistory is a password a user had, kept so the last ones can not be reused
+type PasswordHistory struct {
+	ID           string `gorm:"default:(-)"`
+	UserID       string
+	PasswordHash string
+	CreatedAt    time.Time
+}
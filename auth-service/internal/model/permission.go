@@ -0,0 +1,64 @@
+package model
+
+import "slices"
+
+// Scopes are the permissions carried in access tokens, services check them instead of role names
+const (
+	ScopeUsersRead    = "users:read"
+	ScopeUsersWrite   = "users:write"
+	ScopeClientsWrite = "oauth_clients:write"
+	ScopeAuditRead    = "audit:read"
+	ScopeInvitesWrite = "invites:write"
+)
+
+// roleScopes lists the scopes granted by each role, a role without entry grants no scope
+var roleScopes = map[string][]string{
+	RoleUser: {},
+	RoleModerator: {
+		ScopeUsersRead,
+	},
+	RoleAdmin: {
+		ScopeUsersRead,
+		ScopeUsersWrite,
+		ScopeClientsWrite,
+		ScopeAuditRead,
+		ScopeInvitesWrite,
+	},
+}
+
+// IsValidRole report whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// ScopesForRole return the scopes granted by role
+func ScopesForRole(role string) []string {
+	scopes := roleScopes[role]
+	res := make([]string, len(scopes))
+	copy(res, scopes)
+	return res
+}
+
+// IsPermissionScope report whether scope is granted by at least one role
+func IsPermissionScope(scope string) bool {
+	for _, scopes := range roleScopes {
+		if slices.Contains(scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantedScopes return the scopes of requested a user with role can actually hand over to a third-party app:
+// OpenID Connect scopes are kept, permission scopes only if the role grants them
+func GrantedScopes(role string, requested []string) []string {
+	roleScopes := ScopesForRole(role)
+	res := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if IsOpenIDScope(scope) || slices.Contains(roleScopes, scope) {
+			res = append(res, scope)
+		}
+	}
+	return res
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// PersonalAccessTokenPrefix starts every personal access token, it tells them apart from JWTs
+const PersonalAccessTokenPrefix = "lsp_pat_"
+
+// PersonalAccessToken is a long-lived token created by a user for bots and scripts, only its SHA-256 hash is stored
+type PersonalAccessToken struct {
+	ID        string `gorm:"default:(-)"`
+	UserID    string
+	Name      string
+	TokenHash string
+	// Scopes is the space separated list of scopes granted to the token
+	Scopes     string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
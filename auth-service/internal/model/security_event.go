@@ -0,0 +1,69 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// Types of the security events recorded in the audit log
+const (
+	SecurityEventLoginSucceeded       = "login_succeeded"
+	SecurityEventLoginFailed          = "login_failed"
+	SecurityEventTokenRefreshed       = "token_refreshed"
+	SecurityEventRefreshTokenReused   = "refresh_token_reused"
+	SecurityEventLogout               = "logout"
+	SecurityEventLogoutAll            = "logout_all"
+	SecurityEventSessionRevoked       = "session_revoked"
+	SecurityEventPasswordChanged      = "password_changed"
+	SecurityEventPasswordReset        = "password_reset"
+	SecurityEventRoleChanged          = "role_changed"
+	SecurityEventUserSuspended        = "user_suspended"
+	SecurityEventUserBanned           = "user_banned"
+	SecurityEventUserUnbanned         = "user_unbanned"
+	SecurityEventUserDeleted          = "user_deleted"
+	SecurityEventLockoutCleared       = "lockout_cleared"
+	SecurityEventInviteCreated        = "invite_created"
+	SecurityEventInviteRevoked        = "invite_revoked"
+	SecurityEventUsernameChanged      = "username_changed"
+	SecurityEventEmailChangeRequested = "email_change_requested"
+	SecurityEventEmailChanged         = "email_changed"
+	SecurityEventEmailChangeCancelled = "email_change_cancelled"
+	// SecurityEventEmailChangeReverted is a confirmed email change cancelled from the old address
+	SecurityEventEmailChangeReverted      = "email_change_reverted"
+	SecurityEventDataExportRequested      = "data_export_requested"
+	SecurityEventAccountDeletionScheduled = "account_deletion_scheduled"
+	SecurityEventAccountDeletionCancelled = "account_deletion_cancelled"
+	// SecurityEventAccountDeleted is the erasure of an account at the end of the grace period the user asked for
+	SecurityEventAccountDeleted = "account_deleted"
+)
+
+// SecurityEvent is an entry of the append-only audit log.
+// UserID is the account the event is about, ActorID who performed it when it differs, e.g. an admin
+type SecurityEvent struct {
+	ID        string `gorm:"default:(-)"`
+	Type      string
+	UserID    *string
+	ActorID   *string
+	IP        string
+	UserAgent string
+	// Details holds the data specific to the event type, e.g. the reason of a failed login
+	Details   map[string]string `gorm:"serializer:json"`
+	CreatedAt time.Time
+}
+
+// SecurityEventFilter restricts the security events returned by a query, empty fields are ignored
+type SecurityEventFilter struct {
+	UserID string
+	Type   string
+	From   time.Time
+	To     time.Time
+}
+
+// EmailFingerprint pseudonymize an email for the details of a security event. The same address always gives the same
+// fingerprint, so the events about it can be correlated without the audit log keeping the address
+func EmailFingerprint(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:16])
+}
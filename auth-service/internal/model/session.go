@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Session represents one refresh token family, i.e. one logged-in device.
+type Session struct {
+	ID             string `json:"id"`
+	UserID         string `json:"user_id"`
+	RefreshTokenID string `json:"refresh_token_id"`
+	// AccessTokenID is the jti of the last access token issued to the session
+	AccessTokenID string    `json:"access_token_id"`
+	DeviceName    string    `json:"device_name"`
+	UserAgent     string    `json:"user_agent"`
+	IP            string    `json:"ip"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastUsedAt    time.Time `json:"last_used_at"`
+	// ClientID is set when the session has been granted to a third-party app, Scopes are then the scopes it was granted
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
@@ -1,19 +1,76 @@
 package model
 
-import "time"
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
 
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
 )
 
 type User struct {
-	ID        string `gorm:"default:(-)"`
-	Email     string
-	Password  string
-	FirstName string
-	LastName  string
-	Role      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID       string `gorm:"default:(-)"`
+	Email    string
+	Password string
+	// Username is the public handle of the user, unique regardless of case. It is empty until the user picks one
+	Username string
+	// UsernameChangedAt is when Username was last set, it can only be changed again after a cooldown
+	UsernameChangedAt *time.Time
+	FirstName         string
+	LastName          string
+	Bio               string
+	Role              string
+	// EmailVerified is set once the user followed the link sent to their email address
+	EmailVerified bool
+	Status        string
+	// SuspendedUntil is the end of the suspension, the user is active again once it is over
+	SuspendedUntil *time.Time
+	StatusReason   string
+	// DeletionScheduledAt is when the account is erased, the user asked for it and can cancel until then
+	DeletionScheduledAt *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	DeletedAt           gorm.DeletedAt
+}
+
+// EffectiveStatus return the status of the user at t, taking the end of suspensions into account
+func (u User) EffectiveStatus(t time.Time) string {
+	if u.Status == UserStatusSuspended && u.SuspendedUntil != nil && !t.Before(*u.SuspendedUntil) {
+		return UserStatusActive
+	}
+	if u.Status == "" {
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+// usernamePattern is the format of usernames, they are safe to use in URLs and mentions
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// reservedUsernames could be mistaken for the platform or its staff
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"moderator":     true,
+	"root":          true,
+	"support":       true,
+	"system":        true,
+	"me":            true,
+}
+
+// IsValidUsername report whether username has the format of usernames and is not reserved
+func IsValidUsername(username string) bool {
+	return usernamePattern.MatchString(username) && !reservedUsernames[strings.ToLower(username)]
 }
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Fields the users can be sorted on, the id is always used as tie-breaker
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortEmail     = "email"
+	UserSortFirstName = "first_name"
+	UserSortLastName  = "last_name"
+)
+
+var userSortFields = []string{UserSortCreatedAt, UserSortEmail, UserSortFirstName, UserSortLastName}
+
+// IsUserSortField report whether the users can be sorted on field
+func IsUserSortField(field string) bool {
+	return slices.Contains(userSortFields, field)
+}
+
+// IsValidUserStatus report whether status is one of the known statuses
+func IsValidUserStatus(status string) bool {
+	return status == UserStatusActive || status == UserStatusSuspended || status == UserStatusBanned
+}
+
+// UserQuery selects and sorts the users returned to admins, empty fields are ignored
+type UserQuery struct {
+	// Email matches the users whose email starts with it
+	Email string
+	// Name matches the users whose first name, last name or username contains it, case-insensitively
+	Name   string
+	Role   string
+	Status string
+	// CreatedFrom is inclusive and CreatedTo exclusive
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	SortBy      string
+	Desc        bool
+	// After is the position of the last user of the previous page
+	After *UserCursor
+	Limit int
+}
+
+// UserCursor is the position of a user in the sort order of a UserQuery
+type UserCursor struct {
+	Value string
+	ID    string
+}
+
+// SortValue return the value of the field the users are sorted on, as stored in a UserCursor
+func (u User) SortValue(field string) string {
+	switch field {
+	case UserSortEmail:
+		return u.Email
+	case UserSortFirstName:
+		return u.FirstName
+	case UserSortLastName:
+		return u.LastName
+	default:
+		return u.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
@@ -0,0 +1,277 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// clockSkew is tolerated between the provider and us when checking the token lifetime
+const clockSkew = time.Minute
+
+// IDTokenClaims are the claims of a verified ID token used to find or create the user
+type IDTokenClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+}
+
+// flexibleBool accept booleans sent as strings, which some providers do for email_verified
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*b = false
+		return nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b = flexibleBool(v)
+	return nil
+}
+
+// audience is either a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type idTokenClaims struct {
+	Issuer            string       `json:"iss"`
+	Subject           string       `json:"sub"`
+	Audience          audience     `json:"aud"`
+	AuthorizedParty   string       `json:"azp"`
+	ExpiresAt         int64        `json:"exp"`
+	IssuedAt          int64        `json:"iat"`
+	Nonce             string       `json:"nonce"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	GivenName         string       `json:"given_name"`
+	FamilyName        string       `json:"family_name"`
+	PreferredUsername string       `json:"preferred_username"`
+	// CasdoorEmailVerified is the emailVerified field Casdoor copies from its user object
+	CasdoorEmailVerified flexibleBool `json:"emailVerified"`
+}
+
+// Valid only check the token lifetime, the other claims are checked by VerifyIDToken
+func (c *idTokenClaims) Valid() error {
+	now := time.Now()
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token is expired")
+	}
+	if c.IssuedAt != 0 && now.Add(clockSkew).Before(time.Unix(c.IssuedAt, 0)) {
+		return errors.New("token used before issued")
+	}
+	return nil
+}
+
+// VerifyIDToken check the signature of the ID token against the provider keys, then its issuer,
+// audience, lifetime and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (IDTokenClaims, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w", err)
+	}
+	jwksURL := p.backchannel(doc.JWKSURI)
+	var claims idTokenClaims
+	parser := jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}}
+	_, err = parser.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.get(ctx, jwksURL, kid, token.Method.Alg())
+	})
+	if err != nil {
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w: %w", ErrInvalidIDToken, err)
+	}
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(p.cfg.Issuer, "/"):
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !slices.Contains(claims.Audience, p.cfg.ClientID):
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w: unexpected audience", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID:
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w: unexpected authorized party", ErrInvalidIDToken)
+	case claims.Nonce != nonce:
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w: nonce mismatch", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return IDTokenClaims{}, fmt.Errorf("oidc.VerifyIDToken: %w: missing subject", ErrInvalidIDToken)
+	}
+	return IDTokenClaims{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified || claims.CasdoorEmailVerified),
+		Name:              claims.Name,
+		GivenName:         claims.GivenName,
+		FamilyName:        claims.FamilyName,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// keySet caches the provider signing keys, unknown kids trigger a refetch at most every minRefresh
+type keySet struct {
+	client     *http.Client
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]jsonWebKey
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	alg string
+	key interface{}
+}
+
+func newKeySet(client *http.Client) *keySet {
+	return &keySet{
+		client:     client,
+		minRefresh: 10 * time.Second,
+		keys:       map[string]jsonWebKey{},
+	}
+}
+
+func (k *keySet) get(ctx context.Context, jwksURL string, kid string, alg string) (interface{}, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.lookup(kid, alg)
+	if !ok && time.Since(k.fetchedAt) > k.minRefresh {
+		if err := k.refresh(ctx, jwksURL); err != nil {
+			return nil, err
+		}
+		key, ok = k.lookup(kid, alg)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup find the key by kid, a token without kid is accepted if the provider has a single key
+func (k *keySet) lookup(kid string, alg string) (interface{}, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for kid = range k.keys {
+		}
+	}
+	key, ok := k.keys[kid]
+	if !ok || (key.alg != "" && key.alg != alg) {
+		return nil, false
+	}
+	switch key.key.(type) {
+	case *rsa.PublicKey:
+		return key.key, strings.HasPrefix(alg, "RS")
+	case *ecdsa.PublicKey:
+		return key.key, strings.HasPrefix(alg, "ES")
+	}
+	return nil, false
+}
+
+func (k *keySet) refresh(ctx context.Context, jwksURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks status %d", resp.StatusCode)
+	}
+	var payload struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return err
+	}
+	keys := make(map[string]jsonWebKey, len(payload.Keys))
+	for _, jwk := range payload.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+			if err != nil {
+				continue
+			}
+			e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+			if err != nil {
+				continue
+			}
+			keys[jwk.Kid] = jsonWebKey{alg: jwk.Alg, key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if err != nil {
+				continue
+			}
+			y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if err != nil {
+				continue
+			}
+			keys[jwk.Kid] = jsonWebKey{alg: jwk.Alg, key: &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}}
+		}
+	}
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,199 @@
+// Package oidc implements the relying party side of the OpenID Connect authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	// ErrProvider is returned when the provider could not be reached or answered with an error
+	ErrProvider = errors.New("oidc provider error")
+)
+
+type Config struct {
+	// Issuer is the iss claim of the ID tokens, the discovery document is served under it
+	Issuer string
+	// BackchannelURL replace the issuer in the URLs called by the server, when the provider is reached
+	// through another address than the browser (e.g. inside docker-compose). Optional
+	BackchannelURL string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	Scopes         []string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Provider is an OpenID provider, its discovery document and signing keys are fetched lazily and cached
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	keys   *keySet
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+}
+
+func NewProvider(cfg Config) *Provider {
+	p := &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	p.keys = newKeySet(p.client)
+	return p
+}
+
+// backchannel rewrite a URL advertised by the provider to the address the server can reach it at
+func (p *Provider) backchannel(u string) string {
+	if p.cfg.BackchannelURL == "" {
+		return u
+	}
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	if strings.HasPrefix(u, issuer) {
+		return strings.TrimSuffix(p.cfg.BackchannelURL, "/") + strings.TrimPrefix(u, issuer)
+	}
+	return u
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	wellKnown := p.backchannel(strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc.getDiscovery: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc.getDiscovery: %w: %w", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc.getDiscovery: %w: status %d", ErrProvider, resp.StatusCode)
+	}
+	var doc discoveryDocument
+	if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("oidc.getDiscovery: %w: %w", ErrProvider, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc.getDiscovery: %w: issuer mismatch %q", ErrProvider, doc.Issuer)
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// AuthCodeURL return the URL of the provider the browser is sent to, codeChallenge is the S256 PKCE challenge
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", fmt.Errorf("oidc.AuthCodeURL: %w", err)
+	}
+	u, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc.AuthCodeURL: %w: %w", ErrProvider, err)
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeem an authorization code for the tokens of the user
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (TokenResponse, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w", err)
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.backchannel(doc.TokenEndpoint), strings.NewReader(form.Encode()))
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w: %w", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w: %w", ErrProvider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w: status %d: %s", ErrProvider, resp.StatusCode, body)
+	}
+	var token TokenResponse
+	if err = json.Unmarshal(body, &token); err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w: %w", ErrProvider, err)
+	}
+	if token.IDToken == "" {
+		return TokenResponse{}, fmt.Errorf("oidc.Exchange: %w: no id_token in response", ErrProvider)
+	}
+	return token, nil
+}
+
+// randomString return n random bytes encoded in base64url
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NewState return a random value for the state and nonce parameters
+func NewState() (string, error) {
+	return randomString(24)
+}
+
+// NewCodeVerifier return a random PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	return randomString(32)
+}
+
+// CodeChallengeS256 derive the PKCE code challenge from the verifier
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+// Package password hashes passwords with bcrypt or argon2id and checks them against the password policy
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash algorithms new passwords can be hashed with
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+const argon2SaltSize = 16
+
+var errUnknownHash = errors.New("unknown hash format")
+
+type HasherConfig struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	Argon2KeyLength   uint32
+}
+
+// Hasher hash passwords with the configured algorithm and verify hashes made with any supported algorithm,
+// so the algorithm or its cost can change without invalidating the existing passwords
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify report whether password matches hash, an empty hash never matches
+	Verify(hash string, password string) bool
+	// NeedsRehash report whether hash was not made with the configured algorithm and parameters
+	NeedsRehash(hash string) bool
+}
+
+type hasher struct {
+	cfg HasherConfig
+}
+
+// argon2Params are the parameters encoded in an argon2id hash, in the PHC string format
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("hasher.Hash: %w", err)
+		}
+		return string(hash), nil
+	}
+	salt := make([]byte, argon2SaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("hasher.Hash: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.cfg.Argon2Iterations, h.cfg.Argon2Memory, h.cfg.Argon2Parallelism, h.cfg.Argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.cfg.Argon2Memory,
+		h.cfg.Argon2Iterations,
+		h.cfg.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (*hasher) decodeArgon2(hash string) (argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return argon2Params{}, errUnknownHash
+	}
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return argon2Params{}, errUnknownHash
+	}
+	var p argon2Params
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism)
+	if err != nil {
+		return argon2Params{}, errUnknownHash
+	}
+	p.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, errUnknownHash
+	}
+	p.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(p.key) == 0 {
+		return argon2Params{}, errUnknownHash
+	}
+	return p, nil
+}
+
+func (h *hasher) Verify(hash string, password string) bool {
+	if strings.HasPrefix(hash, "$"+AlgorithmArgon2id+"$") {
+		p, err := h.decodeArgon2(hash)
+		if err != nil {
+			return false
+		}
+		key := argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+		return subtle.ConstantTimeCompare(key, p.key) == 1
+	}
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (h *hasher) NeedsRehash(hash string) bool {
+	if h.cfg.Algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.cfg.BcryptCost
+	}
+	p, err := h.decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+	return p.memory != h.cfg.Argon2Memory ||
+		p.iterations != h.cfg.Argon2Iterations ||
+		p.parallelism != h.cfg.Argon2Parallelism ||
+		uint32(len(p.key)) != h.cfg.Argon2KeyLength
+}
+
+// NewHasher return a hasher for cfg, an unknown algorithm is an error so a typo does not silently weaken the hashes
+func NewHasher(cfg HasherConfig) (Hasher, error) {
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("password.NewHasher: invalid bcrypt cost %d", cfg.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+		if cfg.Argon2Memory == 0 || cfg.Argon2Iterations == 0 || cfg.Argon2Parallelism == 0 || cfg.Argon2KeyLength < 16 {
+			return nil, errors.New("password.NewHasher: invalid argon2id parameters")
+		}
+	default:
+		return nil, fmt.Errorf("password.NewHasher: unknown algorithm %q", cfg.Algorithm)
+	}
+	return &hasher{cfg: cfg}, nil
+}
@@ -0,0 +1,98 @@
+package password
+
+import (
+	apperrors "auth-service/internal/error"
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords are the bundled most used passwords, lower-cased
+var commonPasswords = func() map[string]struct{} {
+	res := map[string]struct{}{}
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			res[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return res
+}()
+
+type PolicyConfig struct {
+	MinLength int
+	MaxLength int
+	// MinCharacterClasses is the number of classes among lowercase, uppercase, digits and symbols the password must mix
+	MinCharacterClasses int
+}
+
+// Policy decide whether a new password is strong enough
+type Policy interface {
+	// Check return an *apperrors.PasswordPolicyError if password is rejected. personal are the email and names
+	// of the user, the password must not contain them
+	Check(password string, personal ...string) error
+}
+
+type policy struct {
+	cfg PolicyConfig
+}
+
+func (*policy) characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *policy) Check(password string, personal ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		return &apperrors.PasswordPolicyError{Reason: fmt.Sprintf("The password must be at least %d characters long", p.cfg.MinLength)}
+	}
+	if p.cfg.MaxLength > 0 && length > p.cfg.MaxLength {
+		return &apperrors.PasswordPolicyError{Reason: fmt.Sprintf("The password must be at most %d characters long", p.cfg.MaxLength)}
+	}
+	if p.characterClasses(password) < p.cfg.MinCharacterClasses {
+		return &apperrors.PasswordPolicyError{Reason: fmt.Sprintf(
+			"The password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.cfg.MinCharacterClasses)}
+	}
+	lower := strings.ToLower(password)
+	if _, ok := commonPasswords[lower]; ok {
+		return &apperrors.PasswordPolicyError{Reason: "The password is too common"}
+	}
+	for _, value := range personal {
+		// only the local part of an email is meaningful, the domain is shared by many users
+		value, _, _ = strings.Cut(strings.ToLower(value), "@")
+		if utf8.RuneCountInString(value) >= 3 && strings.Contains(lower, value) {
+			return &apperrors.PasswordPolicyError{Reason: "The password must not contain your email or name"}
+		}
+	}
+	return nil
+}
+
+func NewPolicy(cfg PolicyConfig) Policy {
+	return &policy{cfg: cfg}
+}
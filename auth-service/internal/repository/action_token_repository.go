@@ -0,0 +1,50 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ActionTokenRepository keep track of the action tokens that have not been used yet, making them single-use
+type ActionTokenRepository interface {
+	SaveActionToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	// ConsumeActionToken return apperrors.ErrInvalidToken if the token has already been used or has expired
+	ConsumeActionToken(ctx context.Context, tokenID string) error
+}
+
+type actionTokenRepository struct {
+	redis *redis.Client
+}
+
+func (*actionTokenRepository) getActionTokenKey(tokenID string) string {
+	return fmt.Sprintf("action_token:%s", tokenID)
+}
+
+func (a *actionTokenRepository) SaveActionToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	err := a.redis.Set(ctx, a.getActionTokenKey(tokenID), 1, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("actionTokenRepository.SaveActionToken: %w", err)
+	}
+	return nil
+}
+
+func (a *actionTokenRepository) ConsumeActionToken(ctx context.Context, tokenID string) error {
+	n, err := a.redis.Del(ctx, a.getActionTokenKey(tokenID)).Result()
+	if err != nil {
+		return fmt.Errorf("actionTokenRepository.ConsumeActionToken: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("actionTokenRepository.ConsumeActionToken: %w", apperrors.ErrInvalidToken)
+	}
+	return nil
+}
+
+func NewActionTokenRepository(redis *redis.Client) ActionTokenRepository {
+	return &actionTokenRepository{
+		redis: redis,
+	}
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DataExportRepository interface {
+	CreateDataExport(ctx context.Context, export model.DataExport) (model.DataExport, error)
+	// GetDataExport return apperrors.ErrDataExportNotFound if the user has no export with this id
+	GetDataExport(ctx context.Context, userID string, id string) (model.DataExport, error)
+	// CompleteDataExport mark the export ready, its archive can be downloaded until expiresAt
+	CompleteDataExport(ctx context.Context, id string, completedAt time.Time, expiresAt time.Time) error
+	FailDataExport(ctx context.Context, id string, completedAt time.Time) error
+	// GetExpiredDataExports return up to limit exports whose archive expired at or before now
+	GetExpiredDataExports(ctx context.Context, now time.Time, limit int) ([]model.DataExport, error)
+	DeleteDataExport(ctx context.Context, id string) error
+}
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+func (d *dataExportRepository) CreateDataExport(ctx context.Context, export model.DataExport) (model.DataExport, error) {
+	err := d.db.WithContext(ctx).Create(&export).Error
+	if err != nil {
+		return model.DataExport{}, fmt.Errorf("dataExportRepository.CreateDataExport: %w", err)
+	}
+	return export, nil
+}
+
+func (d *dataExportRepository) GetDataExport(ctx context.Context, userID string, id string) (model.DataExport, error) {
+	var export model.DataExport
+	result := d.db.WithContext(ctx).First(&export, "id = ? AND user_id = ?", id, userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return export, fmt.Errorf("dataExportRepository.GetDataExport: %w", apperrors.ErrDataExportNotFound)
+		}
+		return export, fmt.Errorf("dataExportRepository.GetDataExport: %w", result.Error)
+	}
+	return export, nil
+}
+
+func (d *dataExportRepository) CompleteDataExport(ctx context.Context, id string, completedAt time.Time, expiresAt time.Time) error {
+	err := d.db.WithContext(ctx).Model(&model.DataExport{}).
+		Where("id = ? AND status = ?", id, model.DataExportStatusPending).
+		Updates(map[string]any{
+			"status":       model.DataExportStatusReady,
+			"completed_at": completedAt,
+			"expires_at":   expiresAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("dataExportRepository.CompleteDataExport: %w", err)
+	}
+	return nil
+}
+
+func (d *dataExportRepository) FailDataExport(ctx context.Context, id string, completedAt time.Time) error {
+	err := d.db.WithContext(ctx).Model(&model.DataExport{}).
+		Where("id = ? AND status = ?", id, model.DataExportStatusPending).
+		Updates(map[string]any{
+			"status":       model.DataExportStatusFailed,
+			"completed_at": completedAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("dataExportRepository.FailDataExport: %w", err)
+	}
+	return nil
+}
+
+func (d *dataExportRepository) GetExpiredDataExports(ctx context.Context, now time.Time, limit int) ([]model.DataExport, error) {
+	var exports []model.DataExport
+	err := d.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&exports).Error
+	if err != nil {
+		return nil, fmt.Errorf("dataExportRepository.GetExpiredDataExports: %w", err)
+	}
+	return exports, nil
+}
+
+func (d *dataExportRepository) DeleteDataExport(ctx context.Context, id string) error {
+	err := d.db.WithContext(ctx).Delete(&model.DataExport{}, "id = ?", id).Error
+	if err != nil {
+		return fmt.Errorf("dataExportRepository.DeleteDataExport: %w", err)
+	}
+	return nil
+}
+
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeviceAuthorizationRepository store the device authorization grants by the hash of their device code,
+// along with an index from the user code
+type DeviceAuthorizationRepository interface {
+	// CreateAuthorization return apperrors.ErrUserCodeTaken if another pending grant uses the same user code
+	CreateAuthorization(ctx context.Context, deviceCodeHash string, authorization model.DeviceAuthorization, ttl time.Duration) error
+	// GetAuthorization return apperrors.ErrInvalidDeviceCode if the grant does not exist or has expired
+	GetAuthorization(ctx context.Context, deviceCodeHash string) (model.DeviceAuthorization, error)
+	// GetAuthorizationByUserCode return the device code hash and the grant,
+	// apperrors.ErrInvalidUserCode is returned if it does not exist or has expired
+	GetAuthorizationByUserCode(ctx context.Context, userCode string) (string, model.DeviceAuthorization, error)
+	// UpdateAuthorization replace the grant keeping its expiration, only if its status is still status.
+	// apperrors.ErrInvalidDeviceCode is returned if it has expired and apperrors.ErrDeviceAuthorizationChanged
+	// if it has been approved or denied meanwhile
+	UpdateAuthorization(ctx context.Context, deviceCodeHash string, status string, authorization model.DeviceAuthorization) error
+	// DeleteAuthorization return apperrors.ErrInvalidDeviceCode if the grant has already been deleted,
+	// so only one caller can redeem it
+	DeleteAuthorization(ctx context.Context, deviceCodeHash string, userCode string) error
+}
+
+// updateAuthorizationScript compare and swap the grant on its status, so a poll of the device does not overwrite
+// the decision of the user, and an expired grant is not recreated without expiration
+var updateAuthorizationScript = redis.NewScript(`
+local data = redis.call('GET', KEYS[1])
+if not data then
+	return -1
+end
+if cjson.decode(data)['status'] ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+return 1
+`)
+
+type deviceAuthorizationRepository struct {
+	redis *redis.Client
+}
+
+func (*deviceAuthorizationRepository) getDeviceCodeKey(deviceCodeHash string) string {
+	return fmt.Sprintf("device_code:%s", deviceCodeHash)
+}
+
+func (*deviceAuthorizationRepository) getUserCodeKey(userCode string) string {
+	return fmt.Sprintf("device_user_code:%s", userCode)
+}
+
+func (d *deviceAuthorizationRepository) CreateAuthorization(ctx context.Context, deviceCodeHash string, authorization model.DeviceAuthorization, ttl time.Duration) error {
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.CreateAuthorization: %w", err)
+	}
+	ok, err := d.redis.SetNX(ctx, d.getUserCodeKey(authorization.UserCode), deviceCodeHash, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.CreateAuthorization: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("deviceAuthorizationRepository.CreateAuthorization: %w", apperrors.ErrUserCodeTaken)
+	}
+	err = d.redis.Set(ctx, d.getDeviceCodeKey(deviceCodeHash), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.CreateAuthorization: %w", err)
+	}
+	return nil
+}
+
+func (d *deviceAuthorizationRepository) GetAuthorization(ctx context.Context, deviceCodeHash string) (model.DeviceAuthorization, error) {
+	data, err := d.redis.Get(ctx, d.getDeviceCodeKey(deviceCodeHash)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorization: %w", apperrors.ErrInvalidDeviceCode)
+		}
+		return model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorization: %w", err)
+	}
+	var authorization model.DeviceAuthorization
+	if err = json.Unmarshal(data, &authorization); err != nil {
+		return model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorization: %w", err)
+	}
+	return authorization, nil
+}
+
+func (d *deviceAuthorizationRepository) GetAuthorizationByUserCode(ctx context.Context, userCode string) (string, model.DeviceAuthorization, error) {
+	deviceCodeHash, err := d.redis.Get(ctx, d.getUserCodeKey(userCode)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorizationByUserCode: %w", apperrors.ErrInvalidUserCode)
+		}
+		return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorizationByUserCode: %w", err)
+	}
+	authorization, err := d.GetAuthorization(ctx, deviceCodeHash)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidDeviceCode) {
+			return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorizationByUserCode: %w", apperrors.ErrInvalidUserCode)
+		}
+		return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationRepository.GetAuthorizationByUserCode: %w", err)
+	}
+	return deviceCodeHash, authorization, nil
+}
+
+func (d *deviceAuthorizationRepository) UpdateAuthorization(ctx context.Context, deviceCodeHash string, status string, authorization model.DeviceAuthorization) error {
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.UpdateAuthorization: %w", err)
+	}
+	res, err := updateAuthorizationScript.Run(ctx, d.redis, []string{d.getDeviceCodeKey(deviceCodeHash)}, status, data).Int()
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.UpdateAuthorization: %w", err)
+	}
+	switch res {
+	case -1:
+		return fmt.Errorf("deviceAuthorizationRepository.UpdateAuthorization: %w", apperrors.ErrInvalidDeviceCode)
+	case 0:
+		return fmt.Errorf("deviceAuthorizationRepository.UpdateAuthorization: %w", apperrors.ErrDeviceAuthorizationChanged)
+	}
+	return nil
+}
+
+func (d *deviceAuthorizationRepository) DeleteAuthorization(ctx context.Context, deviceCodeHash string, userCode string) error {
+	deleted, err := d.redis.Del(ctx, d.getDeviceCodeKey(deviceCodeHash)).Result()
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.DeleteAuthorization: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("deviceAuthorizationRepository.DeleteAuthorization: %w", apperrors.ErrInvalidDeviceCode)
+	}
+	err = d.redis.Del(ctx, d.getUserCodeKey(userCode)).Err()
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationRepository.DeleteAuthorization: %w", err)
+	}
+	return nil
+}
+
+func NewDeviceAuthorizationRepository(redis *redis.Client) DeviceAuthorizationRepository {
+	return &deviceAuthorizationRepository{
+		redis: redis,
+	}
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailChangeRepository interface {
+	// CreateEmailChange cancel the pending email changes of the user, so only the last one can be confirmed
+	CreateEmailChange(ctx context.Context, change model.EmailChange) (model.EmailChange, error)
+	// GetEmailChange return apperrors.ErrEmailChangeNotFound if there is no such change
+	GetEmailChange(ctx context.Context, id string) (model.EmailChange, error)
+	// GetPendingEmailChange return the pending change of the user not expired at now,
+	// apperrors.ErrEmailChangeNotFound is returned if there is none
+	GetPendingEmailChange(ctx context.Context, userID string, now time.Time) (model.EmailChange, error)
+	// ApplyEmailChange swap the email of the user with the new address of the pending change and mark it confirmed.
+	// apperrors.ErrEmailChangeNotFound is returned if the change is not pending, has expired at now or the email of the
+	// user is not the old address anymore, apperrors.ErrUserMailAlreadyExists if another account has the new address
+	ApplyEmailChange(ctx context.Context, id string, now time.Time) (model.EmailChange, error)
+	// RevertEmailChange restore the old address of the confirmed change and mark it reverted.
+	// apperrors.ErrEmailChangeNotFound is returned if the change is not confirmed or the email of the user is not the
+	// new address anymore, apperrors.ErrUserMailAlreadyExists if another account has the old address
+	RevertEmailChange(ctx context.Context, id string, now time.Time) (model.EmailChange, error)
+	// CancelEmailChange return apperrors.ErrEmailChangeNotFound if the change is not pending
+	CancelEmailChange(ctx context.Context, id string, now time.Time) (model.EmailChange, error)
+	// FailEmailChange mark the pending change failed, when its new address has been taken by another account
+	FailEmailChange(ctx context.Context, id string) error
+}
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func (e *emailChangeRepository) CreateEmailChange(ctx context.Context, change model.EmailChange) (model.EmailChange, error) {
+	err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.EmailChange{}).
+			Where("user_id = ? AND status = ?", change.UserID, model.EmailChangeStatusPending).
+			Updates(map[string]any{
+				"status":       model.EmailChangeStatusCancelled,
+				"cancelled_at": change.CreatedAt,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeRepository.CreateEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeRepository) GetEmailChange(ctx context.Context, id string) (model.EmailChange, error) {
+	var change model.EmailChange
+	result := e.db.WithContext(ctx).First(&change, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return change, fmt.Errorf("emailChangeRepository.GetEmailChange: %w", apperrors.ErrEmailChangeNotFound)
+		}
+		return change, fmt.Errorf("emailChangeRepository.GetEmailChange: %w", result.Error)
+	}
+	return change, nil
+}
+
+func (e *emailChangeRepository) GetPendingEmailChange(ctx context.Context, userID string, now time.Time) (model.EmailChange, error) {
+	var change model.EmailChange
+	result := e.db.WithContext(ctx).
+		Where("user_id = ? AND status = ? AND expires_at > ?", userID, model.EmailChangeStatusPending, now).
+		Order("created_at DESC").
+		First(&change)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return change, fmt.Errorf("emailChangeRepository.GetPendingEmailChange: %w", apperrors.ErrEmailChangeNotFound)
+		}
+		return change, fmt.Errorf("emailChangeRepository.GetPendingEmailChange: %w", result.Error)
+	}
+	return change, nil
+}
+
+// setUserEmail replace the email from by to, the address is verified since it was confirmed by email
+func (*emailChangeRepository) setUserEmail(tx *gorm.DB, userID string, from string, to string, now time.Time) error {
+	res := tx.Model(&model.User{}).Where("id = ? AND email = ?", userID, from).Updates(map[string]any{
+		"email":          to,
+		"email_verified": true,
+		"updated_at":     now,
+	})
+	if res.Error != nil {
+		// the uniqueness of emails is only checked when the change is requested, another account may have
+		// taken the address since then
+		var pgErr *pgconn.PgError
+		if errors.As(res.Error, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "users_email_key" {
+			return apperrors.ErrUserMailAlreadyExists
+		}
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apperrors.ErrEmailChangeNotFound
+	}
+	return nil
+}
+
+// transition update the change matching the query and return it, the statement locks the change until the end of
+// tx so it can not be applied or reverted twice
+func (*emailChangeRepository) transition(tx *gorm.DB, updates map[string]any, query string, args ...any) (model.EmailChange, error) {
+	var changes []model.EmailChange
+	res := tx.Model(&changes).Clauses(clause.Returning{}).Where(query, args...).Updates(updates)
+	if res.Error != nil {
+		return model.EmailChange{}, res.Error
+	}
+	if len(changes) == 0 {
+		return model.EmailChange{}, apperrors.ErrEmailChangeNotFound
+	}
+	return changes[0], nil
+}
+
+func (e *emailChangeRepository) ApplyEmailChange(ctx context.Context, id string, now time.Time) (model.EmailChange, error) {
+	var change model.EmailChange
+	err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		change, err = e.transition(tx, map[string]any{
+			"status":       model.EmailChangeStatusConfirmed,
+			"confirmed_at": now,
+		}, "id = ? AND status = ? AND expires_at > ?", id, model.EmailChangeStatusPending, now)
+		if err != nil {
+			return err
+		}
+		return e.setUserEmail(tx, change.UserID, change.OldEmail, change.NewEmail, now)
+	})
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeRepository.ApplyEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeRepository) RevertEmailChange(ctx context.Context, id string, now time.Time) (model.EmailChange, error) {
+	var change model.EmailChange
+	err := e.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		change, err = e.transition(tx, map[string]any{
+			"status":       model.EmailChangeStatusReverted,
+			"cancelled_at": now,
+		}, "id = ? AND status = ?", id, model.EmailChangeStatusConfirmed)
+		if err != nil {
+			return err
+		}
+		return e.setUserEmail(tx, change.UserID, change.NewEmail, change.OldEmail, now)
+	})
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeRepository.RevertEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeRepository) CancelEmailChange(ctx context.Context, id string, now time.Time) (model.EmailChange, error) {
+	change, err := e.transition(e.db.WithContext(ctx), map[string]any{
+		"status":       model.EmailChangeStatusCancelled,
+		"cancelled_at": now,
+	}, "id = ? AND status = ?", id, model.EmailChangeStatusPending)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeRepository.CancelEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeRepository) FailEmailChange(ctx context.Context, id string) error {
+	err := e.db.WithContext(ctx).Model(&model.EmailChange{}).
+		Where("id = ? AND status = ?", id, model.EmailChangeStatusPending).
+		Update("status", model.EmailChangeStatusFailed).Error
+	if err != nil {
+		return fmt.Errorf("emailChangeRepository.FailEmailChange: %w", err)
+	}
+	return nil
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{
+		db: db,
+	}
+}
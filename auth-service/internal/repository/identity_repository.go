@@ -0,0 +1,97 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+type IdentityRepository interface {
+	// CreateIdentity return apperrors.ErrIdentityAlreadyLinked if the provider subject is linked to a user already
+	CreateIdentity(ctx context.Context, identity model.Identity) (model.Identity, error)
+	// GetIdentity return apperrors.ErrIdentityNotFound if the subject is not linked to any user
+	GetIdentity(ctx context.Context, provider string, subject string) (model.Identity, error)
+	TouchIdentity(ctx context.Context, id string, usedAt time.Time) error
+	// GetUserIdentities return the identities of the user, oldest first
+	GetUserIdentities(ctx context.Context, userID string) ([]model.Identity, error)
+	// DeleteUserIdentity return apperrors.ErrIdentityNotFound if the user has no such identity
+	DeleteUserIdentity(ctx context.Context, userID string, id string) error
+	// MoveUserIdentities link every identity of the user fromUserID to toUserID
+	MoveUserIdentities(ctx context.Context, fromUserID string, toUserID string) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func (i *identityRepository) CreateIdentity(ctx context.Context, identity model.Identity) (model.Identity, error) {
+	err := i.db.WithContext(ctx).Create(&identity).Error
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return model.Identity{}, fmt.Errorf("identityRepository.CreateIdentity: %w", apperrors.ErrIdentityAlreadyLinked)
+		}
+		return model.Identity{}, fmt.Errorf("identityRepository.CreateIdentity: %w", err)
+	}
+	return identity, nil
+}
+
+func (i *identityRepository) GetIdentity(ctx context.Context, provider string, subject string) (model.Identity, error) {
+	var identity model.Identity
+	result := i.db.WithContext(ctx).First(&identity, "provider = ? AND subject = ?", provider, subject)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return identity, fmt.Errorf("identityRepository.GetIdentity: %w", apperrors.ErrIdentityNotFound)
+		}
+		return identity, fmt.Errorf("identityRepository.GetIdentity: %w", result.Error)
+	}
+	return identity, nil
+}
+
+func (i *identityRepository) TouchIdentity(ctx context.Context, id string, usedAt time.Time) error {
+	err := i.db.WithContext(ctx).Model(&model.Identity{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+	if err != nil {
+		return fmt.Errorf("identityRepository.TouchIdentity: %w", err)
+	}
+	return nil
+}
+
+func (i *identityRepository) GetUserIdentities(ctx context.Context, userID string) ([]model.Identity, error) {
+	var identities []model.Identity
+	err := i.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	if err != nil {
+		return nil, fmt.Errorf("identityRepository.GetUserIdentities: %w", err)
+	}
+	return identities, nil
+}
+
+func (i *identityRepository) DeleteUserIdentity(ctx context.Context, userID string, id string) error {
+	res := i.db.WithContext(ctx).Delete(&model.Identity{}, "id = ? AND user_id = ?", id, userID)
+	if res.Error != nil {
+		return fmt.Errorf("identityRepository.DeleteUserIdentity: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("identityRepository.DeleteUserIdentity: %w", apperrors.ErrIdentityNotFound)
+	}
+	return nil
+}
+
+func (i *identityRepository) MoveUserIdentities(ctx context.Context, fromUserID string, toUserID string) error {
+	err := i.db.WithContext(ctx).Model(&model.Identity{}).Where("user_id = ?", fromUserID).Update("user_id", toUserID).Error
+	if err != nil {
+		return fmt.Errorf("identityRepository.MoveUserIdentities: %w", err)
+	}
+	return nil
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteRepository interface {
+	CreateInvite(ctx context.Context, invite model.Invite) (model.Invite, error)
+	// GetInvites return the invites, most recent first
+	GetInvites(ctx context.Context, limit int, offset int) ([]model.Invite, error)
+	// GetInviteByID return apperrors.ErrInviteNotFound if there is no such invite
+	GetInviteByID(ctx context.Context, id string) (model.Invite, error)
+	// RevokeInvite keep the first revocation time if the invite is already revoked
+	RevokeInvite(ctx context.Context, id string, revokedAt time.Time) error
+	// RedeemInvite take one use of the invite with this code hash and return it,
+	// apperrors.ErrInvalidInvite is returned if there is no such invite or it is not usable at now
+	RedeemInvite(ctx context.Context, codeHash string, now time.Time) (model.Invite, error)
+	// ReleaseInvite give back a use taken by RedeemInvite
+	ReleaseInvite(ctx context.Context, id string) error
+	AddRedemption(ctx context.Context, redemption model.InviteRedemption) error
+	// GetInvitedUsers return the users who signed up with the invite, in sign up order
+	GetInvitedUsers(ctx context.Context, inviteID string, limit int, offset int) ([]model.InvitedUser, error)
+	// GetUserInvite return apperrors.ErrInviteNotFound if the user did not sign up with an invite
+	GetUserInvite(ctx context.Context, userID string) (model.Invite, error)
+}
+
+type inviteRepository struct {
+	db *gorm.DB
+}
+
+func (i *inviteRepository) CreateInvite(ctx context.Context, invite model.Invite) (model.Invite, error) {
+	err := i.db.WithContext(ctx).Create(&invite).Error
+	if err != nil {
+		return model.Invite{}, fmt.Errorf("inviteRepository.CreateInvite: %w", err)
+	}
+	return invite, nil
+}
+
+func (i *inviteRepository) GetInvites(ctx context.Context, limit int, offset int) ([]model.Invite, error) {
+	var invites []model.Invite
+	err := i.db.WithContext(ctx).Order("created_at DESC").Order("id DESC").Limit(limit).Offset(offset).Find(&invites).Error
+	if err != nil {
+		return nil, fmt.Errorf("inviteRepository.GetInvites: %w", err)
+	}
+	return invites, nil
+}
+
+func (i *inviteRepository) GetInviteByID(ctx context.Context, id string) (model.Invite, error) {
+	var invite model.Invite
+	result := i.db.WithContext(ctx).First(&invite, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return invite, fmt.Errorf("inviteRepository.GetInviteByID: %w", apperrors.ErrInviteNotFound)
+		}
+		return invite, fmt.Errorf("inviteRepository.GetInviteByID: %w", result.Error)
+	}
+	return invite, nil
+}
+
+func (i *inviteRepository) RevokeInvite(ctx context.Context, id string, revokedAt time.Time) error {
+	res := i.db.WithContext(ctx).Model(&model.Invite{}).Where("id = ?", id).
+		Update("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", revokedAt))
+	if res.Error != nil {
+		return fmt.Errorf("inviteRepository.RevokeInvite: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("inviteRepository.RevokeInvite: %w", apperrors.ErrInviteNotFound)
+	}
+	return nil
+}
+
+func (i *inviteRepository) RedeemInvite(ctx context.Context, codeHash string, now time.Time) (model.Invite, error) {
+	var invites []model.Invite
+	// the use is taken in a single statement so concurrent sign ups can not exceed MaxUses
+	res := i.db.WithContext(ctx).Model(&invites).Clauses(clause.Returning{}).
+		Where("code_hash = ? AND revoked_at IS NULL AND expires_at > ? AND use_count < max_uses", codeHash, now).
+		Update("use_count", gorm.Expr("use_count + 1"))
+	if res.Error != nil {
+		return model.Invite{}, fmt.Errorf("inviteRepository.RedeemInvite: %w", res.Error)
+	}
+	if len(invites) == 0 {
+		return model.Invite{}, fmt.Errorf("inviteRepository.RedeemInvite: %w", apperrors.ErrInvalidInvite)
+	}
+	return invites[0], nil
+}
+
+func (i *inviteRepository) ReleaseInvite(ctx context.Context, id string) error {
+	err := i.db.WithContext(ctx).Model(&model.Invite{}).Where("id = ? AND use_count > 0", id).
+		Update("use_count", gorm.Expr("use_count - 1")).Error
+	if err != nil {
+		return fmt.Errorf("inviteRepository.ReleaseInvite: %w", err)
+	}
+	return nil
+}
+
+func (i *inviteRepository) AddRedemption(ctx context.Context, redemption model.InviteRedemption) error {
+	err := i.db.WithContext(ctx).Create(&redemption).Error
+	if err != nil {
+		return fmt.Errorf("inviteRepository.AddRedemption: %w", err)
+	}
+	return nil
+}
+
+func (i *inviteRepository) GetInvitedUsers(ctx context.Context, inviteID string, limit int, offset int) ([]model.InvitedUser, error) {
+	var users []model.InvitedUser
+	err := i.db.WithContext(ctx).Table("invite_redemptions AS r").
+		Select("u.id AS user_id, u.email, u.first_name, u.last_name, r.created_at AS redeemed_at").
+		Joins("JOIN users AS u ON u.id = r.user_id AND u.deleted_at IS NULL").
+		Where("r.invite_id = ?", inviteID).
+		Order("r.created_at").Order("r.id").
+		Limit(limit).Offset(offset).
+		Scan(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("inviteRepository.GetInvitedUsers: %w", err)
+	}
+	return users, nil
+}
+
+func (i *inviteRepository) GetUserInvite(ctx context.Context, userID string) (model.Invite, error) {
+	var invite model.Invite
+	result := i.db.WithContext(ctx).
+		Joins("JOIN invite_redemptions ON invite_redemptions.invite_id = invites.id").
+		Where("invite_redemptions.user_id = ?", userID).
+		First(&invite)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return invite, fmt.Errorf("inviteRepository.GetUserInvite: %w", apperrors.ErrInviteNotFound)
+		}
+		return invite, fmt.Errorf("inviteRepository.GetUserInvite: %w", result.Error)
+	}
+	return invite, nil
+}
+
+func NewInviteRepository(db *gorm.DB) InviteRepository {
+	return &inviteRepository{
+		db: db,
+	}
+}
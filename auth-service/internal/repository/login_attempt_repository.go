@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginAttemptPolicy is how failed login attempts are throttled, see service.LoginGuardConfig
+type LoginAttemptPolicy struct {
+	FailureWindow    time.Duration
+	DelayThreshold   int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	IPFailureLimit   int
+}
+
+// LoginAttemptRepository store the failed login attempts of emails and IPs with the delays and lockouts they cause
+type LoginAttemptRepository interface {
+	// Reserve count the attempt attemptID as a failure of both the email and the ip, and apply the delay or lockout
+	// this failure causes, before the password is verified. Parallel attempts thus see each other.
+	//
+	// It returns the number of failures of the email in the window including this one, or the time left before the
+	// next attempt is allowed if the email is delayed or locked or the ip made too many failed attempts
+	Reserve(ctx context.Context, email string, ip string, attemptID string, policy LoginAttemptPolicy) (int, time.Duration, error)
+	// Release remove the attempt attemptID from the failures of the ip
+	Release(ctx context.Context, ip string, attemptID string) error
+	// Clear remove the failures, the delay and the lockout of the email
+	Clear(ctx context.Context, email string) error
+}
+
+// reserveLoginAttemptScript check and count the attempt in one step, so the delay or lockout written by an attempt
+// is seen by all the attempts made after it
+var reserveLoginAttemptScript = redis.NewScript(`
+local lockout_key, delay_key, email_key, ip_key = KEYS[1], KEYS[2], KEYS[3], KEYS[4]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local delay_threshold, base_delay, max_delay = tonumber(ARGV[3]), tonumber(ARGV[4]), tonumber(ARGV[5])
+local lockout_threshold, lockout_duration = tonumber(ARGV[6]), tonumber(ARGV[7])
+local ip_limit = tonumber(ARGV[8])
+local attempt_id = ARGV[9]
+
+for _, key in ipairs({lockout_key, delay_key}) do
+	local ttl = redis.call('PTTL', key)
+	if ttl > 0 then
+		return {0, ttl}
+	end
+end
+redis.call('ZREMRANGEBYSCORE', ip_key, '-inf', now - window)
+if redis.call('ZCARD', ip_key) >= ip_limit then
+	local oldest = redis.call('ZRANGE', ip_key, 0, 0, 'WITHSCORES')
+	return {0, math.max(math.ceil((tonumber(oldest[2]) + window - now) / 1000), 1)}
+end
+redis.call('ZADD', ip_key, now, attempt_id)
+redis.call('PEXPIRE', ip_key, math.ceil(window / 1000))
+
+redis.call('ZREMRANGEBYSCORE', email_key, '-inf', now - window)
+redis.call('ZADD', email_key, now, attempt_id)
+redis.call('PEXPIRE', email_key, math.ceil(window / 1000))
+local failures = redis.call('ZCARD', email_key)
+if failures >= lockout_threshold then
+	-- the failures are counted again from zero, the next lockout happens after another lockout_threshold failures
+	redis.call('SET', lockout_key, 1, 'PX', lockout_duration)
+	redis.call('DEL', email_key)
+elseif failures >= delay_threshold then
+	local delay = max_delay
+	if failures - delay_threshold < 32 then
+		delay = math.min(base_delay * 2 ^ (failures - delay_threshold), max_delay)
+	end
+	redis.call('SET', delay_key, 1, 'PX', math.floor(delay))
+end
+return {failures, 0}
+`)
+
+type loginAttemptRepository struct {
+	redis *redis.Client
+}
+
+func (*loginAttemptRepository) getDelayKey(email string) string {
+	return fmt.Sprintf("login_delay:%s", email)
+}
+
+func (*loginAttemptRepository) getLockoutKey(email string) string {
+	return fmt.Sprintf("login_lockout:%s", email)
+}
+
+func (*loginAttemptRepository) getEmailFailuresKey(email string) string {
+	return fmt.Sprintf("login_failure:email:%s", email)
+}
+
+func (*loginAttemptRepository) getIPFailuresKey(ip string) string {
+	return fmt.Sprintf("login_failure:ip:%s", ip)
+}
+
+func (l *loginAttemptRepository) Reserve(ctx context.Context, email string, ip string, attemptID string, policy LoginAttemptPolicy) (int, time.Duration, error) {
+	keys := []string{l.getLockoutKey(email), l.getDelayKey(email), l.getEmailFailuresKey(email), l.getIPFailuresKey(ip)}
+	res, err := reserveLoginAttemptScript.Run(ctx, l.redis, keys,
+		time.Now().UnixMicro(),
+		policy.FailureWindow.Microseconds(),
+		policy.DelayThreshold,
+		policy.BaseDelay.Milliseconds(),
+		policy.MaxDelay.Milliseconds(),
+		policy.LockoutThreshold,
+		policy.LockoutDuration.Milliseconds(),
+		policy.IPFailureLimit,
+		attemptID,
+	).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("loginAttemptRepository.Reserve: %w", err)
+	}
+	if len(res) != 2 {
+		return 0, 0, fmt.Errorf("loginAttemptRepository.Reserve: unexpected script result %v", res)
+	}
+	return int(res[0]), time.Duration(res[1]) * time.Millisecond, nil
+}
+
+func (l *loginAttemptRepository) Release(ctx context.Context, ip string, attemptID string) error {
+	err := l.redis.ZRem(ctx, l.getIPFailuresKey(ip), attemptID).Err()
+	if err != nil {
+		return fmt.Errorf("loginAttemptRepository.Release: %w", err)
+	}
+	return nil
+}
+
+func (l *loginAttemptRepository) Clear(ctx context.Context, email string) error {
+	err := l.redis.Del(ctx, l.getDelayKey(email), l.getLockoutKey(email), l.getEmailFailuresKey(email)).Err()
+	if err != nil {
+		return fmt.Errorf("loginAttemptRepository.Clear: %w", err)
+	}
+	return nil
+}
+
+func NewLoginAttemptRepository(redis *redis.Client) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		redis: redis,
+	}
+}
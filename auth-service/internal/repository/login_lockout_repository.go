@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginLockoutRepository interface {
+	CreateLockout(ctx context.Context, lockout model.LoginLockout) (model.LoginLockout, error)
+	// GetUserLockouts return the lockouts of the user, most recent first
+	GetUserLockouts(ctx context.Context, userID string, limit, offset int) ([]model.LoginLockout, error)
+	// ClearUserLockouts mark the lockouts of the user that have not expired yet as cleared by clearedBy
+	ClearUserLockouts(ctx context.Context, userID string, clearedBy string) error
+}
+
+type loginLockoutRepository struct {
+	db *gorm.DB
+}
+
+func (l *loginLockoutRepository) CreateLockout(ctx context.Context, lockout model.LoginLockout) (model.LoginLockout, error) {
+	err := l.db.WithContext(ctx).Create(&lockout).Error
+	if err != nil {
+		return model.LoginLockout{}, fmt.Errorf("loginLockoutRepository.CreateLockout: %w", err)
+	}
+	return lockout, nil
+}
+
+func (l *loginLockoutRepository) GetUserLockouts(ctx context.Context, userID string, limit, offset int) ([]model.LoginLockout, error) {
+	var lockouts []model.LoginLockout
+	err := l.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("locked_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&lockouts).Error
+	if err != nil {
+		return nil, fmt.Errorf("loginLockoutRepository.GetUserLockouts: %w", err)
+	}
+	return lockouts, nil
+}
+
+func (l *loginLockoutRepository) ClearUserLockouts(ctx context.Context, userID string, clearedBy string) error {
+	now := time.Now()
+	err := l.db.WithContext(ctx).Model(&model.LoginLockout{}).
+		Where("user_id = ? AND cleared_at IS NULL AND locked_until > ?", userID, now).
+		Updates(map[string]any{
+			"cleared_at": now,
+			"cleared_by": clearedBy,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("loginLockoutRepository.ClearUserLockouts: %w", err)
+	}
+	return nil
+}
+
+func NewLoginLockoutRepository(db *gorm.DB) LoginLockoutRepository {
+	return &loginLockoutRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository interface {
+	// GetUserMFA return apperrors.ErrMFANotEnrolled if the user has not started a TOTP enrollment
+	GetUserMFA(ctx context.Context, userID string) (model.UserMFA, error)
+	// SavePendingTOTP store a new unconfirmed secret, replacing the previous pending one.
+	// It returns apperrors.ErrMFAAlreadyEnabled if the user already has MFA enabled
+	SavePendingTOTP(ctx context.Context, userID string, secret string) error
+	// EnableTOTP confirm the pending secret and replace the recovery codes of the user
+	EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	// UseTOTPStep record step as used, it returns apperrors.ErrInvalidMFACode if a code of this step or a later one was already used
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodeHashes []string) error
+	// UseRecoveryCode mark the code as used, it returns apperrors.ErrInvalidMFACode if there is no such unused code
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) error
+	CountRemainingRecoveryCodes(ctx context.Context, userID string) (int, error)
+	// DeleteUserMFA remove the TOTP secret and the recovery codes of the user
+	DeleteUserMFA(ctx context.Context, userID string) error
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func (m *mfaRepository) GetUserMFA(ctx context.Context, userID string) (model.UserMFA, error) {
+	var mfa model.UserMFA
+	result := m.db.WithContext(ctx).First(&mfa, "user_id = ?", userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return mfa, fmt.Errorf("mfaRepository.GetUserMFA: %w", apperrors.ErrMFANotEnrolled)
+		}
+		return mfa, fmt.Errorf("mfaRepository.GetUserMFA: %w", result.Error)
+	}
+	return mfa, nil
+}
+
+func (m *mfaRepository) SavePendingTOTP(ctx context.Context, userID string, secret string) error {
+	now := time.Now()
+	mfa := model.UserMFA{
+		UserID:     userID,
+		TOTPSecret: secret,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	res := m.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"totp_secret", "created_at", "updated_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "user_mfa.enabled", Value: false}}},
+	}).Create(&mfa)
+	if res.Error != nil {
+		return fmt.Errorf("mfaRepository.SavePendingTOTP: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("mfaRepository.SavePendingTOTP: %w", apperrors.ErrMFAAlreadyEnabled)
+	}
+	return nil
+}
+
+func (m *mfaRepository) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.UserMFA{}).
+			Where("user_id = ? AND enabled = ?", userID, false).
+			Updates(map[string]any{
+				"enabled":        true,
+				"last_used_step": step,
+				"confirmed_at":   time.Now(),
+				"updated_at":     time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return apperrors.ErrMFAAlreadyEnabled
+		}
+		return m.replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+	if err != nil {
+		return fmt.Errorf("mfaRepository.EnableTOTP: %w", err)
+	}
+	return nil
+}
+
+func (m *mfaRepository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	res := m.db.WithContext(ctx).Model(&model.UserMFA{}).
+		Where("user_id = ? AND enabled = ? AND last_used_step < ?", userID, true, step).
+		Updates(map[string]any{
+			"last_used_step": step,
+			"updated_at":     time.Now(),
+		})
+	if res.Error != nil {
+		return fmt.Errorf("mfaRepository.UseTOTPStep: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("mfaRepository.UseTOTPStep: %w", apperrors.ErrInvalidMFACode)
+	}
+	return nil
+}
+
+func (*mfaRepository) replaceRecoveryCodes(tx *gorm.DB, userID string, recoveryCodeHashes []string) error {
+	err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	if err != nil {
+		return err
+	}
+	if len(recoveryCodeHashes) == 0 {
+		return nil
+	}
+	codes := make([]model.RecoveryCode, 0, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes = append(codes, model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hash,
+		})
+	}
+	return tx.Create(&codes).Error
+}
+
+func (m *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodeHashes []string) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return m.replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+	if err != nil {
+		return fmt.Errorf("mfaRepository.ReplaceRecoveryCodes: %w", err)
+	}
+	return nil
+}
+
+func (m *mfaRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string) error {
+	res := m.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return fmt.Errorf("mfaRepository.UseRecoveryCode: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("mfaRepository.UseRecoveryCode: %w", apperrors.ErrInvalidMFACode)
+	}
+	return nil
+}
+
+func (m *mfaRepository) CountRemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int64
+	err := m.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("mfaRepository.CountRemainingRecoveryCodes: %w", err)
+	}
+	return int(count), nil
+}
+
+func (m *mfaRepository) DeleteUserMFA(ctx context.Context, userID string) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.UserMFA{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("mfaRepository.DeleteUserMFA: %w", err)
+	}
+	return nil
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OAuthClientRepository interface {
+	CreateClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error)
+	// GetClient return apperrors.ErrOAuthClientNotFound if the client does not exist
+	GetClient(ctx context.Context, id string) (model.OAuthClient, error)
+	GetClients(ctx context.Context) ([]model.OAuthClient, error)
+	// DeleteClient return apperrors.ErrOAuthClientNotFound if the client does not exist, its consents are deleted along
+	DeleteClient(ctx context.Context, id string) error
+	// GetConsent return apperrors.ErrConsentNotFound if the user never granted anything to the client
+	GetConsent(ctx context.Context, userID string, clientID string) (model.OAuthConsent, error)
+	// SaveConsent create the consent or replace its scopes
+	SaveConsent(ctx context.Context, consent model.OAuthConsent) error
+	// GetUserConsents return the consents of the user with their client, most recent first
+	GetUserConsents(ctx context.Context, userID string) ([]model.OAuthConsent, error)
+	// DeleteConsent return apperrors.ErrConsentNotFound if the user never granted anything to the client
+	DeleteConsent(ctx context.Context, userID string, clientID string) error
+}
+
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+func (o *oauthClientRepository) CreateClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error) {
+	err := o.db.WithContext(ctx).Create(&client).Error
+	if err != nil {
+		return model.OAuthClient{}, fmt.Errorf("oauthClientRepository.CreateClient: %w", err)
+	}
+	return client, nil
+}
+
+func (o *oauthClientRepository) GetClient(ctx context.Context, id string) (model.OAuthClient, error) {
+	var client model.OAuthClient
+	result := o.db.WithContext(ctx).First(&client, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return client, fmt.Errorf("oauthClientRepository.GetClient: %w", apperrors.ErrOAuthClientNotFound)
+		}
+		return client, fmt.Errorf("oauthClientRepository.GetClient: %w", result.Error)
+	}
+	return client, nil
+}
+
+func (o *oauthClientRepository) GetClients(ctx context.Context) ([]model.OAuthClient, error) {
+	var clients []model.OAuthClient
+	err := o.db.WithContext(ctx).Order("created_at DESC").Find(&clients).Error
+	if err != nil {
+		return nil, fmt.Errorf("oauthClientRepository.GetClients: %w", err)
+	}
+	return clients, nil
+}
+
+func (o *oauthClientRepository) DeleteClient(ctx context.Context, id string) error {
+	res := o.db.WithContext(ctx).Delete(&model.OAuthClient{}, "id = ?", id)
+	if res.Error != nil {
+		return fmt.Errorf("oauthClientRepository.DeleteClient: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("oauthClientRepository.DeleteClient: %w", apperrors.ErrOAuthClientNotFound)
+	}
+	return nil
+}
+
+func (o *oauthClientRepository) GetConsent(ctx context.Context, userID string, clientID string) (model.OAuthConsent, error) {
+	var consent model.OAuthConsent
+	result := o.db.WithContext(ctx).First(&consent, "user_id = ? AND client_id = ?", userID, clientID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return consent, fmt.Errorf("oauthClientRepository.GetConsent: %w", apperrors.ErrConsentNotFound)
+		}
+		return consent, fmt.Errorf("oauthClientRepository.GetConsent: %w", result.Error)
+	}
+	return consent, nil
+}
+
+func (o *oauthClientRepository) SaveConsent(ctx context.Context, consent model.OAuthConsent) error {
+	err := o.db.WithContext(ctx).Omit("Client").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scopes", "updated_at"}),
+	}).Create(&consent).Error
+	if err != nil {
+		return fmt.Errorf("oauthClientRepository.SaveConsent: %w", err)
+	}
+	return nil
+}
+
+func (o *oauthClientRepository) GetUserConsents(ctx context.Context, userID string) ([]model.OAuthConsent, error) {
+	var consents []model.OAuthConsent
+	err := o.db.WithContext(ctx).Preload("Client").Where("user_id = ?", userID).Order("updated_at DESC").Find(&consents).Error
+	if err != nil {
+		return nil, fmt.Errorf("oauthClientRepository.GetUserConsents: %w", err)
+	}
+	return consents, nil
+}
+
+func (o *oauthClientRepository) DeleteConsent(ctx context.Context, userID string, clientID string) error {
+	res := o.db.WithContext(ctx).Delete(&model.OAuthConsent{}, "user_id = ? AND client_id = ?", userID, clientID)
+	if res.Error != nil {
+		return fmt.Errorf("oauthClientRepository.DeleteConsent: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("oauthClientRepository.DeleteConsent: %w", apperrors.ErrConsentNotFound)
+	}
+	return nil
+}
+
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &oauthClientRepository{
+		db: db,
+	}
+}
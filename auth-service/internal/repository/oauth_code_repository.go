@@ -0,0 +1,62 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OAuthCodeRepository store the authorization codes issued to third-party apps by the hash of the code
+type OAuthCodeRepository interface {
+	SaveCode(ctx context.Context, codeHash string, code model.OAuthAuthorizationCode, ttl time.Duration) error
+	// ConsumeCode delete and return the code, apperrors.ErrInvalidGrant is returned if it does not exist,
+	// so a code can only be redeemed once
+	ConsumeCode(ctx context.Context, codeHash string) (model.OAuthAuthorizationCode, error)
+}
+
+type oauthCodeRepository struct {
+	redis *redis.Client
+}
+
+func (*oauthCodeRepository) getCodeKey(codeHash string) string {
+	return fmt.Sprintf("oauth_code:%s", codeHash)
+}
+
+func (o *oauthCodeRepository) SaveCode(ctx context.Context, codeHash string, code model.OAuthAuthorizationCode, ttl time.Duration) error {
+	data, err := json.Marshal(code)
+	if err != nil {
+		return fmt.Errorf("oauthCodeRepository.SaveCode: %w", err)
+	}
+	err = o.redis.Set(ctx, o.getCodeKey(codeHash), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("oauthCodeRepository.SaveCode: %w", err)
+	}
+	return nil
+}
+
+func (o *oauthCodeRepository) ConsumeCode(ctx context.Context, codeHash string) (model.OAuthAuthorizationCode, error) {
+	data, err := o.redis.GetDel(ctx, o.getCodeKey(codeHash)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.OAuthAuthorizationCode{}, fmt.Errorf("oauthCodeRepository.ConsumeCode: %w", apperrors.ErrInvalidGrant)
+		}
+		return model.OAuthAuthorizationCode{}, fmt.Errorf("oauthCodeRepository.ConsumeCode: %w", err)
+	}
+	var code model.OAuthAuthorizationCode
+	if err = json.Unmarshal(data, &code); err != nil {
+		return model.OAuthAuthorizationCode{}, fmt.Errorf("oauthCodeRepository.ConsumeCode: %w", err)
+	}
+	return code, nil
+}
+
+func NewOAuthCodeRepository(redis *redis.Client) OAuthCodeRepository {
+	return &oauthCodeRepository{
+		redis: redis,
+	}
+}
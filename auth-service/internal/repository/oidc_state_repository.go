@@ -0,0 +1,61 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OIDCStateRepository store the pending OIDC logins by their state parameter
+type OIDCStateRepository interface {
+	SaveState(ctx context.Context, state string, loginState model.OIDCLoginState, ttl time.Duration) error
+	// ConsumeState delete and return the login state, apperrors.ErrInvalidOIDCState is returned if it does not exist
+	ConsumeState(ctx context.Context, state string) (model.OIDCLoginState, error)
+}
+
+type oidcStateRepository struct {
+	redis *redis.Client
+}
+
+func (*oidcStateRepository) getStateKey(state string) string {
+	return fmt.Sprintf("oidc_state:%s", state)
+}
+
+func (o *oidcStateRepository) SaveState(ctx context.Context, state string, loginState model.OIDCLoginState, ttl time.Duration) error {
+	data, err := json.Marshal(loginState)
+	if err != nil {
+		return fmt.Errorf("oidcStateRepository.SaveState: %w", err)
+	}
+	err = o.redis.Set(ctx, o.getStateKey(state), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("oidcStateRepository.SaveState: %w", err)
+	}
+	return nil
+}
+
+func (o *oidcStateRepository) ConsumeState(ctx context.Context, state string) (model.OIDCLoginState, error) {
+	data, err := o.redis.GetDel(ctx, o.getStateKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.OIDCLoginState{}, fmt.Errorf("oidcStateRepository.ConsumeState: %w", apperrors.ErrInvalidOIDCState)
+		}
+		return model.OIDCLoginState{}, fmt.Errorf("oidcStateRepository.ConsumeState: %w", err)
+	}
+	var loginState model.OIDCLoginState
+	if err = json.Unmarshal(data, &loginState); err != nil {
+		return model.OIDCLoginState{}, fmt.Errorf("oidcStateRepository.ConsumeState: %w", err)
+	}
+	return loginState, nil
+}
+
+func NewOIDCStateRepository(redis *redis.Client) OIDCStateRepository {
+	return &oidcStateRepository{
+		redis: redis,
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PasskeyChallengeRepository store the pending WebAuthn ceremonies, a challenge can only be used once
+type PasskeyChallengeRepository interface {
+	SaveChallenge(ctx context.Context, key string, challenge model.PasskeyChallenge, ttl time.Duration) error
+	// ConsumeChallenge delete and return the challenge, apperrors.ErrInvalidPasskeyChallenge is returned if it does not exist
+	ConsumeChallenge(ctx context.Context, key string) (model.PasskeyChallenge, error)
+}
+
+type passkeyChallengeRepository struct {
+	redis *redis.Client
+}
+
+func (*passkeyChallengeRepository) getChallengeKey(key string) string {
+	return fmt.Sprintf("passkey_challenge:%s", key)
+}
+
+func (p *passkeyChallengeRepository) SaveChallenge(ctx context.Context, key string, challenge model.PasskeyChallenge, ttl time.Duration) error {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return fmt.Errorf("passkeyChallengeRepository.SaveChallenge: %w", err)
+	}
+	err = p.redis.Set(ctx, p.getChallengeKey(key), data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("passkeyChallengeRepository.SaveChallenge: %w", err)
+	}
+	return nil
+}
+
+func (p *passkeyChallengeRepository) ConsumeChallenge(ctx context.Context, key string) (model.PasskeyChallenge, error) {
+	data, err := p.redis.GetDel(ctx, p.getChallengeKey(key)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.PasskeyChallenge{}, fmt.Errorf("passkeyChallengeRepository.ConsumeChallenge: %w", apperrors.ErrInvalidPasskeyChallenge)
+		}
+		return model.PasskeyChallenge{}, fmt.Errorf("passkeyChallengeRepository.ConsumeChallenge: %w", err)
+	}
+	var challenge model.PasskeyChallenge
+	if err = json.Unmarshal(data, &challenge); err != nil {
+		return model.PasskeyChallenge{}, fmt.Errorf("passkeyChallengeRepository.ConsumeChallenge: %w", err)
+	}
+	return challenge, nil
+}
+
+func NewPasskeyChallengeRepository(redis *redis.Client) PasskeyChallengeRepository {
+	return &passkeyChallengeRepository{
+		redis: redis,
+	}
+}
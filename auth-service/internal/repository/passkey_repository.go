@@ -0,0 +1,111 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+type PasskeyRepository interface {
+	// CreatePasskey return apperrors.ErrPasskeyAlreadyExists if the credential is registered already
+	CreatePasskey(ctx context.Context, passkey model.Passkey) (model.Passkey, error)
+	// GetPasskeyByCredentialID return apperrors.ErrPasskeyNotFound if no user registered the credential
+	GetPasskeyByCredentialID(ctx context.Context, credentialID string) (model.Passkey, error)
+	// GetUserPasskeys return the passkeys of the user, oldest first
+	GetUserPasskeys(ctx context.Context, userID string) ([]model.Passkey, error)
+	CountUserPasskeys(ctx context.Context, userID string) (int64, error)
+	// UpdateSignCount store the counter of the last authentication and mark the passkey as used
+	UpdateSignCount(ctx context.Context, id string, signCount int64, usedAt time.Time) error
+	// DeleteUserPasskey return apperrors.ErrPasskeyNotFound if the user has no such passkey
+	DeleteUserPasskey(ctx context.Context, userID string, id string) error
+	// MoveUserPasskeys give every passkey of the user fromUserID to toUserID
+	MoveUserPasskeys(ctx context.Context, fromUserID string, toUserID string) error
+}
+
+type passkeyRepository struct {
+	db *gorm.DB
+}
+
+func (p *passkeyRepository) CreatePasskey(ctx context.Context, passkey model.Passkey) (model.Passkey, error) {
+	err := p.db.WithContext(ctx).Create(&passkey).Error
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return model.Passkey{}, fmt.Errorf("passkeyRepository.CreatePasskey: %w", apperrors.ErrPasskeyAlreadyExists)
+		}
+		return model.Passkey{}, fmt.Errorf("passkeyRepository.CreatePasskey: %w", err)
+	}
+	return passkey, nil
+}
+
+func (p *passkeyRepository) GetPasskeyByCredentialID(ctx context.Context, credentialID string) (model.Passkey, error) {
+	var passkey model.Passkey
+	result := p.db.WithContext(ctx).First(&passkey, "credential_id = ?", credentialID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return passkey, fmt.Errorf("passkeyRepository.GetPasskeyByCredentialID: %w", apperrors.ErrPasskeyNotFound)
+		}
+		return passkey, fmt.Errorf("passkeyRepository.GetPasskeyByCredentialID: %w", result.Error)
+	}
+	return passkey, nil
+}
+
+func (p *passkeyRepository) GetUserPasskeys(ctx context.Context, userID string) ([]model.Passkey, error) {
+	var passkeys []model.Passkey
+	err := p.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&passkeys).Error
+	if err != nil {
+		return nil, fmt.Errorf("passkeyRepository.GetUserPasskeys: %w", err)
+	}
+	return passkeys, nil
+}
+
+func (p *passkeyRepository) CountUserPasskeys(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := p.db.WithContext(ctx).Model(&model.Passkey{}).Where("user_id = ?", userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("passkeyRepository.CountUserPasskeys: %w", err)
+	}
+	return count, nil
+}
+
+func (p *passkeyRepository) UpdateSignCount(ctx context.Context, id string, signCount int64, usedAt time.Time) error {
+	err := p.db.WithContext(ctx).Model(&model.Passkey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"last_used_at": usedAt,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("passkeyRepository.UpdateSignCount: %w", err)
+	}
+	return nil
+}
+
+func (p *passkeyRepository) DeleteUserPasskey(ctx context.Context, userID string, id string) error {
+	res := p.db.WithContext(ctx).Delete(&model.Passkey{}, "id = ? AND user_id = ?", id, userID)
+	if res.Error != nil {
+		return fmt.Errorf("passkeyRepository.DeleteUserPasskey: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("passkeyRepository.DeleteUserPasskey: %w", apperrors.ErrPasskeyNotFound)
+	}
+	return nil
+}
+
+func (p *passkeyRepository) MoveUserPasskeys(ctx context.Context, fromUserID string, toUserID string) error {
+	err := p.db.WithContext(ctx).Model(&model.Passkey{}).Where("user_id = ?", fromUserID).Update("user_id", toUserID).Error
+	if err != nil {
+		return fmt.Errorf("passkeyRepository.MoveUserPasskeys: %w", err)
+	}
+	return nil
+}
+
+func NewPasskeyRepository(db *gorm.DB) PasskeyRepository {
+	return &passkeyRepository{
+		db: db,
+	}
+}
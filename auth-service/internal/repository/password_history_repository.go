@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository interface {
+	// AddPassword save hash as the latest password of the user and forget the ones older than the keep latest
+	AddPassword(ctx context.Context, userID string, hash string, keep int) error
+	// GetRecentPasswords return the hashes of the limit latest passwords of the user, most recent first
+	GetRecentPasswords(ctx context.Context, userID string, limit int) ([]string, error)
+}
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func (p *passwordHistoryRepository) AddPassword(ctx context.Context, userID string, hash string, keep int) error {
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&model.PasswordHistory{UserID: userID, PasswordHash: hash}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id NOT IN (?)", userID,
+			tx.Model(&model.PasswordHistory{}).Select("id").Where("user_id = ?", userID).Order("created_at DESC").Order("id DESC").Limit(keep),
+		).Delete(&model.PasswordHistory{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("passwordHistoryRepository.AddPassword: %w", err)
+	}
+	return nil
+}
+
+func (p *passwordHistoryRepository) GetRecentPasswords(ctx context.Context, userID string, limit int) ([]string, error) {
+	var hashes []string
+	err := p.db.WithContext(ctx).Model(&model.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+	if err != nil {
+		return nil, fmt.Errorf("passwordHistoryRepository.GetRecentPasswords: %w", err)
+	}
+	return hashes, nil
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{
+		db: db,
+	}
+}
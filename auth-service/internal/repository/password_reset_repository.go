@@ -0,0 +1,85 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PasswordResetRepository store password reset tokens by their hash, a user has at most one valid token
+type PasswordResetRepository interface {
+	// SaveResetToken save the token hash and invalidate the previous token of the user
+	SaveResetToken(ctx context.Context, tokenHash string, userID string, ttl time.Duration) error
+	// GetResetTokenUser return the id of the user of the token without consuming it,
+	// apperrors.ErrInvalidToken is returned if the token does not exist
+	GetResetTokenUser(ctx context.Context, tokenHash string) (string, error)
+	// ConsumeResetToken delete the token and return the id of its user,
+	// apperrors.ErrInvalidToken is returned if the token does not exist
+	ConsumeResetToken(ctx context.Context, tokenHash string) (string, error)
+}
+
+type passwordResetRepository struct {
+	redis *redis.Client
+}
+
+func (*passwordResetRepository) getResetTokenKey(tokenHash string) string {
+	return fmt.Sprintf("password_reset:%s", tokenHash)
+}
+
+func (*passwordResetRepository) getUserResetTokenKey(userID string) string {
+	return fmt.Sprintf("user:%s:password_reset", userID)
+}
+
+func (p *passwordResetRepository) SaveResetToken(ctx context.Context, tokenHash string, userID string, ttl time.Duration) error {
+	userKey := p.getUserResetTokenKey(userID)
+	previous, err := p.redis.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("passwordResetRepository.SaveResetToken: %w", err)
+	}
+	pipe := p.redis.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, p.getResetTokenKey(previous))
+	}
+	pipe.Set(ctx, p.getResetTokenKey(tokenHash), userID, ttl)
+	pipe.Set(ctx, userKey, tokenHash, ttl)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("passwordResetRepository.SaveResetToken: %w", err)
+	}
+	return nil
+}
+
+func (p *passwordResetRepository) GetResetTokenUser(ctx context.Context, tokenHash string) (string, error) {
+	userID, err := p.redis.Get(ctx, p.getResetTokenKey(tokenHash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", fmt.Errorf("passwordResetRepository.GetResetTokenUser: %w", apperrors.ErrInvalidToken)
+		}
+		return "", fmt.Errorf("passwordResetRepository.GetResetTokenUser: %w", err)
+	}
+	return userID, nil
+}
+
+func (p *passwordResetRepository) ConsumeResetToken(ctx context.Context, tokenHash string) (string, error) {
+	userID, err := p.redis.GetDel(ctx, p.getResetTokenKey(tokenHash)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", fmt.Errorf("passwordResetRepository.ConsumeResetToken: %w", apperrors.ErrInvalidToken)
+		}
+		return "", fmt.Errorf("passwordResetRepository.ConsumeResetToken: %w", err)
+	}
+	err = p.redis.Del(ctx, p.getUserResetTokenKey(userID)).Err()
+	if err != nil {
+		return "", fmt.Errorf("passwordResetRepository.ConsumeResetToken: %w", err)
+	}
+	return userID, nil
+}
+
+func NewPasswordResetRepository(redis *redis.Client) PasswordResetRepository {
+	return &passwordResetRepository{
+		redis: redis,
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenRepository interface {
+	CreateToken(ctx context.Context, token model.PersonalAccessToken) (model.PersonalAccessToken, error)
+	// GetTokenByHash return apperrors.ErrInvalidToken if no token has this hash
+	GetTokenByHash(ctx context.Context, tokenHash string) (model.PersonalAccessToken, error)
+	// GetUserTokens return the tokens of the user, most recent first
+	GetUserTokens(ctx context.Context, userID string) ([]model.PersonalAccessToken, error)
+	CountUserTokens(ctx context.Context, userID string) (int, error)
+	// DeleteUserToken return apperrors.ErrPersonalAccessTokenNotFound if the user has no such token
+	DeleteUserToken(ctx context.Context, userID string, id string) error
+	// TouchToken set the last used time of the token, unless it has already been set less than interval ago
+	TouchToken(ctx context.Context, id string, usedAt time.Time, interval time.Duration) error
+}
+
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func (p *personalAccessTokenRepository) CreateToken(ctx context.Context, token model.PersonalAccessToken) (model.PersonalAccessToken, error) {
+	err := p.db.WithContext(ctx).Create(&token).Error
+	if err != nil {
+		return model.PersonalAccessToken{}, fmt.Errorf("personalAccessTokenRepository.CreateToken: %w", err)
+	}
+	return token, nil
+}
+
+func (p *personalAccessTokenRepository) GetTokenByHash(ctx context.Context, tokenHash string) (model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	result := p.db.WithContext(ctx).First(&token, "token_hash = ?", tokenHash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return token, fmt.Errorf("personalAccessTokenRepository.GetTokenByHash: %w", apperrors.ErrInvalidToken)
+		}
+		return token, fmt.Errorf("personalAccessTokenRepository.GetTokenByHash: %w", result.Error)
+	}
+	return token, nil
+}
+
+func (p *personalAccessTokenRepository) GetUserTokens(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	var tokens []model.PersonalAccessToken
+	err := p.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, fmt.Errorf("personalAccessTokenRepository.GetUserTokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (p *personalAccessTokenRepository) CountUserTokens(ctx context.Context, userID string) (int, error) {
+	var count int64
+	err := p.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).Where("user_id = ?", userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("personalAccessTokenRepository.CountUserTokens: %w", err)
+	}
+	return int(count), nil
+}
+
+func (p *personalAccessTokenRepository) DeleteUserToken(ctx context.Context, userID string, id string) error {
+	res := p.db.WithContext(ctx).Delete(&model.PersonalAccessToken{}, "id = ? AND user_id = ?", id, userID)
+	if res.Error != nil {
+		return fmt.Errorf("personalAccessTokenRepository.DeleteUserToken: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("personalAccessTokenRepository.DeleteUserToken: %w", apperrors.ErrPersonalAccessTokenNotFound)
+	}
+	return nil
+}
+
+func (p *personalAccessTokenRepository) TouchToken(ctx context.Context, id string, usedAt time.Time, interval time.Duration) error {
+	err := p.db.WithContext(ctx).Model(&model.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, usedAt.Add(-interval)).
+		Update("last_used_at", usedAt).Error
+	if err != nil {
+		return fmt.Errorf("personalAccessTokenRepository.TouchToken: %w", err)
+	}
+	return nil
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type RateLimitRepository interface {
+	// Hit record a hit in the sliding window of key and return the number of hits in the window,
+	// including this one, and the time of the oldest of them
+	Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error)
+	// Count return the number of hits in the sliding window of key without recording a new one
+	Count(ctx context.Context, key string, window time.Duration) (int64, time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type rateLimitRepository struct {
+	redis *redis.Client
+}
+
+func (*rateLimitRepository) getRateLimitKey(key string) string {
+	return fmt.Sprintf("rate_limit:%s", key)
+}
+
+func (r *rateLimitRepository) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	redisKey := r.getRateLimitKey(key)
+	now := time.Now()
+	pipe := r.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, redisKey, "-inf", strconv.FormatInt(now.Add(-window).UnixMicro(), 10))
+	pipe.ZAdd(ctx, redisKey, redis.Z{Score: float64(now.UnixMicro()), Member: uuid.NewString()})
+	count := pipe.ZCard(ctx, redisKey)
+	oldest := pipe.ZRangeWithScores(ctx, redisKey, 0, 0)
+	pipe.PExpire(ctx, redisKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, time.Time{}, fmt.Errorf("rateLimitRepository.Hit: %w", err)
+	}
+	return count.Val(), r.oldestHit(oldest.Val(), now), nil
+}
+
+func (r *rateLimitRepository) Count(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	redisKey := r.getRateLimitKey(key)
+	now := time.Now()
+	pipe := r.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, redisKey, "-inf", strconv.FormatInt(now.Add(-window).UnixMicro(), 10))
+	count := pipe.ZCard(ctx, redisKey)
+	oldest := pipe.ZRangeWithScores(ctx, redisKey, 0, 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, time.Time{}, fmt.Errorf("rateLimitRepository.Count: %w", err)
+	}
+	return count.Val(), r.oldestHit(oldest.Val(), now), nil
+}
+
+func (*rateLimitRepository) oldestHit(z []redis.Z, now time.Time) time.Time {
+	if len(z) == 0 {
+		return now
+	}
+	return time.UnixMicro(int64(z[0].Score))
+}
+
+func (r *rateLimitRepository) Reset(ctx context.Context, key string) error {
+	err := r.redis.Del(ctx, r.getRateLimitKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("rateLimitRepository.Reset: %w", err)
+	}
+	return nil
+}
+
+func NewRateLimitRepository(redis *redis.Client) RateLimitRepository {
+	return &rateLimitRepository{
+		redis: redis,
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SecurityEventRepository only appends to the audit log, the table rejects updates and deletes
+type SecurityEventRepository interface {
+	CreateEvent(ctx context.Context, event model.SecurityEvent) error
+	// PseudonymizeUserEvents erase the personal data of a deleted user from the audit log, through the only database
+	// function the table lets change its rows
+	PseudonymizeUserEvents(ctx context.Context, userID string) error
+	// GetEvents return the events matching filter, most recent first
+	GetEvents(ctx context.Context, filter model.SecurityEventFilter, limit, offset int) ([]model.SecurityEvent, error)
+}
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+func (s *securityEventRepository) CreateEvent(ctx context.Context, event model.SecurityEvent) error {
+	err := s.db.WithContext(ctx).Create(&event).Error
+	if err != nil {
+		return fmt.Errorf("securityEventRepository.CreateEvent: %w", err)
+	}
+	return nil
+}
+
+func (s *securityEventRepository) PseudonymizeUserEvents(ctx context.Context, userID string) error {
+	err := s.db.WithContext(ctx).Exec("SELECT pseudonymize_security_events(?)", userID).Error
+	if err != nil {
+		return fmt.Errorf("securityEventRepository.PseudonymizeUserEvents: %w", err)
+	}
+	return nil
+}
+
+func (s *securityEventRepository) GetEvents(ctx context.Context, filter model.SecurityEventFilter, limit, offset int) ([]model.SecurityEvent, error) {
+	query := s.db.WithContext(ctx)
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	var events []model.SecurityEvent
+	err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("securityEventRepository.GetEvents: %w", err)
+	}
+	return events, nil
+}
+
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepository {
+	return &securityEventRepository{
+		db: db,
+	}
+}
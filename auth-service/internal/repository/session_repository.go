@@ -0,0 +1,181 @@
+package repository
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type SessionRepository interface {
+	// SetSession create or replace a session with expiration time
+	//
+	// Set ttl parameter to -1 to keep existing TTL and 0 to have no expiration time
+	SetSession(ctx context.Context, session model.Session, ttl time.Duration) error
+	// RotateSession replace a session keeping its TTL, only if it still exists and its refresh token is still previousRefreshTokenID.
+	//
+	// ErrSessionNotFound is returned if the session has been deleted meanwhile and ErrRefreshTokenReused if it has already been rotated
+	RotateSession(ctx context.Context, session model.Session, previousRefreshTokenID string) error
+	GetSession(ctx context.Context, sessionID string) (model.Session, error)
+	// GetUserSessions return all sessions of a user, expired sessions are pruned from the user index
+	GetUserSessions(ctx context.Context, userID string) ([]model.Session, error)
+	DeleteSession(ctx context.Context, userID string, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID string) error
+}
+
+// rotateSessionScript compare and swap the session, so a revoked session is not recreated
+// and two concurrent refreshes with the same token cannot both succeed
+var rotateSessionScript = redis.NewScript(`
+local data = redis.call('GET', KEYS[1])
+if not data then
+	return -1
+end
+if cjson.decode(data)['refresh_token_id'] ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+return 1
+`)
+
+type sessionRepository struct {
+	redis *redis.Client
+}
+
+func (*sessionRepository) getSessionKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
+}
+
+func (*sessionRepository) getUserSessionsKey(userID string) string {
+	return fmt.Sprintf("user:%s:sessions", userID)
+}
+
+func (s *sessionRepository) SetSession(ctx context.Context, session model.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("sessionRepository.SetSession: %w", err)
+	}
+	userKey := s.getUserSessionsKey(session.UserID)
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, s.getSessionKey(session.ID), data, ttl)
+	pipe.SAdd(ctx, userKey, session.ID)
+	if ttl > 0 {
+		pipe.Expire(ctx, userKey, ttl)
+	}
+	if _, err = pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("sessionRepository.SetSession: %w", err)
+	}
+	return nil
+}
+
+func (s *sessionRepository) RotateSession(ctx context.Context, session model.Session, previousRefreshTokenID string) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("sessionRepository.RotateSession: %w", err)
+	}
+	res, err := rotateSessionScript.Run(ctx, s.redis, []string{s.getSessionKey(session.ID)}, previousRefreshTokenID, data).Int()
+	if err != nil {
+		return fmt.Errorf("sessionRepository.RotateSession: %w", err)
+	}
+	switch res {
+	case -1:
+		return fmt.Errorf("sessionRepository.RotateSession: %w", apperrors.ErrSessionNotFound)
+	case 0:
+		return fmt.Errorf("sessionRepository.RotateSession: %w", apperrors.ErrRefreshTokenReused)
+	}
+	return nil
+}
+
+func (s *sessionRepository) GetSession(ctx context.Context, sessionID string) (model.Session, error) {
+	data, err := s.redis.Get(ctx, s.getSessionKey(sessionID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.Session{}, fmt.Errorf("sessionRepository.GetSession: %w", apperrors.ErrSessionNotFound)
+		}
+		return model.Session{}, fmt.Errorf("sessionRepository.GetSession: %w", err)
+	}
+	var session model.Session
+	if err = json.Unmarshal(data, &session); err != nil {
+		return model.Session{}, fmt.Errorf("sessionRepository.GetSession: %w", err)
+	}
+	return session, nil
+}
+
+func (s *sessionRepository) GetUserSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	userKey := s.getUserSessionsKey(userID)
+	ids, err := s.redis.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("sessionRepository.GetUserSessions: %w", err)
+	}
+	if len(ids) == 0 {
+		return []model.Session{}, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.getSessionKey(id)
+	}
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("sessionRepository.GetUserSessions: %w", err)
+	}
+	sessions := make([]model.Session, 0, len(values))
+	var expired []interface{}
+	for i, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		var session model.Session
+		if err = json.Unmarshal([]byte(raw), &session); err != nil {
+			return nil, fmt.Errorf("sessionRepository.GetUserSessions: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		if err = s.redis.SRem(ctx, userKey, expired...).Err(); err != nil {
+			return nil, fmt.Errorf("sessionRepository.GetUserSessions: %w", err)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *sessionRepository) DeleteSession(ctx context.Context, userID string, sessionID string) error {
+	pipe := s.redis.TxPipeline()
+	del := pipe.Del(ctx, s.getSessionKey(sessionID))
+	pipe.SRem(ctx, s.getUserSessionsKey(userID), sessionID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("sessionRepository.DeleteSession: %w", err)
+	}
+	if del.Val() == 0 {
+		return fmt.Errorf("sessionRepository.DeleteSession: %w", apperrors.ErrSessionNotFound)
+	}
+	return nil
+}
+
+func (s *sessionRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	userKey := s.getUserSessionsKey(userID)
+	ids, err := s.redis.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return fmt.Errorf("sessionRepository.DeleteUserSessions: %w", err)
+	}
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, s.getSessionKey(id))
+	}
+	keys = append(keys, userKey)
+	if err = s.redis.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("sessionRepository.DeleteUserSessions: %w", err)
+	}
+	return nil
+}
+
+func NewSessionRepository(redis *redis.Client) SessionRepository {
+	return &sessionRepository{
+		redis: redis,
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type TokenRevocationRepository interface {
+	// RevokeToken add the token id to the revocation list, ttl should cover the remaining token lifetime
+	RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// SetUserTokensRevokedBefore revoke every token of the user issued before the given time, kept to the microsecond
+	SetUserTokensRevokedBefore(ctx context.Context, userID string, before time.Time, ttl time.Duration) error
+	// GetUserTokensRevokedBefore return the zero time if no token of the user has been revoked
+	GetUserTokensRevokedBefore(ctx context.Context, userID string) (time.Time, error)
+	// SetUserBlocked mark the user as blocked with the given status, a ttl of 0 means the block never expires
+	SetUserBlocked(ctx context.Context, userID string, status string, ttl time.Duration) error
+	// GetUserBlocked return the status the user is blocked with, or an empty string if the user is not blocked
+	GetUserBlocked(ctx context.Context, userID string) (string, error)
+	ClearUserBlocked(ctx context.Context, userID string) error
+}
+
+type tokenRevocationRepository struct {
+	redis *redis.Client
+}
+
+func (*tokenRevocationRepository) getRevokedTokenKey(tokenID string) string {
+	return fmt.Sprintf("revoked_token:%s", tokenID)
+}
+
+func (*tokenRevocationRepository) getUserRevokedBeforeKey(userID string) string {
+	return fmt.Sprintf("user:%s:revoked_before", userID)
+}
+
+func (*tokenRevocationRepository) getUserBlockedKey(userID string) string {
+	return fmt.Sprintf("user:%s:blocked", userID)
+}
+
+func (t *tokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	err := t.redis.Set(ctx, t.getRevokedTokenKey(tokenID), 1, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("tokenRevocationRepository.RevokeToken: %w", err)
+	}
+	return nil
+}
+
+func (t *tokenRevocationRepository) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := t.redis.Exists(ctx, t.getRevokedTokenKey(tokenID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("tokenRevocationRepository.IsTokenRevoked: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (t *tokenRevocationRepository) SetUserTokensRevokedBefore(ctx context.Context, userID string, before time.Time, ttl time.Duration) error {
+	err := t.redis.Set(ctx, t.getUserRevokedBeforeKey(userID), before.UnixMicro(), ttl).Err()
+	if err != nil {
+		return fmt.Errorf("tokenRevocationRepository.SetUserTokensRevokedBefore: %w", err)
+	}
+	return nil
+}
+
+func (t *tokenRevocationRepository) GetUserTokensRevokedBefore(ctx context.Context, userID string) (time.Time, error) {
+	before, err := t.redis.Get(ctx, t.getUserRevokedBeforeKey(userID)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("tokenRevocationRepository.GetUserTokensRevokedBefore: %w", err)
+	}
+	return time.UnixMicro(before), nil
+}
+
+func (t *tokenRevocationRepository) SetUserBlocked(ctx context.Context, userID string, status string, ttl time.Duration) error {
+	err := t.redis.Set(ctx, t.getUserBlockedKey(userID), status, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("tokenRevocationRepository.SetUserBlocked: %w", err)
+	}
+	return nil
+}
+
+func (t *tokenRevocationRepository) GetUserBlocked(ctx context.Context, userID string) (string, error) {
+	status, err := t.redis.Get(ctx, t.getUserBlockedKey(userID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", fmt.Errorf("tokenRevocationRepository.GetUserBlocked: %w", err)
+	}
+	return status, nil
+}
+
+func (t *tokenRevocationRepository) ClearUserBlocked(ctx context.Context, userID string) error {
+	err := t.redis.Del(ctx, t.getUserBlockedKey(userID)).Err()
+	if err != nil {
+		return fmt.Errorf("tokenRevocationRepository.ClearUserBlocked: %w", err)
+	}
+	return nil
+}
+
+func NewTokenRevocationRepository(redis *redis.Client) TokenRevocationRepository {
+	return &tokenRevocationRepository{
+		redis: redis,
+	}
+}
@@ -6,18 +6,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
+	// CreateUser return apperrors.ErrUserMailAlreadyExists if a user who is not deleted has the email
 	CreateUser(ctx context.Context, user model.User) (model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (model.User, error)
+	// GetUserByUsername find the user regardless of the case of username
+	GetUserByUsername(ctx context.Context, username string) (model.User, error)
 	UpdateUserByID(ctx context.Context, user model.User) error
-	// GetUsers sort users by ID
-	GetUsers(ctx context.Context, userEmail string, sortOrder string, limit, offset int) ([]model.User, error)
+	// SearchUsers return a page of the users matching query, starting after query.After
+	SearchUsers(ctx context.Context, query model.UserQuery) ([]model.User, error)
+	// CountUsers return the number of users matching query, its sort and pagination are ignored
+	CountUsers(ctx context.Context, query model.UserQuery) (int64, error)
 	GetUserByID(ctx context.Context, id string) (model.User, error)
+	SetEmailVerified(ctx context.Context, id string, verified bool) error
+	// UpdateUserStatus set the status of the user, until and reason are cleared when they are empty
+	UpdateUserStatus(ctx context.Context, id string, status string, until *time.Time, reason string) error
+	UpdateUserRole(ctx context.Context, id string, role string) error
+	// UpdateUsername return apperrors.ErrUsernameTaken if another user has the username
+	UpdateUsername(ctx context.Context, id string, username string, changedAt time.Time) error
+	UpdateBio(ctx context.Context, id string, bio string) error
+	// DeleteUserByID soft delete the user, who is then ignored by every other query
+	DeleteUserByID(ctx context.Context, id string) error
+	// SetDeletionScheduledAt schedule the erasure of the account at the given time, it is cancelled when at is nil
+	SetDeletionScheduledAt(ctx context.Context, id string, at *time.Time) error
+	// GetUsersDueForDeletion return up to limit users whose deletion is scheduled at or before now,
+	// including soft deleted ones
+	GetUsersDueForDeletion(ctx context.Context, now time.Time, limit int) ([]model.User, error)
+	// PurgeUserByID permanently delete the user and, by cascade, everything attached to their account
+	PurgeUserByID(ctx context.Context, id string) error
 }
 
 type userRepository struct {
@@ -29,8 +52,11 @@ func (u *userRepository) CreateUser(ctx context.Context, user model.User) (model
 	if result.Error != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(result.Error, &pgErr) && pgErr.Code == "23505" {
-			if pgErr.ConstraintName == "users_email_key" {
+			switch pgErr.ConstraintName {
+			case "users_email_key":
 				return user, fmt.Errorf("userRepository.CreateUser: %w", apperrors.ErrUserMailAlreadyExists)
+			case "users_username_key":
+				return user, fmt.Errorf("userRepository.CreateUser: %w", apperrors.ErrUsernameTaken)
 			}
 		}
 		return model.User{}, fmt.Errorf("userRepository.CreateUser: %w", result.Error)
@@ -50,13 +76,29 @@ func (u *userRepository) GetUserByEmail(ctx context.Context, email string) (mode
 	return user, nil
 }
 
+func (u *userRepository) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
+	var user model.User
+	// the condition on the empty username lets the partial unique index be used
+	result := u.db.WithContext(ctx).First(&user, "lower(username) = lower(?) AND username <> ''", username)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return user, fmt.Errorf("userRepository.GetUserByUsername: %w", apperrors.ErrUserNotFound)
+		}
+		return user, fmt.Errorf("userRepository.GetUserByUsername: %w", result.Error)
+	}
+	return user, nil
+}
+
 func (u *userRepository) UpdateUserByID(ctx context.Context, user model.User) error {
 	res := u.db.WithContext(ctx).Updates(&user)
 	if res.Error != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(res.Error, &pgErr) && pgErr.Code == "23505" {
-			if pgErr.ConstraintName == "users_email_key" {
+			switch pgErr.ConstraintName {
+			case "users_email_key":
 				return apperrors.ErrUserMailAlreadyExists
+			case "users_username_key":
+				return apperrors.ErrUsernameTaken
 			}
 		}
 		return res.Error
@@ -67,19 +109,88 @@ func (u *userRepository) UpdateUserByID(ctx context.Context, user model.User) er
 	return nil
 }
 
-func (u *userRepository) GetUsers(ctx context.Context, userEmail string, sortOrder string, limit, offset int) ([]model.User, error) {
-	query := u.db.WithContext(ctx)
-	if userEmail != "" {
-		query = query.Where("email LIKE ?", userEmail+"%")
+// userSortColumns maps the sort fields to the expression they are sorted by, never build ORDER BY from user input
+var userSortColumns = map[string]string{
+	model.UserSortCreatedAt: "created_at",
+	model.UserSortEmail:     "email",
+	model.UserSortFirstName: "COALESCE(first_name, '')",
+	model.UserSortLastName:  "COALESCE(last_name, '')",
+}
+
+// likeEscaper escape the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// filterUsers apply the filters of query to db
+func (*userRepository) filterUsers(db *gorm.DB, query model.UserQuery) *gorm.DB {
+	if query.Email != "" {
+		db = db.Where("email LIKE ?", likeEscaper.Replace(query.Email)+"%")
+	}
+	if query.Name != "" {
+		pattern := "%" + likeEscaper.Replace(query.Name) + "%"
+		db = db.Where("(first_name ILIKE ? OR last_name ILIKE ? OR username ILIKE ?)", pattern, pattern, pattern)
+	}
+	if query.Role != "" {
+		db = db.Where("role = ?", query.Role)
+	}
+	// a suspension is over once suspended_until is passed, even if the status has not been updated
+	now := time.Now()
+	switch query.Status {
+	case model.UserStatusActive:
+		db = db.Where("(status = ? OR (status = ? AND suspended_until <= ?))", model.UserStatusActive, model.UserStatusSuspended, now)
+	case model.UserStatusSuspended:
+		db = db.Where("status = ? AND (suspended_until IS NULL OR suspended_until > ?)", model.UserStatusSuspended, now)
+	case model.UserStatusBanned:
+		db = db.Where("status = ?", model.UserStatusBanned)
+	}
+	if !query.CreatedFrom.IsZero() {
+		db = db.Where("created_at >= ?", query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		db = db.Where("created_at < ?", query.CreatedTo)
+	}
+	return db
+}
+
+func (u *userRepository) SearchUsers(ctx context.Context, query model.UserQuery) ([]model.User, error) {
+	column, ok := userSortColumns[query.SortBy]
+	if !ok {
+		column = userSortColumns[model.UserSortCreatedAt]
+		query.SortBy = model.UserSortCreatedAt
+	}
+	direction, comparison := "ASC", ">"
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	db := u.filterUsers(u.db.WithContext(ctx), query)
+	if query.After != nil {
+		var value any = query.After.Value
+		if query.SortBy == model.UserSortCreatedAt {
+			createdAt, err := time.Parse(time.RFC3339Nano, query.After.Value)
+			if err != nil {
+				return nil, fmt.Errorf("userRepository.SearchUsers: %w", apperrors.ErrInvalidCursor)
+			}
+			value = createdAt
+		}
+		// column and comparison only come from the whitelists above
+		db = db.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, query.After.ID)
 	}
 	var users []model.User
-	err := query.Order(fmt.Sprintf("%s %s", "id", sortOrder)).Limit(limit).Offset(offset).Find(&users).Error
+	err := db.Order(column + " " + direction).Order("id " + direction).Limit(query.Limit).Find(&users).Error
 	if err != nil {
-		return nil, fmt.Errorf("userRepository.GetUsersList: %w", err)
+		return nil, fmt.Errorf("userRepository.SearchUsers: %w", err)
 	}
 	return users, nil
 }
 
+func (u *userRepository) CountUsers(ctx context.Context, query model.UserQuery) (int64, error) {
+	var count int64
+	err := u.filterUsers(u.db.WithContext(ctx).Model(&model.User{}), query).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("userRepository.CountUsers: %w", err)
+	}
+	return count, nil
+}
+
 func (u *userRepository) GetUserByID(ctx context.Context, id string) (model.User, error) {
 	var user model.User
 	result := u.db.WithContext(ctx).First(&user, "id = ?", id)
@@ -92,6 +203,118 @@ func (u *userRepository) GetUserByID(ctx context.Context, id string) (model.User
 	return user, nil
 }
 
+func (u *userRepository) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	res := u.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("email_verified", verified)
+	if res.Error != nil {
+		return fmt.Errorf("userRepository.SetEmailVerified: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.SetEmailVerified: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) UpdateUserStatus(ctx context.Context, id string, status string, until *time.Time, reason string) error {
+	res := u.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Updates(map[string]any{
+		"status":          status,
+		"suspended_until": until,
+		"status_reason":   reason,
+		"updated_at":      time.Now(),
+	})
+	if res.Error != nil {
+		return fmt.Errorf("userRepository.UpdateUserStatus: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.UpdateUserStatus: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) UpdateUserRole(ctx context.Context, id string, role string) error {
+	res := u.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("role", role)
+	if res.Error != nil {
+		return fmt.Errorf("userRepository.UpdateUserRole: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.UpdateUserRole: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) UpdateUsername(ctx context.Context, id string, username string, changedAt time.Time) error {
+	res := u.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Updates(map[string]any{
+		"username":            username,
+		"username_changed_at": changedAt,
+		"updated_at":          changedAt,
+	})
+	if res.Error != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(res.Error, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "users_username_key" {
+			return fmt.Errorf("userRepository.UpdateUsername: %w", apperrors.ErrUsernameTaken)
+		}
+		return fmt.Errorf("userRepository.UpdateUsername: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.UpdateUsername: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) UpdateBio(ctx context.Context, id string, bio string) error {
+	// Update is used instead of Updates so the bio can be cleared
+	res := u.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("bio", bio)
+	if res.Error != nil {
+		return fmt.Errorf("userRepository.UpdateBio: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.UpdateBio: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) DeleteUserByID(ctx context.Context, id string) error {
+	res := u.db.WithContext(ctx).Delete(&model.User{}, "id = ?", id)
+	if res.Error != nil {
+		return fmt.Errorf("userRepository.DeleteUserByID: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.DeleteUserByID: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) SetDeletionScheduledAt(ctx context.Context, id string, at *time.Time) error {
+	res := u.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("deletion_scheduled_at", at)
+	if res.Error != nil {
+		return fmt.Errorf("userRepository.SetDeletionScheduledAt: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("userRepository.SetDeletionScheduledAt: %w", apperrors.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (u *userRepository) GetUsersDueForDeletion(ctx context.Context, now time.Time, limit int) ([]model.User, error) {
+	var users []model.User
+	err := u.db.WithContext(ctx).Unscoped().
+		Where("deletion_scheduled_at <= ?", now).
+		Order("deletion_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("userRepository.GetUsersDueForDeletion: %w", err)
+	}
+	return users, nil
+}
+
+func (u *userRepository) PurgeUserByID(ctx context.Context, id string) error {
+	err := u.db.WithContext(ctx).Unscoped().Delete(&model.User{}, "id = ?", id).Error
+	if err != nil {
+		return fmt.Errorf("userRepository.PurgeUserByID: %w", err)
+	}
+	return nil
+}
+
 func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{
 		db: db,
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"auth-service/internal/client"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/mailer"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+)
+
+type AccountDataConfig struct {
+	ExportBucket string
+	ExportTTL    time.Duration
+	ExportURLTTL time.Duration
+	// ExportLimit is the number of exports a user can request per ExportLimitWindow
+	ExportLimit         int
+	ExportLimitWindow   time.Duration
+	DeletionGracePeriod time.Duration
+	SweepInterval       time.Duration
+}
+
+// exportBuildTimeout bound the time an archive takes to build, an export still pending after it has been interrupted
+const exportBuildTimeout = 10 * time.Minute
+
+// sweepBatchSize is the number of accounts and expired exports removed per sweep
+const sweepBatchSize = 100
+
+type AccountDataService interface {
+	// RequestExport start building an archive of the data every service keeps about the user, the user is emailed
+	// once it is ready. An *apperrors.RateLimitError is returned if they asked for too many exports
+	RequestExport(ctx context.Context, userID string) (model.DataExport, error)
+	// GetExport return the export of the user and the presigned URL of its archive, the URL is empty until the
+	// archive is ready. apperrors.ErrDataExportNotFound is returned if the user has no such export or it expired
+	GetExport(ctx context.Context, userID string, exportID string) (model.DataExport, string, error)
+	// ScheduleDeletion reauthenticate the user on the session sessionID and schedule the erasure of their account at
+	// the end of the grace period. A deletion already scheduled keeps its date
+	ScheduleDeletion(ctx context.Context, userID string, sessionID string, currentPassword string) (time.Time, error)
+	// CancelDeletion return apperrors.ErrAccountDeletionNotScheduled if the account is not to be deleted
+	CancelDeletion(ctx context.Context, userID string) error
+	// Run erase the accounts whose grace period is over and remove the expired exports every SweepInterval,
+	// until ctx is done
+	Run(ctx context.Context)
+}
+
+type accountDataService struct {
+	userService          UserService
+	reauthService        ReauthenticationService
+	profileService       ProfileService
+	revocationService    RevocationService
+	securityEventService SecurityEventService
+	rateLimiter          RateLimiter
+	userRepo             repository.UserRepository
+	dataExportRepo       repository.DataExportRepository
+	channelClient        client.ChannelClient
+	chatClient           client.ChatClient
+	minioClient          *minio.Client
+	mailer               mailer.Mailer
+	logger               *zap.Logger
+	cfg                  AccountDataConfig
+}
+
+// exportedUser is the account of the user in the archive, the password hash is left out
+type exportedUser struct {
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Bio           string    `json:"bio"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// exportArchive is the document given to the user, the data of the other services is kept as they return it
+type exportArchive struct {
+	ExportedAt   time.Time       `json:"exported_at"`
+	User         exportedUser    `json:"user"`
+	Channel      json.RawMessage `json:"channel"`
+	ChatMessages json.RawMessage `json:"chat_messages"`
+}
+
+func (a *accountDataService) RequestExport(ctx context.Context, userID string) (model.DataExport, error) {
+	err := a.rateLimiter.Allow(ctx, "data_export:user:"+userID, a.cfg.ExportLimit, a.cfg.ExportLimitWindow)
+	if err != nil {
+		return model.DataExport{}, fmt.Errorf("accountDataService.RequestExport: %w", err)
+	}
+	user, err := a.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return model.DataExport{}, fmt.Errorf("accountDataService.RequestExport: %w", err)
+	}
+	export, err := a.dataExportRepo.CreateDataExport(ctx, model.DataExport{
+		UserID:    user.ID,
+		Status:    model.DataExportStatusPending,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return model.DataExport{}, fmt.Errorf("accountDataService.RequestExport: %w", err)
+	}
+	go a.buildExport(export, user)
+	return export, nil
+}
+
+// buildExport gather the data of the user from every service, upload the archive and email the user.
+// It runs in the background, a failure marks the export failed
+func (a *accountDataService) buildExport(export model.DataExport, user model.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+	defer cancel()
+	err := a.uploadExport(ctx, export, user)
+	if err != nil {
+		a.logger.Error("failed to build data export", zap.String("export_id", export.ID), zap.String("user_id", user.ID), zap.Error(err))
+		if failErr := a.dataExportRepo.FailDataExport(ctx, export.ID, time.Now()); failErr != nil {
+			a.logger.Error("failed to mark data export failed", zap.String("export_id", export.ID), zap.Error(failErr))
+		}
+		return
+	}
+	err = a.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hi %s,\n\nThe archive of your data you asked for is ready. You can download it from your account settings for %s.\n",
+			user.FirstName, a.cfg.ExportTTL),
+	})
+	if err != nil {
+		a.logger.Warn("failed to send data export email", zap.String("export_id", export.ID), zap.Error(err))
+	}
+}
+
+func (a *accountDataService) uploadExport(ctx context.Context, export model.DataExport, user model.User) error {
+	channel, err := a.channelClient.ExportUserData(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.uploadExport: %w", err)
+	}
+	messages, err := a.chatClient.ExportUserMessages(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.uploadExport: %w", err)
+	}
+	now := time.Now()
+	archive, err := json.MarshalIndent(exportArchive{
+		ExportedAt: now,
+		User: exportedUser{
+			ID:            user.ID,
+			Email:         user.Email,
+			Username:      user.Username,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			Bio:           user.Bio,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerified,
+			Status:        user.EffectiveStatus(now),
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		Channel:      channel,
+		ChatMessages: messages,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("accountDataService.uploadExport: %w", err)
+	}
+	_, err = a.minioClient.PutObject(
+		ctx,
+		a.cfg.ExportBucket,
+		export.ObjectKey(),
+		bytes.NewReader(archive),
+		int64(len(archive)),
+		minio.PutObjectOptions{
+			ContentType:        "application/json",
+			ContentDisposition: `attachment; filename="data-export.json"`,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("accountDataService.uploadExport: %w", err)
+	}
+	err = a.dataExportRepo.CompleteDataExport(ctx, export.ID, now, now.Add(a.cfg.ExportTTL))
+	if err != nil {
+		return fmt.Errorf("accountDataService.uploadExport: %w", err)
+	}
+	return nil
+}
+
+func (a *accountDataService) GetExport(ctx context.Context, userID string, exportID string) (model.DataExport, string, error) {
+	export, err := a.dataExportRepo.GetDataExport(ctx, userID, exportID)
+	if err != nil {
+		return model.DataExport{}, "", fmt.Errorf("accountDataService.GetExport: %w", err)
+	}
+	now := time.Now()
+	switch export.Status {
+	case model.DataExportStatusPending:
+		// the build was interrupted, e.g. by a restart of the service
+		if now.Sub(export.CreatedAt) > exportBuildTimeout {
+			export.Status = model.DataExportStatusFailed
+		}
+		return export, "", nil
+	case model.DataExportStatusReady:
+		if export.ExpiresAt != nil && !now.Before(*export.ExpiresAt) {
+			return model.DataExport{}, "", fmt.Errorf("accountDataService.GetExport: %w", apperrors.ErrDataExportNotFound)
+		}
+		u, err := a.minioClient.PresignedGetObject(ctx, a.cfg.ExportBucket, export.ObjectKey(), a.cfg.ExportURLTTL, nil)
+		if err != nil {
+			return model.DataExport{}, "", fmt.Errorf("accountDataService.GetExport: %w", err)
+		}
+		return export, u.String(), nil
+	default:
+		return export, "", nil
+	}
+}
+
+func (a *accountDataService) ScheduleDeletion(ctx context.Context, userID string, sessionID string, currentPassword string) (time.Time, error) {
+	user, err := a.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("accountDataService.ScheduleDeletion: %w", err)
+	}
+	err = a.reauthService.Verify(ctx, user, sessionID, currentPassword)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("accountDataService.ScheduleDeletion: %w", err)
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+	deleteAt := time.Now().Add(a.cfg.DeletionGracePeriod)
+	err = a.userRepo.SetDeletionScheduledAt(ctx, userID, &deleteAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("accountDataService.ScheduleDeletion: %w", err)
+	}
+	// the deletion can not be undone once it is carried out, the user is warned so they can still cancel it
+	err = a.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account and all its data will be deleted on %s. Your chat messages will be kept without your name.\n\nIf you did not ask for it or changed your mind, sign in and cancel the deletion from your account settings before then.\n",
+			user.FirstName, deleteAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		a.logger.Warn("failed to send account deletion email", zap.String("user_id", userID), zap.Error(err))
+	}
+	return deleteAt, nil
+}
+
+func (a *accountDataService) CancelDeletion(ctx context.Context, userID string) error {
+	user, err := a.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.CancelDeletion: %w", err)
+	}
+	if user.DeletionScheduledAt == nil {
+		return fmt.Errorf("accountDataService.CancelDeletion: %w", apperrors.ErrAccountDeletionNotScheduled)
+	}
+	err = a.userRepo.SetDeletionScheduledAt(ctx, userID, nil)
+	if err != nil {
+		return fmt.Errorf("accountDataService.CancelDeletion: %w", err)
+	}
+	return nil
+}
+
+// deleteAccount erase the data of the user in every service, then the account itself. Every step can be run again,
+// a deletion that failed halfway is retried on the next sweep
+func (a *accountDataService) deleteAccount(ctx context.Context, userID string) error {
+	err := a.revocationService.RevokeUserTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+	}
+	err = a.chatClient.AnonymizeUserMessages(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+	}
+	err = a.channelClient.DeleteUserData(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+	}
+	err = a.profileService.DeleteAvatar(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+	}
+	for object := range a.minioClient.ListObjects(ctx, a.cfg.ExportBucket, minio.ListObjectsOptions{Prefix: userID + "/", Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("accountDataService.deleteAccount: %w", object.Err)
+		}
+		err = a.minioClient.RemoveObject(ctx, a.cfg.ExportBucket, object.Key, minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+		}
+	}
+	// the audit log keeps the events, without the personal data they hold
+	err = a.securityEventService.PseudonymizeUserEvents(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+	}
+	// the exports and everything else attached to the account are deleted by cascade
+	err = a.userRepo.PurgeUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("accountDataService.deleteAccount: %w", err)
+	}
+	err = a.securityEventService.Record(ctx, model.SecurityEvent{
+		Type:   model.SecurityEventAccountDeleted,
+		UserID: &userID,
+	})
+	if err != nil {
+		a.logger.Error("failed to record security event "+model.SecurityEventAccountDeleted, zap.String("user_id", userID), zap.Error(err))
+	}
+	return nil
+}
+
+func (a *accountDataService) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.SweepInterval)
+	defer ticker.Stop()
+	for {
+		a.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep erase the accounts due for deletion and remove the expired export archives, failures are retried on the
+// next sweep
+func (a *accountDataService) sweep(ctx context.Context) {
+	now := time.Now()
+	users, err := a.userRepo.GetUsersDueForDeletion(ctx, now, sweepBatchSize)
+	if err != nil {
+		a.logger.Error("failed to get accounts due for deletion", zap.Error(err))
+	}
+	for _, user := range users {
+		err = a.deleteAccount(ctx, user.ID)
+		if err != nil {
+			a.logger.Error("failed to delete account", zap.String("user_id", user.ID), zap.Error(err))
+		}
+	}
+	exports, err := a.dataExportRepo.GetExpiredDataExports(ctx, now, sweepBatchSize)
+	if err != nil {
+		a.logger.Error("failed to get expired data exports", zap.Error(err))
+	}
+	for _, export := range exports {
+		err = a.minioClient.RemoveObject(ctx, a.cfg.ExportBucket, export.ObjectKey(), minio.RemoveObjectOptions{})
+		if err == nil {
+			err = a.dataExportRepo.DeleteDataExport(ctx, export.ID)
+		}
+		if err != nil {
+			a.logger.Error("failed to remove expired data export", zap.String("export_id", export.ID), zap.Error(err))
+		}
+	}
+}
+
+func NewAccountDataService(userService UserService, reauthService ReauthenticationService, profileService ProfileService, revocationService RevocationService, securityEventService SecurityEventService, rateLimiter RateLimiter, userRepo repository.UserRepository, dataExportRepo repository.DataExportRepository, channelClient client.ChannelClient, chatClient client.ChatClient, minioClient *minio.Client, mailer mailer.Mailer, logger *zap.Logger, cfg AccountDataConfig) AccountDataService {
+	return &accountDataService{
+		userService:          userService,
+		reauthService:        reauthService,
+		profileService:       profileService,
+		revocationService:    revocationService,
+		securityEventService: securityEventService,
+		rateLimiter:          rateLimiter,
+		userRepo:             userRepo,
+		dataExportRepo:       dataExportRepo,
+		channelClient:        channelClient,
+		chatClient:           chatClient,
+		minioClient:          minioClient,
+		mailer:               mailer,
+		logger:               logger,
+		cfg:                  cfg,
+	}
+}
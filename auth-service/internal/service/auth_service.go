@@ -5,11 +5,15 @@ import (
 	"auth-service/internal/jwt"
 	"auth-service/internal/model"
 	"auth-service/internal/repository"
+	"auth-service/internal/webauthn"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type AuthenticationResponse struct {
@@ -19,103 +23,526 @@ type AuthenticationResponse struct {
 	RefreshTokenTTL time.Duration
 }
 
+// Second factors a MFA challenge can be completed with
+const (
+	MFAMethodTOTP    = "totp"
+	MFAMethodPasskey = "passkey"
+)
+
+// MFAChallenge is returned by Login instead of tokens when the user has MFA enabled or a passkey,
+// its token is exchanged for real tokens by CompleteMFALogin or CompleteMFALoginWithPasskey
+type MFAChallenge struct {
+	Token string
+	TTL   time.Duration
+	// Methods are the second factors the user can complete the challenge with
+	Methods []string
+}
+
+// AuthUserInfo is the identity an access token has been issued to, along with the scopes it grants
 type AuthUserInfo struct {
 	UserID     string
 	UserScopes []string
 }
 
+// HasScope report whether the token grants scope
+func (a AuthUserInfo) HasScope(scope string) bool {
+	return slices.Contains(a.UserScopes, scope)
+}
+
+// ClientGrant describes the third-party app a session is granted to and the scopes the user consented to
+type ClientGrant struct {
+	ClientID   string
+	ClientName string
+	Scopes     []string
+}
+
+// ClientInfo describes the device a session is created or refreshed from
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
+
 type AuthService interface {
-	Register(ctx context.Context, user model.User) (model.User, error)
-	Login(ctx context.Context, email, password string) (AuthenticationResponse, error)
-	Logout(ctx context.Context, userID string) error
-	Refresh(ctx context.Context, refreshToken string) (AuthenticationResponse, error)
+	// Register create the user and send them a verification email. If only the email could not be sent,
+	// the created user is returned along with an error wrapping apperrors.ErrMailDeliveryFailed.
+	// inviteCode is required in the invite-only registration mode, the user gets the role of the invite
+	Register(ctx context.Context, user model.User, inviteCode string) (model.User, error)
+	// Login return a challenge instead of tokens if the user has MFA enabled.
+	// Failed attempts are throttled, an *apperrors.RateLimitError is returned while the email or ip is blocked
+	Login(ctx context.Context, email, password string, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error)
+	// LoginWithUser sign in a user already authenticated by other means than their password, e.g. an identity provider.
+	// Like Login, a challenge is returned instead of tokens if the user has MFA enabled
+	LoginWithUser(ctx context.Context, user model.User, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error)
+	// CompleteMFALogin exchange a challenge token and a valid TOTP or recovery code for a new session
+	CompleteMFALogin(ctx context.Context, challengeToken string, code string, client ClientInfo) (AuthenticationResponse, error)
+	// BeginMFAPasskey return the options to complete a challenge with one of the passkeys of the user
+	BeginMFAPasskey(ctx context.Context, challengeToken string) (webauthn.RequestOptions, error)
+	// CompleteMFALoginWithPasskey exchange a challenge token and a passkey assertion for a new session
+	CompleteMFALoginWithPasskey(ctx context.Context, challengeToken string, credential webauthn.AssertionResponse, client ClientInfo) (AuthenticationResponse, error)
+	// IssueSession create a session for a user who already proved who they are from another signed in device,
+	// e.g. by approving a device authorization. No second factor is asked
+	IssueSession(ctx context.Context, user model.User, client ClientInfo) (AuthenticationResponse, error)
+	// IssueClientSession create a session granted to a third-party app, its access tokens only carry the granted scopes
+	IssueClientSession(ctx context.Context, user model.User, grant ClientGrant, client ClientInfo) (AuthenticationResponse, error)
+	// LoginWithPasskey sign in the owner of the passkey. The authenticator verified the user so no second factor is asked
+	LoginWithPasskey(ctx context.Context, challengeID string, credential webauthn.AssertionResponse, client ClientInfo) (AuthenticationResponse, error)
+	// Logout revoke the access token and only the session it belongs to
+	Logout(ctx context.Context, userID string, sessionID string, accessTokenID string) error
+	// LogoutAll revoke every session and access token of the user
+	LogoutAll(ctx context.Context, userID string) error
+	// Refresh rotate the refresh token of the session it belongs to, sessions granted to third-party apps are rejected
+	Refresh(ctx context.Context, refreshToken string, client ClientInfo) (AuthenticationResponse, error)
+	// RefreshClientSession rotate the refresh token of a session granted to the third-party app clientID
+	RefreshClientSession(ctx context.Context, clientID string, refreshToken string, client ClientInfo) (AuthenticationResponse, error)
+	GetSessions(ctx context.Context, userID string) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID string, sessionID string) error
 }
 
 type authService struct {
-	userService    UserService
-	jwt            jwt.Utils
-	tokenRepo      repository.RefreshTokenRepository
-	userSessionTTL time.Duration
+	userService              UserService
+	revocationService        RevocationService
+	emailVerificationService EmailVerificationService
+	mfaService               MFAService
+	passkeyService           PasskeyService
+	loginGuard               LoginGuard
+	securityEventService     SecurityEventService
+	inviteService            InviteService
+	jwt                      jwt.Utils
+	sessionRepo              repository.SessionRepository
+	actionTokenRepo          repository.ActionTokenRepository
+	logger                   *zap.Logger
+	userSessionTTL           time.Duration
+	mfaChallengeTTL          time.Duration
 }
 
-func (a *authService) Register(ctx context.Context, user model.User) (model.User, error) {
+func (a *authService) Register(ctx context.Context, user model.User, inviteCode string) (model.User, error) {
+	invite, err := a.inviteService.Admit(ctx, inviteCode)
+	if err != nil {
+		return model.User{}, fmt.Errorf("authService.Register: %w", err)
+	}
 	createdUser, err := a.userService.CreateUser(ctx, user)
 	if err != nil {
+		if invite != nil {
+			if releaseErr := a.inviteService.Release(ctx, invite.ID); releaseErr != nil {
+				return model.User{}, fmt.Errorf("authService.Register: %w: %w", err, releaseErr)
+			}
+		}
 		return model.User{}, fmt.Errorf("authService.Register: %w", err)
 	}
+	if invite != nil {
+		err = a.inviteService.RecordRedemption(ctx, invite.ID, createdUser.ID)
+		if err != nil {
+			return model.User{}, fmt.Errorf("authService.Register: %w", err)
+		}
+		if invite.Role != createdUser.Role {
+			err = a.userService.ChangeUserRole(ctx, createdUser.ID, invite.Role)
+			if err != nil {
+				return model.User{}, fmt.Errorf("authService.Register: %w", err)
+			}
+			createdUser.Role = invite.Role
+		}
+	}
+	err = a.emailVerificationService.SendVerificationEmail(ctx, createdUser)
+	if err != nil {
+		return createdUser, fmt.Errorf("authService.Register: %w", err)
+	}
 	return createdUser, nil
 }
 
-func (a *authService) Login(ctx context.Context, email, password string) (AuthenticationResponse, error) {
+func (a *authService) Login(ctx context.Context, email, password string, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error) {
+	attempt, err := a.loginGuard.Reserve(ctx, email, client.IP)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", err)
+	}
 	user, err := a.userService.GetUserByEmail(ctx, email)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Login: %w", err)
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			if guardErr := a.loginGuard.RecordFailure(ctx, attempt, ""); guardErr != nil {
+				return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", guardErr)
+			}
+			a.recordLoginFailure(ctx, "", email, "unknown_email", client)
+		}
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", err)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = a.userService.VerifyPassword(ctx, user, password)
+	if errors.Is(err, apperrors.ErrInvalidPassword) {
+		if guardErr := a.loginGuard.RecordFailure(ctx, attempt, user.ID); guardErr != nil {
+			return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", guardErr)
+		}
+		a.recordLoginFailure(ctx, user.ID, email, "invalid_password", client)
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", apperrors.ErrInvalidPassword)
+	}
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", err)
+	}
+	// the password is right, the attempt must not count as a failure even if the user is not allowed to sign in
+	err = a.loginGuard.RecordSuccess(ctx, attempt)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", err)
+	}
+	err = checkUserStatus(user)
+	if err != nil {
+		a.recordLoginFailure(ctx, user.ID, email, user.EffectiveStatus(time.Now()), client)
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", err)
+	}
+	res, challenge, err := a.LoginWithUser(ctx, user, client)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.Login: %w", err)
+	}
+	return res, challenge, nil
+}
+
+// recordLoginFailure add a failed sign in attempt to the audit log, userID is empty when the email is unknown.
+// The attempt fails with its own error even if it can not be recorded
+func (a *authService) recordLoginFailure(ctx context.Context, userID string, email string, reason string, client ClientInfo) {
+	details := map[string]string{"reason": reason}
+	if email != "" {
+		details["email_fingerprint"] = model.EmailFingerprint(email)
+	}
+	err := a.securityEventService.Record(ctx, newSecurityEvent(model.SecurityEventLoginFailed, userID, client, details))
+	if err != nil {
+		a.logger.Error("failed to record security event "+model.SecurityEventLoginFailed, zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+func (a *authService) LoginWithUser(ctx context.Context, user model.User, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error) {
+	err := checkUserStatus(user)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Login: %w", apperrors.ErrInvalidPassword)
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.LoginWithUser: %w", err)
 	}
-	accessToken, err := a.jwt.CreateAccessToken(user.ID, user.Role)
+	var methods []string
+	mfaEnabled, err := a.mfaService.IsEnabled(ctx, user.ID)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Login: %w", err)
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.LoginWithUser: %w", err)
 	}
-	refreshToken, err := a.jwt.CreateRefreshToken(user.ID)
+	if mfaEnabled {
+		methods = append(methods, MFAMethodTOTP)
+	}
+	hasPasskeys, err := a.passkeyService.HasPasskeys(ctx, user.ID)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.LoginWithUser: %w", err)
+	}
+	if hasPasskeys {
+		methods = append(methods, MFAMethodPasskey)
+	}
+	if len(methods) > 0 {
+		challenge, err := a.jwt.CreateActionToken(user.ID, jwt.TokenTypeMFAChallenge, a.mfaChallengeTTL, map[string]string{
+			"device_name": client.DeviceName,
+		})
+		if err != nil {
+			return AuthenticationResponse{}, nil, fmt.Errorf("authService.LoginWithUser: %w", err)
+		}
+		err = a.actionTokenRepo.SaveActionToken(ctx, challenge.JTI, challenge.TTL)
+		if err != nil {
+			return AuthenticationResponse{}, nil, fmt.Errorf("authService.LoginWithUser: %w", err)
+		}
+		return AuthenticationResponse{}, &MFAChallenge{
+			Token:   challenge.Token,
+			TTL:     challenge.TTL,
+			Methods: methods,
+		}, nil
+	}
+	res, err := a.createSession(ctx, user, client, nil)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("authService.LoginWithUser: %w", err)
+	}
+	return res, nil, nil
+}
+
+func (a *authService) CompleteMFALogin(ctx context.Context, challengeToken string, code string, client ClientInfo) (AuthenticationResponse, error) {
+	// wrong codes are rate limited by the MFA service
+	res, err := a.completeMFALogin(ctx, challengeToken, client, func(userID string, _ string) error {
+		return a.mfaService.VerifyCode(ctx, userID, code)
+	})
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.CompleteMFALogin: %w", err)
+	}
+	return res, nil
+}
+
+func (a *authService) BeginMFAPasskey(ctx context.Context, challengeToken string) (webauthn.RequestOptions, error) {
+	userID, jti, _, err := a.verifyMFAChallenge(challengeToken)
+	if err != nil {
+		return webauthn.RequestOptions{}, fmt.Errorf("authService.BeginMFAPasskey: %w", err)
+	}
+	options, err := a.passkeyService.BeginVerification(ctx, userID, jti)
+	if err != nil {
+		return webauthn.RequestOptions{}, fmt.Errorf("authService.BeginMFAPasskey: %w", err)
+	}
+	return options, nil
+}
+
+func (a *authService) CompleteMFALoginWithPasskey(ctx context.Context, challengeToken string, credential webauthn.AssertionResponse, client ClientInfo) (AuthenticationResponse, error) {
+	res, err := a.completeMFALogin(ctx, challengeToken, client, func(userID string, jti string) error {
+		return a.passkeyService.Verify(ctx, userID, jti, credential)
+	})
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.CompleteMFALoginWithPasskey: %w", err)
+	}
+	return res, nil
+}
+
+// verifyMFAChallenge return the user, the id and the claims of a challenge token
+func (a *authService) verifyMFAChallenge(challengeToken string) (string, string, map[string]interface{}, error) {
+	claims, err := a.jwt.VerifyToken(challengeToken, jwt.TokenTypeMFAChallenge)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("authService.verifyMFAChallenge: %w", err)
+	}
+	userID, _ := claims["user_id"].(string)
+	jti, _ := claims["jti"].(string)
+	if userID == "" || jti == "" {
+		return "", "", nil, fmt.Errorf("authService.verifyMFAChallenge: %w", apperrors.ErrInvalidToken)
+	}
+	return userID, jti, claims, nil
+}
+
+// completeMFALogin create the session of a challenge once verify accepted the second factor
+func (a *authService) completeMFALogin(ctx context.Context, challengeToken string, client ClientInfo, verify func(userID string, jti string) error) (AuthenticationResponse, error) {
+	userID, jti, claims, err := a.verifyMFAChallenge(challengeToken)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.completeMFALogin: %w", err)
+	}
+	// the challenge is only consumed once the second factor is valid so a typo does not require logging in again
+	err = verify(userID, jti)
+	if err != nil {
+		a.recordLoginFailure(ctx, userID, "", "invalid_second_factor", client)
+		return AuthenticationResponse{}, fmt.Errorf("authService.completeMFALogin: %w", err)
+	}
+	err = a.actionTokenRepo.ConsumeActionToken(ctx, jti)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.completeMFALogin: %w", err)
+	}
+	user, err := a.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.completeMFALogin: %w", err)
+	}
+	if deviceName, _ := claims["device_name"].(string); deviceName != "" {
+		client.DeviceName = deviceName
+	}
+	res, err := a.createSession(ctx, user, client, nil)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.completeMFALogin: %w", err)
+	}
+	return res, nil
+}
+
+func (a *authService) LoginWithPasskey(ctx context.Context, challengeID string, credential webauthn.AssertionResponse, client ClientInfo) (AuthenticationResponse, error) {
+	user, err := a.passkeyService.FinishLogin(ctx, challengeID, credential)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Login: %w", err)
+		return AuthenticationResponse{}, fmt.Errorf("authService.LoginWithPasskey: %w", err)
 	}
-	err = a.tokenRepo.SetRefreshTokenID(ctx, user.ID, refreshToken.JTI, a.userSessionTTL)
+	res, err := a.createSession(ctx, user, client, nil)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Login: %w", err)
+		return AuthenticationResponse{}, fmt.Errorf("authService.LoginWithPasskey: %w", err)
 	}
-	res := AuthenticationResponse{
+	return res, nil
+}
+
+func (a *authService) IssueSession(ctx context.Context, user model.User, client ClientInfo) (AuthenticationResponse, error) {
+	res, err := a.createSession(ctx, user, client, nil)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.IssueSession: %w", err)
+	}
+	return res, nil
+}
+
+func (a *authService) IssueClientSession(ctx context.Context, user model.User, grant ClientGrant, client ClientInfo) (AuthenticationResponse, error) {
+	client.DeviceName = grant.ClientName
+	res, err := a.createSession(ctx, user, client, &grant)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.IssueClientSession: %w", err)
+	}
+	return res, nil
+}
+
+// createSession start a new refresh token family for user and issue its first token pair.
+// The session belongs to a third-party app if grant is not nil
+func (a *authService) createSession(ctx context.Context, user model.User, client ClientInfo, grant *ClientGrant) (AuthenticationResponse, error) {
+	err := checkUserStatus(user)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.createSession: %w", err)
+	}
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.createSession: %w", err)
+	}
+	now := time.Now()
+	session := model.Session{
+		ID:         sessionID.String(),
+		UserID:     user.ID,
+		DeviceName: client.DeviceName,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	if grant != nil {
+		session.ClientID = grant.ClientID
+		session.Scopes = grant.Scopes
+	}
+	accessToken, err := a.createAccessToken(user, session)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.createSession: %w", err)
+	}
+	refreshToken, err := a.jwt.CreateRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.createSession: %w", err)
+	}
+	session.RefreshTokenID = refreshToken.JTI
+	session.AccessTokenID = accessToken.JTI
+	err = a.sessionRepo.SetSession(ctx, session, a.userSessionTTL)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.createSession: %w", err)
+	}
+	details := map[string]string{"session_id": session.ID}
+	if session.ClientID != "" {
+		details["client_id"] = session.ClientID
+	}
+	// the session is already stored, failing to audit it must not fail the sign in
+	err = a.securityEventService.Record(ctx, newSecurityEvent(model.SecurityEventLoginSucceeded, user.ID, client, details))
+	if err != nil {
+		a.logger.Error("failed to record security event "+model.SecurityEventLoginSucceeded, zap.String("user_id", user.ID), zap.Error(err))
+	}
+	return AuthenticationResponse{
 		AccessToken:     accessToken.Token,
 		RefreshToken:    refreshToken.Token,
 		AccessTokenTTL:  accessToken.TTL,
 		RefreshTokenTTL: refreshToken.TTL,
+	}, nil
+}
+
+// createAccessToken issue an access token for the session, scoped to the grant of the third-party app it belongs to if any.
+// The role of the user is checked again so a demoted user loses the scopes they granted
+func (a *authService) createAccessToken(user model.User, session model.Session) (jwt.AccessToken, error) {
+	if session.ClientID == "" {
+		return a.jwt.CreateAccessToken(user, session.ID)
 	}
-	return res, nil
+	return a.jwt.CreateClientAccessToken(user, session.ID, session.ClientID, model.GrantedScopes(user.Role, session.Scopes))
 }
 
-func (a *authService) Logout(ctx context.Context, userID string) error {
-	err := a.tokenRepo.DeleteRefreshToken(ctx, userID)
+func (a *authService) Logout(ctx context.Context, userID string, sessionID string, accessTokenID string) error {
+	err := a.revocationService.RevokeAccessToken(ctx, accessTokenID)
 	if err != nil {
 		return fmt.Errorf("authService.Logout: %w", err)
 	}
+	err = a.revokeSession(ctx, userID, sessionID)
+	if err != nil && !errors.Is(err, apperrors.ErrSessionNotFound) {
+		return fmt.Errorf("authService.Logout: %w", err)
+	}
 	return nil
 }
 
-func (a *authService) Refresh(ctx context.Context, refreshToken string) (AuthenticationResponse, error) {
-	claims, err := a.jwt.VerifyToken(refreshToken)
+// revokeSession delete the session and revoke the last access token issued to it
+func (a *authService) revokeSession(ctx context.Context, userID string, sessionID string) error {
+	session, err := a.sessionRepo.GetSession(ctx, sessionID)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Refresh: %w", err)
+		return fmt.Errorf("authService.revokeSession: %w", err)
+	}
+	if session.UserID != userID {
+		return fmt.Errorf("authService.revokeSession: %w", apperrors.ErrSessionNotFound)
+	}
+	err = a.revocationService.RevokeAccessToken(ctx, session.AccessTokenID)
+	if err != nil {
+		return fmt.Errorf("authService.revokeSession: %w", err)
+	}
+	err = a.sessionRepo.DeleteSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("authService.revokeSession: %w", err)
+	}
+	return nil
+}
+
+func (a *authService) LogoutAll(ctx context.Context, userID string) error {
+	err := a.revocationService.RevokeUserTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("authService.LogoutAll: %w", err)
 	}
-	userID := claims["user_id"].(string)
-	savedJTI, err := a.tokenRepo.GetRefreshTokenID(ctx, userID)
+	return nil
+}
+
+func (a *authService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (AuthenticationResponse, error) {
+	res, err := a.refresh(ctx, "", refreshToken, client)
 	if err != nil {
 		return AuthenticationResponse{}, fmt.Errorf("authService.Refresh: %w", err)
 	}
-	if savedJTI != claims["jti"].(string) {
-		err = a.tokenRepo.DeleteRefreshToken(ctx, userID)
-		if err != nil {
-			return AuthenticationResponse{}, fmt.Errorf("authService.Refresh: %w", err)
-		}
-		return AuthenticationResponse{}, fmt.Errorf("authService.Refresh: %w", apperrors.ErrInvalidToken)
+	return res, nil
+}
+
+func (a *authService) RefreshClientSession(ctx context.Context, clientID string, refreshToken string, client ClientInfo) (AuthenticationResponse, error) {
+	res, err := a.refresh(ctx, clientID, refreshToken, client)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.RefreshClientSession: %w", err)
+	}
+	return res, nil
+}
+
+// refresh rotate the refresh token of a session belonging to the third-party app clientID,
+// or to the platform itself if clientID is empty
+func (a *authService) refresh(ctx context.Context, clientID string, refreshToken string, client ClientInfo) (AuthenticationResponse, error) {
+	claims, err := a.jwt.VerifyToken(refreshToken, jwt.TokenTypeRefresh)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
+	}
+	userID, _ := claims["user_id"].(string)
+	sessionID, _ := claims["sid"].(string)
+	jti, _ := claims["jti"].(string)
+	if userID == "" || sessionID == "" || jti == "" {
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", apperrors.ErrInvalidToken)
+	}
+	session, err := a.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
+	}
+	if session.UserID != userID || session.ClientID != clientID {
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", apperrors.ErrInvalidToken)
+	}
+	if session.RefreshTokenID != jti {
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", a.revokeReusedSession(ctx, userID, sessionID, client))
 	}
 	user, err := a.userService.GetUserById(ctx, userID)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("authService.Refresh: %w", err)
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
 	}
-	accessToken, err := a.jwt.CreateAccessToken(user.ID, user.Role)
+	err = checkUserStatus(user)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("AuthService.Refresh: %w", err)
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
 	}
-	newRefreshToken, err := a.jwt.CreateRefreshToken(user.ID)
+	accessToken, err := a.createAccessToken(user, session)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("AuthService.Refresh: %w", err)
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
 	}
-	err = a.tokenRepo.SetRefreshTokenID(ctx, user.ID, newRefreshToken.JTI, -1)
+	newRefreshToken, err := a.jwt.CreateRefreshToken(user.ID, session.ID)
 	if err != nil {
-		return AuthenticationResponse{}, fmt.Errorf("AuthService.Refresh: %w", err)
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
+	}
+	session.RefreshTokenID = newRefreshToken.JTI
+	session.AccessTokenID = accessToken.JTI
+	session.LastUsedAt = time.Now()
+	if client.IP != "" {
+		session.IP = client.IP
+	}
+	if client.UserAgent != "" {
+		session.UserAgent = client.UserAgent
+	}
+	err = a.sessionRepo.RotateSession(ctx, session, jti)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrRefreshTokenReused) {
+			// another refresh with the same token won the race
+			return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", a.revokeReusedSession(ctx, userID, sessionID, client))
+		}
+		if errors.Is(err, apperrors.ErrSessionNotFound) {
+			// the session has been revoked since it was read
+			return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", apperrors.ErrInvalidToken)
+		}
+		return AuthenticationResponse{}, fmt.Errorf("authService.refresh: %w", err)
+	}
+	// the session is already rotated, failing to audit it must not lose the new refresh token
+	err = a.securityEventService.Record(ctx, newSecurityEvent(model.SecurityEventTokenRefreshed, userID, client, map[string]string{
+		"session_id": session.ID,
+	}))
+	if err != nil {
+		a.logger.Error("failed to record security event "+model.SecurityEventTokenRefreshed, zap.String("user_id", userID), zap.Error(err))
 	}
 	return AuthenticationResponse{
 		AccessToken:     accessToken.Token,
@@ -125,11 +552,53 @@ func (a *authService) Refresh(ctx context.Context, refreshToken string) (Authent
 	}, nil
 }
 
-func NewAuthService(userService UserService, jwt jwt.Utils, tokenRepo repository.RefreshTokenRepository, userSessionTTL time.Duration) AuthService {
+func (a *authService) GetSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	sessions, err := a.sessionRepo.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("authService.GetSessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (a *authService) RevokeSession(ctx context.Context, userID string, sessionID string) error {
+	err := a.revokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("authService.RevokeSession: %w", err)
+	}
+	return nil
+}
+
+// revokeReusedSession revoke the whole refresh token family of a session whose already rotated refresh token is being reused,
+// ErrInvalidToken is returned once the family is revoked
+func (a *authService) revokeReusedSession(ctx context.Context, userID string, sessionID string, client ClientInfo) error {
+	err := a.revokeSession(ctx, userID, sessionID)
+	if err != nil && !errors.Is(err, apperrors.ErrSessionNotFound) {
+		return fmt.Errorf("authService.revokeReusedSession: %w", err)
+	}
+	err = a.securityEventService.Record(ctx, newSecurityEvent(model.SecurityEventRefreshTokenReused, userID, client, map[string]string{
+		"session_id": sessionID,
+	}))
+	if err != nil {
+		a.logger.Error("failed to record security event "+model.SecurityEventRefreshTokenReused, zap.String("user_id", userID), zap.Error(err))
+	}
+	return apperrors.ErrInvalidToken
+}
+
+func NewAuthService(userService UserService, revocationService RevocationService, emailVerificationService EmailVerificationService, mfaService MFAService, passkeyService PasskeyService, loginGuard LoginGuard, securityEventService SecurityEventService, inviteService InviteService, jwt jwt.Utils, sessionRepo repository.SessionRepository, actionTokenRepo repository.ActionTokenRepository, logger *zap.Logger, userSessionTTL time.Duration, mfaChallengeTTL time.Duration) AuthService {
 	return &authService{
-		userService:    userService,
-		jwt:            jwt,
-		tokenRepo:      tokenRepo,
-		userSessionTTL: userSessionTTL,
+		userService:              userService,
+		revocationService:        revocationService,
+		emailVerificationService: emailVerificationService,
+		mfaService:               mfaService,
+		passkeyService:           passkeyService,
+		loginGuard:               loginGuard,
+		securityEventService:     securityEventService,
+		inviteService:            inviteService,
+		jwt:                      jwt,
+		sessionRepo:              sessionRepo,
+		actionTokenRepo:          actionTokenRepo,
+		logger:                   logger,
+		userSessionTTL:           userSessionTTL,
+		mfaChallengeTTL:          mfaChallengeTTL,
 	}
 }
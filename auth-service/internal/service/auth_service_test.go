@@ -0,0 +1,88 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (f *fakeUserService) GetUserByEmail(_ context.Context, email string) (model.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return model.User{}, apperrors.ErrUserNotFound
+}
+
+func (f *fakeUserService) VerifyPassword(_ context.Context, user model.User, password string) error {
+	if user.Password != password {
+		return apperrors.ErrInvalidPassword
+	}
+	return nil
+}
+
+// fakeLoginGuard let every attempt through
+type fakeLoginGuard struct {
+	LoginGuard
+}
+
+func (f *fakeLoginGuard) Reserve(_ context.Context, email string, ip string) (LoginAttempt, error) {
+	return LoginAttempt{Email: email, IP: ip}, nil
+}
+
+func (f *fakeLoginGuard) RecordFailure(context.Context, LoginAttempt, string) error {
+	return nil
+}
+
+func (f *fakeLoginGuard) RecordSuccess(context.Context, LoginAttempt) error {
+	return nil
+}
+
+// failingSecurityEventService can not record any event
+type failingSecurityEventService struct {
+	SecurityEventService
+}
+
+func (f *failingSecurityEventService) Record(context.Context, model.SecurityEvent) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestAuthServiceLoginFailureNotRecorded(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "unknown email", email: "nobody@example.com", password: "secret", wantErr: apperrors.ErrUserNotFound},
+		{name: "invalid password", email: "alice@example.com", password: "wrong", wantErr: apperrors.ErrInvalidPassword},
+		{name: "banned user", email: "bob@example.com", password: "secret", wantErr: apperrors.ErrUserBanned},
+	}
+	a := NewAuthService(
+		&fakeUserService{users: map[string]model.User{
+			"alice": {ID: "alice", Email: "alice@example.com", Password: "secret", Status: model.UserStatusActive},
+			"bob":   {ID: "bob", Email: "bob@example.com", Password: "secret", Status: model.UserStatusBanned},
+		}},
+		nil, nil, nil, nil,
+		&fakeLoginGuard{},
+		&failingSecurityEventService{},
+		nil, nil, nil, nil,
+		zap.NewNop(),
+		time.Hour, time.Minute,
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the failed attempt can not be audited, the caller still learns why it failed
+			_, _, err := a.Login(context.Background(), tt.email, tt.password, ClientInfo{IP: "192.0.2.1"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
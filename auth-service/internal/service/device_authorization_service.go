@@ -0,0 +1,273 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// userCodeAlphabet has no vowels, so codes do not spell words, and no characters that look alike
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const userCodeLength = 8
+
+// slowDownStep is added to the poll interval each time a device polls too fast
+const slowDownStep = 5
+
+type DeviceAuthorizationConfig struct {
+	ClientIDs       []string
+	CodeTTL         time.Duration
+	PollInterval    time.Duration
+	VerificationURL string
+	IPLimit         int
+	UserLimit       int
+	LimitWindow     time.Duration
+}
+
+// DeviceCode is returned to the device starting an authorization, it shows the user code and the
+// verification URL to the user then polls with the device code
+type DeviceCode struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               time.Duration
+	Interval                time.Duration
+}
+
+type DeviceAuthorizationService interface {
+	// StartAuthorization create a device code and a user code for the client,
+	// apperrors.ErrInvalidClient is returned if the client is not allowed to use the device flow
+	StartAuthorization(ctx context.Context, clientID string, deviceName string, ip string) (DeviceCode, error)
+	// GetAuthorization return the pending authorization of the user code, so the user can check which device they approve
+	GetAuthorization(ctx context.Context, userID string, userCode string) (model.DeviceAuthorization, error)
+	Approve(ctx context.Context, userID string, userCode string) error
+	Deny(ctx context.Context, userID string, userCode string) error
+	// PollToken issue the tokens of an approved authorization, once. apperrors.ErrAuthorizationPending is returned
+	// until the user decided, apperrors.ErrSlowDown if the device polls faster than the interval
+	PollToken(ctx context.Context, clientID string, deviceCode string, client ClientInfo) (AuthenticationResponse, error)
+}
+
+type deviceAuthorizationService struct {
+	userService       UserService
+	authService       AuthService
+	authorizationRepo repository.DeviceAuthorizationRepository
+	rateLimiter       RateLimiter
+	cfg               DeviceAuthorizationConfig
+}
+
+func (*deviceAuthorizationService) hashDeviceCode(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeUserCode accept the user code in any case, with or without separators
+func (*deviceAuthorizationService) normalizeUserCode(userCode string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (*deviceAuthorizationService) generateUserCode() (string, error) {
+	var b strings.Builder
+	alphabetSize := big.NewInt(int64(len(userCodeAlphabet)))
+	for range userCodeLength {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// formatUserCode split the user code in two halves so it is easier to read, e.g. BDFH-JKLM
+func (*deviceAuthorizationService) formatUserCode(userCode string) string {
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+func (d *deviceAuthorizationService) StartAuthorization(ctx context.Context, clientID string, deviceName string, ip string) (DeviceCode, error) {
+	if !slices.Contains(d.cfg.ClientIDs, clientID) {
+		return DeviceCode{}, fmt.Errorf("deviceAuthorizationService.StartAuthorization: %w", apperrors.ErrInvalidClient)
+	}
+	err := d.rateLimiter.Allow(ctx, "device_code:ip:"+ip, d.cfg.IPLimit, d.cfg.LimitWindow)
+	if err != nil {
+		return DeviceCode{}, fmt.Errorf("deviceAuthorizationService.StartAuthorization: %w", err)
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return DeviceCode{}, fmt.Errorf("deviceAuthorizationService.StartAuthorization: %w", err)
+	}
+	deviceCode := base64.RawURLEncoding.EncodeToString(b)
+	if deviceName == "" {
+		deviceName = clientID
+	}
+	// user codes are short, retry on the rare collision with a pending one
+	for range 3 {
+		var userCode string
+		userCode, err = d.generateUserCode()
+		if err != nil {
+			return DeviceCode{}, fmt.Errorf("deviceAuthorizationService.StartAuthorization: %w", err)
+		}
+		err = d.authorizationRepo.CreateAuthorization(ctx, d.hashDeviceCode(deviceCode), model.DeviceAuthorization{
+			ClientID:   clientID,
+			DeviceName: deviceName,
+			UserCode:   userCode,
+			Status:     model.DeviceAuthorizationPending,
+			Interval:   int(d.cfg.PollInterval.Seconds()),
+			ExpiresAt:  time.Now().Add(d.cfg.CodeTTL),
+		}, d.cfg.CodeTTL)
+		if errors.Is(err, apperrors.ErrUserCodeTaken) {
+			continue
+		}
+		if err != nil {
+			return DeviceCode{}, fmt.Errorf("deviceAuthorizationService.StartAuthorization: %w", err)
+		}
+		formatted := d.formatUserCode(userCode)
+		return DeviceCode{
+			DeviceCode:              deviceCode,
+			UserCode:                formatted,
+			VerificationURI:         d.cfg.VerificationURL,
+			VerificationURIComplete: d.cfg.VerificationURL + "?user_code=" + url.QueryEscape(formatted),
+			ExpiresIn:               d.cfg.CodeTTL,
+			Interval:                d.cfg.PollInterval,
+		}, nil
+	}
+	return DeviceCode{}, fmt.Errorf("deviceAuthorizationService.StartAuthorization: %w", err)
+}
+
+// lookupUserCode find a pending authorization, lookups are rate limited per user so user codes can not be guessed
+func (d *deviceAuthorizationService) lookupUserCode(ctx context.Context, userID string, userCode string) (string, model.DeviceAuthorization, error) {
+	err := d.rateLimiter.Allow(ctx, "device_user_code:user:"+userID, d.cfg.UserLimit, d.cfg.LimitWindow)
+	if err != nil {
+		return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationService.lookupUserCode: %w", err)
+	}
+	deviceCodeHash, authorization, err := d.authorizationRepo.GetAuthorizationByUserCode(ctx, d.normalizeUserCode(userCode))
+	if err != nil {
+		return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationService.lookupUserCode: %w", err)
+	}
+	if authorization.Status != model.DeviceAuthorizationPending {
+		return "", model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationService.lookupUserCode: %w", apperrors.ErrInvalidUserCode)
+	}
+	return deviceCodeHash, authorization, nil
+}
+
+func (d *deviceAuthorizationService) GetAuthorization(ctx context.Context, userID string, userCode string) (model.DeviceAuthorization, error) {
+	_, authorization, err := d.lookupUserCode(ctx, userID, userCode)
+	if err != nil {
+		return model.DeviceAuthorization{}, fmt.Errorf("deviceAuthorizationService.GetAuthorization: %w", err)
+	}
+	authorization.UserCode = d.formatUserCode(authorization.UserCode)
+	return authorization, nil
+}
+
+func (d *deviceAuthorizationService) Approve(ctx context.Context, userID string, userCode string) error {
+	err := d.decide(ctx, userID, userCode, model.DeviceAuthorizationApproved)
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationService.Approve: %w", err)
+	}
+	return nil
+}
+
+func (d *deviceAuthorizationService) Deny(ctx context.Context, userID string, userCode string) error {
+	err := d.decide(ctx, userID, userCode, model.DeviceAuthorizationDenied)
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationService.Deny: %w", err)
+	}
+	return nil
+}
+
+func (d *deviceAuthorizationService) decide(ctx context.Context, userID string, userCode string, status string) error {
+	deviceCodeHash, authorization, err := d.lookupUserCode(ctx, userID, userCode)
+	if err != nil {
+		return fmt.Errorf("deviceAuthorizationService.decide: %w", err)
+	}
+	authorization.Status = status
+	authorization.UserID = userID
+	err = d.authorizationRepo.UpdateAuthorization(ctx, deviceCodeHash, model.DeviceAuthorizationPending, authorization)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidDeviceCode) || errors.Is(err, apperrors.ErrDeviceAuthorizationChanged) {
+			return fmt.Errorf("deviceAuthorizationService.decide: %w", apperrors.ErrInvalidUserCode)
+		}
+		return fmt.Errorf("deviceAuthorizationService.decide: %w", err)
+	}
+	return nil
+}
+
+func (d *deviceAuthorizationService) PollToken(ctx context.Context, clientID string, deviceCode string, client ClientInfo) (AuthenticationResponse, error) {
+	deviceCodeHash := d.hashDeviceCode(deviceCode)
+	authorization, err := d.authorizationRepo.GetAuthorization(ctx, deviceCodeHash)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+	}
+	if authorization.ClientID != clientID {
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", apperrors.ErrInvalidClient)
+	}
+	now := time.Now()
+	lastPolledAt := authorization.LastPolledAt
+	authorization.LastPolledAt = &now
+	if lastPolledAt != nil && now.Sub(*lastPolledAt) < time.Duration(authorization.Interval)*time.Second {
+		authorization.Interval += slowDownStep
+		// if the user decided meanwhile, the decision is kept and returned on the next poll
+		err = d.authorizationRepo.UpdateAuthorization(ctx, deviceCodeHash, authorization.Status, authorization)
+		if err != nil && !errors.Is(err, apperrors.ErrDeviceAuthorizationChanged) {
+			return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+		}
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", apperrors.ErrSlowDown)
+	}
+	switch authorization.Status {
+	case model.DeviceAuthorizationPending:
+		err = d.authorizationRepo.UpdateAuthorization(ctx, deviceCodeHash, model.DeviceAuthorizationPending, authorization)
+		if err != nil && !errors.Is(err, apperrors.ErrDeviceAuthorizationChanged) {
+			return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+		}
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", apperrors.ErrAuthorizationPending)
+	case model.DeviceAuthorizationDenied:
+		err = d.authorizationRepo.DeleteAuthorization(ctx, deviceCodeHash, authorization.UserCode)
+		if err != nil {
+			return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+		}
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", apperrors.ErrDeviceAccessDenied)
+	}
+	// deleting first makes the device code single-use even if the device polls concurrently
+	err = d.authorizationRepo.DeleteAuthorization(ctx, deviceCodeHash, authorization.UserCode)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+	}
+	user, err := d.userService.GetUserById(ctx, authorization.UserID)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+	}
+	client.DeviceName = authorization.DeviceName
+	res, err := d.authService.IssueSession(ctx, user, client)
+	if err != nil {
+		return AuthenticationResponse{}, fmt.Errorf("deviceAuthorizationService.PollToken: %w", err)
+	}
+	return res, nil
+}
+
+func NewDeviceAuthorizationService(userService UserService, authService AuthService, authorizationRepo repository.DeviceAuthorizationRepository, rateLimiter RateLimiter, cfg DeviceAuthorizationConfig) DeviceAuthorizationService {
+	return &deviceAuthorizationService{
+		userService:       userService,
+		authService:       authService,
+		authorizationRepo: authorizationRepo,
+		rateLimiter:       rateLimiter,
+		cfg:               cfg,
+	}
+}
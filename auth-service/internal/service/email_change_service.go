@@ -0,0 +1,241 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/jwt"
+	"auth-service/internal/mailer"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type EmailChangeConfig struct {
+	TokenTTL time.Duration
+	// CancelTTL is how long the old address can cancel the change, including after it has been confirmed
+	CancelTTL   time.Duration
+	Limit       int
+	LimitWindow time.Duration
+	FrontendURL string
+}
+
+type EmailChangeService interface {
+	// RequestEmailChange reauthenticate the user on the session sessionID, then email a confirmation link to newEmail
+	// and a cancel link to the current address. The email is unchanged until the new address is confirmed, a previous
+	// pending change of the user is cancelled
+	RequestEmailChange(ctx context.Context, userID string, sessionID string, currentPassword string, newEmail string) (model.EmailChange, error)
+	// GetPendingEmailChange return apperrors.ErrEmailChangeNotFound if the user has no pending change
+	GetPendingEmailChange(ctx context.Context, userID string) (model.EmailChange, error)
+	// CancelPendingEmailChange cancel the pending change of the user, apperrors.ErrEmailChangeNotFound is returned if
+	// there is none
+	CancelPendingEmailChange(ctx context.Context, userID string) (model.EmailChange, error)
+	// ConfirmEmailChange consume a confirmation token and swap the email of the user. apperrors.ErrUserMailAlreadyExists
+	// is returned if another account took the new address since the change was requested
+	ConfirmEmailChange(ctx context.Context, token string) (model.EmailChange, error)
+	// CancelEmailChange consume a cancel token. A pending change is cancelled, a confirmed one is reverted and every
+	// token of the user is revoked since the account may have been taken over
+	CancelEmailChange(ctx context.Context, token string) (model.EmailChange, error)
+}
+
+type emailChangeService struct {
+	userService       UserService
+	reauthService     ReauthenticationService
+	emailChangeRepo   repository.EmailChangeRepository
+	actionTokenRepo   repository.ActionTokenRepository
+	revocationService RevocationService
+	rateLimiter       RateLimiter
+	jwt               jwt.Utils
+	mailer            mailer.Mailer
+	cfg               EmailChangeConfig
+}
+
+// createLink create a single-use token of type tokenType for the change and return the frontend link it is sent in
+func (e *emailChangeService) createLink(ctx context.Context, change model.EmailChange, tokenType string, ttl time.Duration, path string) (string, error) {
+	token, err := e.jwt.CreateActionToken(change.UserID, tokenType, ttl, map[string]string{
+		"change_id": change.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("emailChangeService.createLink: %w", err)
+	}
+	err = e.actionTokenRepo.SaveActionToken(ctx, token.JTI, token.TTL)
+	if err != nil {
+		return "", fmt.Errorf("emailChangeService.createLink: %w", err)
+	}
+	return fmt.Sprintf("%s/%s?token=%s", e.cfg.FrontendURL, path, url.QueryEscape(token.Token)), nil
+}
+
+func (e *emailChangeService) RequestEmailChange(ctx context.Context, userID string, sessionID string, currentPassword string, newEmail string) (model.EmailChange, error) {
+	err := e.rateLimiter.Allow(ctx, "email_change:user:"+userID, e.cfg.Limit, e.cfg.LimitWindow)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	user, err := e.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	err = e.reauthService.Verify(ctx, user, sessionID, currentPassword)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", apperrors.ErrEmailUnchanged)
+	}
+	// checked again when the change is confirmed, the address can be taken in the meantime
+	_, err = e.userService.GetUserByEmail(ctx, newEmail)
+	if err == nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", apperrors.ErrUserMailAlreadyExists)
+	}
+	if !errors.Is(err, apperrors.ErrUserNotFound) {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	now := time.Now()
+	change, err := e.emailChangeRepo.CreateEmailChange(ctx, model.EmailChange{
+		UserID:    user.ID,
+		OldEmail:  user.Email,
+		NewEmail:  newEmail,
+		Status:    model.EmailChangeStatusPending,
+		ExpiresAt: now.Add(e.cfg.TokenTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	cancelLink, err := e.createLink(ctx, change, jwt.TokenTypeEmailChangeCancel, e.cfg.CancelTTL, "cancel-email-change")
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	confirmLink, err := e.createLink(ctx, change, jwt.TokenTypeEmailChange, e.cfg.TokenTTL, "confirm-email-change")
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w", err)
+	}
+	// the old address is alerted first, a change its owner could not be told about is not kept
+	err = e.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address of your account to %s. If it was not you, cancel the change and secure your account by opening the link below:\n\n%s\n\nThe link works for %s, even after the new address has been confirmed.\n",
+			user.FirstName, newEmail, cancelLink, e.cfg.CancelTTL),
+	})
+	if err != nil {
+		if _, cancelErr := e.emailChangeRepo.CancelEmailChange(ctx, change.ID, time.Now()); cancelErr != nil {
+			return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w: %w: %w", apperrors.ErrMailDeliveryFailed, err, cancelErr)
+		}
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w: %w", apperrors.ErrMailDeliveryFailed, err)
+	}
+	err = e.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that this is the new email address of your account by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not ask for it, you can ignore this email.\n",
+			user.FirstName, confirmLink, e.cfg.TokenTTL),
+	})
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.RequestEmailChange: %w: %w", apperrors.ErrMailDeliveryFailed, err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeService) GetPendingEmailChange(ctx context.Context, userID string) (model.EmailChange, error) {
+	change, err := e.emailChangeRepo.GetPendingEmailChange(ctx, userID, time.Now())
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.GetPendingEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeService) CancelPendingEmailChange(ctx context.Context, userID string) (model.EmailChange, error) {
+	now := time.Now()
+	change, err := e.emailChangeRepo.GetPendingEmailChange(ctx, userID, now)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelPendingEmailChange: %w", err)
+	}
+	change, err = e.emailChangeRepo.CancelEmailChange(ctx, change.ID, now)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelPendingEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+// consumeToken verify the token is of type tokenType and has not been used, then return the id of its change
+func (e *emailChangeService) consumeToken(ctx context.Context, token string, tokenType string) (string, error) {
+	claims, err := e.jwt.VerifyToken(token, tokenType)
+	if err != nil {
+		return "", fmt.Errorf("emailChangeService.consumeToken: %w", err)
+	}
+	changeID, _ := claims["change_id"].(string)
+	jti, _ := claims["jti"].(string)
+	if changeID == "" {
+		return "", fmt.Errorf("emailChangeService.consumeToken: %w", apperrors.ErrInvalidToken)
+	}
+	err = e.actionTokenRepo.ConsumeActionToken(ctx, jti)
+	if err != nil {
+		return "", fmt.Errorf("emailChangeService.consumeToken: %w", err)
+	}
+	return changeID, nil
+}
+
+func (e *emailChangeService) ConfirmEmailChange(ctx context.Context, token string) (model.EmailChange, error) {
+	changeID, err := e.consumeToken(ctx, token, jwt.TokenTypeEmailChange)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.ConfirmEmailChange: %w", err)
+	}
+	change, err := e.emailChangeRepo.ApplyEmailChange(ctx, changeID, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrUserMailAlreadyExists):
+			// the change can never be applied, the user has to request another one
+			if failErr := e.emailChangeRepo.FailEmailChange(ctx, changeID); failErr != nil {
+				return model.EmailChange{}, fmt.Errorf("emailChangeService.ConfirmEmailChange: %w: %w", err, failErr)
+			}
+		case errors.Is(err, apperrors.ErrEmailChangeNotFound):
+			// cancelled, superseded, expired, or the email has been changed since
+			return model.EmailChange{}, fmt.Errorf("emailChangeService.ConfirmEmailChange: %w", apperrors.ErrInvalidToken)
+		}
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.ConfirmEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func (e *emailChangeService) CancelEmailChange(ctx context.Context, token string) (model.EmailChange, error) {
+	changeID, err := e.consumeToken(ctx, token, jwt.TokenTypeEmailChangeCancel)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelEmailChange: %w", err)
+	}
+	now := time.Now()
+	change, err := e.emailChangeRepo.CancelEmailChange(ctx, changeID, now)
+	if err == nil {
+		return change, nil
+	}
+	if !errors.Is(err, apperrors.ErrEmailChangeNotFound) {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelEmailChange: %w", err)
+	}
+	// the change is not pending anymore, it is reverted if it has been confirmed
+	change, err = e.emailChangeRepo.RevertEmailChange(ctx, changeID, now)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrEmailChangeNotFound) {
+			return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelEmailChange: %w", apperrors.ErrInvalidToken)
+		}
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelEmailChange: %w", err)
+	}
+	err = e.revocationService.RevokeUserTokens(ctx, change.UserID)
+	if err != nil {
+		return model.EmailChange{}, fmt.Errorf("emailChangeService.CancelEmailChange: %w", err)
+	}
+	return change, nil
+}
+
+func NewEmailChangeService(userService UserService, reauthService ReauthenticationService, emailChangeRepo repository.EmailChangeRepository, actionTokenRepo repository.ActionTokenRepository, revocationService RevocationService, rateLimiter RateLimiter, jwt jwt.Utils, mailer mailer.Mailer, cfg EmailChangeConfig) EmailChangeService {
+	return &emailChangeService{
+		userService:       userService,
+		reauthService:     reauthService,
+		emailChangeRepo:   emailChangeRepo,
+		actionTokenRepo:   actionTokenRepo,
+		revocationService: revocationService,
+		rateLimiter:       rateLimiter,
+		jwt:               jwt,
+		mailer:            mailer,
+		cfg:               cfg,
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/jwt"
+	"auth-service/internal/mailer"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+type EmailVerificationService interface {
+	// SendVerificationEmail error wraps apperrors.ErrMailDeliveryFailed if the mail could not be sent
+	SendVerificationEmail(ctx context.Context, user model.User) error
+	ResendVerificationEmail(ctx context.Context, userID string) error
+	// VerifyEmail consume a verification token and mark the email it was sent to as verified
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+type emailVerificationService struct {
+	userService     UserService
+	jwt             jwt.Utils
+	actionTokenRepo repository.ActionTokenRepository
+	mailer          mailer.Mailer
+	tokenTTL        time.Duration
+	frontendURL     string
+}
+
+func (e *emailVerificationService) SendVerificationEmail(ctx context.Context, user model.User) error {
+	token, err := e.jwt.CreateActionToken(user.ID, jwt.TokenTypeEmailVerification, e.tokenTTL, map[string]string{
+		"email": user.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.SendVerificationEmail: %w", err)
+	}
+	err = e.actionTokenRepo.SaveActionToken(ctx, token.JTI, token.TTL)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.SendVerificationEmail: %w", err)
+	}
+	link := fmt.Sprintf("%s/verify-email?token=%s", e.frontendURL, url.QueryEscape(token.Token))
+	err = e.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.FirstName, link, token.TTL),
+	})
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.SendVerificationEmail: %w: %w", apperrors.ErrMailDeliveryFailed, err)
+	}
+	return nil
+}
+
+func (e *emailVerificationService) ResendVerificationEmail(ctx context.Context, userID string) error {
+	user, err := e.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.ResendVerificationEmail: %w", err)
+	}
+	if user.EmailVerified {
+		return fmt.Errorf("emailVerificationService.ResendVerificationEmail: %w", apperrors.ErrEmailAlreadyVerified)
+	}
+	err = e.SendVerificationEmail(ctx, user)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.ResendVerificationEmail: %w", err)
+	}
+	return nil
+}
+
+func (e *emailVerificationService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := e.jwt.VerifyToken(token, jwt.TokenTypeEmailVerification)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.VerifyEmail: %w", err)
+	}
+	userID := claims["user_id"].(string)
+	email, _ := claims["email"].(string)
+	jti, _ := claims["jti"].(string)
+	err = e.actionTokenRepo.ConsumeActionToken(ctx, jti)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.VerifyEmail: %w", err)
+	}
+	user, err := e.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.VerifyEmail: %w", err)
+	}
+	// the email has been changed since the token was sent
+	if user.Email != email {
+		return fmt.Errorf("emailVerificationService.VerifyEmail: %w", apperrors.ErrInvalidToken)
+	}
+	err = e.userService.SetEmailVerified(ctx, userID, true)
+	if err != nil {
+		return fmt.Errorf("emailVerificationService.VerifyEmail: %w", err)
+	}
+	return nil
+}
+
+func NewEmailVerificationService(userService UserService, jwt jwt.Utils, actionTokenRepo repository.ActionTokenRepository, mailer mailer.Mailer, tokenTTL time.Duration, frontendURL string) EmailVerificationService {
+	return &emailVerificationService{
+		userService:     userService,
+		jwt:             jwt,
+		actionTokenRepo: actionTokenRepo,
+		mailer:          mailer,
+		tokenTTL:        tokenTTL,
+		frontendURL:     frontendURL,
+	}
+}
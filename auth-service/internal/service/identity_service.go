@@ -0,0 +1,135 @@
+package service
+
+import (
+	"auth-service/internal/client"
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// LoginMethods are the credentials a user can sign in with
+type LoginMethods struct {
+	HasPassword bool
+	Identities  []model.Identity
+	Passkeys    int
+}
+
+// count return the number of credentials the user can sign in with
+func (l LoginMethods) count() int {
+	n := len(l.Identities) + l.Passkeys
+	if l.HasPassword {
+		n++
+	}
+	return n
+}
+
+type IdentityService interface {
+	GetLoginMethods(ctx context.Context, userID string) (LoginMethods, error)
+	// UnlinkIdentity return apperrors.ErrLastLoginMethod if the user could not sign in anymore without it
+	UnlinkIdentity(ctx context.Context, userID string, identityID string) error
+	// MergeUsers move the identities, the passkeys and the channel of sourceID to targetID then delete sourceID.
+	// The password of sourceID is kept if targetID has none
+	MergeUsers(ctx context.Context, targetID string, sourceID string) error
+}
+
+type identityService struct {
+	userService   UserService
+	identityRepo  repository.IdentityRepository
+	passkeyRepo   repository.PasskeyRepository
+	channelClient client.ChannelClient
+}
+
+func (i *identityService) GetLoginMethods(ctx context.Context, userID string) (LoginMethods, error) {
+	user, err := i.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return LoginMethods{}, fmt.Errorf("identityService.GetLoginMethods: %w", err)
+	}
+	identities, err := i.identityRepo.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return LoginMethods{}, fmt.Errorf("identityService.GetLoginMethods: %w", err)
+	}
+	passkeys, err := i.passkeyRepo.CountUserPasskeys(ctx, userID)
+	if err != nil {
+		return LoginMethods{}, fmt.Errorf("identityService.GetLoginMethods: %w", err)
+	}
+	return LoginMethods{
+		HasPassword: user.Password != "",
+		Identities:  identities,
+		Passkeys:    int(passkeys),
+	}, nil
+}
+
+func (i *identityService) UnlinkIdentity(ctx context.Context, userID string, identityID string) error {
+	methods, err := i.GetLoginMethods(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("identityService.UnlinkIdentity: %w", err)
+	}
+	found := false
+	for _, identity := range methods.Identities {
+		if identity.ID == identityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("identityService.UnlinkIdentity: %w", apperrors.ErrIdentityNotFound)
+	}
+	if methods.count() <= 1 {
+		return fmt.Errorf("identityService.UnlinkIdentity: %w", apperrors.ErrLastLoginMethod)
+	}
+	err = i.identityRepo.DeleteUserIdentity(ctx, userID, identityID)
+	if err != nil {
+		return fmt.Errorf("identityService.UnlinkIdentity: %w", err)
+	}
+	return nil
+}
+
+func (i *identityService) MergeUsers(ctx context.Context, targetID string, sourceID string) error {
+	if targetID == sourceID {
+		return fmt.Errorf("identityService.MergeUsers: %w", apperrors.ErrMergeSameUser)
+	}
+	target, err := i.userService.GetUserById(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("identityService.MergeUsers: %w", err)
+	}
+	source, err := i.userService.GetUserById(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("identityService.MergeUsers: %w", err)
+	}
+	// the channel is transferred first, so a conflict aborts the merge before anything else changed
+	err = i.channelClient.TransferChannel(ctx, sourceID, targetID)
+	if err != nil && !errors.Is(err, apperrors.ErrChannelNotFound) {
+		return fmt.Errorf("identityService.MergeUsers: %w", err)
+	}
+	err = i.identityRepo.MoveUserIdentities(ctx, sourceID, targetID)
+	if err != nil {
+		return fmt.Errorf("identityService.MergeUsers: %w", err)
+	}
+	err = i.passkeyRepo.MoveUserPasskeys(ctx, sourceID, targetID)
+	if err != nil {
+		return fmt.Errorf("identityService.MergeUsers: %w", err)
+	}
+	if target.Password == "" && source.Password != "" {
+		err = i.userService.UpdateUserByID(ctx, model.User{ID: targetID, Password: source.Password})
+		if err != nil {
+			return fmt.Errorf("identityService.MergeUsers: %w", err)
+		}
+	}
+	err = i.userService.DeleteUser(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("identityService.MergeUsers: %w", err)
+	}
+	return nil
+}
+
+func NewIdentityService(userService UserService, identityRepo repository.IdentityRepository, passkeyRepo repository.PasskeyRepository, channelClient client.ChannelClient) IdentityService {
+	return &identityService{
+		userService:   userService,
+		identityRepo:  identityRepo,
+		passkeyRepo:   passkeyRepo,
+		channelClient: channelClient,
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// inviteCodeAlphabet has no vowels, so codes do not spell words, and no characters that look alike
+const inviteCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const inviteCodeLength = 16
+
+type InviteService interface {
+	// CreateInvite return the created invite and its code, which can not be retrieved later.
+	// The users who sign up with it get role, model.RoleUser when it is empty
+	CreateInvite(ctx context.Context, createdBy string, role string, maxUses int, ttl time.Duration, note string) (model.Invite, string, error)
+	GetInvites(ctx context.Context, limit int, offset int) ([]model.Invite, error)
+	GetInvite(ctx context.Context, id string) (model.Invite, error)
+	// RevokeInvite prevent the invite from being used again, the accounts already created with it are kept
+	RevokeInvite(ctx context.Context, id string) error
+	// GetInvitedUsers return the users who signed up with the invite
+	GetInvitedUsers(ctx context.Context, id string, limit int, offset int) ([]model.InvitedUser, error)
+	// GetUserInvite return the invite the user signed up with
+	GetUserInvite(ctx context.Context, userID string) (model.Invite, error)
+	// Admit check that an account can be created in the registration mode and redeem code when it is given.
+	// The returned invite is nil when no code is given, otherwise its use must be given back with Release if the
+	// account is not created, or recorded with RecordRedemption once it is
+	Admit(ctx context.Context, code string) (*model.Invite, error)
+	Release(ctx context.Context, inviteID string) error
+	RecordRedemption(ctx context.Context, inviteID string, userID string) error
+}
+
+type inviteService struct {
+	inviteRepo repository.InviteRepository
+	// registrationMode is one of the model.RegistrationMode constants
+	registrationMode string
+}
+
+func (*inviteService) hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeCode accept the code in any case, with or without separators
+func (*inviteService) normalizeCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if strings.ContainsRune(inviteCodeAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (*inviteService) generateCode() (string, error) {
+	var b strings.Builder
+	alphabetSize := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for range inviteCodeLength {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(inviteCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// formatCode split the code in groups of four so it is easier to read, e.g. BDFH-JKLM-NPQR-STVW
+func (*inviteService) formatCode(code string) string {
+	groups := make([]string, 0, len(code)/4)
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:i+4])
+	}
+	return strings.Join(groups, "-")
+}
+
+func (i *inviteService) CreateInvite(ctx context.Context, createdBy string, role string, maxUses int, ttl time.Duration, note string) (model.Invite, string, error) {
+	if role == "" {
+		role = model.RoleUser
+	}
+	if !model.IsValidRole(role) {
+		return model.Invite{}, "", fmt.Errorf("inviteService.CreateInvite: %w", apperrors.ErrInvalidRoles)
+	}
+	code, err := i.generateCode()
+	if err != nil {
+		return model.Invite{}, "", fmt.Errorf("inviteService.CreateInvite: %w", err)
+	}
+	now := time.Now()
+	invite, err := i.inviteRepo.CreateInvite(ctx, model.Invite{
+		CodeHash:  i.hashCode(code),
+		Role:      role,
+		Note:      note,
+		MaxUses:   maxUses,
+		ExpiresAt: now.Add(ttl),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return model.Invite{}, "", fmt.Errorf("inviteService.CreateInvite: %w", err)
+	}
+	return invite, i.formatCode(code), nil
+}
+
+func (i *inviteService) GetInvites(ctx context.Context, limit int, offset int) ([]model.Invite, error) {
+	invites, err := i.inviteRepo.GetInvites(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("inviteService.GetInvites: %w", err)
+	}
+	return invites, nil
+}
+
+func (i *inviteService) GetInvite(ctx context.Context, id string) (model.Invite, error) {
+	invite, err := i.inviteRepo.GetInviteByID(ctx, id)
+	if err != nil {
+		return model.Invite{}, fmt.Errorf("inviteService.GetInvite: %w", err)
+	}
+	return invite, nil
+}
+
+func (i *inviteService) RevokeInvite(ctx context.Context, id string) error {
+	err := i.inviteRepo.RevokeInvite(ctx, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("inviteService.RevokeInvite: %w", err)
+	}
+	return nil
+}
+
+func (i *inviteService) GetInvitedUsers(ctx context.Context, id string, limit int, offset int) ([]model.InvitedUser, error) {
+	// an unknown invite is reported instead of an empty list
+	_, err := i.inviteRepo.GetInviteByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inviteService.GetInvitedUsers: %w", err)
+	}
+	users, err := i.inviteRepo.GetInvitedUsers(ctx, id, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("inviteService.GetInvitedUsers: %w", err)
+	}
+	return users, nil
+}
+
+func (i *inviteService) GetUserInvite(ctx context.Context, userID string) (model.Invite, error) {
+	invite, err := i.inviteRepo.GetUserInvite(ctx, userID)
+	if err != nil {
+		return model.Invite{}, fmt.Errorf("inviteService.GetUserInvite: %w", err)
+	}
+	return invite, nil
+}
+
+func (i *inviteService) Admit(ctx context.Context, code string) (*model.Invite, error) {
+	if i.registrationMode == model.RegistrationModeClosed {
+		return nil, fmt.Errorf("inviteService.Admit: %w", apperrors.ErrRegistrationClosed)
+	}
+	code = i.normalizeCode(code)
+	if code == "" {
+		if i.registrationMode == model.RegistrationModeInviteOnly {
+			return nil, fmt.Errorf("inviteService.Admit: %w", apperrors.ErrInviteRequired)
+		}
+		return nil, nil
+	}
+	invite, err := i.inviteRepo.RedeemInvite(ctx, i.hashCode(code), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("inviteService.Admit: %w", err)
+	}
+	return &invite, nil
+}
+
+func (i *inviteService) Release(ctx context.Context, inviteID string) error {
+	err := i.inviteRepo.ReleaseInvite(ctx, inviteID)
+	if err != nil {
+		return fmt.Errorf("inviteService.Release: %w", err)
+	}
+	return nil
+}
+
+func (i *inviteService) RecordRedemption(ctx context.Context, inviteID string, userID string) error {
+	err := i.inviteRepo.AddRedemption(ctx, model.InviteRedemption{
+		InviteID:  inviteID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("inviteService.RecordRedemption: %w", err)
+	}
+	return nil
+}
+
+func NewInviteService(inviteRepo repository.InviteRepository, registrationMode string) InviteService {
+	return &inviteService{
+		inviteRepo:       inviteRepo,
+		registrationMode: registrationMode,
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoginGuardConfig struct {
+	// FailureWindow is the sliding window failed attempts are counted in
+	FailureWindow time.Duration
+	// DelayThreshold is the number of failures for an email after which each attempt has to wait
+	// BaseDelay, doubled on every new failure up to MaxDelay
+	DelayThreshold int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	// LockoutThreshold is the number of failures for an email that locks it for LockoutDuration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// IPFailureLimit is the number of failures allowed from an IP, whatever the email
+	IPFailureLimit int
+}
+
+// LoginAttempt is a login attempt reserved by LoginGuard.Reserve
+type LoginAttempt struct {
+	ID    string
+	Email string
+	IP    string
+	// Failures is the number of failures of the email in the window if this attempt fails
+	Failures int
+}
+
+// LoginGuard protect the password login against brute force with progressive delays and temporary lockouts
+type LoginGuard interface {
+	// Reserve count the attempt as failed before the password is verified, so parallel attempts cannot all get through
+	// before a delay or lockout is applied. It returns an *apperrors.RateLimitError if the email is locked or delayed,
+	// or if the ip made too many failed attempts
+	Reserve(ctx context.Context, email string, ip string) (LoginAttempt, error)
+	// RecordFailure keep the reserved attempt as failed, userID is empty if no user has its email
+	RecordFailure(ctx context.Context, attempt LoginAttempt, userID string) error
+	// RecordSuccess release the reserved attempt and reset the failed attempts of its email
+	RecordSuccess(ctx context.Context, attempt LoginAttempt) error
+	GetLockouts(ctx context.Context, userID string, limit, offset int) ([]model.LoginLockout, error)
+	// ClearLockout lift the current lockout and delay of the user and reset their failed attempts
+	ClearLockout(ctx context.Context, userID string, clearedBy string) error
+}
+
+type loginGuard struct {
+	userService      UserService
+	loginAttemptRepo repository.LoginAttemptRepository
+	lockoutRepo      repository.LoginLockoutRepository
+	cfg              LoginGuardConfig
+}
+
+func (l *loginGuard) Reserve(ctx context.Context, email string, ip string) (LoginAttempt, error) {
+	attempt := LoginAttempt{
+		ID:    uuid.NewString(),
+		Email: strings.ToLower(email),
+		IP:    ip,
+	}
+	failures, retryAfter, err := l.loginAttemptRepo.Reserve(ctx, attempt.Email, attempt.IP, attempt.ID, repository.LoginAttemptPolicy{
+		FailureWindow:    l.cfg.FailureWindow,
+		DelayThreshold:   l.cfg.DelayThreshold,
+		BaseDelay:        l.cfg.BaseDelay,
+		MaxDelay:         l.cfg.MaxDelay,
+		LockoutThreshold: l.cfg.LockoutThreshold,
+		LockoutDuration:  l.cfg.LockoutDuration,
+		IPFailureLimit:   l.cfg.IPFailureLimit,
+	})
+	if err != nil {
+		return LoginAttempt{}, fmt.Errorf("loginGuard.Reserve: %w", err)
+	}
+	if retryAfter > 0 {
+		return LoginAttempt{}, fmt.Errorf("loginGuard.Reserve: %w", &apperrors.RateLimitError{RetryAfter: retryAfter})
+	}
+	attempt.Failures = failures
+	return attempt, nil
+}
+
+func (l *loginGuard) RecordFailure(ctx context.Context, attempt LoginAttempt, userID string) error {
+	// the delay or lockout has been applied by Reserve, only the lockouts of known users are recorded to show them
+	if attempt.Failures < l.cfg.LockoutThreshold || userID == "" {
+		return nil
+	}
+	now := time.Now()
+	_, err := l.lockoutRepo.CreateLockout(ctx, model.LoginLockout{
+		UserID:         userID,
+		Email:          attempt.Email,
+		IP:             attempt.IP,
+		FailedAttempts: attempt.Failures,
+		LockedAt:       now,
+		LockedUntil:    now.Add(l.cfg.LockoutDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("loginGuard.RecordFailure: %w", err)
+	}
+	return nil
+}
+
+func (l *loginGuard) RecordSuccess(ctx context.Context, attempt LoginAttempt) error {
+	err := l.loginAttemptRepo.Release(ctx, attempt.IP, attempt.ID)
+	if err != nil {
+		return fmt.Errorf("loginGuard.RecordSuccess: %w", err)
+	}
+	err = l.loginAttemptRepo.Clear(ctx, attempt.Email)
+	if err != nil {
+		return fmt.Errorf("loginGuard.RecordSuccess: %w", err)
+	}
+	return nil
+}
+
+func (l *loginGuard) GetLockouts(ctx context.Context, userID string, limit, offset int) ([]model.LoginLockout, error) {
+	_, err := l.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("loginGuard.GetLockouts: %w", err)
+	}
+	lockouts, err := l.lockoutRepo.GetUserLockouts(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("loginGuard.GetLockouts: %w", err)
+	}
+	return lockouts, nil
+}
+
+func (l *loginGuard) ClearLockout(ctx context.Context, userID string, clearedBy string) error {
+	user, err := l.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("loginGuard.ClearLockout: %w", err)
+	}
+	err = l.loginAttemptRepo.Clear(ctx, strings.ToLower(user.Email))
+	if err != nil {
+		return fmt.Errorf("loginGuard.ClearLockout: %w", err)
+	}
+	err = l.lockoutRepo.ClearUserLockouts(ctx, userID, clearedBy)
+	if err != nil {
+		return fmt.Errorf("loginGuard.ClearLockout: %w", err)
+	}
+	return nil
+}
+
+func NewLoginGuard(userService UserService, loginAttemptRepo repository.LoginAttemptRepository, lockoutRepo repository.LoginLockoutRepository, cfg LoginGuardConfig) LoginGuard {
+	return &loginGuard{
+		userService:      userService,
+		loginAttemptRepo: loginAttemptRepo,
+		lockoutRepo:      lockoutRepo,
+		cfg:              cfg,
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/jwt"
+	"auth-service/internal/mailer"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type MagicLinkConfig struct {
+	TokenTTL    time.Duration
+	EmailLimit  int
+	IPLimit     int
+	LimitWindow time.Duration
+	FrontendURL string
+}
+
+type MagicLinkService interface {
+	// RequestMagicLink email a single-use sign in link to the user. The link is sent in the background and unknown
+	// emails are silently ignored, so neither the response nor its timing tells whether an account exists.
+	// Only rate limiting errors are returned
+	RequestMagicLink(ctx context.Context, email string, deviceName string, ip string) error
+	// ConsumeMagicLink sign in the user the link was sent to. Like Login, a challenge is returned
+	// instead of tokens if the user has a second factor
+	ConsumeMagicLink(ctx context.Context, token string, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error)
+}
+
+type magicLinkService struct {
+	userService     UserService
+	authService     AuthService
+	jwt             jwt.Utils
+	actionTokenRepo repository.ActionTokenRepository
+	rateLimiter     RateLimiter
+	mailer          mailer.Mailer
+	logger          *zap.Logger
+	cfg             MagicLinkConfig
+}
+
+func (m *magicLinkService) RequestMagicLink(ctx context.Context, email string, deviceName string, ip string) error {
+	err := m.rateLimiter.Allow(ctx, "magic_link:ip:"+ip, m.cfg.IPLimit, m.cfg.LimitWindow)
+	if err != nil {
+		return fmt.Errorf("magicLinkService.RequestMagicLink: %w", err)
+	}
+	err = m.rateLimiter.Allow(ctx, "magic_link:email:"+strings.ToLower(email), m.cfg.EmailLimit, m.cfg.LimitWindow)
+	if err != nil {
+		return fmt.Errorf("magicLinkService.RequestMagicLink: %w", err)
+	}
+	go m.sendMagicLink(email, deviceName)
+	return nil
+}
+
+// sendMagicLink email a sign in link to the user owning email if any, failures are logged
+func (m *magicLinkService) sendMagicLink(email string, deviceName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundMailTimeout)
+	defer cancel()
+	user, err := m.userService.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, apperrors.ErrUserNotFound) {
+			m.logger.Error("failed to look up user for magic link", zap.Error(err))
+		}
+		return
+	}
+	token, err := m.jwt.CreateActionToken(user.ID, jwt.TokenTypeMagicLink, m.cfg.TokenTTL, map[string]string{
+		"email":       user.Email,
+		"device_name": deviceName,
+	})
+	if err != nil {
+		m.logger.Error("failed to create magic link token", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	err = m.actionTokenRepo.SaveActionToken(ctx, token.JTI, token.TTL)
+	if err != nil {
+		m.logger.Error("failed to save magic link token", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	link := fmt.Sprintf("%s/magic-link?token=%s", m.cfg.FrontendURL, url.QueryEscape(token.Token))
+	err = m.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign in link",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to sign in:\n\n%s\n\nThe link can only be used once and expires in %s. If you did not ask for it, you can ignore this email.\n",
+			user.FirstName, link, token.TTL),
+	})
+	if err != nil {
+		m.logger.Warn("failed to send magic link email", zap.String("user_id", user.ID), zap.Error(err))
+	}
+}
+
+func (m *magicLinkService) ConsumeMagicLink(ctx context.Context, token string, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error) {
+	claims, err := m.jwt.VerifyToken(token, jwt.TokenTypeMagicLink)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", err)
+	}
+	userID, _ := claims["user_id"].(string)
+	email, _ := claims["email"].(string)
+	jti, _ := claims["jti"].(string)
+	if userID == "" || jti == "" {
+		return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", apperrors.ErrInvalidToken)
+	}
+	err = m.actionTokenRepo.ConsumeActionToken(ctx, jti)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", err)
+	}
+	user, err := m.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", err)
+	}
+	// the email has been changed since the link was sent
+	if user.Email != email {
+		return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", apperrors.ErrInvalidToken)
+	}
+	// following the emailed link proves the ownership of the address
+	if !user.EmailVerified {
+		err = m.userService.SetEmailVerified(ctx, userID, true)
+		if err != nil {
+			return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", err)
+		}
+		user.EmailVerified = true
+	}
+	if client.DeviceName == "" {
+		client.DeviceName, _ = claims["device_name"].(string)
+	}
+	res, challenge, err := m.authService.LoginWithUser(ctx, user, client)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("magicLinkService.ConsumeMagicLink: %w", err)
+	}
+	return res, challenge, nil
+}
+
+func NewMagicLinkService(userService UserService, authService AuthService, jwt jwt.Utils, actionTokenRepo repository.ActionTokenRepository, rateLimiter RateLimiter, mailer mailer.Mailer, logger *zap.Logger, cfg MagicLinkConfig) MagicLinkService {
+	return &magicLinkService{
+		userService:     userService,
+		authService:     authService,
+		jwt:             jwt,
+		actionTokenRepo: actionTokenRepo,
+		rateLimiter:     rateLimiter,
+		mailer:          mailer,
+		logger:          logger,
+		cfg:             cfg,
+	}
+}
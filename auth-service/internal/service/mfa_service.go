@@ -0,0 +1,247 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/repository"
+	"auth-service/internal/totp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type MFAConfig struct {
+	// Issuer is displayed by the authenticator apps next to the account name
+	Issuer            string
+	MaxAttempts       int
+	AttemptWindow     time.Duration
+	RecoveryCodeCount int
+}
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+type MFAStatus struct {
+	Enabled                bool
+	RecoveryCodesRemaining int
+}
+
+type MFAService interface {
+	GetStatus(ctx context.Context, userID string) (MFAStatus, error)
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	// EnrollTOTP generate a new pending secret, it only becomes active once confirmed with ConfirmTOTP
+	EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error)
+	// ConfirmTOTP enable MFA if code is valid for the pending secret and return the new recovery codes
+	ConfirmTOTP(ctx context.Context, userID string, code string) ([]string, error)
+	// VerifyCode check a TOTP code or a recovery code, each of them can only be used once
+	VerifyCode(ctx context.Context, userID string, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID string, code string) ([]string, error)
+	// Disable remove MFA once the user proved they still own the second factor
+	Disable(ctx context.Context, userID string, code string) error
+	// Reset remove MFA without any check, it is meant for admins helping users who lost their second factor
+	Reset(ctx context.Context, userID string) error
+}
+
+type mfaService struct {
+	userService UserService
+	mfaRepo     repository.MFARepository
+	rateLimiter RateLimiter
+	cfg         MFAConfig
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (*mfaService) hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes return the codes to display to the user and their hashes to store
+func (m *mfaService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, m.cfg.RecoveryCodeCount)
+	hashes := make([]string, 0, m.cfg.RecoveryCodeCount)
+	for range m.cfg.RecoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("mfaService.generateRecoveryCodes: %w", err)
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		code := raw[:4] + "-" + raw[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, m.hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func (m *mfaService) attemptKey(userID string) string {
+	return "mfa:user:" + userID
+}
+
+func (m *mfaService) GetStatus(ctx context.Context, userID string) (MFAStatus, error) {
+	mfa, err := m.mfaRepo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrMFANotEnrolled) {
+			return MFAStatus{}, nil
+		}
+		return MFAStatus{}, fmt.Errorf("mfaService.GetStatus: %w", err)
+	}
+	if !mfa.Enabled {
+		return MFAStatus{}, nil
+	}
+	remaining, err := m.mfaRepo.CountRemainingRecoveryCodes(ctx, userID)
+	if err != nil {
+		return MFAStatus{}, fmt.Errorf("mfaService.GetStatus: %w", err)
+	}
+	return MFAStatus{
+		Enabled:                true,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+func (m *mfaService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	mfa, err := m.mfaRepo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrMFANotEnrolled) {
+			return false, nil
+		}
+		return false, fmt.Errorf("mfaService.IsEnabled: %w", err)
+	}
+	return mfa.Enabled, nil
+}
+
+func (m *mfaService) EnrollTOTP(ctx context.Context, userID string) (TOTPEnrollment, error) {
+	user, err := m.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService.EnrollTOTP: %w", err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService.EnrollTOTP: %w", err)
+	}
+	err = m.mfaRepo.SavePendingTOTP(ctx, userID, secret)
+	if err != nil {
+		return TOTPEnrollment{}, fmt.Errorf("mfaService.EnrollTOTP: %w", err)
+	}
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(m.cfg.Issuer, user.Email, secret),
+	}, nil
+}
+
+func (m *mfaService) ConfirmTOTP(ctx context.Context, userID string, code string) ([]string, error) {
+	err := m.rateLimiter.Allow(ctx, m.attemptKey(userID), m.cfg.MaxAttempts, m.cfg.AttemptWindow)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP: %w", err)
+	}
+	mfa, err := m.mfaRepo.GetUserMFA(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP: %w", err)
+	}
+	if mfa.Enabled {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP: %w", apperrors.ErrMFAAlreadyEnabled)
+	}
+	step, ok := totp.Validate(mfa.TOTPSecret, code, time.Now(), 1)
+	if !ok {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP: %w", apperrors.ErrInvalidMFACode)
+	}
+	codes, hashes, err := m.generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP: %w", err)
+	}
+	err = m.mfaRepo.EnableTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.ConfirmTOTP: %w", err)
+	}
+	_ = m.rateLimiter.Reset(ctx, m.attemptKey(userID))
+	return codes, nil
+}
+
+func (m *mfaService) VerifyCode(ctx context.Context, userID string, code string) error {
+	err := m.rateLimiter.Allow(ctx, m.attemptKey(userID), m.cfg.MaxAttempts, m.cfg.AttemptWindow)
+	if err != nil {
+		return fmt.Errorf("mfaService.VerifyCode: %w", err)
+	}
+	mfa, err := m.mfaRepo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrMFANotEnrolled) {
+			return fmt.Errorf("mfaService.VerifyCode: %w", apperrors.ErrMFANotEnabled)
+		}
+		return fmt.Errorf("mfaService.VerifyCode: %w", err)
+	}
+	if !mfa.Enabled {
+		return fmt.Errorf("mfaService.VerifyCode: %w", apperrors.ErrMFANotEnabled)
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(mfa.TOTPSecret, code, time.Now(), 1)
+		if !ok {
+			return fmt.Errorf("mfaService.VerifyCode: %w", apperrors.ErrInvalidMFACode)
+		}
+		err = m.mfaRepo.UseTOTPStep(ctx, userID, step)
+	} else {
+		err = m.mfaRepo.UseRecoveryCode(ctx, userID, m.hashRecoveryCode(code))
+	}
+	if err != nil {
+		return fmt.Errorf("mfaService.VerifyCode: %w", err)
+	}
+	_ = m.rateLimiter.Reset(ctx, m.attemptKey(userID))
+	return nil
+}
+
+func (m *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID string, code string) ([]string, error) {
+	err := m.VerifyCode(ctx, userID, code)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.RegenerateRecoveryCodes: %w", err)
+	}
+	codes, hashes, err := m.generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.RegenerateRecoveryCodes: %w", err)
+	}
+	err = m.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("mfaService.RegenerateRecoveryCodes: %w", err)
+	}
+	return codes, nil
+}
+
+func (m *mfaService) Disable(ctx context.Context, userID string, code string) error {
+	err := m.VerifyCode(ctx, userID, code)
+	if err != nil {
+		return fmt.Errorf("mfaService.Disable: %w", err)
+	}
+	err = m.mfaRepo.DeleteUserMFA(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("mfaService.Disable: %w", err)
+	}
+	return nil
+}
+
+func (m *mfaService) Reset(ctx context.Context, userID string) error {
+	_, err := m.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("mfaService.Reset: %w", err)
+	}
+	err = m.mfaRepo.DeleteUserMFA(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("mfaService.Reset: %w", err)
+	}
+	_ = m.rateLimiter.Reset(ctx, m.attemptKey(userID))
+	return nil
+}
+
+func NewMFAService(userService UserService, mfaRepo repository.MFARepository, rateLimiter RateLimiter, cfg MFAConfig) MFAService {
+	return &mfaService{
+		userService: userService,
+		mfaRepo:     mfaRepo,
+		rateLimiter: rateLimiter,
+		cfg:         cfg,
+	}
+}
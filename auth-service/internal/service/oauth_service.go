@@ -0,0 +1,714 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/jwt"
+	"auth-service/internal/model"
+	"auth-service/internal/oidc"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Grant types accepted by the token endpoint
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+const (
+	responseTypeCode        = "code"
+	codeChallengeMethodS256 = "S256"
+)
+
+type OAuthConfig struct {
+	// Issuer is the public URL of auth-service, the endpoints advertised in the discovery document are built from it
+	Issuer string
+	// ConsentURL is the frontend page where signed in users approve authorization requests
+	ConsentURL       string
+	CodeTTL          time.Duration
+	SigningAlgorithm string
+	// ServiceTokenTTL is the lifetime of the tokens issued to backend services with the client credentials grant
+	ServiceTokenTTL time.Duration
+}
+
+// ClientRegistration is the app an admin registers, a secret is only generated for confidential clients
+type ClientRegistration struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	Public       bool
+	CreatedBy    string
+}
+
+// AuthorizationRequest are the parameters of an authorization request, see RFC 6749 section 4.1.1.
+// PKCE (RFC 7636) with the S256 method is required from every client
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// AuthorizationPrompt is what the consent page shows to the user
+type AuthorizationPrompt struct {
+	Client model.OAuthClient
+	Scopes []string
+	// Consented is set when the user already granted every requested scope, the page can approve without asking
+	Consented bool
+}
+
+// TokenRequest are the parameters of a token request, the client credentials come from the body or basic auth
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	// Scope is only read by the client credentials grant, all the service scopes of the client are granted if it is empty
+	Scope string
+}
+
+// OAuthTokens are the tokens issued to a client, IDToken is only set when the openid scope has been granted
+type OAuthTokens struct {
+	AuthenticationResponse
+	IDToken string
+	Scopes  []string
+}
+
+// Introspection is the state of a token, see RFC 7662. Only Active is set for an inactive token
+type Introspection struct {
+	Active   bool
+	Scopes   []string
+	ClientID string
+	// Subject is the user the token has been issued to, or the client itself for service tokens
+	Subject   string
+	TokenType string
+	JTI       string
+	IssuedAt  int64
+	ExpiresAt int64
+}
+
+// ProviderMetadata is the OpenID Connect discovery document
+type ProviderMetadata struct {
+	Issuer                            string
+	AuthorizationEndpoint             string
+	TokenEndpoint                     string
+	UserInfoEndpoint                  string
+	IntrospectionEndpoint             string
+	JWKSURI                           string
+	ScopesSupported                   []string
+	ResponseTypesSupported            []string
+	GrantTypesSupported               []string
+	SubjectTypesSupported             []string
+	IDTokenSigningAlgValuesSupported  []string
+	TokenEndpointAuthMethodsSupported []string
+	CodeChallengeMethodsSupported     []string
+	ClaimsSupported                   []string
+}
+
+type OAuthService interface {
+	// CreateClient register a third-party app and return its plain text secret, which can not be retrieved later.
+	// The secret is empty for public clients
+	CreateClient(ctx context.Context, registration ClientRegistration) (model.OAuthClient, string, error)
+	GetClients(ctx context.Context) ([]model.OAuthClient, error)
+	GetClient(ctx context.Context, clientID string) (model.OAuthClient, error)
+	// DeleteClient delete the client and the consents given to it, its refresh tokens stop working.
+	// Its service tokens stay valid for the services verifying them with the JWKS until they expire
+	DeleteClient(ctx context.Context, clientID string) error
+	// StartAuthorization validate the request and return the consent page the browser has to be redirected to.
+	// apperrors.ErrInvalidClient or apperrors.ErrInvalidRedirectURI are returned if the user must not be redirected
+	// back to the client, any other error is to be reported to the redirect uri
+	StartAuthorization(ctx context.Context, req AuthorizationRequest) (string, error)
+	GetAuthorizationPrompt(ctx context.Context, userID string, req AuthorizationRequest) (AuthorizationPrompt, error)
+	// Authorize record the decision of the user and return the redirect uri of the client, carrying either
+	// an authorization code or the access_denied error
+	Authorize(ctx context.Context, userID string, req AuthorizationRequest, approve bool) (string, error)
+	// Token redeem an authorization code or a refresh token, or issue a service token to a confidential client.
+	// apperrors.ErrInvalidClient is returned if the client could not be authenticated
+	// and apperrors.ErrInvalidGrant if the code or token is not valid
+	Token(ctx context.Context, req TokenRequest, client ClientInfo) (OAuthTokens, error)
+	// Introspect return the state of an access, service or personal access token to a confidential client,
+	// public clients get apperrors.ErrUnauthorizedClient
+	Introspect(ctx context.Context, clientID string, clientSecret string, token string) (Introspection, error)
+	// UserInfo return the user an access token has been granted for
+	UserInfo(ctx context.Context, userID string) (model.User, error)
+	GetUserConsents(ctx context.Context, userID string) ([]model.OAuthConsent, error)
+	// RevokeConsent delete the consent and every session the user granted to the client
+	RevokeConsent(ctx context.Context, userID string, clientID string) error
+	Metadata() ProviderMetadata
+}
+
+type oauthService struct {
+	userService       UserService
+	authService       AuthService
+	revocationService RevocationService
+	patService        PersonalAccessTokenService
+	jwt               jwt.Utils
+	oauthClientRepo   repository.OAuthClientRepository
+	oauthCodeRepo     repository.OAuthCodeRepository
+	cfg               OAuthConfig
+}
+
+func (*oauthService) hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func (*oauthService) randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validRedirectURI report whether uri is an absolute uri without fragment, custom schemes are allowed for native apps
+func (*oauthService) validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme != "" && u.Fragment == "" && (u.Host != "" || u.Opaque != "" || u.Path != "")
+}
+
+func (o *oauthService) CreateClient(ctx context.Context, registration ClientRegistration) (model.OAuthClient, string, error) {
+	for _, uri := range registration.RedirectURIs {
+		if !o.validRedirectURI(uri) {
+			return model.OAuthClient{}, "", fmt.Errorf("oauthService.CreateClient: %w", apperrors.ErrInvalidRedirectURI)
+		}
+	}
+	for _, scope := range registration.Scopes {
+		// a public client can not authenticate with the client credentials grant, it has no use of service scopes
+		serviceScope := model.IsServiceScope(scope) && !registration.Public
+		if !model.IsOpenIDScope(scope) && !model.IsPermissionScope(scope) && !serviceScope {
+			return model.OAuthClient{}, "", fmt.Errorf("oauthService.CreateClient: %w", apperrors.ErrInvalidScopes)
+		}
+	}
+	var secret, secretHash string
+	if !registration.Public {
+		var err error
+		secret, err = o.randomToken()
+		if err != nil {
+			return model.OAuthClient{}, "", fmt.Errorf("oauthService.CreateClient: %w", err)
+		}
+		secretHash = o.hash(secret)
+	}
+	slices.Sort(registration.Scopes)
+	client, err := o.oauthClientRepo.CreateClient(ctx, model.OAuthClient{
+		Name:         registration.Name,
+		SecretHash:   secretHash,
+		RedirectURIs: strings.Join(slices.Compact(registration.RedirectURIs), " "),
+		Scopes:       strings.Join(slices.Compact(registration.Scopes), " "),
+		CreatedBy:    registration.CreatedBy,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return model.OAuthClient{}, "", fmt.Errorf("oauthService.CreateClient: %w", err)
+	}
+	return client, secret, nil
+}
+
+func (o *oauthService) GetClients(ctx context.Context) ([]model.OAuthClient, error) {
+	clients, err := o.oauthClientRepo.GetClients(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("oauthService.GetClients: %w", err)
+	}
+	return clients, nil
+}
+
+func (o *oauthService) GetClient(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	client, err := o.oauthClientRepo.GetClient(ctx, clientID)
+	if err != nil {
+		return model.OAuthClient{}, fmt.Errorf("oauthService.GetClient: %w", err)
+	}
+	return client, nil
+}
+
+func (o *oauthService) DeleteClient(ctx context.Context, clientID string) error {
+	err := o.oauthClientRepo.DeleteClient(ctx, clientID)
+	if err != nil {
+		return fmt.Errorf("oauthService.DeleteClient: %w", err)
+	}
+	return nil
+}
+
+func (o *oauthService) StartAuthorization(ctx context.Context, req AuthorizationRequest) (string, error) {
+	_, err := o.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("oauthService.StartAuthorization: %w", err)
+	}
+	consentURL, err := o.redirectURI(o.cfg.ConsentURL, map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+		"nonce":                 req.Nonce,
+	})
+	if err != nil {
+		return "", fmt.Errorf("oauthService.StartAuthorization: %w", err)
+	}
+	return consentURL, nil
+}
+
+// validateAuthorizationRequest return the client of the request if it can be granted
+func (o *oauthService) validateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) (model.OAuthClient, error) {
+	client, err := o.oauthClientRepo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrOAuthClientNotFound) {
+			return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", apperrors.ErrInvalidClient)
+		}
+		return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", err)
+	}
+	// redirect uris are compared exactly, a prefix match would let an attacker pick where the code is sent
+	if !slices.Contains(strings.Fields(client.RedirectURIs), req.RedirectURI) {
+		return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", apperrors.ErrInvalidRedirectURI)
+	}
+	if req.ResponseType != responseTypeCode {
+		return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", apperrors.ErrUnsupportedResponseType)
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != codeChallengeMethodS256 {
+		return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", apperrors.ErrInvalidAuthorizationRequest)
+	}
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", apperrors.ErrInvalidScopes)
+	}
+	allowed := strings.Fields(client.Scopes)
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) || model.IsServiceScope(scope) {
+			return model.OAuthClient{}, fmt.Errorf("oauthService.validateAuthorizationRequest: %w", apperrors.ErrInvalidScopes)
+		}
+	}
+	return client, nil
+}
+
+// requestedScopes return the sorted scopes of an authorization request without duplicates
+func (*oauthService) requestedScopes(req AuthorizationRequest) []string {
+	scopes := strings.Fields(req.Scope)
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
+
+func (o *oauthService) GetAuthorizationPrompt(ctx context.Context, userID string, req AuthorizationRequest) (AuthorizationPrompt, error) {
+	client, err := o.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return AuthorizationPrompt{}, fmt.Errorf("oauthService.GetAuthorizationPrompt: %w", err)
+	}
+	scopes := o.requestedScopes(req)
+	consented := false
+	consent, err := o.oauthClientRepo.GetConsent(ctx, userID, client.ID)
+	switch {
+	case err == nil:
+		granted := strings.Fields(consent.Scopes)
+		consented = !slices.ContainsFunc(scopes, func(scope string) bool {
+			return !slices.Contains(granted, scope)
+		})
+	case !errors.Is(err, apperrors.ErrConsentNotFound):
+		return AuthorizationPrompt{}, fmt.Errorf("oauthService.GetAuthorizationPrompt: %w", err)
+	}
+	return AuthorizationPrompt{
+		Client:    client,
+		Scopes:    scopes,
+		Consented: consented,
+	}, nil
+}
+
+// redirectURI add the non empty params to the query of redirectURI
+func (*oauthService) redirectURI(redirectURI string, params map[string]string) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for k, v := range params {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (o *oauthService) Authorize(ctx context.Context, userID string, req AuthorizationRequest, approve bool) (string, error) {
+	client, err := o.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	if !approve {
+		redirect, err := o.redirectURI(req.RedirectURI, map[string]string{
+			"error": "access_denied",
+			"state": req.State,
+		})
+		if err != nil {
+			return "", fmt.Errorf("oauthService.Authorize: %w", err)
+		}
+		return redirect, nil
+	}
+	user, err := o.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	err = checkUserStatus(user)
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	scopes := o.requestedScopes(req)
+	// the consent keeps the scopes granted before, so requesting less does not ask the user again later
+	granted := slices.Clone(scopes)
+	consent, err := o.oauthClientRepo.GetConsent(ctx, userID, client.ID)
+	switch {
+	case err == nil:
+		granted = append(granted, strings.Fields(consent.Scopes)...)
+		slices.Sort(granted)
+		granted = slices.Compact(granted)
+	case !errors.Is(err, apperrors.ErrConsentNotFound):
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	now := time.Now()
+	err = o.oauthClientRepo.SaveConsent(ctx, model.OAuthConsent{
+		UserID:    userID,
+		ClientID:  client.ID,
+		Scopes:    strings.Join(granted, " "),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	code, err := o.randomToken()
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	err = o.oauthCodeRepo.SaveCode(ctx, o.hash(code), model.OAuthAuthorizationCode{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+	}, o.cfg.CodeTTL)
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	redirect, err := o.redirectURI(req.RedirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	})
+	if err != nil {
+		return "", fmt.Errorf("oauthService.Authorize: %w", err)
+	}
+	return redirect, nil
+}
+
+// authenticateClient return the client if the secret matches, public clients must not send one
+func (o *oauthService) authenticateClient(ctx context.Context, clientID string, secret string) (model.OAuthClient, error) {
+	client, err := o.oauthClientRepo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrOAuthClientNotFound) {
+			return model.OAuthClient{}, fmt.Errorf("oauthService.authenticateClient: %w", apperrors.ErrInvalidClient)
+		}
+		return model.OAuthClient{}, fmt.Errorf("oauthService.authenticateClient: %w", err)
+	}
+	if client.IsPublic() {
+		if secret != "" {
+			return model.OAuthClient{}, fmt.Errorf("oauthService.authenticateClient: %w", apperrors.ErrInvalidClient)
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(o.hash(secret)), []byte(client.SecretHash)) != 1 {
+		return model.OAuthClient{}, fmt.Errorf("oauthService.authenticateClient: %w", apperrors.ErrInvalidClient)
+	}
+	return client, nil
+}
+
+func (o *oauthService) Token(ctx context.Context, req TokenRequest, clientInfo ClientInfo) (OAuthTokens, error) {
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials:
+	default:
+		return OAuthTokens{}, fmt.Errorf("oauthService.Token: %w", apperrors.ErrUnsupportedGrantType)
+	}
+	client, err := o.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return OAuthTokens{}, fmt.Errorf("oauthService.Token: %w", err)
+	}
+	var tokens OAuthTokens
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		tokens, err = o.exchangeCode(ctx, client, req, clientInfo)
+	case GrantTypeRefreshToken:
+		tokens, err = o.refresh(ctx, client, req, clientInfo)
+	case GrantTypeClientCredentials:
+		tokens, err = o.issueServiceToken(client, req)
+	}
+	if err != nil {
+		return OAuthTokens{}, fmt.Errorf("oauthService.Token: %w", err)
+	}
+	return tokens, nil
+}
+
+// exchangeCode redeem an authorization code for a new session granted to the client
+func (o *oauthService) exchangeCode(ctx context.Context, client model.OAuthClient, req TokenRequest, clientInfo ClientInfo) (OAuthTokens, error) {
+	code, err := o.oauthCodeRepo.ConsumeCode(ctx, o.hash(req.Code))
+	if err != nil {
+		return OAuthTokens{}, fmt.Errorf("oauthService.exchangeCode: %w", err)
+	}
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return OAuthTokens{}, fmt.Errorf("oauthService.exchangeCode: %w", apperrors.ErrInvalidGrant)
+	}
+	challenge := oidc.CodeChallengeS256(req.CodeVerifier)
+	if req.CodeVerifier == "" || subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return OAuthTokens{}, fmt.Errorf("oauthService.exchangeCode: %w", apperrors.ErrInvalidGrant)
+	}
+	user, err := o.userService.GetUserById(ctx, code.UserID)
+	if err != nil {
+		return OAuthTokens{}, fmt.Errorf("oauthService.exchangeCode: %w", err)
+	}
+	res, err := o.authService.IssueClientSession(ctx, user, ClientGrant{
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		Scopes:     code.Scopes,
+	}, clientInfo)
+	if err != nil {
+		return OAuthTokens{}, fmt.Errorf("oauthService.exchangeCode: %w", err)
+	}
+	tokens := OAuthTokens{
+		AuthenticationResponse: res,
+		Scopes:                 model.GrantedScopes(user.Role, code.Scopes),
+	}
+	if slices.Contains(code.Scopes, model.ScopeOpenID) {
+		tokens.IDToken, err = o.jwt.CreateIDToken(user, client.ID, code.Nonce, code.Scopes)
+		if err != nil {
+			return OAuthTokens{}, fmt.Errorf("oauthService.exchangeCode: %w", err)
+		}
+	}
+	return tokens, nil
+}
+
+// refresh rotate the refresh token of a session granted to the client
+func (o *oauthService) refresh(ctx context.Context, client model.OAuthClient, req TokenRequest, clientInfo ClientInfo) (OAuthTokens, error) {
+	res, err := o.authService.RefreshClientSession(ctx, client.ID, req.RefreshToken, clientInfo)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidToken) || errors.Is(err, apperrors.ErrSessionNotFound) {
+			return OAuthTokens{}, fmt.Errorf("oauthService.refresh: %w", apperrors.ErrInvalidGrant)
+		}
+		return OAuthTokens{}, fmt.Errorf("oauthService.refresh: %w", err)
+	}
+	return OAuthTokens{AuthenticationResponse: res}, nil
+}
+
+// issueServiceToken issue a token identifying the client itself, only service scopes can be requested
+func (o *oauthService) issueServiceToken(client model.OAuthClient, req TokenRequest) (OAuthTokens, error) {
+	if client.IsPublic() {
+		return OAuthTokens{}, fmt.Errorf("oauthService.issueServiceToken: %w", apperrors.ErrUnauthorizedClient)
+	}
+	allowed := slices.DeleteFunc(strings.Fields(client.Scopes), func(scope string) bool {
+		return !model.IsServiceScope(scope)
+	})
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return OAuthTokens{}, fmt.Errorf("oauthService.issueServiceToken: %w", apperrors.ErrInvalidScopes)
+		}
+	}
+	if len(scopes) == 0 {
+		return OAuthTokens{}, fmt.Errorf("oauthService.issueServiceToken: %w", apperrors.ErrUnauthorizedClient)
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	token, err := o.jwt.CreateServiceToken(client.ID, scopes, o.cfg.ServiceTokenTTL)
+	if err != nil {
+		return OAuthTokens{}, fmt.Errorf("oauthService.issueServiceToken: %w", err)
+	}
+	return OAuthTokens{
+		AuthenticationResponse: AuthenticationResponse{
+			AccessToken:    token.Token,
+			AccessTokenTTL: token.TTL,
+		},
+		Scopes: scopes,
+	}, nil
+}
+
+func (o *oauthService) Introspect(ctx context.Context, clientID string, clientSecret string, token string) (Introspection, error) {
+	client, err := o.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return Introspection{}, fmt.Errorf("oauthService.Introspect: %w", err)
+	}
+	if client.IsPublic() {
+		return Introspection{}, fmt.Errorf("oauthService.Introspect: %w", apperrors.ErrUnauthorizedClient)
+	}
+	var introspection Introspection
+	switch {
+	case strings.HasPrefix(token, model.PersonalAccessTokenPrefix):
+		introspection, err = o.introspectPersonalAccessToken(ctx, token)
+	default:
+		introspection, err = o.introspectJWT(ctx, token)
+	}
+	if err != nil {
+		return Introspection{}, fmt.Errorf("oauthService.Introspect: %w", err)
+	}
+	return introspection, nil
+}
+
+// isInactiveToken report whether err only means the token can not be used anymore
+func (*oauthService) isInactiveToken(err error) bool {
+	return errors.Is(err, apperrors.ErrInvalidToken) ||
+		errors.Is(err, apperrors.ErrTokenRevoked) ||
+		errors.Is(err, apperrors.ErrUserNotFound) ||
+		errors.Is(err, apperrors.ErrUserSuspended) ||
+		errors.Is(err, apperrors.ErrUserBanned) ||
+		errors.Is(err, apperrors.ErrOAuthClientNotFound)
+}
+
+func (o *oauthService) introspectPersonalAccessToken(ctx context.Context, token string) (Introspection, error) {
+	user, scopes, err := o.patService.Authenticate(ctx, token)
+	if err != nil {
+		if o.isInactiveToken(err) {
+			return Introspection{}, nil
+		}
+		return Introspection{}, fmt.Errorf("oauthService.introspectPersonalAccessToken: %w", err)
+	}
+	return Introspection{
+		Active:    true,
+		Scopes:    scopes,
+		Subject:   user.ID,
+		TokenType: jwt.TokenTypePersonalAccess,
+	}, nil
+}
+
+// introspectJWT check an access token the same way the platform does, or a service token against its client
+func (o *oauthService) introspectJWT(ctx context.Context, token string) (Introspection, error) {
+	tokenType := jwt.TokenTypeAccess
+	claims, err := o.jwt.VerifyToken(token, jwt.TokenTypeAccess)
+	if err == nil {
+		err = o.revocationService.CheckAccessToken(ctx, claims)
+	} else {
+		tokenType = jwt.TokenTypeService
+		claims, err = o.jwt.VerifyToken(token, jwt.TokenTypeService)
+		if err == nil {
+			clientID, _ := claims["client_id"].(string)
+			// introspection reports the service tokens of a deleted client as inactive at once, but channel-service and
+			// chat-service verify them locally with the JWKS and keep accepting them until they expire, at most ServiceTokenTTL
+			_, err = o.oauthClientRepo.GetClient(ctx, clientID)
+		}
+	}
+	if err != nil {
+		if o.isInactiveToken(err) {
+			return Introspection{}, nil
+		}
+		return Introspection{}, fmt.Errorf("oauthService.introspectJWT: %w", err)
+	}
+	scope, _ := claims["scope"].(string)
+	clientID, _ := claims["client_id"].(string)
+	subject, _ := claims["user_id"].(string)
+	if tokenType == jwt.TokenTypeService {
+		subject = clientID
+	}
+	jti, _ := claims["jti"].(string)
+	iat, _ := claims["iat"].(float64)
+	exp, _ := claims["exp"].(float64)
+	return Introspection{
+		Active:    true,
+		Scopes:    strings.Fields(scope),
+		ClientID:  clientID,
+		Subject:   subject,
+		TokenType: tokenType,
+		JTI:       jti,
+		IssuedAt:  int64(iat),
+		ExpiresAt: int64(exp),
+	}, nil
+}
+
+func (o *oauthService) UserInfo(ctx context.Context, userID string) (model.User, error) {
+	user, err := o.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("oauthService.UserInfo: %w", err)
+	}
+	return user, nil
+}
+
+func (o *oauthService) GetUserConsents(ctx context.Context, userID string) ([]model.OAuthConsent, error) {
+	consents, err := o.oauthClientRepo.GetUserConsents(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("oauthService.GetUserConsents: %w", err)
+	}
+	return consents, nil
+}
+
+func (o *oauthService) RevokeConsent(ctx context.Context, userID string, clientID string) error {
+	err := o.oauthClientRepo.DeleteConsent(ctx, userID, clientID)
+	if err != nil {
+		return fmt.Errorf("oauthService.RevokeConsent: %w", err)
+	}
+	sessions, err := o.authService.GetSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("oauthService.RevokeConsent: %w", err)
+	}
+	for _, session := range sessions {
+		if session.ClientID != clientID {
+			continue
+		}
+		err = o.authService.RevokeSession(ctx, userID, session.ID)
+		if err != nil && !errors.Is(err, apperrors.ErrSessionNotFound) {
+			return fmt.Errorf("oauthService.RevokeConsent: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *oauthService) Metadata() ProviderMetadata {
+	issuer := strings.TrimSuffix(o.cfg.Issuer, "/")
+	return ProviderMetadata{
+		Issuer:                issuer,
+		AuthorizationEndpoint: issuer + "/oauth/authorize",
+		TokenEndpoint:         issuer + "/oauth/token",
+		UserInfoEndpoint:      issuer + "/oauth/userinfo",
+		IntrospectionEndpoint: issuer + "/oauth/introspect",
+		JWKSURI:               issuer + "/.well-known/jwks.json",
+		ScopesSupported: []string{
+			model.ScopeOpenID, model.ScopeProfile, model.ScopeEmail,
+			model.ScopeUsersRead, model.ScopeUsersWrite,
+			model.ScopeChatThreadsWrite,
+		},
+		ResponseTypesSupported:            []string{responseTypeCode},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{o.cfg.SigningAlgorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "email", "email_verified", "name", "given_name", "family_name", "preferred_username"},
+	}
+}
+
+func NewOAuthService(userService UserService, authService AuthService, revocationService RevocationService, patService PersonalAccessTokenService, jwt jwt.Utils, oauthClientRepo repository.OAuthClientRepository, oauthCodeRepo repository.OAuthCodeRepository, cfg OAuthConfig) OAuthService {
+	return &oauthService{
+		userService:       userService,
+		authService:       authService,
+		revocationService: revocationService,
+		patService:        patService,
+		jwt:               jwt,
+		oauthClientRepo:   oauthClientRepo,
+		oauthCodeRepo:     oauthCodeRepo,
+		cfg:               cfg,
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/oidc"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type OIDCConfig struct {
+	// ProviderName is stored along the linked identities
+	ProviderName string
+	StateTTL     time.Duration
+}
+
+type OIDCService interface {
+	// AuthorizationURL start a login and return the provider URL the browser has to be redirected to
+	AuthorizationURL(ctx context.Context, deviceName string) (string, error)
+	// Callback finish the login started with AuthorizationURL. The provider identity is linked to the user
+	// with the same verified email, or to a new user, then tokens are issued like Login does
+	Callback(ctx context.Context, code string, state string, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error)
+	// LinkAuthorizationURL start linking a provider identity to the signed in user
+	LinkAuthorizationURL(ctx context.Context, userID string) (string, error)
+	// CompleteLink finish the linking started with LinkAuthorizationURL,
+	// apperrors.ErrIdentityAlreadyLinked is returned if the identity belongs to another user
+	CompleteLink(ctx context.Context, userID string, code string, state string) (model.Identity, error)
+}
+
+type oidcService struct {
+	authService   AuthService
+	userService   UserService
+	inviteService InviteService
+	provider      *oidc.Provider
+	identityRepo  repository.IdentityRepository
+	stateRepo     repository.OIDCStateRepository
+	cfg           OIDCConfig
+}
+
+func (o *oidcService) AuthorizationURL(ctx context.Context, deviceName string) (string, error) {
+	authURL, err := o.startFlow(ctx, model.OIDCLoginState{DeviceName: deviceName})
+	if err != nil {
+		return "", fmt.Errorf("oidcService.AuthorizationURL: %w", err)
+	}
+	return authURL, nil
+}
+
+func (o *oidcService) LinkAuthorizationURL(ctx context.Context, userID string) (string, error) {
+	authURL, err := o.startFlow(ctx, model.OIDCLoginState{LinkUserID: userID})
+	if err != nil {
+		return "", fmt.Errorf("oidcService.LinkAuthorizationURL: %w", err)
+	}
+	return authURL, nil
+}
+
+// startFlow generate the state, nonce and PKCE verifier of a new authorization request and save them
+func (o *oidcService) startFlow(ctx context.Context, loginState model.OIDCLoginState) (string, error) {
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", fmt.Errorf("oidcService.startFlow: %w", err)
+	}
+	loginState.Nonce, err = oidc.NewState()
+	if err != nil {
+		return "", fmt.Errorf("oidcService.startFlow: %w", err)
+	}
+	loginState.CodeVerifier, err = oidc.NewCodeVerifier()
+	if err != nil {
+		return "", fmt.Errorf("oidcService.startFlow: %w", err)
+	}
+	authURL, err := o.provider.AuthCodeURL(ctx, state, loginState.Nonce, oidc.CodeChallengeS256(loginState.CodeVerifier))
+	if err != nil {
+		return "", fmt.Errorf("oidcService.startFlow: %w", err)
+	}
+	err = o.stateRepo.SaveState(ctx, state, loginState, o.cfg.StateTTL)
+	if err != nil {
+		return "", fmt.Errorf("oidcService.startFlow: %w", err)
+	}
+	return authURL, nil
+}
+
+// finishFlow redeem the authorization code of the flow started with state and verify the returned ID token
+func (o *oidcService) finishFlow(ctx context.Context, code string, state string) (model.OIDCLoginState, oidc.IDTokenClaims, error) {
+	loginState, err := o.stateRepo.ConsumeState(ctx, state)
+	if err != nil {
+		return model.OIDCLoginState{}, oidc.IDTokenClaims{}, fmt.Errorf("oidcService.finishFlow: %w", err)
+	}
+	token, err := o.provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		return model.OIDCLoginState{}, oidc.IDTokenClaims{}, fmt.Errorf("oidcService.finishFlow: %w", err)
+	}
+	claims, err := o.provider.VerifyIDToken(ctx, token.IDToken, loginState.Nonce)
+	if err != nil {
+		return model.OIDCLoginState{}, oidc.IDTokenClaims{}, fmt.Errorf("oidcService.finishFlow: %w", err)
+	}
+	return loginState, claims, nil
+}
+
+func (o *oidcService) CompleteLink(ctx context.Context, userID string, code string, state string) (model.Identity, error) {
+	loginState, claims, err := o.finishFlow(ctx, code, state)
+	if err != nil {
+		return model.Identity{}, fmt.Errorf("oidcService.CompleteLink: %w", err)
+	}
+	if loginState.LinkUserID != userID {
+		return model.Identity{}, fmt.Errorf("oidcService.CompleteLink: %w", apperrors.ErrInvalidOIDCState)
+	}
+	identity, err := o.identityRepo.GetIdentity(ctx, o.cfg.ProviderName, claims.Subject)
+	if err == nil {
+		if identity.UserID != userID {
+			return model.Identity{}, fmt.Errorf("oidcService.CompleteLink: %w", apperrors.ErrIdentityAlreadyLinked)
+		}
+		return identity, nil
+	}
+	if !errors.Is(err, apperrors.ErrIdentityNotFound) {
+		return model.Identity{}, fmt.Errorf("oidcService.CompleteLink: %w", err)
+	}
+	identity, err = o.identityRepo.CreateIdentity(ctx, model.Identity{
+		UserID:   userID,
+		Provider: o.cfg.ProviderName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return model.Identity{}, fmt.Errorf("oidcService.CompleteLink: %w", err)
+	}
+	return identity, nil
+}
+
+func (o *oidcService) Callback(ctx context.Context, code string, state string, client ClientInfo) (AuthenticationResponse, *MFAChallenge, error) {
+	loginState, claims, err := o.finishFlow(ctx, code, state)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("oidcService.Callback: %w", err)
+	}
+	if loginState.LinkUserID != "" {
+		return AuthenticationResponse{}, nil, fmt.Errorf("oidcService.Callback: %w", apperrors.ErrInvalidOIDCState)
+	}
+	user, err := o.resolveUser(ctx, claims)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("oidcService.Callback: %w", err)
+	}
+	if client.DeviceName == "" {
+		client.DeviceName = loginState.DeviceName
+	}
+	res, challenge, err := o.authService.LoginWithUser(ctx, user, client)
+	if err != nil {
+		return AuthenticationResponse{}, nil, fmt.Errorf("oidcService.Callback: %w", err)
+	}
+	return res, challenge, nil
+}
+
+// resolveUser return the user the provider identity is linked to, linking it first if needed
+func (o *oidcService) resolveUser(ctx context.Context, claims oidc.IDTokenClaims) (model.User, error) {
+	identity, err := o.identityRepo.GetIdentity(ctx, o.cfg.ProviderName, claims.Subject)
+	if err == nil {
+		user, err := o.userService.GetUserById(ctx, identity.UserID)
+		if err != nil {
+			return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+		}
+		err = o.identityRepo.TouchIdentity(ctx, identity.ID, time.Now())
+		if err != nil {
+			return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, apperrors.ErrIdentityNotFound) {
+		return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+	}
+	// an unverified email could belong to anyone, it is never used to find or create an account
+	if claims.Email == "" || !claims.EmailVerified {
+		return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", apperrors.ErrOIDCEmailNotVerified)
+	}
+	user, err := o.userService.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !user.EmailVerified {
+			return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", apperrors.ErrOIDCAccountConflict)
+		}
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		// there is no invite code in this flow, accounts can only be created while the registration is open
+		_, err = o.inviteService.Admit(ctx, "")
+		if err != nil {
+			return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+		}
+		firstName, lastName := claims.GivenName, claims.FamilyName
+		if firstName == "" && lastName == "" {
+			firstName, lastName, _ = strings.Cut(claims.Name, " ")
+		}
+		user, err = o.userService.CreateExternalUser(ctx, model.User{
+			Email:         claims.Email,
+			FirstName:     firstName,
+			LastName:      lastName,
+			EmailVerified: true,
+		})
+		if err != nil {
+			return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+		}
+	default:
+		return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+	}
+	now := time.Now()
+	_, err = o.identityRepo.CreateIdentity(ctx, model.Identity{
+		UserID:     user.ID,
+		Provider:   o.cfg.ProviderName,
+		Subject:    claims.Subject,
+		Email:      claims.Email,
+		LastUsedAt: &now,
+	})
+	if err != nil {
+		return model.User{}, fmt.Errorf("oidcService.resolveUser: %w", err)
+	}
+	return user, nil
+}
+
+func NewOIDCService(authService AuthService, userService UserService, inviteService InviteService, provider *oidc.Provider, identityRepo repository.IdentityRepository, stateRepo repository.OIDCStateRepository, cfg OIDCConfig) OIDCService {
+	return &oidcService{
+		authService:   authService,
+		userService:   userService,
+		inviteService: inviteService,
+		provider:      provider,
+		identityRepo:  identityRepo,
+		stateRepo:     stateRepo,
+		cfg:           cfg,
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"auth-service/internal/webauthn"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasskeyConfig struct {
+	ChallengeTTL time.Duration
+}
+
+type PasskeyService interface {
+	// BeginRegistration return the options the browser needs to create a new passkey for the user
+	BeginRegistration(ctx context.Context, userID string) (webauthn.CreationOptions, error)
+	// FinishRegistration verify the credential created from the options of BeginRegistration and store it
+	FinishRegistration(ctx context.Context, userID string, name string, credential webauthn.AttestationResponse) (model.Passkey, error)
+	GetPasskeys(ctx context.Context, userID string) ([]model.Passkey, error)
+	HasPasskeys(ctx context.Context, userID string) (bool, error)
+	// DeletePasskey return apperrors.ErrLastLoginMethod if the user could not sign in anymore without it
+	DeletePasskey(ctx context.Context, userID string, id string) error
+	// BeginLogin return the id of the ceremony and the options to sign in with any passkey stored on the authenticator
+	BeginLogin(ctx context.Context) (string, webauthn.RequestOptions, error)
+	// FinishLogin return the owner of the passkey. The authenticator must have verified the user with a PIN
+	// or biometrics, so the passkey alone is enough to sign in
+	FinishLogin(ctx context.Context, challengeID string, credential webauthn.AssertionResponse) (model.User, error)
+	// BeginVerification return the options to prove the user holds one of their passkeys,
+	// key identifies the ceremony and must be given back to Verify
+	BeginVerification(ctx context.Context, userID string, key string) (webauthn.RequestOptions, error)
+	// Verify check the credential returned for the options of BeginVerification, it is used as a second factor
+	Verify(ctx context.Context, userID string, key string, credential webauthn.AssertionResponse) error
+}
+
+type passkeyService struct {
+	userService     UserService
+	identityService IdentityService
+	passkeyRepo     repository.PasskeyRepository
+	challengeRepo   repository.PasskeyChallengeRepository
+	rp              *webauthn.RelyingParty
+	cfg             PasskeyConfig
+}
+
+func (*passkeyService) registrationKey(userID string) string {
+	return "registration:" + userID
+}
+
+func (*passkeyService) loginKey(challengeID string) string {
+	return "login:" + challengeID
+}
+
+func (*passkeyService) verificationKey(key string) string {
+	return "verification:" + key
+}
+
+// descriptors list the passkeys of the user for the allow and exclude lists of the options
+func (p *passkeyService) descriptors(ctx context.Context, userID string) ([]webauthn.CredentialDescriptor, error) {
+	passkeys, err := p.passkeyRepo.GetUserPasskeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.descriptors: %w", err)
+	}
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		var transports []string
+		if passkey.Transports != "" {
+			transports = strings.Split(passkey.Transports, ",")
+		}
+		descriptors = append(descriptors, webauthn.NewCredentialDescriptor(passkey.CredentialID, transports))
+	}
+	return descriptors, nil
+}
+
+func (p *passkeyService) BeginRegistration(ctx context.Context, userID string) (webauthn.CreationOptions, error) {
+	user, err := p.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return webauthn.CreationOptions{}, fmt.Errorf("passkeyService.BeginRegistration: %w", err)
+	}
+	exclude, err := p.descriptors(ctx, userID)
+	if err != nil {
+		return webauthn.CreationOptions{}, fmt.Errorf("passkeyService.BeginRegistration: %w", err)
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.CreationOptions{}, fmt.Errorf("passkeyService.BeginRegistration: %w", err)
+	}
+	err = p.challengeRepo.SaveChallenge(ctx, p.registrationKey(userID), model.PasskeyChallenge{
+		Challenge: challenge,
+		UserID:    userID,
+	}, p.cfg.ChallengeTTL)
+	if err != nil {
+		return webauthn.CreationOptions{}, fmt.Errorf("passkeyService.BeginRegistration: %w", err)
+	}
+	displayName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if displayName == "" {
+		displayName = user.Email
+	}
+	// the user handle is the user id, FinishLogin uses it to check the credential belongs to its owner
+	return p.rp.CreationOptions(challenge, webauthn.User{
+		ID:          []byte(user.ID),
+		Name:        user.Email,
+		DisplayName: displayName,
+	}, exclude), nil
+}
+
+func (p *passkeyService) FinishRegistration(ctx context.Context, userID string, name string, credential webauthn.AttestationResponse) (model.Passkey, error) {
+	challenge, err := p.challengeRepo.ConsumeChallenge(ctx, p.registrationKey(userID))
+	if err != nil {
+		return model.Passkey{}, fmt.Errorf("passkeyService.FinishRegistration: %w", err)
+	}
+	verified, err := p.rp.VerifyRegistration(challenge.Challenge, credential)
+	if err != nil {
+		return model.Passkey{}, fmt.Errorf("passkeyService.FinishRegistration: %w: %w", apperrors.ErrInvalidPasskey, err)
+	}
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey, err := p.passkeyRepo.CreatePasskey(ctx, model.Passkey{
+		UserID:       userID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(verified.ID),
+		PublicKey:    verified.PublicKey,
+		SignCount:    int64(verified.SignCount),
+		Transports:   strings.Join(verified.Transports, ","),
+		Name:         name,
+	})
+	if err != nil {
+		return model.Passkey{}, fmt.Errorf("passkeyService.FinishRegistration: %w", err)
+	}
+	return passkey, nil
+}
+
+func (p *passkeyService) GetPasskeys(ctx context.Context, userID string) ([]model.Passkey, error) {
+	passkeys, err := p.passkeyRepo.GetUserPasskeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("passkeyService.GetPasskeys: %w", err)
+	}
+	return passkeys, nil
+}
+
+func (p *passkeyService) HasPasskeys(ctx context.Context, userID string) (bool, error) {
+	count, err := p.passkeyRepo.CountUserPasskeys(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("passkeyService.HasPasskeys: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (p *passkeyService) DeletePasskey(ctx context.Context, userID string, id string) error {
+	methods, err := p.identityService.GetLoginMethods(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("passkeyService.DeletePasskey: %w", err)
+	}
+	if methods.count() <= 1 && methods.Passkeys == 1 {
+		return fmt.Errorf("passkeyService.DeletePasskey: %w", apperrors.ErrLastLoginMethod)
+	}
+	err = p.passkeyRepo.DeleteUserPasskey(ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("passkeyService.DeletePasskey: %w", err)
+	}
+	return nil
+}
+
+func (p *passkeyService) BeginLogin(ctx context.Context) (string, webauthn.RequestOptions, error) {
+	challengeID, err := uuid.NewRandom()
+	if err != nil {
+		return "", webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginLogin: %w", err)
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginLogin: %w", err)
+	}
+	err = p.challengeRepo.SaveChallenge(ctx, p.loginKey(challengeID.String()), model.PasskeyChallenge{
+		Challenge: challenge,
+	}, p.cfg.ChallengeTTL)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginLogin: %w", err)
+	}
+	return challengeID.String(), p.rp.RequestOptions(challenge, nil, webauthn.UserVerificationRequired), nil
+}
+
+func (p *passkeyService) FinishLogin(ctx context.Context, challengeID string, credential webauthn.AssertionResponse) (model.User, error) {
+	challenge, err := p.challengeRepo.ConsumeChallenge(ctx, p.loginKey(challengeID))
+	if err != nil {
+		return model.User{}, fmt.Errorf("passkeyService.FinishLogin: %w", err)
+	}
+	passkey, assertion, err := p.verifyAssertion(ctx, challenge.Challenge, credential)
+	if err != nil {
+		return model.User{}, fmt.Errorf("passkeyService.FinishLogin: %w", err)
+	}
+	if !assertion.UserVerified {
+		return model.User{}, fmt.Errorf("passkeyService.FinishLogin: %w: user not verified", apperrors.ErrInvalidPasskey)
+	}
+	if len(assertion.UserHandle) > 0 && string(assertion.UserHandle) != passkey.UserID {
+		return model.User{}, fmt.Errorf("passkeyService.FinishLogin: %w: user handle mismatch", apperrors.ErrInvalidPasskey)
+	}
+	user, err := p.userService.GetUserById(ctx, passkey.UserID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("passkeyService.FinishLogin: %w", err)
+	}
+	return user, nil
+}
+
+func (p *passkeyService) BeginVerification(ctx context.Context, userID string, key string) (webauthn.RequestOptions, error) {
+	allow, err := p.descriptors(ctx, userID)
+	if err != nil {
+		return webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginVerification: %w", err)
+	}
+	if len(allow) == 0 {
+		return webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginVerification: %w", apperrors.ErrPasskeyNotFound)
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginVerification: %w", err)
+	}
+	err = p.challengeRepo.SaveChallenge(ctx, p.verificationKey(key), model.PasskeyChallenge{
+		Challenge: challenge,
+		UserID:    userID,
+	}, p.cfg.ChallengeTTL)
+	if err != nil {
+		return webauthn.RequestOptions{}, fmt.Errorf("passkeyService.BeginVerification: %w", err)
+	}
+	return p.rp.RequestOptions(challenge, allow, webauthn.UserVerificationDiscouraged), nil
+}
+
+func (p *passkeyService) Verify(ctx context.Context, userID string, key string, credential webauthn.AssertionResponse) error {
+	challenge, err := p.challengeRepo.ConsumeChallenge(ctx, p.verificationKey(key))
+	if err != nil {
+		return fmt.Errorf("passkeyService.Verify: %w", err)
+	}
+	if challenge.UserID != userID {
+		return fmt.Errorf("passkeyService.Verify: %w", apperrors.ErrInvalidPasskeyChallenge)
+	}
+	passkey, _, err := p.verifyAssertion(ctx, challenge.Challenge, credential)
+	if err != nil {
+		return fmt.Errorf("passkeyService.Verify: %w", err)
+	}
+	if passkey.UserID != userID {
+		return fmt.Errorf("passkeyService.Verify: %w: credential of another user", apperrors.ErrInvalidPasskey)
+	}
+	return nil
+}
+
+// verifyAssertion check the signature of the stored passkey the credential was created with and update its counter
+func (p *passkeyService) verifyAssertion(ctx context.Context, challenge string, credential webauthn.AssertionResponse) (model.Passkey, webauthn.Assertion, error) {
+	passkey, err := p.passkeyRepo.GetPasskeyByCredentialID(ctx, credential.ID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrPasskeyNotFound) {
+			return model.Passkey{}, webauthn.Assertion{}, fmt.Errorf("passkeyService.verifyAssertion: %w: %w", apperrors.ErrInvalidPasskey, err)
+		}
+		return model.Passkey{}, webauthn.Assertion{}, fmt.Errorf("passkeyService.verifyAssertion: %w", err)
+	}
+	assertion, err := p.rp.VerifyAssertion(challenge, credential, passkey.PublicKey, uint32(passkey.SignCount))
+	if err != nil {
+		return model.Passkey{}, webauthn.Assertion{}, fmt.Errorf("passkeyService.verifyAssertion: %w: %w", apperrors.ErrInvalidPasskey, err)
+	}
+	err = p.passkeyRepo.UpdateSignCount(ctx, passkey.ID, int64(assertion.SignCount), time.Now())
+	if err != nil {
+		return model.Passkey{}, webauthn.Assertion{}, fmt.Errorf("passkeyService.verifyAssertion: %w", err)
+	}
+	return passkey, assertion, nil
+}
+
+func NewPasskeyService(userService UserService, identityService IdentityService, passkeyRepo repository.PasskeyRepository, challengeRepo repository.PasskeyChallengeRepository, rp *webauthn.RelyingParty, cfg PasskeyConfig) PasskeyService {
+	return &passkeyService{
+		userService:     userService,
+		identityService: identityService,
+		passkeyRepo:     passkeyRepo,
+		challengeRepo:   challengeRepo,
+		rp:              rp,
+		cfg:             cfg,
+	}
+}
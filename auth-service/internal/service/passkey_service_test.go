@@ -0,0 +1,277 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"auth-service/internal/webauthn"
+	"auth-service/internal/webauthn/webauthntest"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// fakeUserService only implement the methods the passkey service uses
+type fakeUserService struct {
+	UserService
+	users map[string]model.User
+}
+
+func (f *fakeUserService) GetUserById(_ context.Context, id string) (model.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return model.User{}, apperrors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakePasskeyRepository struct {
+	repository.PasskeyRepository
+	passkeys []model.Passkey
+}
+
+func (f *fakePasskeyRepository) CreatePasskey(_ context.Context, passkey model.Passkey) (model.Passkey, error) {
+	for _, existing := range f.passkeys {
+		if existing.CredentialID == passkey.CredentialID {
+			return model.Passkey{}, apperrors.ErrPasskeyAlreadyExists
+		}
+	}
+	passkey.ID = fmt.Sprintf("passkey-%d", len(f.passkeys)+1)
+	f.passkeys = append(f.passkeys, passkey)
+	return passkey, nil
+}
+
+func (f *fakePasskeyRepository) GetPasskeyByCredentialID(_ context.Context, credentialID string) (model.Passkey, error) {
+	for _, passkey := range f.passkeys {
+		if passkey.CredentialID == credentialID {
+			return passkey, nil
+		}
+	}
+	return model.Passkey{}, apperrors.ErrPasskeyNotFound
+}
+
+func (f *fakePasskeyRepository) GetUserPasskeys(_ context.Context, userID string) ([]model.Passkey, error) {
+	var passkeys []model.Passkey
+	for _, passkey := range f.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (f *fakePasskeyRepository) UpdateSignCount(_ context.Context, id string, signCount int64, usedAt time.Time) error {
+	for i := range f.passkeys {
+		if f.passkeys[i].ID == id {
+			f.passkeys[i].SignCount = signCount
+			f.passkeys[i].LastUsedAt = &usedAt
+			return nil
+		}
+	}
+	return apperrors.ErrPasskeyNotFound
+}
+
+type fakePasskeyChallengeRepository struct {
+	challenges map[string]model.PasskeyChallenge
+}
+
+func (f *fakePasskeyChallengeRepository) SaveChallenge(_ context.Context, key string, challenge model.PasskeyChallenge, _ time.Duration) error {
+	f.challenges[key] = challenge
+	return nil
+}
+
+func (f *fakePasskeyChallengeRepository) ConsumeChallenge(_ context.Context, key string) (model.PasskeyChallenge, error) {
+	challenge, ok := f.challenges[key]
+	if !ok {
+		return model.PasskeyChallenge{}, apperrors.ErrInvalidPasskeyChallenge
+	}
+	delete(f.challenges, key)
+	return challenge, nil
+}
+
+// newTestPasskeyService return the service with the users alice and bob, and alice's authenticator registered
+func newTestPasskeyService(t *testing.T) (PasskeyService, *fakePasskeyRepository, *webauthntest.Authenticator) {
+	t.Helper()
+	passkeyRepo := &fakePasskeyRepository{}
+	p := NewPasskeyService(
+		&fakeUserService{users: map[string]model.User{
+			"alice": {ID: "alice", Email: "alice@example.com"},
+			"bob":   {ID: "bob", Email: "bob@example.com"},
+		}},
+		nil,
+		passkeyRepo,
+		&fakePasskeyChallengeRepository{challenges: map[string]model.PasskeyChallenge{}},
+		webauthn.NewRelyingParty(webauthn.Config{RPID: testRPID, RPName: "Example", Origins: []string{testOrigin}}),
+		PasskeyConfig{ChallengeTTL: time.Minute},
+	)
+	a, err := webauthntest.NewES256(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	options, err := p.BeginRegistration(ctx, "alice")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	a.UserHandle = []byte("alice")
+	a.SignCount = 1
+	passkey, err := p.FinishRegistration(ctx, "alice", "", a.Create(options.Challenge))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	if passkey.UserID != "alice" || passkey.Name != "Passkey" || passkey.SignCount != 1 {
+		t.Fatalf("FinishRegistration() = %+v", passkey)
+	}
+	return p, passkeyRepo, a
+}
+
+func TestPasskeyServiceFinishRegistration(t *testing.T) {
+	p, _, a := newTestPasskeyService(t)
+	ctx := context.Background()
+
+	// the registration challenge can only be used once
+	options, err := p.BeginRegistration(ctx, "bob")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	if len(options.ExcludeCredentials) != 0 {
+		t.Errorf("BeginRegistration() excluded %d credentials, want 0", len(options.ExcludeCredentials))
+	}
+	_, err = p.FinishRegistration(ctx, "bob", "", a.Create("YW5vdGhlciBjaGFsbGVuZ2U"))
+	if !errors.Is(err, apperrors.ErrInvalidPasskey) {
+		t.Errorf("FinishRegistration() with another challenge error = %v, want %v", err, apperrors.ErrInvalidPasskey)
+	}
+	_, err = p.FinishRegistration(ctx, "bob", "", a.Create(options.Challenge))
+	if !errors.Is(err, apperrors.ErrInvalidPasskeyChallenge) {
+		t.Errorf("FinishRegistration() with a consumed challenge error = %v, want %v", err, apperrors.ErrInvalidPasskeyChallenge)
+	}
+
+	// the credential of alice can not be registered again
+	options, err = p.BeginRegistration(ctx, "alice")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	if len(options.ExcludeCredentials) != 1 {
+		t.Errorf("BeginRegistration() excluded %d credentials, want 1", len(options.ExcludeCredentials))
+	}
+	_, err = p.FinishRegistration(ctx, "alice", "", a.Create(options.Challenge))
+	if !errors.Is(err, apperrors.ErrPasskeyAlreadyExists) {
+		t.Errorf("FinishRegistration() of the same credential error = %v, want %v", err, apperrors.ErrPasskeyAlreadyExists)
+	}
+}
+
+func TestPasskeyServiceFinishLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(a *webauthntest.Authenticator)
+		wantErr error
+	}{
+		{name: "user verified", prepare: func(a *webauthntest.Authenticator) { a.UserVerified = true; a.SignCount = 2 }},
+		{name: "user not verified", prepare: func(a *webauthntest.Authenticator) { a.SignCount = 2 }, wantErr: apperrors.ErrInvalidPasskey},
+		{name: "user not present", prepare: func(a *webauthntest.Authenticator) {
+			a.UserVerified = true
+			a.UserPresent = false
+			a.SignCount = 2
+		}, wantErr: apperrors.ErrInvalidPasskey},
+		{name: "counter went backwards", prepare: func(a *webauthntest.Authenticator) { a.UserVerified = true; a.SignCount = 0 }, wantErr: apperrors.ErrInvalidPasskey},
+		{name: "user handle of another user", prepare: func(a *webauthntest.Authenticator) {
+			a.UserVerified = true
+			a.SignCount = 2
+			a.UserHandle = []byte("bob")
+		}, wantErr: apperrors.ErrInvalidPasskey},
+		{name: "unknown credential", prepare: func(a *webauthntest.Authenticator) {
+			a.UserVerified = true
+			a.SignCount = 2
+			a.CredentialID = []byte("unknown")
+		}, wantErr: apperrors.ErrInvalidPasskey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, passkeyRepo, a := newTestPasskeyService(t)
+			ctx := context.Background()
+			challengeID, options, err := p.BeginLogin(ctx)
+			if err != nil {
+				t.Fatalf("BeginLogin() error = %v", err)
+			}
+			if options.UserVerification != webauthn.UserVerificationRequired {
+				t.Errorf("BeginLogin() user verification = %q, want %q", options.UserVerification, webauthn.UserVerificationRequired)
+			}
+			tt.prepare(a)
+			response, err := a.Get(options.Challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, err := p.FinishLogin(ctx, challengeID, response)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FinishLogin() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FinishLogin() error = %v", err)
+			}
+			if user.ID != "alice" {
+				t.Errorf("FinishLogin() user = %q, want %q", user.ID, "alice")
+			}
+			if passkeyRepo.passkeys[0].SignCount != int64(a.SignCount) || passkeyRepo.passkeys[0].LastUsedAt == nil {
+				t.Errorf("FinishLogin() stored counter %d, want %d", passkeyRepo.passkeys[0].SignCount, a.SignCount)
+			}
+			// the challenge can only be used once
+			_, err = p.FinishLogin(ctx, challengeID, response)
+			if !errors.Is(err, apperrors.ErrInvalidPasskeyChallenge) {
+				t.Errorf("FinishLogin() replayed error = %v, want %v", err, apperrors.ErrInvalidPasskeyChallenge)
+			}
+		})
+	}
+}
+
+func TestPasskeyServiceVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		// userID is the user signing in, the ceremony is begun for alice
+		userID  string
+		prepare func(a *webauthntest.Authenticator)
+		wantErr error
+	}{
+		// as a second factor the password was checked already, user verification is not required
+		{name: "user not verified", userID: "alice", prepare: func(a *webauthntest.Authenticator) { a.SignCount = 2 }},
+		{name: "another user", userID: "bob", prepare: func(a *webauthntest.Authenticator) { a.SignCount = 2 }, wantErr: apperrors.ErrInvalidPasskeyChallenge},
+		{name: "counter went backwards", userID: "alice", prepare: func(a *webauthntest.Authenticator) { a.SignCount = 1 }, wantErr: apperrors.ErrInvalidPasskey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, a := newTestPasskeyService(t)
+			ctx := context.Background()
+			options, err := p.BeginVerification(ctx, "alice", "mfa-challenge")
+			if err != nil {
+				t.Fatalf("BeginVerification() error = %v", err)
+			}
+			if len(options.AllowCredentials) != 1 {
+				t.Errorf("BeginVerification() allowed %d credentials, want 1", len(options.AllowCredentials))
+			}
+			tt.prepare(a)
+			response, err := a.Get(options.Challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.Verify(ctx, tt.userID, "mfa-challenge", response)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	p, _, _ := newTestPasskeyService(t)
+	_, err := p.BeginVerification(context.Background(), "bob", "mfa-challenge")
+	if !errors.Is(err, apperrors.ErrPasskeyNotFound) {
+		t.Errorf("BeginVerification() without passkey error = %v, want %v", err, apperrors.ErrPasskeyNotFound)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/mailer"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type PasswordResetConfig struct {
+	TokenTTL    time.Duration
+	EmailLimit  int
+	IPLimit     int
+	LimitWindow time.Duration
+	FrontendURL string
+}
+
+// backgroundMailTimeout bound the time spent looking up a user and emailing them a link in the background
+const backgroundMailTimeout = time.Minute
+
+type PasswordResetService interface {
+	// RequestPasswordReset email a reset link to the user. The link is sent in the background and unknown emails
+	// are silently ignored, so neither the response nor its timing tells whether an account exists.
+	// Only rate limiting errors are returned
+	RequestPasswordReset(ctx context.Context, email string, ip string) error
+	// ResetPassword consume the reset token, set the new password and revoke all sessions of the user.
+	// The id of the user is returned
+	ResetPassword(ctx context.Context, token string, newPassword string) (string, error)
+}
+
+type passwordResetService struct {
+	userService UserService
+	resetRepo   repository.PasswordResetRepository
+	rateLimiter RateLimiter
+	mailer      mailer.Mailer
+	logger      *zap.Logger
+	cfg         PasswordResetConfig
+}
+
+func (*passwordResetService) hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *passwordResetService) RequestPasswordReset(ctx context.Context, email string, ip string) error {
+	err := p.rateLimiter.Allow(ctx, "password_reset:ip:"+ip, p.cfg.IPLimit, p.cfg.LimitWindow)
+	if err != nil {
+		return fmt.Errorf("passwordResetService.RequestPasswordReset: %w", err)
+	}
+	err = p.rateLimiter.Allow(ctx, "password_reset:email:"+strings.ToLower(email), p.cfg.EmailLimit, p.cfg.LimitWindow)
+	if err != nil {
+		return fmt.Errorf("passwordResetService.RequestPasswordReset: %w", err)
+	}
+	go p.sendResetLink(email)
+	return nil
+}
+
+// sendResetLink email a reset link to the user owning email if any, failures are logged
+func (p *passwordResetService) sendResetLink(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), backgroundMailTimeout)
+	defer cancel()
+	user, err := p.userService.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, apperrors.ErrUserNotFound) {
+			p.logger.Error("failed to look up user for password reset", zap.Error(err))
+		}
+		return
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		p.logger.Error("failed to generate password reset token", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	err = p.resetRepo.SaveResetToken(ctx, p.hashToken(token), user.ID, p.cfg.TokenTTL)
+	if err != nil {
+		p.logger.Error("failed to save password reset token", zap.String("user_id", user.ID), zap.Error(err))
+		return
+	}
+	link := fmt.Sprintf("%s/reset-password?token=%s", p.cfg.FrontendURL, url.QueryEscape(token))
+	err = p.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open the link below:\n\n%s\n\nThe link expires in %s. If you did not ask for it, you can ignore this email.\n",
+			user.FirstName, link, p.cfg.TokenTTL),
+	})
+	if err != nil {
+		p.logger.Warn("failed to send password reset email", zap.String("user_id", user.ID), zap.Error(err))
+	}
+}
+
+func (p *passwordResetService) ResetPassword(ctx context.Context, token string, newPassword string) (string, error) {
+	tokenHash := p.hashToken(token)
+	userID, err := p.resetRepo.GetResetTokenUser(ctx, tokenHash)
+	if err != nil {
+		return "", fmt.Errorf("passwordResetService.ResetPassword: %w", err)
+	}
+	// a rejected password must not burn the token, the user can retry with the same link
+	err = p.userService.CheckNewPassword(ctx, userID, newPassword)
+	if err != nil {
+		return "", fmt.Errorf("passwordResetService.ResetPassword: %w", err)
+	}
+	userID, err = p.resetRepo.ConsumeResetToken(ctx, tokenHash)
+	if err != nil {
+		return "", fmt.Errorf("passwordResetService.ResetPassword: %w", err)
+	}
+	err = p.userService.ResetPassword(ctx, userID, newPassword)
+	if err != nil {
+		return "", fmt.Errorf("passwordResetService.ResetPassword: %w", err)
+	}
+	// following the emailed link proves the ownership of the address
+	err = p.userService.SetEmailVerified(ctx, userID, true)
+	if err != nil {
+		return "", fmt.Errorf("passwordResetService.ResetPassword: %w", err)
+	}
+	return userID, nil
+}
+
+func NewPasswordResetService(userService UserService, resetRepo repository.PasswordResetRepository, rateLimiter RateLimiter, mailer mailer.Mailer, logger *zap.Logger, cfg PasswordResetConfig) PasswordResetService {
+	return &passwordResetService{
+		userService: userService,
+		resetRepo:   resetRepo,
+		rateLimiter: rateLimiter,
+		mailer:      mailer,
+		logger:      logger,
+		cfg:         cfg,
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type PersonalAccessTokenConfig struct {
+	MaxPerUser int
+	// LastUsedInterval is the precision of the last used time, it avoids a database write on every request
+	LastUsedInterval time.Duration
+}
+
+type PersonalAccessTokenService interface {
+	// CreateToken return the created token and its plain text value, which can not be retrieved later.
+	// The scopes must be granted by the role of the user
+	CreateToken(ctx context.Context, userID string, name string, scopes []string, ttl time.Duration) (model.PersonalAccessToken, string, error)
+	GetUserTokens(ctx context.Context, userID string) ([]model.PersonalAccessToken, error)
+	DeleteUserToken(ctx context.Context, userID string, id string) error
+	// Authenticate return the user the token belongs to and the scopes it currently grants, which are the scopes
+	// of the token still granted by the role of the user. It returns apperrors.ErrInvalidToken if the token is unknown or expired
+	Authenticate(ctx context.Context, token string) (model.User, []string, error)
+}
+
+type personalAccessTokenService struct {
+	userService UserService
+	patRepo     repository.PersonalAccessTokenRepository
+	cfg         PersonalAccessTokenConfig
+}
+
+func (*personalAccessTokenService) hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *personalAccessTokenService) CreateToken(ctx context.Context, userID string, name string, scopes []string, ttl time.Duration) (model.PersonalAccessToken, string, error) {
+	user, err := p.userService.GetUserById(ctx, userID)
+	if err != nil {
+		return model.PersonalAccessToken{}, "", fmt.Errorf("personalAccessTokenService.CreateToken: %w", err)
+	}
+	granted := model.ScopesForRole(user.Role)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return model.PersonalAccessToken{}, "", fmt.Errorf("personalAccessTokenService.CreateToken: %w", apperrors.ErrInvalidScopes)
+		}
+	}
+	count, err := p.patRepo.CountUserTokens(ctx, userID)
+	if err != nil {
+		return model.PersonalAccessToken{}, "", fmt.Errorf("personalAccessTokenService.CreateToken: %w", err)
+	}
+	if count >= p.cfg.MaxPerUser {
+		return model.PersonalAccessToken{}, "", fmt.Errorf("personalAccessTokenService.CreateToken: %w", apperrors.ErrTooManyTokens)
+	}
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return model.PersonalAccessToken{}, "", fmt.Errorf("personalAccessTokenService.CreateToken: %w", err)
+	}
+	plain := model.PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	slices.Sort(scopes)
+	now := time.Now()
+	token, err := p.patRepo.CreateToken(ctx, model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: p.hashToken(plain),
+		Scopes:    strings.Join(slices.Compact(scopes), " "),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return model.PersonalAccessToken{}, "", fmt.Errorf("personalAccessTokenService.CreateToken: %w", err)
+	}
+	return token, plain, nil
+}
+
+func (p *personalAccessTokenService) GetUserTokens(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	tokens, err := p.patRepo.GetUserTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("personalAccessTokenService.GetUserTokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (p *personalAccessTokenService) DeleteUserToken(ctx context.Context, userID string, id string) error {
+	err := p.patRepo.DeleteUserToken(ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("personalAccessTokenService.DeleteUserToken: %w", err)
+	}
+	return nil
+}
+
+func (p *personalAccessTokenService) Authenticate(ctx context.Context, token string) (model.User, []string, error) {
+	if !strings.HasPrefix(token, model.PersonalAccessTokenPrefix) {
+		return model.User{}, nil, fmt.Errorf("personalAccessTokenService.Authenticate: %w", apperrors.ErrInvalidToken)
+	}
+	pat, err := p.patRepo.GetTokenByHash(ctx, p.hashToken(token))
+	if err != nil {
+		return model.User{}, nil, fmt.Errorf("personalAccessTokenService.Authenticate: %w", err)
+	}
+	now := time.Now()
+	if !now.Before(pat.ExpiresAt) {
+		return model.User{}, nil, fmt.Errorf("personalAccessTokenService.Authenticate: %w", apperrors.ErrInvalidToken)
+	}
+	user, err := p.userService.GetUserById(ctx, pat.UserID)
+	if err != nil {
+		return model.User{}, nil, fmt.Errorf("personalAccessTokenService.Authenticate: %w", err)
+	}
+	err = checkUserStatus(user)
+	if err != nil {
+		return model.User{}, nil, fmt.Errorf("personalAccessTokenService.Authenticate: %w", err)
+	}
+	granted := model.ScopesForRole(user.Role)
+	scopes := slices.DeleteFunc(strings.Fields(pat.Scopes), func(scope string) bool {
+		return !slices.Contains(granted, scope)
+	})
+	err = p.patRepo.TouchToken(ctx, pat.ID, now, p.cfg.LastUsedInterval)
+	if err != nil {
+		return model.User{}, nil, fmt.Errorf("personalAccessTokenService.Authenticate: %w", err)
+	}
+	return user, scopes, nil
+}
+
+func NewPersonalAccessTokenService(userService UserService, patRepo repository.PersonalAccessTokenRepository, cfg PersonalAccessTokenConfig) PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		userService: userService,
+		patRepo:     patRepo,
+		cfg:         cfg,
+	}
+}
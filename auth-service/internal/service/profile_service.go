@@ -0,0 +1,176 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// avatarContentTypes are the image formats accepted as avatars, the type is sniffed from the content
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type ProfileService interface {
+	// ChangeUsername return apperrors.ErrInvalidUsername, apperrors.ErrUsernameTaken, or an *apperrors.RateLimitError
+	// if the username was changed during the cooldown. The access tokens of the user are revoked so the
+	// new username is carried by the tokens issued on the next refresh
+	ChangeUsername(ctx context.Context, userID string, username string) error
+	UpdateBio(ctx context.Context, userID string, bio string) error
+	// SetAvatar replace the avatar of the user, apperrors.ErrInvalidAvatar is returned if the file is not
+	// a supported image or is too large
+	SetAvatar(ctx context.Context, userID string, fileHeader *multipart.FileHeader) error
+	DeleteAvatar(ctx context.Context, userID string) error
+	// GetAvatarURL return a presigned URL of the avatar, apperrors.ErrAvatarNotFound is returned if the user has none
+	GetAvatarURL(ctx context.Context, userID string) (string, error)
+	// GetPublicProfile return the user with the username and the URL of their avatar, which is empty if they have none.
+	// Banned users are not found
+	GetPublicProfile(ctx context.Context, username string) (model.User, string, error)
+}
+
+type profileService struct {
+	userRepo          repository.UserRepository
+	revocationService RevocationService
+	minioClient       *minio.Client
+	avatarBucket      string
+	// usernameChangeCooldown is the time a user must wait between two username changes
+	usernameChangeCooldown time.Duration
+	avatarMaxSize          int64
+	avatarURLTTL           time.Duration
+}
+
+func (p *profileService) ChangeUsername(ctx context.Context, userID string, username string) error {
+	if !model.IsValidUsername(username) {
+		return fmt.Errorf("profileService.ChangeUsername: %w", apperrors.ErrInvalidUsername)
+	}
+	user, err := p.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("profileService.ChangeUsername: %w", err)
+	}
+	if user.Username == username {
+		return nil
+	}
+	now := time.Now()
+	// picking the first username is not limited
+	if user.Username != "" && user.UsernameChangedAt != nil {
+		retryAfter := user.UsernameChangedAt.Add(p.usernameChangeCooldown).Sub(now)
+		if retryAfter > 0 {
+			return fmt.Errorf("profileService.ChangeUsername: %w", &apperrors.RateLimitError{RetryAfter: retryAfter})
+		}
+	}
+	err = p.userRepo.UpdateUsername(ctx, userID, username, now)
+	if err != nil {
+		return fmt.Errorf("profileService.ChangeUsername: %w", err)
+	}
+	err = p.revocationService.RevokeUserAccessTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("profileService.ChangeUsername: %w", err)
+	}
+	return nil
+}
+
+func (p *profileService) UpdateBio(ctx context.Context, userID string, bio string) error {
+	err := p.userRepo.UpdateBio(ctx, userID, bio)
+	if err != nil {
+		return fmt.Errorf("profileService.UpdateBio: %w", err)
+	}
+	return nil
+}
+
+func (p *profileService) SetAvatar(ctx context.Context, userID string, fileHeader *multipart.FileHeader) error {
+	if fileHeader.Size <= 0 || fileHeader.Size > p.avatarMaxSize {
+		return fmt.Errorf("profileService.SetAvatar: %w", apperrors.ErrInvalidAvatar)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fmt.Errorf("profileService.SetAvatar: %w", apperrors.ErrInvalidAvatar)
+	}
+	defer file.Close()
+	// the content type given by the client is not trusted, the avatars are served to other users
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("profileService.SetAvatar: %w", apperrors.ErrInvalidAvatar)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !avatarContentTypes[contentType] {
+		return fmt.Errorf("profileService.SetAvatar: %w", apperrors.ErrInvalidAvatar)
+	}
+	_, err = p.minioClient.PutObject(
+		ctx,
+		p.avatarBucket,
+		userID,
+		io.MultiReader(bytes.NewReader(head[:n]), file),
+		fileHeader.Size,
+		minio.PutObjectOptions{
+			ContentType: contentType,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("profileService.SetAvatar: %w", err)
+	}
+	return nil
+}
+
+func (p *profileService) DeleteAvatar(ctx context.Context, userID string) error {
+	err := p.minioClient.RemoveObject(ctx, p.avatarBucket, userID, minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("profileService.DeleteAvatar: %w", err)
+	}
+	return nil
+}
+
+func (p *profileService) GetAvatarURL(ctx context.Context, userID string) (string, error) {
+	_, err := p.minioClient.StatObject(ctx, p.avatarBucket, userID, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return "", fmt.Errorf("profileService.GetAvatarURL: %w", apperrors.ErrAvatarNotFound)
+		}
+		return "", fmt.Errorf("profileService.GetAvatarURL: %w", err)
+	}
+	u, err := p.minioClient.PresignedGetObject(ctx, p.avatarBucket, userID, p.avatarURLTTL, nil)
+	if err != nil {
+		return "", fmt.Errorf("profileService.GetAvatarURL: %w", err)
+	}
+	return u.String(), nil
+}
+
+func (p *profileService) GetPublicProfile(ctx context.Context, username string) (model.User, string, error) {
+	user, err := p.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		return model.User{}, "", fmt.Errorf("profileService.GetPublicProfile: %w", err)
+	}
+	if user.EffectiveStatus(time.Now()) == model.UserStatusBanned {
+		return model.User{}, "", fmt.Errorf("profileService.GetPublicProfile: %w", apperrors.ErrUserNotFound)
+	}
+	avatarURL, err := p.GetAvatarURL(ctx, user.ID)
+	if err != nil && !errors.Is(err, apperrors.ErrAvatarNotFound) {
+		return model.User{}, "", fmt.Errorf("profileService.GetPublicProfile: %w", err)
+	}
+	return user, avatarURL, nil
+}
+
+func NewProfileService(userRepo repository.UserRepository, revocationService RevocationService, minioClient *minio.Client, avatarBucket string, usernameChangeCooldown time.Duration, avatarMaxSize int64, avatarURLTTL time.Duration) ProfileService {
+	return &profileService{
+		userRepo:               userRepo,
+		revocationService:      revocationService,
+		minioClient:            minioClient,
+		avatarBucket:           avatarBucket,
+		usernameChangeCooldown: usernameChangeCooldown,
+		avatarMaxSize:          avatarMaxSize,
+		avatarURLTTL:           avatarURLTTL,
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"time"
+)
+
+// RateLimiter is a sliding window rate limiter shared by the endpoints sending emails or checking credentials
+type RateLimiter interface {
+	// Allow record a hit for key and return an *apperrors.RateLimitError if more than limit hits happened in window
+	Allow(ctx context.Context, key string, limit int, window time.Duration) error
+	Reset(ctx context.Context, key string) error
+}
+
+type rateLimiter struct {
+	rateLimitRepo repository.RateLimitRepository
+}
+
+func (r *rateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) error {
+	count, oldest, err := r.rateLimitRepo.Hit(ctx, key, window)
+	if err != nil {
+		return fmt.Errorf("rateLimiter.Allow: %w", err)
+	}
+	if count > int64(limit) {
+		return fmt.Errorf("rateLimiter.Allow: %w", &apperrors.RateLimitError{RetryAfter: time.Until(oldest.Add(window))})
+	}
+	return nil
+}
+
+func (r *rateLimiter) Reset(ctx context.Context, key string) error {
+	err := r.rateLimitRepo.Reset(ctx, key)
+	if err != nil {
+		return fmt.Errorf("rateLimiter.Reset: %w", err)
+	}
+	return nil
+}
+
+func NewRateLimiter(rateLimitRepo repository.RateLimitRepository) RateLimiter {
+	return &rateLimiter{
+		rateLimitRepo: rateLimitRepo,
+	}
+}
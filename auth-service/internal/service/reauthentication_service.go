@@ -0,0 +1,58 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReauthenticationService check that a user proved their identity again before a sensitive change of their account
+type ReauthenticationService interface {
+	// Verify check the current password of the user. Users without a password, who sign in with OIDC or a passkey,
+	// must instead have signed in to the session sessionID less than maxAge ago.
+	// apperrors.ErrInvalidPassword or apperrors.ErrReauthenticationRequired is returned otherwise
+	Verify(ctx context.Context, user model.User, sessionID string, currentPassword string) error
+}
+
+type reauthenticationService struct {
+	userService UserService
+	sessionRepo repository.SessionRepository
+	maxAge      time.Duration
+}
+
+func (r *reauthenticationService) Verify(ctx context.Context, user model.User, sessionID string, currentPassword string) error {
+	if user.Password != "" {
+		err := r.userService.VerifyPassword(ctx, user, currentPassword)
+		if err != nil {
+			return fmt.Errorf("reauthenticationService.Verify: %w", err)
+		}
+		return nil
+	}
+	if sessionID == "" {
+		return fmt.Errorf("reauthenticationService.Verify: %w", apperrors.ErrReauthenticationRequired)
+	}
+	session, err := r.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrSessionNotFound) {
+			return fmt.Errorf("reauthenticationService.Verify: %w", apperrors.ErrReauthenticationRequired)
+		}
+		return fmt.Errorf("reauthenticationService.Verify: %w", err)
+	}
+	// refreshing the session keeps its creation date, which is when the user last signed in on this device
+	if session.UserID != user.ID || time.Since(session.CreatedAt) > r.maxAge {
+		return fmt.Errorf("reauthenticationService.Verify: %w", apperrors.ErrReauthenticationRequired)
+	}
+	return nil
+}
+
+func NewReauthenticationService(userService UserService, sessionRepo repository.SessionRepository, maxAge time.Duration) ReauthenticationService {
+	return &reauthenticationService{
+		userService: userService,
+		sessionRepo: sessionRepo,
+		maxAge:      maxAge,
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type RevocationService interface {
+	// RevokeAccessToken revoke a single access token by its jti
+	RevokeAccessToken(ctx context.Context, tokenID string) error
+	// RevokeUserTokens revoke every access token issued to the user so far and delete all of their sessions
+	RevokeUserTokens(ctx context.Context, userID string) error
+	// RevokeUserAccessTokens revoke every access token issued to the user so far but keep their sessions,
+	// the next refresh issues tokens carrying their updated claims
+	RevokeUserAccessTokens(ctx context.Context, userID string) error
+	// BlockUser revoke every token of the user and reject their access tokens with the status until the given time,
+	// a nil until blocks the user until UnblockUser is called
+	BlockUser(ctx context.Context, userID string, status string, until *time.Time) error
+	UnblockUser(ctx context.Context, userID string) error
+	// CheckAccessToken return apperrors.ErrTokenRevoked if the verified access token claims have been revoked,
+	// apperrors.ErrUserSuspended or apperrors.ErrUserBanned if the user is blocked
+	CheckAccessToken(ctx context.Context, claims jwt.MapClaims) error
+}
+
+type revocationService struct {
+	revocationRepo repository.TokenRevocationRepository
+	sessionRepo    repository.SessionRepository
+	accessTokenTTL time.Duration
+}
+
+func (r *revocationService) RevokeAccessToken(ctx context.Context, tokenID string) error {
+	if tokenID == "" {
+		return nil
+	}
+	err := r.revocationRepo.RevokeToken(ctx, tokenID, r.accessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("revocationService.RevokeAccessToken: %w", err)
+	}
+	return nil
+}
+
+func (r *revocationService) RevokeUserTokens(ctx context.Context, userID string) error {
+	// access tokens issued before now are expired after accessTokenTTL, no need to keep the marker longer
+	err := r.revocationRepo.SetUserTokensRevokedBefore(ctx, userID, time.Now(), r.accessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("revocationService.RevokeUserTokens: %w", err)
+	}
+	err = r.sessionRepo.DeleteUserSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("revocationService.RevokeUserTokens: %w", err)
+	}
+	return nil
+}
+
+func (r *revocationService) RevokeUserAccessTokens(ctx context.Context, userID string) error {
+	err := r.revocationRepo.SetUserTokensRevokedBefore(ctx, userID, time.Now(), r.accessTokenTTL)
+	if err != nil {
+		return fmt.Errorf("revocationService.RevokeUserAccessTokens: %w", err)
+	}
+	return nil
+}
+
+func (r *revocationService) BlockUser(ctx context.Context, userID string, status string, until *time.Time) error {
+	var ttl time.Duration
+	if until != nil {
+		ttl = time.Until(*until)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	// the marker is set first so a token issued in the same second as the revocation is rejected as well
+	err := r.revocationRepo.SetUserBlocked(ctx, userID, status, ttl)
+	if err != nil {
+		return fmt.Errorf("revocationService.BlockUser: %w", err)
+	}
+	err = r.RevokeUserTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("revocationService.BlockUser: %w", err)
+	}
+	return nil
+}
+
+func (r *revocationService) UnblockUser(ctx context.Context, userID string) error {
+	err := r.revocationRepo.ClearUserBlocked(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("revocationService.UnblockUser: %w", err)
+	}
+	return nil
+}
+
+func (r *revocationService) CheckAccessToken(ctx context.Context, claims jwt.MapClaims) error {
+	userID, _ := claims["user_id"].(string)
+	tokenID, _ := claims["jti"].(string)
+	issuedAt, _ := claims["iat"].(float64)
+	blocked, err := r.revocationRepo.GetUserBlocked(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("revocationService.CheckAccessToken: %w", err)
+	}
+	switch blocked {
+	case model.UserStatusBanned:
+		return fmt.Errorf("revocationService.CheckAccessToken: %w", apperrors.ErrUserBanned)
+	case model.UserStatusSuspended:
+		return fmt.Errorf("revocationService.CheckAccessToken: %w", apperrors.ErrUserSuspended)
+	}
+	if tokenID != "" {
+		revoked, err := r.revocationRepo.IsTokenRevoked(ctx, tokenID)
+		if err != nil {
+			return fmt.Errorf("revocationService.CheckAccessToken: %w", err)
+		}
+		if revoked {
+			return fmt.Errorf("revocationService.CheckAccessToken: %w", apperrors.ErrTokenRevoked)
+		}
+	}
+	before, err := r.revocationRepo.GetUserTokensRevokedBefore(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("revocationService.CheckAccessToken: %w", err)
+	}
+	// iat is fractional, tokens with a whole second iat issued in the same second as the revocation are revoked too
+	if !before.IsZero() && int64(math.Round(issuedAt*1e6)) <= before.UnixMicro() {
+		return fmt.Errorf("revocationService.CheckAccessToken: %w", apperrors.ErrTokenRevoked)
+	}
+	return nil
+}
+
+func NewRevocationService(revocationRepo repository.TokenRevocationRepository, sessionRepo repository.SessionRepository, accessTokenTTL time.Duration) RevocationService {
+	return &revocationService{
+		revocationRepo: revocationRepo,
+		sessionRepo:    sessionRepo,
+		accessTokenTTL: accessTokenTTL,
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// fakeTokenRevocationRepository keep the revocations in memory
+type fakeTokenRevocationRepository struct {
+	repository.TokenRevocationRepository
+	revokedBefore map[string]time.Time
+}
+
+func (f *fakeTokenRevocationRepository) IsTokenRevoked(context.Context, string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeTokenRevocationRepository) GetUserBlocked(context.Context, string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeTokenRevocationRepository) SetUserTokensRevokedBefore(_ context.Context, userID string, before time.Time, _ time.Duration) error {
+	f.revokedBefore[userID] = before
+	return nil
+}
+
+func (f *fakeTokenRevocationRepository) GetUserTokensRevokedBefore(_ context.Context, userID string) (time.Time, error) {
+	return f.revokedBefore[userID], nil
+}
+
+func TestRevocationServiceCheckAccessToken(t *testing.T) {
+	// the tokens of alice were revoked half a second into a second, bob has no revocation
+	marker := time.Unix(1760000000, 500_000_000)
+	tests := []struct {
+		name     string
+		userID   string
+		issuedAt float64
+		revoked  bool
+	}{
+		{name: "issued a second before", userID: "alice", issuedAt: 1759999999.7, revoked: true},
+		{name: "issued earlier in the same second", userID: "alice", issuedAt: 1760000000.2, revoked: true},
+		{name: "issued at the revocation", userID: "alice", issuedAt: 1760000000.5, revoked: true},
+		{name: "whole second iat of the same second", userID: "alice", issuedAt: 1760000000, revoked: true},
+		{name: "issued later in the same second", userID: "alice", issuedAt: 1760000000.8},
+		{name: "issued a second after", userID: "alice", issuedAt: 1760000001},
+		{name: "no revocation", userID: "bob", issuedAt: 1760000000.2},
+	}
+	repo := &fakeTokenRevocationRepository{revokedBefore: map[string]time.Time{}}
+	r := NewRevocationService(repo, nil, time.Hour)
+	if err := repo.SetUserTokensRevokedBefore(context.Background(), "alice", marker, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.CheckAccessToken(context.Background(), jwt.MapClaims{
+				"user_id": tt.userID,
+				"jti":     "token",
+				"iat":     tt.issuedAt,
+			})
+			if tt.revoked && !errors.Is(err, apperrors.ErrTokenRevoked) {
+				t.Errorf("CheckAccessToken() error = %v, want %v", err, apperrors.ErrTokenRevoked)
+			}
+			if !tt.revoked && err != nil {
+				t.Errorf("CheckAccessToken() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"time"
+)
+
+// SecurityEventService record the authentication events of the users in the audit log and query them back
+type SecurityEventService interface {
+	Record(ctx context.Context, event model.SecurityEvent) error
+	// PseudonymizeUserEvents erase the ip, user agent, email and username of a deleted user from their events
+	PseudonymizeUserEvents(ctx context.Context, userID string) error
+	// GetEvents return the events matching filter, most recent first
+	GetEvents(ctx context.Context, filter model.SecurityEventFilter, limit, offset int) ([]model.SecurityEvent, error)
+	// GetUserEvents return the events about the user, most recent first
+	GetUserEvents(ctx context.Context, userID string, limit, offset int) ([]model.SecurityEvent, error)
+}
+
+// newSecurityEvent build an event about userID happening on the device described by client, userID may be empty
+func newSecurityEvent(eventType string, userID string, client ClientInfo, details map[string]string) model.SecurityEvent {
+	event := model.SecurityEvent{
+		Type:      eventType,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Details:   details,
+	}
+	if userID != "" {
+		event.UserID = &userID
+	}
+	return event
+}
+
+type securityEventService struct {
+	securityEventRepo repository.SecurityEventRepository
+}
+
+func (s *securityEventService) Record(ctx context.Context, event model.SecurityEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if event.Details == nil {
+		event.Details = map[string]string{}
+	}
+	err := s.securityEventRepo.CreateEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("securityEventService.Record: %w", err)
+	}
+	return nil
+}
+
+func (s *securityEventService) PseudonymizeUserEvents(ctx context.Context, userID string) error {
+	err := s.securityEventRepo.PseudonymizeUserEvents(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("securityEventService.PseudonymizeUserEvents: %w", err)
+	}
+	return nil
+}
+
+func (s *securityEventService) GetEvents(ctx context.Context, filter model.SecurityEventFilter, limit, offset int) ([]model.SecurityEvent, error) {
+	events, err := s.securityEventRepo.GetEvents(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("securityEventService.GetEvents: %w", err)
+	}
+	return events, nil
+}
+
+func (s *securityEventService) GetUserEvents(ctx context.Context, userID string, limit, offset int) ([]model.SecurityEvent, error) {
+	events, err := s.securityEventRepo.GetEvents(ctx, model.SecurityEventFilter{UserID: userID}, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("securityEventService.GetUserEvents: %w", err)
+	}
+	return events, nil
+}
+
+func NewSecurityEventService(securityEventRepo repository.SecurityEventRepository) SecurityEventService {
+	return &securityEventService{
+		securityEventRepo: securityEventRepo,
+	}
+}
@@ -3,46 +3,214 @@ package service
 import (
 	apperrors "auth-service/internal/error"
 	"auth-service/internal/model"
+	"auth-service/internal/password"
 	"auth-service/internal/repository"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-
-	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 type UserService interface {
+	// CreateUser return apperrors.ErrInvalidUsername or apperrors.ErrUsernameTaken if the user picked an unusable username
 	CreateUser(ctx context.Context, user model.User) (model.User, error)
+	// CreateExternalUser create a user authenticated by an identity provider, they have no password until they reset it
+	CreateExternalUser(ctx context.Context, user model.User) (model.User, error)
 	GetUserByEmail(ctx context.Context, email string) (model.User, error)
 	GetUserById(ctx context.Context, id string) (model.User, error)
+	// UpdateUserByID return apperrors.ErrEmailChangeRequiresConfirmation if the email changes, it can only be changed
+	// through the EmailChangeService
 	UpdateUserByID(ctx context.Context, user model.User) error
+	SetEmailVerified(ctx context.Context, id string, verified bool) error
+	// VerifyPassword return apperrors.ErrInvalidPassword if password is not the one of the user.
+	// The stored hash is upgraded when it was made with another algorithm or cost than the configured ones
+	VerifyPassword(ctx context.Context, user model.User, password string) error
+	// UpdateUserPassword revoke every outstanding token of the user on success.
+	// The new password must follow the password policy and not be one of the last ones of the user
 	UpdateUserPassword(ctx context.Context, id string, currentPassword string, newPassword string) error
-	// GetUsers will sort user by ID
-	GetUsers(ctx context.Context, userEmail string, sortOrder string, limit, offset int) ([]model.User, error)
+	// CheckNewPassword return an error if newPassword breaks the password policy or is one of the last passwords of the user
+	CheckNewPassword(ctx context.Context, id string, newPassword string) error
+	// ResetPassword set the password without checking the current one and revoke every outstanding token of the user.
+	// The new password must follow the password policy and not be one of the last ones of the user
+	ResetPassword(ctx context.Context, id string, newPassword string) error
+	// SearchUsers return a page of the users matching query and their total count.
+	// cursor is the NextCursor of the previous page, apperrors.ErrInvalidCursor is returned if it does not belong to query
+	SearchUsers(ctx context.Context, query model.UserQuery, cursor string) (UserPage, error)
+	// ExportUsers call fn with every user matching query, in its sort order
+	ExportUsers(ctx context.Context, query model.UserQuery, fn func(model.User) error) error
+	// SuspendUser block the user until the given time and revoke all their tokens
+	SuspendUser(ctx context.Context, id string, until time.Time, reason string) error
+	// BanUser block the user until they are unbanned and revoke all their tokens
+	BanUser(ctx context.Context, id string, reason string) error
+	// UnbanUser lift a suspension or a ban
+	UnbanUser(ctx context.Context, id string) error
+	// ChangeUserRole revoke the access tokens of the user so the new role is applied on the next refresh
+	ChangeUserRole(ctx context.Context, id string, role string) error
+	DeleteUser(ctx context.Context, id string) error
+}
+
+// checkUserStatus return apperrors.ErrUserSuspended or apperrors.ErrUserBanned if the user is not allowed to sign in
+func checkUserStatus(user model.User) error {
+	switch user.EffectiveStatus(time.Now()) {
+	case model.UserStatusSuspended:
+		return apperrors.ErrUserSuspended
+	case model.UserStatusBanned:
+		return apperrors.ErrUserBanned
+	}
+	return nil
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo            repository.UserRepository
+	passwordHistoryRepo repository.PasswordHistoryRepository
+	revocationService   RevocationService
+	hasher              password.Hasher
+	policy              password.Policy
+	// passwordHistorySize is the number of last passwords a new password must differ from
+	passwordHistorySize int
+}
+
+// UserPage is a page of the users matching a query, NextCursor is empty on the last page
+type UserPage struct {
+	Users      []model.User
+	Total      int64
+	NextCursor string
+}
+
+// userCursor is the opaque cursor given to clients, it is bound to the sort of the query it was issued for
+type userCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
 }
 
-func (u *userService) GetUsers(ctx context.Context, userEmail string, sortOrder string, limit, offset int) ([]model.User, error) {
-	users, err := u.userRepo.GetUsers(ctx, userEmail, sortOrder, limit, offset)
+// exportBatchSize is the number of users fetched at once by ExportUsers
+const exportBatchSize = 500
+
+func (*userService) encodeCursor(query model.UserQuery, user model.User) (string, error) {
+	b, err := json.Marshal(userCursor{
+		SortBy: query.SortBy,
+		Desc:   query.Desc,
+		Value:  user.SortValue(query.SortBy),
+		ID:     user.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("userService.encodeCursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (*userService) decodeCursor(query model.UserQuery, cursor string) (*model.UserCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("userService.decodeCursor: %w", apperrors.ErrInvalidCursor)
+	}
+	var c userCursor
+	if json.Unmarshal(b, &c) != nil || c.ID == "" || c.SortBy != query.SortBy || c.Desc != query.Desc {
+		return nil, fmt.Errorf("userService.decodeCursor: %w", apperrors.ErrInvalidCursor)
+	}
+	return &model.UserCursor{Value: c.Value, ID: c.ID}, nil
+}
+
+func (u *userService) SearchUsers(ctx context.Context, query model.UserQuery, cursor string) (UserPage, error) {
+	if !model.IsUserSortField(query.SortBy) {
+		query.SortBy = model.UserSortCreatedAt
+	}
+	if cursor != "" {
+		after, err := u.decodeCursor(query, cursor)
+		if err != nil {
+			return UserPage{}, fmt.Errorf("userService.SearchUsers: %w", err)
+		}
+		query.After = after
+	}
+	total, err := u.userRepo.CountUsers(ctx, query)
+	if err != nil {
+		return UserPage{}, fmt.Errorf("userService.SearchUsers: %w", err)
+	}
+	// one more user is fetched to know whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	users, err := u.userRepo.SearchUsers(ctx, query)
+	if err != nil {
+		return UserPage{}, fmt.Errorf("userService.SearchUsers: %w", err)
+	}
+	page := UserPage{Users: users, Total: total}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor, err = u.encodeCursor(query, page.Users[limit-1])
+		if err != nil {
+			return UserPage{}, fmt.Errorf("userService.SearchUsers: %w", err)
+		}
+	}
+	return page, nil
+}
+
+func (u *userService) ExportUsers(ctx context.Context, query model.UserQuery, fn func(model.User) error) error {
+	if !model.IsUserSortField(query.SortBy) {
+		query.SortBy = model.UserSortCreatedAt
+	}
+	query.Limit = exportBatchSize
+	query.After = nil
+	for {
+		users, err := u.userRepo.SearchUsers(ctx, query)
+		if err != nil {
+			return fmt.Errorf("userService.ExportUsers: %w", err)
+		}
+		for _, user := range users {
+			err = fn(user)
+			if err != nil {
+				return fmt.Errorf("userService.ExportUsers: %w", err)
+			}
+		}
+		if len(users) < exportBatchSize {
+			return nil
+		}
+		last := users[len(users)-1]
+		query.After = &model.UserCursor{Value: last.SortValue(query.SortBy), ID: last.ID}
 	}
-	return users, nil
 }
 
 func (u *userService) CreateUser(ctx context.Context, user model.User) (model.User, error) {
 	user.Role = model.RoleUser
-	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	user.Status = model.UserStatusActive
+	if user.Username != "" {
+		if !model.IsValidUsername(user.Username) {
+			return model.User{}, fmt.Errorf("userService.CreateUser: %w", apperrors.ErrInvalidUsername)
+		}
+		now := time.Now()
+		user.UsernameChangedAt = &now
+	}
+	err := u.policy.Check(user.Password, user.Email, user.FirstName, user.LastName)
 	if err != nil {
-		return model.User{}, fmt.Errorf("UserService.Register hashing password err: %w", err)
+		return model.User{}, fmt.Errorf("userService.CreateUser: %w", err)
+	}
+	hash, err := u.hasher.Hash(user.Password)
+	if err != nil {
+		return model.User{}, fmt.Errorf("userService.CreateUser hashing password: %w", err)
 	}
-	user.Password = string(hash)
+	user.Password = hash
 	createdUser, err := u.userRepo.CreateUser(ctx, user)
 	if err != nil {
 		return model.User{}, fmt.Errorf("userService.CreateUser: %w", err)
 	}
+	err = u.passwordHistoryRepo.AddPassword(ctx, createdUser.ID, hash, u.passwordHistorySize)
+	if err != nil {
+		return model.User{}, fmt.Errorf("userService.CreateUser: %w", err)
+	}
+	return createdUser, nil
+}
+
+func (u *userService) CreateExternalUser(ctx context.Context, user model.User) (model.User, error) {
+	user.Role = model.RoleUser
+	user.Status = model.UserStatusActive
+	// an empty hash never matches any password
+	user.Password = ""
+	createdUser, err := u.userRepo.CreateUser(ctx, user)
+	if err != nil {
+		return model.User{}, fmt.Errorf("userService.CreateExternalUser: %w", err)
+	}
 	return createdUser, nil
 }
 
@@ -63,6 +231,17 @@ func (u *userService) GetUserById(ctx context.Context, id string) (model.User, e
 }
 
 func (u *userService) UpdateUserByID(ctx context.Context, user model.User) error {
+	if user.Email != "" {
+		current, err := u.userRepo.GetUserByID(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("userService.UpdateUserByID: %w", err)
+		}
+		if current.Email != user.Email {
+			return fmt.Errorf("userService.UpdateUserByID: %w", apperrors.ErrEmailChangeRequiresConfirmation)
+		}
+		// clients may send back the current email with the other fields
+		user.Email = ""
+	}
 	err := u.userRepo.UpdateUserByID(ctx, user)
 	if err != nil {
 		return fmt.Errorf("userService.UpdateUserByID: %w", err)
@@ -70,29 +249,186 @@ func (u *userService) UpdateUserByID(ctx context.Context, user model.User) error
 	return nil
 }
 
+func (u *userService) SetEmailVerified(ctx context.Context, id string, verified bool) error {
+	err := u.userRepo.SetEmailVerified(ctx, id, verified)
+	if err != nil {
+		return fmt.Errorf("userService.SetEmailVerified: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) VerifyPassword(ctx context.Context, user model.User, password string) error {
+	if !u.hasher.Verify(user.Password, password) {
+		return fmt.Errorf("userService.VerifyPassword: %w", apperrors.ErrInvalidPassword)
+	}
+	if !u.hasher.NeedsRehash(user.Password) {
+		return nil
+	}
+	hash, err := u.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("userService.VerifyPassword hashing password: %w", err)
+	}
+	// the password did not change, the tokens of the user stay valid
+	err = u.userRepo.UpdateUserByID(ctx, model.User{ID: user.ID, Password: hash})
+	if err != nil {
+		return fmt.Errorf("userService.VerifyPassword: %w", err)
+	}
+	return nil
+}
+
 func (u *userService) UpdateUserPassword(ctx context.Context, id string, currentPassword string, newPassword string) error {
 	user, err := u.userRepo.GetUserByID(ctx, id)
 	if err != nil {
 		return fmt.Errorf("userService.UpdateUserPassword: %w", err)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword))
-	if err != nil {
+	if !u.hasher.Verify(user.Password, currentPassword) {
 		return fmt.Errorf("userService.UpdateUserPassword: %w", apperrors.ErrInvalidPassword)
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	err = u.setPassword(ctx, user, newPassword)
 	if err != nil {
-		return fmt.Errorf("userService.UpdateUserPassword hashing password: %w", err)
+		return fmt.Errorf("userService.UpdateUserPassword: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) ResetPassword(ctx context.Context, id string, newPassword string) error {
+	user, err := u.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userService.ResetPassword: %w", err)
 	}
-	user.Password = string(hash)
-	err = u.userRepo.UpdateUserByID(ctx, model.User{ID: id, Password: user.Password})
+	err = u.setPassword(ctx, user, newPassword)
 	if err != nil {
-		return fmt.Errorf("userService.UpdateUserPassword: %w", err)
+		return fmt.Errorf("userService.ResetPassword: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) CheckNewPassword(ctx context.Context, id string, newPassword string) error {
+	user, err := u.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userService.CheckNewPassword: %w", err)
+	}
+	err = u.checkNewPassword(ctx, user, newPassword)
+	if err != nil {
+		return fmt.Errorf("userService.CheckNewPassword: %w", err)
+	}
+	return nil
+}
+
+// checkNewPassword return an error if newPassword breaks the policy or is the current or one of the last passwords of user
+func (u *userService) checkNewPassword(ctx context.Context, user model.User, newPassword string) error {
+	err := u.policy.Check(newPassword, user.Email, user.FirstName, user.LastName)
+	if err != nil {
+		return fmt.Errorf("userService.checkNewPassword: %w", err)
+	}
+	hashes, err := u.passwordHistoryRepo.GetRecentPasswords(ctx, user.ID, u.passwordHistorySize)
+	if err != nil {
+		return fmt.Errorf("userService.checkNewPassword: %w", err)
+	}
+	// the history of users created before it existed is empty, their current password is still checked
+	for _, hash := range append(hashes, user.Password) {
+		if u.hasher.Verify(hash, newPassword) {
+			return fmt.Errorf("userService.checkNewPassword: %w", apperrors.ErrPasswordReused)
+		}
+	}
+	return nil
+}
+
+// setPassword check, hash and save the new password then revoke every outstanding token of the user
+func (u *userService) setPassword(ctx context.Context, user model.User, newPassword string) error {
+	err := u.checkNewPassword(ctx, user, newPassword)
+	if err != nil {
+		return fmt.Errorf("userService.setPassword: %w", err)
+	}
+	hash, err := u.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("userService.setPassword hashing password: %w", err)
+	}
+	err = u.userRepo.UpdateUserByID(ctx, model.User{ID: user.ID, Password: hash})
+	if err != nil {
+		return fmt.Errorf("userService.setPassword: %w", err)
+	}
+	err = u.passwordHistoryRepo.AddPassword(ctx, user.ID, hash, u.passwordHistorySize)
+	if err != nil {
+		return fmt.Errorf("userService.setPassword: %w", err)
+	}
+	err = u.revocationService.RevokeUserTokens(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("userService.setPassword: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) SuspendUser(ctx context.Context, id string, until time.Time, reason string) error {
+	err := u.userRepo.UpdateUserStatus(ctx, id, model.UserStatusSuspended, &until, reason)
+	if err != nil {
+		return fmt.Errorf("userService.SuspendUser: %w", err)
+	}
+	err = u.revocationService.BlockUser(ctx, id, model.UserStatusSuspended, &until)
+	if err != nil {
+		return fmt.Errorf("userService.SuspendUser: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) BanUser(ctx context.Context, id string, reason string) error {
+	err := u.userRepo.UpdateUserStatus(ctx, id, model.UserStatusBanned, nil, reason)
+	if err != nil {
+		return fmt.Errorf("userService.BanUser: %w", err)
+	}
+	err = u.revocationService.BlockUser(ctx, id, model.UserStatusBanned, nil)
+	if err != nil {
+		return fmt.Errorf("userService.BanUser: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) UnbanUser(ctx context.Context, id string) error {
+	err := u.userRepo.UpdateUserStatus(ctx, id, model.UserStatusActive, nil, "")
+	if err != nil {
+		return fmt.Errorf("userService.UnbanUser: %w", err)
+	}
+	err = u.revocationService.UnblockUser(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userService.UnbanUser: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) ChangeUserRole(ctx context.Context, id string, role string) error {
+	if !model.IsValidRole(role) {
+		return fmt.Errorf("userService.ChangeUserRole: %w", apperrors.ErrInvalidRoles)
+	}
+	err := u.userRepo.UpdateUserRole(ctx, id, role)
+	if err != nil {
+		return fmt.Errorf("userService.ChangeUserRole: %w", err)
+	}
+	err = u.revocationService.RevokeUserAccessTokens(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userService.ChangeUserRole: %w", err)
+	}
+	return nil
+}
+
+func (u *userService) DeleteUser(ctx context.Context, id string) error {
+	err := u.userRepo.DeleteUserByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userService.DeleteUser: %w", err)
+	}
+	err = u.revocationService.RevokeUserTokens(ctx, id)
+	if err != nil {
+		return fmt.Errorf("userService.DeleteUser: %w", err)
 	}
 	return nil
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, passwordHistoryRepo repository.PasswordHistoryRepository, revocationService RevocationService, hasher password.Hasher, policy password.Policy, passwordHistorySize int) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:            userRepo,
+		passwordHistoryRepo: passwordHistoryRepo,
+		revocationService:   revocationService,
+		hasher:              hasher,
+		policy:              policy,
+		passwordHistorySize: passwordHistorySize,
 	}
 }
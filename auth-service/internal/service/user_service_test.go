@@ -0,0 +1,95 @@
+package service
+
+import (
+	apperrors "auth-service/internal/error"
+	"auth-service/internal/model"
+	"auth-service/internal/password"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeUserRepository keep the users in memory, an email is unique among the users who are not soft deleted
+// like the users_email_key index
+type fakeUserRepository struct {
+	repository.UserRepository
+	users []model.User
+}
+
+func (f *fakeUserRepository) CreateUser(_ context.Context, user model.User) (model.User, error) {
+	for _, existing := range f.users {
+		if existing.Email == user.Email && !existing.DeletedAt.Valid {
+			return model.User{}, fmt.Errorf("fakeUserRepository.CreateUser: %w", apperrors.ErrUserMailAlreadyExists)
+		}
+	}
+	user.ID = fmt.Sprintf("user-%d", len(f.users)+1)
+	f.users = append(f.users, user)
+	return user, nil
+}
+
+func (f *fakeUserRepository) DeleteUserByID(_ context.Context, id string) error {
+	for i := range f.users {
+		if f.users[i].ID == id && !f.users[i].DeletedAt.Valid {
+			f.users[i].DeletedAt = gorm.DeletedAt{Valid: true}
+			return nil
+		}
+	}
+	return apperrors.ErrUserNotFound
+}
+
+type fakePasswordHistoryRepository struct {
+	repository.PasswordHistoryRepository
+}
+
+func (f *fakePasswordHistoryRepository) AddPassword(context.Context, string, string, int) error {
+	return nil
+}
+
+type fakeRevocationService struct {
+	RevocationService
+}
+
+func (f *fakeRevocationService) RevokeUserTokens(context.Context, string) error {
+	return nil
+}
+
+func TestUserServiceCreateUserAfterDeletion(t *testing.T) {
+	hasher, err := password.NewHasher(password.HasherConfig{Algorithm: password.AlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRepo := &fakeUserRepository{}
+	u := NewUserService(userRepo, &fakePasswordHistoryRepository{}, &fakeRevocationService{}, hasher,
+		password.NewPolicy(password.PolicyConfig{MinLength: 8, MaxLength: 64, MinCharacterClasses: 2}), 5)
+	ctx := context.Background()
+	user := model.User{Email: "alice@example.com", Password: "Tr0ub4dor&3"}
+
+	first, err := u.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	_, err = u.CreateUser(ctx, user)
+	if !errors.Is(err, apperrors.ErrUserMailAlreadyExists) {
+		t.Errorf("CreateUser() with the email of a user error = %v, want %v", err, apperrors.ErrUserMailAlreadyExists)
+	}
+
+	// once the account is deleted its email can be registered again
+	if err = u.DeleteUser(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	second, err := u.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("CreateUser() with the email of a deleted user error = %v", err)
+	}
+	if second.ID == first.ID {
+		t.Errorf("CreateUser() returned the deleted user %q", first.ID)
+	}
+	_, err = u.CreateUser(ctx, user)
+	if !errors.Is(err, apperrors.ErrUserMailAlreadyExists) {
+		t.Errorf("CreateUser() with the email of the new user error = %v, want %v", err, apperrors.ErrUserMailAlreadyExists)
+	}
+}
@@ -0,0 +1,136 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errCBOR = errors.New("malformed cbor")
+
+// maxCBORDepth bounds the nesting of decoded values, authenticators never go deeper than a few levels
+const maxCBORDepth = 16
+
+// decodeCBOR decode the first CBOR item of data and return it with the number of bytes it used.
+// Only the definite length encodings WebAuthn requires are supported: unsigned and negative integers
+// (as int64), byte strings ([]byte), text strings (string), arrays ([]any), maps (map[any]any),
+// booleans and null.
+func decodeCBOR(data []byte) (any, int, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, int, error) {
+	if depth > maxCBORDepth {
+		return nil, 0, fmt.Errorf("%w: too deeply nested", errCBOR)
+	}
+	if len(data) == 0 {
+		return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+	}
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, 1, nil
+		case 21:
+			return true, 1, nil
+		case 22:
+			return nil, 1, nil
+		}
+		return nil, 0, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+	}
+	arg, n, err := readCBORArgument(data, info)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, 0, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return int64(arg), n, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, 0, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return -1 - int64(arg), n, nil
+	case 2, 3:
+		if arg > uint64(len(data)-n) {
+			return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		end := n + int(arg)
+		if major == 2 {
+			return append([]byte(nil), data[n:end]...), end, nil
+		}
+		return string(data[n:end]), end, nil
+	case 4:
+		if arg > uint64(len(data)) {
+			return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			n += used
+		}
+		return items, n, nil
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		items := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += used
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, fmt.Errorf("%w: unsupported map key", errCBOR)
+			}
+			value, used, err := decodeCBORItem(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += used
+			items[key] = value
+		}
+		return items, n, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unsupported major type %d", errCBOR, major)
+}
+
+// readCBORArgument read the argument following the initial byte, returning it and the length of the header
+func readCBORArgument(data []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info == 24:
+		if len(data) < 2 {
+			return 0, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		return uint64(data[1]), 2, nil
+	case info == 25:
+		if len(data) < 3 {
+			return 0, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		return uint64(binary.BigEndian.Uint16(data[1:3])), 3, nil
+	case info == 26:
+		if len(data) < 5 {
+			return 0, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		return uint64(binary.BigEndian.Uint32(data[1:5])), 5, nil
+	case info == 27:
+		if len(data) < 9 {
+			return 0, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		return binary.BigEndian.Uint64(data[1:9]), 9, nil
+	}
+	return 0, 0, fmt.Errorf("%w: indefinite lengths are not supported", errCBOR)
+}
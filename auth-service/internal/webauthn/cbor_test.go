@@ -0,0 +1,88 @@
+package webauthn
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want any
+		used int
+	}{
+		{name: "small unsigned", data: []byte{0x17}, want: int64(23), used: 1},
+		{name: "one byte unsigned", data: []byte{0x18, 0xff}, want: int64(255), used: 2},
+		{name: "two bytes unsigned", data: []byte{0x19, 0x01, 0x00}, want: int64(256), used: 3},
+		{name: "negative", data: []byte{0x38, 0x18}, want: int64(-25), used: 2},
+		{name: "byte string", data: []byte{0x43, 1, 2, 3}, want: []byte{1, 2, 3}, used: 4},
+		{name: "text string", data: []byte{0x63, 'f', 'm', 't'}, want: "fmt", used: 4},
+		{name: "array", data: []byte{0x82, 0x01, 0xf5}, want: []any{int64(1), true}, used: 3},
+		{name: "map", data: []byte{0xa2, 0x01, 0x02, 0x20, 0xf6}, want: map[any]any{int64(1): int64(2), int64(-1): nil}, used: 5},
+		{name: "trailing bytes", data: []byte{0x01, 0x02}, want: int64(1), used: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, used, err := decodeCBOR(tt.data)
+			if err != nil {
+				t.Fatalf("decodeCBOR() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || used != tt.used {
+				t.Errorf("decodeCBOR() = %#v, %d, want %#v, %d", got, used, tt.want, tt.used)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "truncated argument", data: []byte{0x19, 0x01}},
+		{name: "truncated byte string", data: []byte{0x45, 1, 2}},
+		{name: "truncated text string", data: []byte{0x65, 'a'}},
+		{name: "huge byte string", data: []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "truncated array", data: []byte{0x83, 0x01, 0x02}},
+		{name: "huge array", data: []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{name: "truncated map", data: []byte{0xa1, 0x01}},
+		{name: "huge map", data: []byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "unsupported map key", data: []byte{0xa1, 0x41, 0x00, 0x01}},
+		{name: "indefinite length", data: []byte{0x5f, 0x41, 0x00, 0xff}},
+		{name: "integer overflow", data: []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "float", data: []byte{0xf9, 0x3c, 0x00}},
+		{name: "tag", data: []byte{0xc0, 0x01}},
+		{name: "too deeply nested", data: append(nested, 0x01)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); !errors.Is(err, errCBOR) {
+				t.Errorf("decodeCBOR() error = %v, want %v", err, errCBOR)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORTruncated(t *testing.T) {
+	// an attestation object with a nested COSE key, every prefix of it is malformed
+	data := []byte{
+		0xa3,
+		0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e',
+		0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0,
+		0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a',
+		0xa2, 0x01, 0x02, 0x21, 0x44, 1, 2, 3, 4,
+	}
+	if _, used, err := decodeCBOR(data); err != nil || used != len(data) {
+		t.Fatalf("decodeCBOR() = %d, %v, want %d, nil", used, err, len(data))
+	}
+	for n := range len(data) {
+		if _, _, err := decodeCBOR(data[:n]); !errors.Is(err, errCBOR) {
+			t.Errorf("decodeCBOR() of the first %d bytes error = %v, want %v", n, err, errCBOR)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the supported credential keys
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// COSE key parameters, see RFC 9053
+const (
+	coseKeyKty = 1
+	coseKeyAlg = 3
+	coseKeyCrv = -1
+	coseKeyX   = -2
+	coseKeyY   = -3
+	coseKeyN   = -1
+	coseKeyE   = -2
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+var errUnsupportedKey = errors.New("unsupported credential public key")
+
+// publicKey is a credential public key decoded from its COSE encoding
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey decode a COSE_Key, returning the number of bytes it used
+func parsePublicKey(data []byte) (publicKey, int, error) {
+	raw, n, err := decodeCBOR(data)
+	if err != nil {
+		return publicKey{}, 0, err
+	}
+	m, ok := raw.(map[any]any)
+	if !ok {
+		return publicKey{}, 0, fmt.Errorf("%w: not a map", errUnsupportedKey)
+	}
+	kty, _ := m[int64(coseKeyKty)].(int64)
+	alg, _ := m[int64(coseKeyAlg)].(int64)
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := m[int64(coseKeyCrv)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		y, _ := m[int64(coseKeyY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, 0, fmt.Errorf("%w: invalid P-256 key", errUnsupportedKey)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, 0, fmt.Errorf("%w: point not on curve", errUnsupportedKey)
+		}
+		return publicKey{alg: alg, key: key}, n, nil
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := m[int64(coseKeyCrv)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, 0, fmt.Errorf("%w: invalid Ed25519 key", errUnsupportedKey)
+		}
+		return publicKey{alg: alg, key: ed25519.PublicKey(x)}, n, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		modulus, _ := m[int64(coseKeyN)].([]byte)
+		exponent, _ := m[int64(coseKeyE)].([]byte)
+		if len(modulus) < 256 || len(exponent) == 0 || len(exponent) > 4 {
+			return publicKey{}, 0, fmt.Errorf("%w: invalid RSA key", errUnsupportedKey)
+		}
+		return publicKey{alg: alg, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}}, n, nil
+	}
+	return publicKey{}, 0, fmt.Errorf("%w: kty %d alg %d", errUnsupportedKey, kty, alg)
+}
+
+// verify check the signature of message, ECDSA signatures are ASN.1 encoded as WebAuthn mandates
+func (p publicKey) verify(message []byte, signature []byte) bool {
+	switch key := p.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
@@ -0,0 +1,392 @@
+// Package webauthn implements the relying party side of the WebAuthn registration and authentication ceremonies.
+// Attestation statements are not verified, the options request "none" conveyance so any authenticator is accepted.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidResponse = errors.New("invalid webauthn response")
+	// ErrSignCount is returned when the signature counter did not increase, the credential may have been cloned
+	ErrSignCount = errors.New("webauthn signature counter did not increase")
+)
+
+// authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// User verification requirements of the options
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+type Config struct {
+	// RPID is the domain the credentials are scoped to
+	RPID   string
+	RPName string
+	// Origins are the exact origins the ceremonies are accepted from, e.g. https://example.com
+	Origins []string
+	// Timeout is the hint given to the browser, the challenge lifetime is enforced by the caller
+	Timeout time.Duration
+}
+
+// RelyingParty build the options sent to the browser and verify the credentials it returns
+type RelyingParty struct {
+	cfg      Config
+	rpIDHash [32]byte
+}
+
+func NewRelyingParty(cfg Config) *RelyingParty {
+	return &RelyingParty{
+		cfg:      cfg,
+		rpIDHash: sha256.Sum256([]byte(cfg.RPID)),
+	}
+}
+
+// NewChallenge return a random challenge encoded in base64url
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// User is the account a credential is registered for, ID is returned as the user handle on authentication
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// CredentialDescriptor identifies a credential in the options, ID is encoded in base64url
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// NewCredentialDescriptor describe the public key credential with the given base64url id
+func NewCredentialDescriptor(id string, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: "public-key", ID: id, Transports: transports}
+}
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the publicKey options of navigator.credentials.create, binary fields are encoded in base64url
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the publicKey options of navigator.credentials.get, binary fields are encoded in base64url
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions return the options to register a new credential for user, the exclude credentials
+// prevent registering the same authenticator twice. Resident keys are preferred so the credential can
+// be used without typing an email
+func (r *RelyingParty) CreationOptions(challenge string, user User, exclude []CredentialDescriptor) CreationOptions {
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return CreationOptions{
+		Challenge: challenge,
+		RP:        rpEntity{ID: r.cfg.RPID, Name: r.cfg.RPName},
+		User: userEntity{
+			ID:          base64.RawURLEncoding.EncodeToString(user.ID),
+			Name:        user.Name,
+			DisplayName: user.DisplayName,
+		},
+		PubKeyCredParams: []credentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            r.cfg.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: UserVerificationPreferred,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions return the options to authenticate with one of the allowed credentials,
+// an empty allow list lets the authenticator pick any discoverable credential
+func (r *RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor, userVerification string) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             r.cfg.RPID,
+		Timeout:          r.cfg.Timeout.Milliseconds(),
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// AttestationResponse is the PublicKeyCredential returned by navigator.credentials.create,
+// binary fields are encoded in base64url
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the PublicKeyCredential returned by navigator.credentials.get,
+// binary fields are encoded in base64url
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential is a verified new credential, PublicKey is kept in its COSE encoding
+type Credential struct {
+	ID           []byte
+	PublicKey    []byte
+	SignCount    uint32
+	Transports   []string
+	UserVerified bool
+}
+
+// Assertion is the result of a verified authentication
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+	UserHandle   []byte
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// VerifyRegistration check the response of navigator.credentials.create against the challenge it was created with
+func (r *RelyingParty) VerifyRegistration(challenge string, response AttestationResponse) (Credential, error) {
+	if response.Type != "public-key" {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: unexpected type %q", ErrInvalidResponse, response.Type)
+	}
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: client data: %w", ErrInvalidResponse, err)
+	}
+	if err = r.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w", err)
+	}
+	attestationObject, err := decodeBase64URL(response.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: attestation object: %w", ErrInvalidResponse, err)
+	}
+	raw, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: attestation object: %w", ErrInvalidResponse, err)
+	}
+	attestation, ok := raw.(map[any]any)
+	if !ok {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: attestation object is not a map", ErrInvalidResponse)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: missing authenticator data", ErrInvalidResponse)
+	}
+	authData, err := r.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w", err)
+	}
+	if authData.credentialID == nil {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: missing attested credential", ErrInvalidResponse)
+	}
+	if response.ID != "" && response.ID != base64.RawURLEncoding.EncodeToString(authData.credentialID) {
+		return Credential{}, fmt.Errorf("webauthn.VerifyRegistration: %w: credential id mismatch", ErrInvalidResponse)
+	}
+	return Credential{
+		ID:           authData.credentialID,
+		PublicKey:    authData.publicKey,
+		SignCount:    authData.signCount,
+		Transports:   response.Response.Transports,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion check the response of navigator.credentials.get against the challenge it was created with
+// and the stored public key of the credential. signCount is the last counter seen for the credential,
+// ErrSignCount is returned if the authenticator reports a counter that did not increase
+func (r *RelyingParty) VerifyAssertion(challenge string, response AssertionResponse, storedPublicKey []byte, signCount uint32) (Assertion, error) {
+	if response.Type != "public-key" {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w: unexpected type %q", ErrInvalidResponse, response.Type)
+	}
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w: client data: %w", ErrInvalidResponse, err)
+	}
+	if err = r.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w", err)
+	}
+	rawAuthData, err := decodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w: authenticator data: %w", ErrInvalidResponse, err)
+	}
+	authData, err := r.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w", err)
+	}
+	signature, err := decodeBase64URL(response.Response.Signature)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w: signature: %w", ErrInvalidResponse, err)
+	}
+	userHandle, err := decodeBase64URL(response.Response.UserHandle)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w: user handle: %w", ErrInvalidResponse, err)
+	}
+	key, _, err := parsePublicKey(storedPublicKey)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w", err)
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if !key.verify(signed, signature) {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w: bad signature", ErrInvalidResponse)
+	}
+	// authenticators without a counter always report zero
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return Assertion{}, fmt.Errorf("webauthn.VerifyAssertion: %w", ErrSignCount)
+	}
+	return Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		UserHandle:   userHandle,
+	}, nil
+}
+
+func (r *RelyingParty) verifyClientData(raw []byte, ceremony string, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: client data: %w", ErrInvalidResponse, err)
+	}
+	switch {
+	case data.Type != ceremony:
+		return fmt.Errorf("%w: unexpected ceremony %q", ErrInvalidResponse, data.Type)
+	case subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")), []byte(challenge)) != 1:
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidResponse)
+	case !slices.Contains(r.cfg.Origins, data.Origin):
+		return fmt.Errorf("%w: unexpected origin %q", ErrInvalidResponse, data.Origin)
+	case data.CrossOrigin:
+		return fmt.Errorf("%w: cross origin ceremony", ErrInvalidResponse)
+	}
+	return nil
+}
+
+// parseAuthenticatorData decode the authenticator data and check it is scoped to the relying party
+// and the user was present
+func (r *RelyingParty) parseAuthenticatorData(data []byte) (authenticatorData, error) {
+	if len(data) < 37 {
+		return authenticatorData{}, fmt.Errorf("%w: authenticator data too short", ErrInvalidResponse)
+	}
+	authData := authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if !bytes.Equal(authData.rpIDHash, r.rpIDHash[:]) {
+		return authenticatorData{}, fmt.Errorf("%w: rp id mismatch", ErrInvalidResponse)
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return authenticatorData{}, fmt.Errorf("%w: user not present", ErrInvalidResponse)
+	}
+	if authData.flags&flagAttested == 0 {
+		return authData, nil
+	}
+	// attested credential data: aaguid (16), credential id length (2), credential id, COSE public key
+	rest := data[37:]
+	if len(rest) < 18 {
+		return authenticatorData{}, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+	}
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+		return authenticatorData{}, fmt.Errorf("%w: invalid credential id", ErrInvalidResponse)
+	}
+	authData.credentialID = append([]byte(nil), rest[:idLen]...)
+	rest = rest[idLen:]
+	_, keyLen, err := parsePublicKey(rest)
+	if err != nil {
+		return authenticatorData{}, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	authData.publicKey = append([]byte(nil), rest[:keyLen]...)
+	return authData, nil
+}
+
+// decodeBase64URL accept base64url with or without padding, as browsers and libraries differ
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,242 @@
+package webauthn_test
+
+import (
+	"auth-service/internal/webauthn"
+	"auth-service/internal/webauthn/webauthntest"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+const (
+	testRPID      = "example.com"
+	testOrigin    = "https://example.com"
+	testChallenge = "dGhlIGNoYWxsZW5nZSBvZiB0aGUgdGVzdHM"
+)
+
+func newRelyingParty() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty(webauthn.Config{
+		RPID:    testRPID,
+		RPName:  "Example",
+		Origins: []string{testOrigin},
+	})
+}
+
+type newAuthenticatorFunc func(rpID string, origin string) (*webauthntest.Authenticator, error)
+
+var authenticators = map[string]newAuthenticatorFunc{
+	"ES256":   webauthntest.NewES256,
+	"Ed25519": webauthntest.NewEd25519,
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare change the authenticator before the ceremony, response the response it returned
+		prepare      func(a *webauthntest.Authenticator)
+		response     func(a *webauthntest.Authenticator, r *webauthn.AttestationResponse)
+		challenge    string
+		wantErr      bool
+		userVerified bool
+	}{
+		{name: "valid"},
+		{name: "user verified", prepare: func(a *webauthntest.Authenticator) { a.UserVerified = true }, userVerified: true},
+		{name: "signature counter", prepare: func(a *webauthntest.Authenticator) { a.SignCount = 7 }},
+		{name: "padded challenge", response: func(a *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			r.Response.ClientDataJSON = encode(a.ClientDataJSON("webauthn.create", testChallenge+"="))
+		}},
+		{name: "challenge mismatch", challenge: "YW5vdGhlciBjaGFsbGVuZ2U", wantErr: true},
+		{name: "rp id mismatch", prepare: func(a *webauthntest.Authenticator) { a.RPID = "evil.example" }, wantErr: true},
+		{name: "origin mismatch", prepare: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example" }, wantErr: true},
+		{name: "cross origin", prepare: func(a *webauthntest.Authenticator) { a.CrossOrigin = true }, wantErr: true},
+		{name: "user not present", prepare: func(a *webauthntest.Authenticator) { a.UserPresent = false }, wantErr: true},
+		{name: "authentication ceremony", response: func(a *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			r.Response.ClientDataJSON = encode(a.ClientDataJSON("webauthn.get", testChallenge))
+		}, wantErr: true},
+		{name: "wrong type", response: func(_ *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			r.Type = "password"
+		}, wantErr: true},
+		{name: "credential id mismatch", response: func(_ *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			r.ID = encode([]byte("another credential"))
+		}, wantErr: true},
+		{name: "missing attested credential", response: func(a *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			r.Response.AttestationObject = encode(webauthntest.AttestationObject(a.AuthenticatorData(false)))
+		}, wantErr: true},
+		{name: "truncated attestation object", response: func(_ *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			object, _ := base64.RawURLEncoding.DecodeString(r.Response.AttestationObject)
+			r.Response.AttestationObject = encode(object[:len(object)-10])
+		}, wantErr: true},
+		{name: "truncated public key", response: func(a *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			authData := a.AuthenticatorData(true)
+			r.Response.AttestationObject = encode(webauthntest.AttestationObject(authData[:len(authData)-5]))
+		}, wantErr: true},
+		{name: "truncated credential id", response: func(a *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			authData := a.AuthenticatorData(true)
+			r.Response.AttestationObject = encode(webauthntest.AttestationObject(authData[:37+18+4]))
+		}, wantErr: true},
+		{name: "invalid base64", response: func(_ *webauthntest.Authenticator, r *webauthn.AttestationResponse) {
+			r.Response.ClientDataJSON = "not base64!"
+		}, wantErr: true},
+	}
+	rp := newRelyingParty()
+	for alg, newAuthenticator := range authenticators {
+		for _, tt := range tests {
+			t.Run(alg+"/"+tt.name, func(t *testing.T) {
+				a, err := newAuthenticator(testRPID, testOrigin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.prepare != nil {
+					tt.prepare(a)
+				}
+				response := a.Create(testChallenge)
+				if tt.response != nil {
+					tt.response(a, &response)
+				}
+				challenge := testChallenge
+				if tt.challenge != "" {
+					challenge = tt.challenge
+				}
+				credential, err := rp.VerifyRegistration(challenge, response)
+				if tt.wantErr {
+					if !errors.Is(err, webauthn.ErrInvalidResponse) {
+						t.Fatalf("VerifyRegistration() error = %v, want %v", err, webauthn.ErrInvalidResponse)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("VerifyRegistration() error = %v", err)
+				}
+				if !bytes.Equal(credential.ID, a.CredentialID) {
+					t.Errorf("VerifyRegistration() ID = %x, want %x", credential.ID, a.CredentialID)
+				}
+				if !bytes.Equal(credential.PublicKey, a.PublicKey()) {
+					t.Errorf("VerifyRegistration() PublicKey = %x, want %x", credential.PublicKey, a.PublicKey())
+				}
+				if credential.SignCount != a.SignCount {
+					t.Errorf("VerifyRegistration() SignCount = %d, want %d", credential.SignCount, a.SignCount)
+				}
+				if credential.UserVerified != tt.userVerified {
+					t.Errorf("VerifyRegistration() UserVerified = %v, want %v", credential.UserVerified, tt.userVerified)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	tests := []struct {
+		name string
+		// storedSignCount is the counter of the last authentication, signCount the one the authenticator reports
+		storedSignCount uint32
+		signCount       uint32
+		prepare         func(a *webauthntest.Authenticator)
+		response        func(a *webauthntest.Authenticator, r *webauthn.AssertionResponse)
+		challenge       string
+		wantErr         error
+		userVerified    bool
+	}{
+		{name: "valid"},
+		{name: "user verified", prepare: func(a *webauthntest.Authenticator) { a.UserVerified = true }, userVerified: true},
+		{name: "counter increased", storedSignCount: 4, signCount: 5},
+		{name: "counter went backwards", storedSignCount: 10, signCount: 3, wantErr: webauthn.ErrSignCount},
+		{name: "counter unchanged", storedSignCount: 10, signCount: 10, wantErr: webauthn.ErrSignCount},
+		{name: "counter reset to zero", storedSignCount: 10, signCount: 0, wantErr: webauthn.ErrSignCount},
+		{name: "challenge mismatch", challenge: "YW5vdGhlciBjaGFsbGVuZ2U", wantErr: webauthn.ErrInvalidResponse},
+		{name: "rp id mismatch", prepare: func(a *webauthntest.Authenticator) { a.RPID = "evil.example" }, wantErr: webauthn.ErrInvalidResponse},
+		{name: "origin mismatch", prepare: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.example" }, wantErr: webauthn.ErrInvalidResponse},
+		{name: "cross origin", prepare: func(a *webauthntest.Authenticator) { a.CrossOrigin = true }, wantErr: webauthn.ErrInvalidResponse},
+		{name: "user not present", prepare: func(a *webauthntest.Authenticator) { a.UserPresent = false }, wantErr: webauthn.ErrInvalidResponse},
+		{name: "registration ceremony", response: func(a *webauthntest.Authenticator, r *webauthn.AssertionResponse) {
+			clientDataJSON := a.ClientDataJSON("webauthn.create", testChallenge)
+			signature, _ := a.Sign(a.AuthenticatorData(false), clientDataJSON)
+			r.Response.ClientDataJSON = encode(clientDataJSON)
+			r.Response.Signature = encode(signature)
+		}, wantErr: webauthn.ErrInvalidResponse},
+		{name: "tampered authenticator data", response: func(a *webauthntest.Authenticator, r *webauthn.AssertionResponse) {
+			a.UserVerified = true
+			r.Response.AuthenticatorData = encode(a.AuthenticatorData(false))
+		}, wantErr: webauthn.ErrInvalidResponse},
+		{name: "signed by another key", response: func(a *webauthntest.Authenticator, r *webauthn.AssertionResponse) {
+			other, _ := webauthntest.NewES256(testRPID, testOrigin)
+			signature, _ := other.Sign(a.AuthenticatorData(false), a.ClientDataJSON("webauthn.get", testChallenge))
+			r.Response.Signature = encode(signature)
+		}, wantErr: webauthn.ErrInvalidResponse},
+		{name: "truncated authenticator data", response: func(a *webauthntest.Authenticator, r *webauthn.AssertionResponse) {
+			r.Response.AuthenticatorData = encode(a.AuthenticatorData(false)[:36])
+		}, wantErr: webauthn.ErrInvalidResponse},
+		{name: "wrong type", response: func(_ *webauthntest.Authenticator, r *webauthn.AssertionResponse) {
+			r.Type = "password"
+		}, wantErr: webauthn.ErrInvalidResponse},
+	}
+	rp := newRelyingParty()
+	for alg, newAuthenticator := range authenticators {
+		for _, tt := range tests {
+			t.Run(alg+"/"+tt.name, func(t *testing.T) {
+				a, err := newAuthenticator(testRPID, testOrigin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				a.UserHandle = []byte("user-id")
+				a.SignCount = tt.signCount
+				// the public key is stored at registration, before the test changes the authenticator
+				publicKey := a.PublicKey()
+				if tt.prepare != nil {
+					tt.prepare(a)
+				}
+				response, err := a.Get(testChallenge)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.response != nil {
+					tt.response(a, &response)
+				}
+				challenge := testChallenge
+				if tt.challenge != "" {
+					challenge = tt.challenge
+				}
+				assertion, err := rp.VerifyAssertion(challenge, response, publicKey, tt.storedSignCount)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("VerifyAssertion() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("VerifyAssertion() error = %v", err)
+				}
+				if assertion.SignCount != tt.signCount {
+					t.Errorf("VerifyAssertion() SignCount = %d, want %d", assertion.SignCount, tt.signCount)
+				}
+				if assertion.UserVerified != tt.userVerified {
+					t.Errorf("VerifyAssertion() UserVerified = %v, want %v", assertion.UserVerified, tt.userVerified)
+				}
+				if !bytes.Equal(assertion.UserHandle, a.UserHandle) {
+					t.Errorf("VerifyAssertion() UserHandle = %q, want %q", assertion.UserHandle, a.UserHandle)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyAssertionTruncatedPublicKey(t *testing.T) {
+	a, err := webauthntest.NewES256(testRPID, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := a.Get(testChallenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := a.PublicKey()
+	for n := range len(publicKey) {
+		if _, err := newRelyingParty().VerifyAssertion(testChallenge, response, publicKey[:n], 0); err == nil {
+			t.Fatalf("VerifyAssertion() with %d of the %d bytes of the public key succeeded", n, len(publicKey))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package webauthntest provides a software authenticator to run the WebAuthn ceremonies in unit tests.
+package webauthntest
+
+import (
+	"auth-service/internal/webauthn"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// Authenticator holds a single credential and answers the ceremonies of the relying party RPID from Origin.
+// The fields can be changed between ceremonies to produce invalid responses
+type Authenticator struct {
+	RPID   string
+	Origin string
+	// CrossOrigin is reported in the client data, as for a ceremony run in a cross-origin iframe
+	CrossOrigin  bool
+	CredentialID []byte
+	// UserHandle is returned on authentication, it is the user id of the creation options
+	UserHandle []byte
+	// SignCount is reported as is, it is not increased by the ceremonies
+	SignCount uint32
+	// UserPresent and UserVerified set the UP and UV flags of the authenticator data
+	UserPresent  bool
+	UserVerified bool
+
+	alg    int64
+	signer crypto.Signer
+}
+
+func newAuthenticator(rpID string, origin string, alg int64, signer crypto.Signer) (*Authenticator, error) {
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: credentialID,
+		UserPresent:  true,
+		alg:          alg,
+		signer:       signer,
+	}, nil
+}
+
+// NewES256 return an authenticator with a P-256 ECDSA credential
+func NewES256(rpID string, origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("webauthntest.NewES256: %w", err)
+	}
+	return newAuthenticator(rpID, origin, webauthn.AlgES256, key)
+}
+
+// NewEd25519 return an authenticator with an Ed25519 credential
+func NewEd25519(rpID string, origin string) (*Authenticator, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("webauthntest.NewEd25519: %w", err)
+	}
+	return newAuthenticator(rpID, origin, webauthn.AlgEdDSA, key)
+}
+
+// PublicKey return the COSE encoding of the credential public key
+func (a *Authenticator) PublicKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x := make([]byte, 32)
+		y := make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return encodeCBOR(map[int64]any{1: int64(2), 3: webauthn.AlgES256, -1: int64(1), -2: x, -3: y})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[int64]any{1: int64(1), 3: webauthn.AlgEdDSA, -1: int64(6), -2: []byte(key)})
+	}
+	return nil
+}
+
+// ClientDataJSON return the client data the browser collects for the ceremony, webauthn.create or webauthn.get
+func (a *Authenticator) ClientDataJSON(ceremony string, challenge string) []byte {
+	data, _ := json.Marshal(struct {
+		Type        string `json:"type"`
+		Challenge   string `json:"challenge"`
+		Origin      string `json:"origin"`
+		CrossOrigin bool   `json:"crossOrigin"`
+	}{
+		Type:        ceremony,
+		Challenge:   challenge,
+		Origin:      a.Origin,
+		CrossOrigin: a.CrossOrigin,
+	})
+	return data
+}
+
+// AuthenticatorData return the authenticator data, with the attested credential data if attested
+func (a *Authenticator) AuthenticatorData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	var flags byte
+	if a.UserPresent {
+		flags |= flagUserPresent
+	}
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+	if attested {
+		flags |= flagAttested
+	}
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+	if attested {
+		// an empty aaguid, as authenticators asked for "none" attestation report
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.CredentialID)))
+		data = append(data, a.CredentialID...)
+		data = append(data, a.PublicKey()...)
+	}
+	return data
+}
+
+// AttestationObject return the attestation object of the "none" format wrapping authData
+func AttestationObject(authData []byte) []byte {
+	return encodeCBOR(map[string]any{"fmt": "none", "attStmt": map[string]any{}, "authData": authData})
+}
+
+// Create answer navigator.credentials.create for the challenge
+func (a *Authenticator) Create(challenge string) webauthn.AttestationResponse {
+	var response webauthn.AttestationResponse
+	response.ID = base64.RawURLEncoding.EncodeToString(a.CredentialID)
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(a.ClientDataJSON("webauthn.create", challenge))
+	response.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(AttestationObject(a.AuthenticatorData(true)))
+	response.Response.Transports = []string{"internal"}
+	return response
+}
+
+// Get answer navigator.credentials.get for the challenge
+func (a *Authenticator) Get(challenge string) (webauthn.AssertionResponse, error) {
+	clientDataJSON := a.ClientDataJSON("webauthn.get", challenge)
+	authData := a.AuthenticatorData(false)
+	signature, err := a.Sign(authData, clientDataJSON)
+	if err != nil {
+		return webauthn.AssertionResponse{}, fmt.Errorf("Authenticator.Get: %w", err)
+	}
+	var response webauthn.AssertionResponse
+	response.ID = base64.RawURLEncoding.EncodeToString(a.CredentialID)
+	response.Type = "public-key"
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientDataJSON)
+	response.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	response.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	response.Response.UserHandle = base64.RawURLEncoding.EncodeToString(a.UserHandle)
+	return response, nil
+}
+
+// Sign return the assertion signature of authData and the hash of clientDataJSON
+func (a *Authenticator) Sign(authData []byte, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	message := append(append([]byte{}, authData...), clientDataHash[:]...)
+	if a.alg == webauthn.AlgEdDSA {
+		return a.signer.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(message)
+	return a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
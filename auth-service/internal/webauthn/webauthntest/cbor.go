@@ -0,0 +1,63 @@
+package webauthntest
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// encodeCBOR encode the values authenticators produce: integers, byte and text strings and maps of them.
+// Map keys are written in a stable order so the encoding of a value never changes
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case map[int64]any:
+		keys := make([]int64, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		data := cborHeader(5, uint64(len(v)))
+		for _, key := range keys {
+			data = append(data, encodeCBOR(key)...)
+			data = append(data, encodeCBOR(v[key])...)
+		}
+		return data
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		data := cborHeader(5, uint64(len(v)))
+		for _, key := range keys {
+			data = append(data, encodeCBOR(key)...)
+			data = append(data, encodeCBOR(v[key])...)
+		}
+		return data
+	}
+	panic("webauthntest: unsupported cbor value")
+}
+
+// cborHeader return the initial byte of the major type with its argument in the shortest form
+func cborHeader(major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return []byte{major | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major | 27}, arg)
+}
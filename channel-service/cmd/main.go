@@ -2,7 +2,9 @@ package main
 
 import (
 	"channel-service/internal/api/handler"
+	"channel-service/internal/api/middleware"
 	"channel-service/internal/api/route"
+	"channel-service/internal/auth"
 	"channel-service/internal/client"
 	"channel-service/internal/config"
 	"channel-service/internal/infra"
@@ -80,24 +82,31 @@ func main() {
 	}
 
 	channelRepo := repo.NewChannelRepository(db, esClient)
-	channelService := service.NewChannelService(channelRepo, logger, minioClient, appConfig.Minio.Endpoint)
+	streamRepo := repo.NewStreamRepository(esClient)
+	channelService := service.NewChannelService(channelRepo, streamRepo, logger, minioClient, appConfig.Minio.Endpoint)
 	channelHandler := handler.NewChannelHandler(logger, channelService)
 
 	categoryRepo := repo.NewCategoryRepository(esClient)
 	categoryService := service.NewCategoryService(categoryRepo)
 	categoryHandler := handler.NewCategoryHandler(logger, categoryService)
 
-	streamRepo := repo.NewStreamRepository(esClient)
-	chatClient := client.NewChatClient(appConfig.Server.ChatServerUrl)
+	serviceTokenSource := client.NewServiceTokenSource(appConfig.Auth.TokenURL, appConfig.Auth.ClientID, appConfig.Auth.ClientSecret, auth.ScopeChatThreadsWrite)
+	chatClient := client.NewChatClient(appConfig.Server.ChatServerUrl, serviceTokenSource)
 	streamService := service.NewStreamService(channelService, categoryService, streamRepo, appConfig.Server.SrtServerUrl, appConfig.Server.HlsServerUrl, chatClient)
 	streamHandler := handler.NewStreamHandler(logger, streamService)
 
+	authMiddleware := middleware.NewAuthMiddleware(
+		auth.NewJWKS(appConfig.Auth.JWKSURL, appConfig.Auth.JWKSCacheTTL),
+		auth.NewVerifier(appConfig.Auth.VerifyURL),
+		appConfig.Policy.RequireVerifiedEmail,
+	)
+
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
-	route.SetUpChannelRoutes(r, channelHandler)
+	route.SetUpChannelRoutes(r, channelHandler, authMiddleware)
 	route.SetUpCategoryRoutes(r, categoryHandler)
-	route.SetUpStreamRoutes(r, streamHandler)
+	route.SetUpStreamRoutes(r, streamHandler, authMiddleware)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", appConfig.Server.Port),
@@ -0,0 +1,6 @@
+package request
+
+type TransferChannelRequest struct {
+	// ToUserID is the user who becomes the owner of the channel
+	ToUserID string `json:"to_user_id" binding:"required"`
+}
@@ -0,0 +1,6 @@
+package response
+
+type UserDataResponse struct {
+	Channel *ChannelResponse `json:"channel"`
+	Streams []StreamResponse `json:"streams"`
+}
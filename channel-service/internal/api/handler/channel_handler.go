@@ -21,6 +21,9 @@ type ChannelHandler interface {
 	GetChannelByID() gin.HandlerFunc
 	GetChannelBySearchText() gin.HandlerFunc
 	SetChannelAvatar() gin.HandlerFunc
+	TransferChannel() gin.HandlerFunc
+	ExportUserData() gin.HandlerFunc
+	DeleteUserData() gin.HandlerFunc
 }
 
 type channelHandler struct {
@@ -176,6 +179,48 @@ func (ch *channelHandler) UpdateChannelByID() gin.HandlerFunc {
 	}
 }
 
+func (ch *channelHandler) TransferChannel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.TransferChannelRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validatorError validator.ValidationErrors
+			if errors.As(err, &validatorError) {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: ch.formatValidationError(validatorError[0]),
+				})
+			} else {
+				c.JSON(http.StatusBadRequest, response.Response{
+					Message: "invalid request body",
+				})
+			}
+			return
+		}
+		id := c.Param("id")
+		err := ch.channelService.TransferChannel(c, id, req.ToUserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, apperrors.ErrChannelNotFound):
+				c.JSON(http.StatusNotFound, response.Response{
+					Error: "channel not found",
+				})
+			case errors.Is(err, apperrors.ErrChannelAlreadyExists):
+				c.JSON(http.StatusConflict, response.Response{
+					Error: "the user already owns a channel",
+				})
+			default:
+				ch.logger.Error(err.Error())
+				c.JSON(http.StatusInternalServerError, response.Response{
+					Error: "internal server error",
+				})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "channel transferred successfully",
+		})
+	}
+}
+
 func (ch *channelHandler) GetChannelByID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
@@ -203,6 +248,59 @@ func (ch *channelHandler) GetChannelByID() gin.HandlerFunc {
 	}
 }
 
+func (ch *channelHandler) ExportUserData() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := ch.channelService.ExportUserData(c, c.Param("id"))
+		if err != nil {
+			ch.logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Error: "internal server error",
+			})
+			return
+		}
+		res := response.UserDataResponse{
+			Streams: make([]response.StreamResponse, 0, len(data.Streams)),
+		}
+		if data.Channel != nil {
+			res.Channel = &response.ChannelResponse{
+				ID:          data.Channel.ID,
+				Title:       data.Channel.Title,
+				Description: data.Channel.Description,
+			}
+		}
+		for _, stream := range data.Streams {
+			res.Streams = append(res.Streams, response.StreamResponse{
+				ID:           stream.ID,
+				Title:        stream.Title,
+				HlsURL:       stream.HlsURL,
+				LiveChatURL:  stream.LiveChatURL,
+				SrtServerURL: stream.SrtServerURL,
+				Description:  stream.Description,
+				Status:       stream.Status,
+				Channel:      stream.Channel,
+				Category:     stream.Category,
+			})
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+func (ch *channelHandler) DeleteUserData() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := ch.channelService.DeleteUserData(c, c.Param("id"))
+		if err != nil {
+			ch.logger.Error(err.Error())
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Error: "internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, response.Response{
+			Message: "user data deleted successfully",
+		})
+	}
+}
+
 func NewChannelHandler(logger *zap.Logger, channelService service.ChannelService) ChannelHandler {
 	return &channelHandler{
 		logger:         logger,
@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -63,10 +62,7 @@ func (s *streamHandler) CreateStream() gin.HandlerFunc {
 		stream.Category.ID = req.CategoryID
 		stream.Channel.ID = id
 
-		authHeader := c.GetHeader("Authorization")
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		token = strings.TrimSpace(token)
-		newStream, err := s.streamService.CreateStream(c, stream, token)
+		newStream, err := s.streamService.CreateStream(c, stream)
 		if err != nil {
 			s.logger.Error("error creating stream", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, response.Response{
@@ -1,29 +1,179 @@
 package middleware
 
 import (
+	"channel-service/internal/auth"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthMiddleware interface {
-	CheckUserPermission(requiredRole string) gin.HandlerFunc
+	// ValidateAndExtractJwt verify the bearer token or access_token cookie against the auth-service JWKS and
+	// overwrite the X-User-Id, X-User-Role and X-User-Scopes headers with its claims.
+	// Personal access tokens are resolved by auth-service instead
+	ValidateAndExtractJwt() gin.HandlerFunc
+	// ValidateServiceJwt only let through the service tokens of the backend services and overwrite the X-Client-Id
+	// and X-User-Scopes headers with their claims, user tokens are rejected
+	ValidateServiceJwt() gin.HandlerFunc
+	// RequireScopes reject requests whose X-User-Scopes header does not contain every one of the scopes
+	RequireScopes(scopes ...string) gin.HandlerFunc
+	// RequireVerifiedEmail reject users who have not verified their email, if the policy is enabled
+	RequireVerifiedEmail() gin.HandlerFunc
 }
 
-type authMiddleware struct{}
+type authMiddleware struct {
+	jwks                 *auth.JWKS
+	verifier             *auth.Verifier
+	requireVerifiedEmail bool
+}
+
+type userClaims struct {
+	Type          string `json:"typ"`
+	UserID        string `json:"user_id"`
+	Role          string `json:"role"`
+	Scope         string `json:"scope"`
+	EmailVerified bool   `json:"email_verified"`
+	// ClientID is set on the access tokens auth-service grants to third-party apps, they are rejected
+	ClientID string `json:"client_id"`
+	jwt.RegisteredClaims
+}
+
+type serviceClaims struct {
+	Type     string `json:"typ"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// extractToken return the bearer token of the Authorization header, or the access_token cookie of the cookie session
+// mode of auth-service. Requests authenticated by cookie that change state must carry the double-submit CSRF token
+func (a authMiddleware) extractToken(c *gin.Context) (string, bool) {
+	if c.GetHeader("Authorization") == "" {
+		token, err := c.Cookie("access_token")
+		if err != nil || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": "Authorization header is invalid",
+			})
+			return "", false
+		}
+		if !a.checkCSRF(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "Invalid CSRF token",
+			})
+			return "", false
+		}
+		return token, true
+	}
+	header := strings.Fields(c.GetHeader("Authorization"))
+	if len(header) != 2 || header[0] != "Bearer" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "Authorization header is invalid",
+		})
+		return "", false
+	}
+	return header[1], true
+}
+
+func (authMiddleware) checkCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	cookie, err := c.Cookie("csrf_token")
+	if err != nil || cookie == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(c.GetHeader("X-CSRF-Token"))) == 1
+}
+
+func (a authMiddleware) ValidateAndExtractJwt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken, ok := a.extractToken(c)
+		if !ok {
+			return
+		}
+		if strings.HasPrefix(accessToken, auth.PersonalAccessTokenPrefix) {
+			a.verifyPersonalAccessToken(c, accessToken)
+			return
+		}
+		var claims userClaims
+		token, err := jwt.ParseWithClaims(accessToken, &claims, a.jwks.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+			jwt.WithLeeway(10*time.Second))
+		if err != nil || !token.Valid || claims.Type != "access" || claims.ClientID != "" || claims.UserID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": "Invalid access token",
+			})
+			return
+		}
+		c.Request.Header.Set("X-User-Id", claims.UserID)
+		c.Request.Header.Set("X-User-Role", claims.Role)
+		c.Request.Header.Set("X-User-Scopes", claims.Scope)
+		c.Request.Header.Set("X-User-Email-Verified", strconv.FormatBool(claims.EmailVerified))
+		c.Next()
+	}
+}
 
-func (a authMiddleware) CheckUserPermission(requiredRole string) gin.HandlerFunc {
+func (a authMiddleware) ValidateServiceJwt() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role := c.Request.Header.Get("X-User-Role")
-		if len(role) == 0 {
+		header := strings.Fields(c.GetHeader("Authorization"))
+		if len(header) != 2 || header[0] != "Bearer" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"message": "X-User-Role header is empty",
+				"message": "Authorization header is invalid",
 			})
 			return
 		}
-		if role != requiredRole {
+		var claims serviceClaims
+		token, err := jwt.ParseWithClaims(header[1], &claims, a.jwks.Keyfunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+			jwt.WithLeeway(10*time.Second),
+			jwt.WithExpirationRequired())
+		if err != nil || !token.Valid || claims.Type != "service" || claims.ClientID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": "Invalid service token",
+			})
+			return
+		}
+		c.Request.Header.Del("X-User-Id")
+		c.Request.Header.Set("X-Client-Id", claims.ClientID)
+		c.Request.Header.Set("X-User-Scopes", claims.Scope)
+		c.Next()
+	}
+}
+
+func (a authMiddleware) verifyPersonalAccessToken(c *gin.Context, token string) {
+	identity, err := a.verifier.Verify(c, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"message": "Permission denied",
+				"message": "Invalid access token",
+			})
+		} else {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "internal server error",
+			})
+		}
+		return
+	}
+	c.Request.Header.Set("X-User-Id", identity.UserID)
+	c.Request.Header.Set("X-User-Role", identity.Role)
+	c.Request.Header.Set("X-User-Scopes", identity.Scope)
+	c.Request.Header.Set("X-User-Email-Verified", strconv.FormatBool(identity.EmailVerified))
+	c.Next()
+}
+
+func (a authMiddleware) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.requireVerifiedEmail && c.Request.Header.Get("X-User-Email-Verified") != "true" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"message": "Email address must be verified",
 			})
 			return
 		}
@@ -31,6 +181,21 @@ func (a authMiddleware) CheckUserPermission(requiredRole string) gin.HandlerFunc
 	}
 }
 
-func NewAuthMiddleware() AuthMiddleware {
-	return &authMiddleware{}
+func (a authMiddleware) RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := strings.Fields(c.Request.Header.Get("X-User-Scopes"))
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"message": "Permission denied",
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func NewAuthMiddleware(jwks *auth.JWKS, verifier *auth.Verifier, requireVerifiedEmail bool) AuthMiddleware {
+	return &authMiddleware{jwks: jwks, verifier: verifier, requireVerifiedEmail: requireVerifiedEmail}
 }
@@ -2,17 +2,27 @@ package route
 
 import (
 	"channel-service/internal/api/handler"
+	"channel-service/internal/api/middleware"
+	"channel-service/internal/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetUpChannelRoutes(r *gin.Engine, h handler.ChannelHandler) {
+func SetUpChannelRoutes(r *gin.Engine, h handler.ChannelHandler, m middleware.AuthMiddleware) {
 	publicChannelRoutes := r.Group("/public/channels")
 	publicChannelRoutes.GET("/:id", h.GetChannelByID())
 	publicChannelRoutes.POST("/search", h.GetChannelBySearchText())
 
-	privateChannelRoutes := r.Group("/channels")
-	privateChannelRoutes.POST("", h.CreateChannel())
+	privateChannelRoutes := r.Group("/channels", m.ValidateAndExtractJwt())
+	privateChannelRoutes.POST("", m.RequireVerifiedEmail(), h.CreateChannel())
 	privateChannelRoutes.PATCH("/self", h.UpdateChannelByID())
 	privateChannelRoutes.PUT("/self/avatar", h.SetChannelAvatar())
+
+	// called by auth-service with its service token when an admin merges two users, the id is the source user's
+	r.POST("/channels/:id/transfer", m.ValidateServiceJwt(), m.RequireScopes(auth.ScopeChannelsTransfer), h.TransferChannel())
+
+	// called by auth-service with its service token when a user exports or deletes their account, the id is the user's
+	userDataRoutes := r.Group("/users/:id/data", m.ValidateServiceJwt())
+	userDataRoutes.GET("", m.RequireScopes(auth.ScopeUserDataRead), h.ExportUserData())
+	userDataRoutes.DELETE("", m.RequireScopes(auth.ScopeUserDataDelete), h.DeleteUserData())
 }
@@ -2,17 +2,19 @@ package route
 
 import (
 	"channel-service/internal/api/handler"
+	"channel-service/internal/api/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetUpStreamRoutes(r *gin.Engine, h handler.StreamHandler) {
+func SetUpStreamRoutes(r *gin.Engine, h handler.StreamHandler, m middleware.AuthMiddleware) {
 	publicStreamRoutes := r.Group("/public/streams")
 	publicStreamRoutes.GET("/:id", h.GetStreamByID())
 	publicStreamRoutes.POST("/search", h.GetStreamBySearchText())
 	publicStreamRoutes.GET("/channels/:id", h.GetStreamByChannelID())
 
 	privateStreamRoutes := r.Group("/streams")
-	privateStreamRoutes.POST("", h.CreateStream())
+	privateStreamRoutes.POST("", m.ValidateAndExtractJwt(), m.RequireVerifiedEmail(), h.CreateStream())
+	// called by OvenMediaEngine directly, not through the gateway
 	privateStreamRoutes.POST("/notify", h.OVMNotify())
 }
@@ -0,0 +1,13 @@
+package auth
+
+// ScopeChatThreadsWrite is the service scope channel-service requests to create the chat threads of streams
+const ScopeChatThreadsWrite = "chat:threads:write"
+
+// Service scopes auth-service is granted to export and erase the data of a user
+const (
+	ScopeUserDataRead   = "user_data:read"
+	ScopeUserDataDelete = "user_data:delete"
+)
+
+// ScopeChannelsTransfer is the service scope auth-service is granted to move a channel when it merges two users
+const ScopeChannelsTransfer = "channels:transfer"
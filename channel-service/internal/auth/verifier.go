@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// PersonalAccessTokenPrefix marks the opaque tokens of auth-service, they cannot be verified with the JWKS
+const PersonalAccessTokenPrefix = "lsp_pat_"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity is the user a token resolved to, as reported by auth-service
+type Identity struct {
+	UserID        string
+	Role          string
+	Scope         string
+	EmailVerified bool
+}
+
+// Verifier resolves tokens through the auth-service forward-auth endpoint.
+type Verifier struct {
+	verifyURL string
+	client    *http.Client
+}
+
+func NewVerifier(verifyURL string) *Verifier {
+	return &Verifier{
+		verifyURL: verifyURL,
+		client:    &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.verifyURL, nil)
+	if err != nil {
+		return Identity{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return Identity{}, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Identity{
+			UserID:        resp.Header.Get("X-User-Id"),
+			Role:          resp.Header.Get("X-User-Role"),
+			Scope:         resp.Header.Get("X-User-Scopes"),
+			EmailVerified: resp.Header.Get("X-User-Email-Verified") == "true",
+		}, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return Identity{}, ErrInvalidToken
+	default:
+		return Identity{}, fmt.Errorf("auth verify status %d", resp.StatusCode)
+	}
+}
@@ -10,15 +10,21 @@ import (
 )
 
 type ChatClient interface {
-	CreateRoomChat(ctx context.Context, jwtToken string, streamID string, endpoint string) (string, error)
+	// CreateRoomChat create the chat thread of a stream, authenticated as channel-service rather than as the user
+	CreateRoomChat(ctx context.Context, streamID string, endpoint string) (string, error)
 }
 
 type chatClient struct {
 	client        *http.Client
 	chatServerURL string
+	tokenSource   ServiceTokenSource
 }
 
-func (c *chatClient) CreateRoomChat(ctx context.Context, jwtToken string, streamID string, endpoint string) (string, error) {
+func (c *chatClient) CreateRoomChat(ctx context.Context, streamID string, endpoint string) (string, error) {
+	token, err := c.tokenSource.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get service token: %w", err)
+	}
 	requestUrl := fmt.Sprintf("%s%s", c.chatServerURL, endpoint)
 	body := struct {
 		StreamID string `json:"stream_id"`
@@ -33,7 +39,7 @@ func (c *chatClient) CreateRoomChat(ctx context.Context, jwtToken string, stream
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+jwtToken)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -52,9 +58,10 @@ func (c *chatClient) CreateRoomChat(ctx context.Context, jwtToken string, stream
 	return result.WsURL, nil
 }
 
-func NewChatClient(chatServerURL string) ChatClient {
+func NewChatClient(chatServerURL string, tokenSource ServiceTokenSource) ChatClient {
 	return &chatClient{
 		client:        &http.Client{},
 		chatServerURL: chatServerURL,
+		tokenSource:   tokenSource,
 	}
 }
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceTokenSource return the access token channel-service uses to call the other backend services.
+// The token is obtained from auth-service with the client credentials grant and cached until shortly before it expires
+type ServiceTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type serviceTokenSource struct {
+	client       *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// refreshMargin is how long before its expiry a cached token is replaced
+const refreshMargin = 30 * time.Second
+
+func (s *serviceTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Add(refreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(s.scopes) > 0 {
+		form.Set("scope", strings.Join(s.scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("service token status %d", resp.StatusCode)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	s.token = result.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+func NewServiceTokenSource(tokenURL string, clientID string, clientSecret string, scopes ...string) ServiceTokenSource {
+	return &serviceTokenSource{
+		client:       &http.Client{Timeout: 5 * time.Second},
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+	}
+}
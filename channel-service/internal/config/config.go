@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 )
@@ -10,6 +12,8 @@ type AppConfig struct {
 	Postgres PostgresConfig
 	Elastic  ElasticsearchConfig
 	Minio    MinioConfig
+	Auth     AuthConfig
+	Policy   PolicyConfig
 }
 
 type ServerConfig struct {
@@ -37,6 +41,22 @@ type MinioConfig struct {
 	SecretKey string `envconfig:"MINIO_SECRET_KEY" required:"true"`
 }
 
+type AuthConfig struct {
+	JWKSURL      string        `envconfig:"AUTH_JWKS_URL" default:"http://auth-service:8080/.well-known/jwks.json"`
+	JWKSCacheTTL time.Duration `envconfig:"AUTH_JWKS_CACHE_TTL" default:"10m"`
+	// VerifyURL resolves personal access tokens, which are not JWTs
+	VerifyURL string `envconfig:"AUTH_VERIFY_URL" default:"http://auth-service:8080/auth/verify"`
+	// TokenURL, ClientID and ClientSecret get the service token used to call chat-service
+	TokenURL     string `envconfig:"AUTH_TOKEN_URL" default:"http://auth-service:8080/oauth/token"`
+	ClientID     string `envconfig:"AUTH_CLIENT_ID" required:"true"`
+	ClientSecret string `envconfig:"AUTH_CLIENT_SECRET" required:"true"`
+}
+
+type PolicyConfig struct {
+	// RequireVerifiedEmail block users who have not verified their email from creating channels or streams
+	RequireVerifiedEmail bool `envconfig:"POLICY_REQUIRE_VERIFIED_EMAIL" default:"false"`
+}
+
 func LoadConfig(path string) (AppConfig, error) {
 	_ = godotenv.Load(path)
 
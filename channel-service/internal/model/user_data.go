@@ -0,0 +1,8 @@
+package model
+
+// UserData is what channel-service keeps about a user, it is exported on their request
+type UserData struct {
+	// Channel is nil if the user has no channel
+	Channel *Channel
+	Streams []Stream
+}
@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
 	"github.com/elastic/go-elasticsearch/v9"
 	"github.com/elastic/go-elasticsearch/v9/esapi"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -19,6 +21,10 @@ type ChannelRepository interface {
 	UpdateChannelByID(ctx context.Context, channel model.Channel) error
 	GetChannelByID(ctx context.Context, id string) (model.Channel, error)
 	GetChannelBySearchText(ctx context.Context, searchText string, limit, offset int) ([]model.Channel, error)
+	// TransferChannel change the id, i.e. the owner, of the channel fromID to toID
+	TransferChannel(ctx context.Context, fromID string, toID string) error
+	// DeleteChannel delete the channel id and its search document, nothing is done if it does not exist
+	DeleteChannel(ctx context.Context, id string) error
 }
 
 type channelRepository struct {
@@ -122,6 +128,53 @@ func (c *channelRepository) UpdateChannelByID(ctx context.Context, channel model
 	return nil
 }
 
+func (c *channelRepository) TransferChannel(ctx context.Context, fromID string, toID string) error {
+	result := c.db.WithContext(ctx).Model(&model.Channel{}).Where("id = ?", fromID).Updates(map[string]interface{}{
+		"id":         toID,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(result.Error, &pgErr) && pgErr.Code == "23505" {
+			if pgErr.ConstraintName == "channels_pkey" {
+				return apperrors.ErrChannelAlreadyExists
+			}
+		}
+		return fmt.Errorf("channelRepository.TransferChannel: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.ErrChannelNotFound
+	}
+	return nil
+}
+
+func (c *channelRepository) DeleteChannel(ctx context.Context, id string) error {
+	result := c.db.WithContext(ctx).Delete(&model.Channel{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("channelRepository.DeleteChannel: %w", result.Error)
+	}
+	// the pipeline also deletes the document once it gets the change, it is deleted right away so the channel stops
+	// showing up in the searches
+	req := esapi.DeleteRequest{
+		Index:      channelsIndex,
+		DocumentID: id,
+		Refresh:    "true",
+	}
+	resp, err := req.Do(ctx, c.es)
+	if err != nil {
+		return fmt.Errorf("channelRepository.DeleteChannel: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.IsError() && resp.StatusCode != 404 {
+		var e EsErrorResponse
+		if err = json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			return fmt.Errorf("channelRepository.DeleteChannel: %w", err)
+		}
+		return apperrors.NewElasticSearchError(resp.StatusCode, e.Error.Type, e.Error.Reason)
+	}
+	return nil
+}
+
 func (c *channelRepository) GetChannelByID(ctx context.Context, id string) (model.Channel, error) {
 	req := esapi.GetRequest{
 		Index:      channelsIndex,
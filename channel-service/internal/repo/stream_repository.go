@@ -18,6 +18,10 @@ type StreamRepository interface {
 	GetStreamByChannelID(ctx context.Context, channelID string, status string, limit int, offset int) ([]model.Stream, error)
 	UpdateStreamById(ctx context.Context, stream model.Stream) error
 	GetStreamBySearchText(ctx context.Context, searchText string, status string, limit int, offset int) ([]model.Stream, error)
+	// ReassignChannelStreams move every stream of the channel fromChannelID to toChannelID
+	ReassignChannelStreams(ctx context.Context, fromChannelID string, toChannelID string) error
+	// DeleteChannelStreams delete every stream of the channel channelID
+	DeleteChannelStreams(ctx context.Context, channelID string) error
 }
 
 type streamRepository struct {
@@ -330,6 +334,89 @@ func (s *streamRepository) GetStreamBySearchText(ctx context.Context, searchText
 	return streams, nil
 }
 
+func (s *streamRepository) ReassignChannelStreams(ctx context.Context, fromChannelID string, toChannelID string) error {
+	body := map[string]interface{}{
+		"query": map[string]interface{}{
+			"nested": map[string]interface{}{
+				"path": "channel",
+				"query": map[string]interface{}{
+					"term": map[string]interface{}{
+						"channel.id": fromChannelID,
+					},
+				},
+			},
+		},
+		"script": map[string]interface{}{
+			"source": "ctx._source.channel.id = params.channel_id",
+			"lang":   "painless",
+			"params": map[string]interface{}{
+				"channel_id": toChannelID,
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return fmt.Errorf("streamRepo.ReassignChannelStreams: %w", err)
+	}
+	res, err := s.es.UpdateByQuery(
+		[]string{streamsIndex},
+		s.es.UpdateByQuery.WithBody(&buf),
+		s.es.UpdateByQuery.WithConflicts("proceed"),
+		s.es.UpdateByQuery.WithRefresh(true),
+		s.es.UpdateByQuery.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("streamRepo.ReassignChannelStreams: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		var e EsErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return fmt.Errorf("streamRepo.ReassignChannelStreams: %w", err)
+		}
+		return apperrors.NewElasticSearchError(res.StatusCode, e.Error.Type, e.Error.Reason)
+	}
+	return nil
+}
+
+func (s *streamRepository) DeleteChannelStreams(ctx context.Context, channelID string) error {
+	body := map[string]interface{}{
+		"query": map[string]interface{}{
+			"nested": map[string]interface{}{
+				"path": "channel",
+				"query": map[string]interface{}{
+					"term": map[string]interface{}{
+						"channel.id": channelID,
+					},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return fmt.Errorf("streamRepo.DeleteChannelStreams: %w", err)
+	}
+	res, err := s.es.DeleteByQuery(
+		[]string{streamsIndex},
+		&buf,
+		s.es.DeleteByQuery.WithConflicts("proceed"),
+		s.es.DeleteByQuery.WithRefresh(true),
+		s.es.DeleteByQuery.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("streamRepo.DeleteChannelStreams: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		var e EsErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return fmt.Errorf("streamRepo.DeleteChannelStreams: %w", err)
+		}
+		return apperrors.NewElasticSearchError(res.StatusCode, e.Error.Type, e.Error.Reason)
+	}
+	return nil
+}
+
 func NewStreamRepository(es *elasticsearch.Client) StreamRepository {
 	return &streamRepository{
 		es: es,
@@ -6,6 +6,7 @@ import (
 	"channel-service/internal/repo"
 	"context"
 	"errors"
+	"fmt"
 	"mime/multipart"
 	"time"
 
@@ -21,10 +22,22 @@ type ChannelService interface {
 	GetChannelByID(ctx context.Context, channelID string) (model.Channel, error)
 	GetChannelBySearchText(ctx context.Context, searchText string, limit, offset int) ([]model.Channel, error)
 	SetChannelAvatar(ctx context.Context, fileHeader *multipart.FileHeader, channelID string) error
+	// TransferChannel give the channel of the user fromID, its avatar and streams to the user toID,
+	// who must not own a channel yet
+	TransferChannel(ctx context.Context, fromID string, toID string) error
+	// ExportUserData return the channel of the user and all its streams, the stream keys are credentials
+	// and are left out
+	ExportUserData(ctx context.Context, userID string) (model.UserData, error)
+	// DeleteUserData delete the channel of the user, its avatar and streams. It can be called again after a failure
+	DeleteUserData(ctx context.Context, userID string) error
 }
 
+// exportPageSize is the number of streams read at once while exporting the data of a user
+const exportPageSize = 100
+
 type channelService struct {
 	channelRepo   repo.ChannelRepository
+	streamRepo    repo.StreamRepository
 	minioClient   *minio.Client
 	logger        *zap.Logger
 	minioEndpoint string
@@ -54,6 +67,69 @@ func (c *channelService) SetChannelAvatar(ctx context.Context, fileHeader *multi
 	return err
 }
 
+func (c *channelService) TransferChannel(ctx context.Context, fromID string, toID string) error {
+	err := c.channelRepo.TransferChannel(ctx, fromID, toID)
+	if err != nil {
+		return err
+	}
+	_, err = c.minioClient.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: imagesBucket, Object: toID},
+		minio.CopySrcOptions{Bucket: imagesBucket, Object: fromID},
+	)
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("channelService.TransferChannel copy avatar: %w", err)
+	}
+	if err == nil {
+		err = c.minioClient.RemoveObject(ctx, imagesBucket, fromID, minio.RemoveObjectOptions{})
+		if err != nil {
+			c.logger.Error("failed to remove transferred avatar", zap.String("channel_id", fromID), zap.Error(err))
+		}
+	}
+	return c.streamRepo.ReassignChannelStreams(ctx, fromID, toID)
+}
+
+func (c *channelService) ExportUserData(ctx context.Context, userID string) (model.UserData, error) {
+	var data model.UserData
+	channel, err := c.channelRepo.GetChannelByID(ctx, userID)
+	if err != nil && !errors.Is(err, apperrors.ErrChannelNotFound) {
+		return model.UserData{}, fmt.Errorf("channelService.ExportUserData: %w", err)
+	}
+	if err == nil {
+		data.Channel = &channel
+	}
+	for offset := 0; ; offset += exportPageSize {
+		streams, err := c.streamRepo.GetStreamByChannelID(ctx, userID, "", exportPageSize, offset)
+		if err != nil {
+			return model.UserData{}, fmt.Errorf("channelService.ExportUserData: %w", err)
+		}
+		for _, stream := range streams {
+			stream.StreamKey = ""
+			data.Streams = append(data.Streams, stream)
+		}
+		if len(streams) < exportPageSize {
+			break
+		}
+	}
+	return data, nil
+}
+
+func (c *channelService) DeleteUserData(ctx context.Context, userID string) error {
+	err := c.streamRepo.DeleteChannelStreams(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("channelService.DeleteUserData: %w", err)
+	}
+	err = c.minioClient.RemoveObject(ctx, imagesBucket, userID, minio.RemoveObjectOptions{})
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("channelService.DeleteUserData remove avatar: %w", err)
+	}
+	// the channel is deleted last, so a deletion that failed halfway can be retried
+	err = c.channelRepo.DeleteChannel(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("channelService.DeleteUserData: %w", err)
+	}
+	return nil
+}
+
 func (c *channelService) CreateChannel(ctx context.Context, channel model.Channel) error {
 	return c.channelRepo.CreateChannel(ctx, channel)
 }
@@ -110,9 +186,10 @@ func (c *channelService) GetChannelBySearchText(ctx context.Context, searchText
 	return channels, nil
 }
 
-func NewChannelService(channelRepo repo.ChannelRepository, logger *zap.Logger, minioClient *minio.Client, minioEndpoint string) ChannelService {
+func NewChannelService(channelRepo repo.ChannelRepository, streamRepo repo.StreamRepository, logger *zap.Logger, minioClient *minio.Client, minioEndpoint string) ChannelService {
 	return &channelService{
 		channelRepo:   channelRepo,
+		streamRepo:    streamRepo,
 		logger:        logger,
 		minioClient:   minioClient,
 		minioEndpoint: minioEndpoint,
@@ -13,7 +13,7 @@ import (
 )
 
 type StreamService interface {
-	CreateStream(ctx context.Context, stream model.Stream, userToken string) (model.Stream, error)
+	CreateStream(ctx context.Context, stream model.Stream) (model.Stream, error)
 	GetStreamByID(ctx context.Context, id string) (model.Stream, error)
 	GetStreamByChannelID(ctx context.Context, channelID string, status string, limit int, offset int) ([]model.Stream, error)
 	UpdateStreamById(ctx context.Context, stream model.Stream) error
@@ -61,7 +61,7 @@ func (s *streamService) HandleOVMNotify(ctx context.Context, request request.OVM
 	}, nil
 }
 
-func (s *streamService) CreateStream(ctx context.Context, stream model.Stream, userToken string) (model.Stream, error) {
+func (s *streamService) CreateStream(ctx context.Context, stream model.Stream) (model.Stream, error) {
 	stream.ID = uuid.New().String()
 	if stream.Category.ID != "" {
 		category, err := s.categoryService.GetCategoryByID(ctx, stream.Category.ID)
@@ -79,7 +79,7 @@ func (s *streamService) CreateStream(ctx context.Context, stream model.Stream, u
 	stream.StreamKey = fmt.Sprintf("default/app/%s", stream.ID)
 	stream.HlsURL = fmt.Sprintf("%s/app/%s/master.m3u8", s.hlsServerURL, stream.ID)
 	stream.Status = model.StatusStreamInit
-	roomChatUrl, err := s.chatClient.CreateRoomChat(ctx, userToken, stream.ID, "/api/chat/thread")
+	roomChatUrl, err := s.chatClient.CreateRoomChat(ctx, stream.ID, "/api/chat/thread")
 	if err != nil {
 		return model.Stream{}, fmt.Errorf("create room chat failed: %w", err)
 	}
@@ -0,0 +1,144 @@
+package auth
+
+// This file is copied byte for byte in channel-service/internal/auth/jwks.go and chat-service/internal/auth/jwks.go,
+// the two services are separate modules. Change both copies together.
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS fetches and caches the public signing keys published by auth-service.
+// Unknown kids trigger a refetch, rate limited by minRefresh, so rotated keys are picked up.
+type JWKS struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]jwksKey
+	fetchedAt time.Time
+}
+
+type jwksKey struct {
+	alg string
+	key interface{}
+}
+
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		ttl:        ttl,
+		minRefresh: 10 * time.Second,
+		keys:       map[string]jwksKey{},
+	}
+}
+
+// Keyfunc resolves the verification key of a token from its kid header.
+func (j *JWKS) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token missing kid")
+	}
+	k, ok := j.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if k.alg != t.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return k.key, nil
+}
+
+func (j *JWKS) lookup(kid string) (jwksKey, bool) {
+	j.mu.RLock()
+	k, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > j.ttl
+	canRefresh := time.Since(j.fetchedAt) > j.minRefresh
+	j.mu.RUnlock()
+	if ok && !stale {
+		return k, true
+	}
+	if !canRefresh {
+		return k, ok
+	}
+	if err := j.refresh(context.Background()); err != nil {
+		// keep serving the cached keys while auth-service is unreachable
+		return k, ok
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	k, ok = j.keys[kid]
+	return k, ok
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks status %d", resp.StatusCode)
+	}
+	var payload struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return err
+	}
+	keys := make(map[string]jwksKey, len(payload.Keys))
+	for _, k := range payload.Keys {
+		switch {
+		case k.Kty == "RSA" && k.Alg == jwt.SigningMethodRS256.Alg():
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				continue
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				continue
+			}
+			keys[k.Kid] = jwksKey{alg: k.Alg, key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			keys[k.Kid] = jwksKey{alg: jwt.SigningMethodEdDSA.Alg(), key: ed25519.PublicKey(x)}
+		}
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.mu.Unlock()
+	return nil
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"errors"
 	"net/http"
 	"strings"
@@ -10,15 +11,62 @@ import (
 )
 
 type ChatClaims struct {
+	Type   string `json:"typ"`
 	UserID string `json:"user_id"`
+	// Username is the public handle of the user, empty if they have not picked one yet
+	Username string `json:"username"`
+	// ClientID is set on the access tokens auth-service grants to third-party apps, they can not use the chat
+	ClientID string `json:"client_id"`
+	// Token is the raw token the claims were parsed from
+	Token string `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// ServiceClaims are the claims of the tokens backend services get from auth-service with the client credentials grant
+type ServiceClaims struct {
+	Type     string `json:"typ"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// HasScope report whether the service token was granted scope
+func (c *ServiceClaims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Token types of the typ claim, auth-service signs other tokens (refresh, email links, MFA challenges) with the
+// same keys so the type must be checked
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeService = "service"
+)
+
+// ScopeChatThreadsWrite allows a service to create chat threads
+const ScopeChatThreadsWrite = "chat:threads:write"
+
+// ScopeUserDataRead and ScopeUserDataDelete allow auth-service to export and anonymize the messages of a user
+const (
+	ScopeUserDataRead   = "user_data:read"
+	ScopeUserDataDelete = "user_data:delete"
+)
+
+// PersonalAccessTokenPrefix marks the opaque tokens of auth-service, they are resolved by the RevocationChecker
+const PersonalAccessTokenPrefix = "lsp_pat_"
+
 type ctxKey string
 
 const CtxUserKey ctxKey = "chatUser"
 
-func ParseJWTFromRequest(r *http.Request, secret []byte) (*ChatClaims, error) {
+// CtxServiceKey holds the *ServiceClaims of the backend service making the request
+const CtxServiceKey ctxKey = "chatService"
+
+func ParseJWTFromRequest(r *http.Request, keyfunc jwt.Keyfunc) (*ChatClaims, error) {
 	authz := r.Header.Get("Authorization")
 	if authz == "" {
 		if c, err := r.Cookie("access_token"); err == nil {
@@ -35,12 +83,12 @@ func ParseJWTFromRequest(r *http.Request, secret []byte) (*ChatClaims, error) {
 	}
 
 	tokenStr := strings.TrimPrefix(authz, "Bearer ")
-	token, err := jwt.ParseWithClaims(tokenStr, &ChatClaims{}, func(t *jwt.Token) (interface{}, error) {
-		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, errors.New("unexpected signing method")
-		}
-		return secret, nil
-	}, jwt.WithLeeway(10*time.Second))
+	if strings.HasPrefix(tokenStr, PersonalAccessTokenPrefix) {
+		return &ChatClaims{Token: tokenStr}, nil
+	}
+	token, err := jwt.ParseWithClaims(tokenStr, &ChatClaims{}, keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithLeeway(10*time.Second))
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
@@ -48,11 +96,63 @@ func ParseJWTFromRequest(r *http.Request, secret []byte) (*ChatClaims, error) {
 	if !ok {
 		return nil, errors.New("invalid claims")
 	}
+	if claims.Type != TokenTypeAccess {
+		return nil, errors.New("not an access token")
+	}
+	if claims.ClientID != "" {
+		return nil, errors.New("token granted to a third-party app")
+	}
 	if claims.UserID == "" {
 		return nil, errors.New("claims missing user_id")
 	}
 	if claims.ExpiresAt != nil && !claims.ExpiresAt.After(time.Now()) {
 		return nil, errors.New("token expired")
 	}
+	claims.Token = tokenStr
+	return claims, nil
+}
+
+// CheckCSRF report whether r carries the double-submit CSRF token of auth-service when it is authenticated by the
+// access_token cookie and changes state. The browser sends the cookie with cross-site requests, only the frontend
+// can read the csrf_token cookie and copy it in the X-CSRF-Token header
+func CheckCSRF(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	if _, err := r.Cookie("access_token"); err != nil {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	c, err := r.Cookie("csrf_token")
+	if err != nil || c.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(r.Header.Get("X-CSRF-Token"))) == 1
+}
+
+// ParseServiceJWTFromRequest parse the service token of the Authorization header, user tokens are rejected
+func ParseServiceJWTFromRequest(r *http.Request, keyfunc jwt.Keyfunc) (*ServiceClaims, error) {
+	authz := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authz, "Bearer ") {
+		return nil, errors.New("missing bearer token")
+	}
+	tokenStr := strings.TrimPrefix(authz, "Bearer ")
+	token, err := jwt.ParseWithClaims(tokenStr, &ServiceClaims{}, keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithLeeway(10*time.Second),
+		jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	claims, ok := token.Claims.(*ServiceClaims)
+	if !ok {
+		return nil, errors.New("invalid claims")
+	}
+	if claims.Type != TokenTypeService || claims.ClientID == "" {
+		return nil, errors.New("not a service token")
+	}
 	return claims, nil
 }
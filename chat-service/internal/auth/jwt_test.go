@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestSigner return a function signing claims the way auth-service does, and the keyfunc verifying them
+func newTestSigner(t *testing.T) (func(claims jwt.MapClaims) string, jwt.Keyfunc) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	keyfunc := func(*jwt.Token) (any, error) {
+		return public, nil
+	}
+	return sign, keyfunc
+}
+
+func TestParseJWTFromRequest(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{name: "access token", claims: jwt.MapClaims{"typ": "access", "user_id": "alice", "username": "alice", "exp": exp}},
+		{name: "third-party app token", claims: jwt.MapClaims{"typ": "access", "client_id": "third-party-app", "user_id": "alice", "exp": exp}, wantErr: true},
+		{name: "refresh token", claims: jwt.MapClaims{"typ": "refresh", "user_id": "alice", "exp": exp}, wantErr: true},
+		{name: "mfa challenge", claims: jwt.MapClaims{"typ": "mfa_challenge", "user_id": "alice", "exp": exp}, wantErr: true},
+		{name: "magic link", claims: jwt.MapClaims{"typ": "magic_link", "user_id": "alice", "exp": exp}, wantErr: true},
+		{name: "email verification", claims: jwt.MapClaims{"typ": "email_verification", "user_id": "alice", "exp": exp}, wantErr: true},
+		{name: "service token", claims: jwt.MapClaims{"typ": "service", "client_id": "channel-service", "exp": exp}, wantErr: true},
+		{name: "missing type", claims: jwt.MapClaims{"user_id": "alice", "exp": exp}, wantErr: true},
+		{name: "missing user id", claims: jwt.MapClaims{"typ": "access", "exp": exp}, wantErr: true},
+		{name: "expired", claims: jwt.MapClaims{"typ": "access", "user_id": "alice", "exp": time.Now().Add(-time.Hour).Unix()}, wantErr: true},
+	}
+	sign, keyfunc := newTestSigner(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/chat", nil)
+			r.Header.Set("Authorization", "Bearer "+sign(tt.claims))
+			claims, err := ParseJWTFromRequest(r, keyfunc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseJWTFromRequest() = %+v, want an error", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJWTFromRequest() error = %v", err)
+			}
+			if claims.UserID != "alice" || claims.Username != "alice" {
+				t.Errorf("ParseJWTFromRequest() = %+v", claims)
+			}
+		})
+	}
+}
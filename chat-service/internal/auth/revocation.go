@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// revocationMaxStale is how long a token accepted by auth-service keeps being accepted while auth-service is unreachable
+const revocationMaxStale = 5 * time.Minute
+
+// RevocationChecker asks auth-service whether a token, already verified locally, has been revoked
+// (logout, password change, ban). It uses the same endpoint as the gateway forward-auth.
+// Personal access tokens cannot be verified locally, so Check also returns the user resolved by auth-service.
+// Results are cached for ttl, so a revocation reaches chat-service at most ttl later.
+type RevocationChecker struct {
+	verifyURL string
+	client    *http.Client
+	ttl       time.Duration
+
+	mu       sync.Mutex
+	results  map[string]revocationResult
+	prunedAt time.Time
+}
+
+type revocationResult struct {
+	identity  Identity
+	revoked   bool
+	checkedAt time.Time
+}
+
+// Identity is the user auth-service resolved the token to
+type Identity struct {
+	UserID   string
+	Username string
+}
+
+func NewRevocationChecker(verifyURL string, ttl time.Duration) *RevocationChecker {
+	return &RevocationChecker{
+		verifyURL: verifyURL,
+		client:    &http.Client{Timeout: 2 * time.Second},
+		ttl:       ttl,
+		results:   map[string]revocationResult{},
+	}
+}
+
+func (rc *RevocationChecker) Check(ctx context.Context, token string) (Identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	cached, ok := rc.lookup(key)
+	if ok && time.Since(cached.checkedAt) < rc.ttl {
+		if cached.revoked {
+			return Identity{}, ErrTokenRevoked
+		}
+		return cached.identity, nil
+	}
+	identity, err := rc.verify(ctx, token)
+	switch {
+	case err == nil:
+		rc.store(key, revocationResult{identity: identity, checkedAt: time.Now()})
+		return identity, nil
+	case errors.Is(err, ErrTokenRevoked):
+		rc.store(key, revocationResult{revoked: true, checkedAt: time.Now()})
+		return Identity{}, err
+	}
+	// auth-service is unreachable, keep accepting the tokens it accepted recently so chat stays up
+	if ok && !cached.revoked && time.Since(cached.checkedAt) < revocationMaxStale {
+		return cached.identity, nil
+	}
+	return Identity{}, err
+}
+
+func (rc *RevocationChecker) lookup(key string) (revocationResult, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	r, ok := rc.results[key]
+	return r, ok
+}
+
+func (rc *RevocationChecker) store(key string, r revocationResult) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.results[key] = r
+	if time.Since(rc.prunedAt) < revocationMaxStale {
+		return
+	}
+	for k, v := range rc.results {
+		if time.Since(v.checkedAt) >= revocationMaxStale {
+			delete(rc.results, k)
+		}
+	}
+	rc.prunedAt = time.Now()
+}
+
+func (rc *RevocationChecker) verify(ctx context.Context, token string) (Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rc.verifyURL, nil)
+	if err != nil {
+		return Identity{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return Identity{}, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Identity{
+			UserID:   resp.Header.Get("X-User-Id"),
+			Username: resp.Header.Get("X-Username"),
+		}, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return Identity{}, ErrTokenRevoked
+	default:
+		return Identity{}, fmt.Errorf("auth verify status %d", resp.StatusCode)
+	}
+}
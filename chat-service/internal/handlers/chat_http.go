@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
-	"thanhnt208/chat-service/internal/auth"
 	"thanhnt208/chat-service/internal/models"
 	"time"
 
@@ -35,8 +34,6 @@ func (h *ChatHTTP) CreateChatThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_ = r.Context().Value(auth.CtxUserKey)
-
 	thread := models.ChatThread{
 		ID:        uuid.NewString(),
 		StreamID:  req.StreamID,
@@ -82,3 +79,36 @@ func (h *ChatHTTP) CloseThread(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "closed"})
 }
+
+// ExportUserMessages return every message the user sent, oldest first
+func (h *ChatHTTP) ExportUserMessages(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	messages := make([]models.Message, 0)
+	if err := h.DB.Where("user_id = ?", userID).
+		Order("created_at ASC").Find(&messages).Error; err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(messages)
+}
+
+// AnonymizeUserMessages detach the messages of a deleted user from their account, their content is kept so the
+// conversations still make sense
+func (h *ChatHTTP) AnonymizeUserMessages(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	res := h.DB.Model(&models.Message{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"user_id":  "",
+			"username": models.DeletedUsername,
+		})
+	if res.Error != nil {
+		http.Error(w, "db error: "+res.Error.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int64{"anonymized": res.RowsAffected})
+}
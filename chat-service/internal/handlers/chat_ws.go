@@ -18,15 +18,16 @@ import (
 )
 
 type ChatWS struct {
-	DB       *gorm.DB
-	Hub      *realtime.Hub
-	Upgrader websocket.Upgrader
-	Clock    realtime.ClockCfg
-	JWTKey   []byte
+	DB         *gorm.DB
+	Hub        *realtime.Hub
+	Upgrader   websocket.Upgrader
+	Clock      realtime.ClockCfg
+	JWKS       *auth.JWKS
+	Revocation *auth.RevocationChecker
 }
 
-func NewChatWS(db *gorm.DB, hub *realtime.Hub, up websocket.Upgrader, clk realtime.ClockCfg, jwtKey []byte) *ChatWS {
-	return &ChatWS{DB: db, Hub: hub, Upgrader: up, Clock: clk, JWTKey: jwtKey}
+func NewChatWS(db *gorm.DB, hub *realtime.Hub, up websocket.Upgrader, clk realtime.ClockCfg, jwks *auth.JWKS, revocation *auth.RevocationChecker) *ChatWS {
+	return &ChatWS{DB: db, Hub: hub, Upgrader: up, Clock: clk, JWKS: jwks, Revocation: revocation}
 }
 
 func (h *ChatWS) Handle(w http.ResponseWriter, r *http.Request) {
@@ -38,11 +39,20 @@ func (h *ChatWS) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, err := auth.ParseJWTFromRequest(r, h.JWTKey)
+	claims, err := auth.ParseJWTFromRequest(r, h.JWKS.Keyfunc)
 	if err != nil {
 		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
 		return
 	}
+	identity, err := h.Revocation.Check(r.Context(), claims.Token)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.UserID == "" {
+		claims.UserID = identity.UserID
+		claims.Username = identity.Username
+	}
 
 	conn, err := h.Upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -50,21 +60,23 @@ func (h *ChatWS) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := realtime.NewClient(conn, streamID, claims.UserID, h.Clock, h.Hub)
+	// the username is carried by the token, the channel title is only looked up for users who have not picked one
+	client.Username = claims.Username
 	th := h.Hub.GetOrCreateThreadHub(streamID)
 	th.Register <- client
 
-	userID := claims.UserID
-
-	go func(uid string) {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if username, err := fetchChannelTitle(ctx, uid); err != nil {
-			log.Printf("fetch username by channelId(userID=%s) failed: %v", uid, err)
-			return
-		} else {
-			client.Username = username
-		}
-	}(userID)
+	if claims.Username == "" {
+		go func(uid string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if username, err := fetchChannelTitle(ctx, uid); err != nil {
+				log.Printf("fetch username by channelId(userID=%s) failed: %v", uid, err)
+				return
+			} else {
+				client.Username = username
+			}
+		}(claims.UserID)
+	}
 
 	go realtime.SendHistory(h.Hub, client, streamID, 50)
 	go client.WritePump()
@@ -7,20 +7,52 @@ import (
 	"thanhnt208/chat-service/internal/auth"
 )
 
-func AuthRequired(jwtKey []byte) func(http.Handler) http.Handler {
+func AuthRequired(jwks *auth.JWKS, revocation *auth.RevocationChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, err := auth.ParseJWTFromRequest(r, jwtKey)
+			claims, err := auth.ParseJWTFromRequest(r, jwks.Keyfunc)
 			if err != nil {
 				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
 				return
 			}
+			if !auth.CheckCSRF(r) {
+				http.Error(w, "Forbidden: invalid CSRF token", http.StatusForbidden)
+				return
+			}
+			identity, err := revocation.Check(r.Context(), claims.Token)
+			if err != nil {
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if claims.UserID == "" {
+				claims.UserID = identity.UserID
+				claims.Username = identity.Username
+			}
 			ctx := context.WithValue(r.Context(), auth.CtxUserKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// ServiceRequired only let through the backend services whose token was granted scope
+func ServiceRequired(jwks *auth.JWKS, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := auth.ParseServiceJWTFromRequest(r, jwks.Keyfunc)
+			if err != nil {
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if !claims.HasScope(scope) {
+				http.Error(w, "Forbidden: missing scope "+scope, http.StatusForbidden)
+				return
+			}
+			ctx := context.WithValue(r.Context(), auth.CtxServiceKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 func CheckRequestOrigin(r *http.Request, allowed []string) bool {
 	if len(allowed) == 0 {
 		return false
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"thanhnt208/chat-service/internal/auth"
 	"thanhnt208/chat-service/internal/config"
 	chatHandlers "thanhnt208/chat-service/internal/handlers"
 	"thanhnt208/chat-service/internal/httpserver/middleware"
@@ -38,25 +39,32 @@ func (s *Server) Start() error {
 		},
 	}
 
+	jwks := auth.NewJWKS(s.cfg.JWKSURL, s.cfg.JWKSCacheTTL)
+	revocation := auth.NewRevocationChecker(s.cfg.AuthVerifyURL, s.cfg.AuthVerifyCacheTTL)
+
 	chatHTTP := chatHandlers.NewChatHTTP(s.db)
 	chatWS := chatHandlers.NewChatWS(s.db, s.hub, upgrader, realtime.ClockCfg{
 		MaxWSReadBytes: s.cfg.MaxWSReadBytes,
 		ReadDeadline:   s.cfg.ReadDeadline,
 		WriteDeadline:  s.cfg.WriteDeadline,
 		PingPeriod:     s.cfg.PingPeriod,
-	}, s.cfg.JWTSecret)
+	}, jwks, revocation)
 
 	api := r.PathPrefix("/api").Subrouter()
-	api.Use(middleware.AuthRequired(s.cfg.JWTSecret))
-	api.HandleFunc("/chat/thread", chatHTTP.CreateChatThread).Methods("POST", "OPTIONS")
-	api.HandleFunc("/chat/thread/{streamId}/messages", chatHTTP.GetThreadMessages).Methods("GET", "OPTIONS")
-	api.HandleFunc("/chat/thread/{streamId}/close", chatHTTP.CloseThread).Methods("POST", "OPTIONS")
+	authRequired := middleware.AuthRequired(jwks, revocation)
+	// threads are created by channel-service when a stream starts, with its own service token
+	api.Handle("/chat/thread", middleware.ServiceRequired(jwks, auth.ScopeChatThreadsWrite)(http.HandlerFunc(chatHTTP.CreateChatThread))).Methods("POST", "OPTIONS")
+	api.Handle("/chat/thread/{streamId}/messages", authRequired(http.HandlerFunc(chatHTTP.GetThreadMessages))).Methods("GET", "OPTIONS")
+	api.Handle("/chat/thread/{streamId}/close", authRequired(http.HandlerFunc(chatHTTP.CloseThread))).Methods("POST", "OPTIONS")
+	// auth-service exports and anonymizes the messages of users who download or delete their account
+	api.Handle("/chat/users/{userId}/messages", middleware.ServiceRequired(jwks, auth.ScopeUserDataRead)(http.HandlerFunc(chatHTTP.ExportUserMessages))).Methods("GET")
+	api.Handle("/chat/users/{userId}/messages", middleware.ServiceRequired(jwks, auth.ScopeUserDataDelete)(http.HandlerFunc(chatHTTP.AnonymizeUserMessages))).Methods("DELETE")
 
 	r.HandleFunc("/ws/chat/{streamId}", chatWS.Handle)
 
 	corsOpts := []handlers.CORSOption{
 		handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-CSRF-Token"}),
 	}
 	if len(s.cfg.CORSOrigins) == 1 && s.cfg.CORSOrigins[0] == "*" {
 		corsOpts = append(corsOpts, handlers.AllowedOrigins([]string{"*"}))
@@ -12,8 +12,11 @@ type ChatThread struct {
 type Message struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	StreamID  string    `gorm:"index"  json:"stream_id"`
-	UserID    string    `json:"user_id"`
+	UserID    string    `gorm:"index" json:"user_id"`
 	Username  string    `json:"username"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// DeletedUsername replaces the username of the messages of deleted accounts
+const DeletedUsername = "deleted user"
@@ -3,14 +3,244 @@ CREATE DATABASE auth;
 
 CREATE TABLE users (
     id UUID PRIMARY KEY DEFAULT uuidv7(),
-    email TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
     password TEXT NOT NULL,
+    username TEXT NOT NULL DEFAULT '',
+    username_changed_at TIMESTAMP WITH TIME ZONE,
     first_name TEXT,
     last_name TEXT,
+    bio TEXT NOT NULL DEFAULT '',
     role TEXT,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'active',
+    suspended_until TIMESTAMP WITH TIME ZONE,
+    status_reason TEXT NOT NULL DEFAULT '',
+    deletion_scheduled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- emails are unique among the live users, the email of a soft deleted account can be registered again
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+-- usernames are unique regardless of case, users who did not pick one have an empty username
+CREATE UNIQUE INDEX users_username_key ON users (lower(username)) WHERE username <> '';
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+INSERT INTO users (email, password, first_name, last_name,role, email_verified, created_at, updated_at)
+VALUES ('admin@gmail.com', '$2a$04$CHxMEXL8vezb4FCk9BoHMu4isGPn.6Md.8GQfbwyGDF5UESazaPKq', 'admin', 'admin','admin', TRUE, NOW(), NOW());
+
+CREATE TABLE user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL,
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL
 );
 
-INSERT INTO users (email, password, first_name, last_name,role, created_at, updated_at)
-VALUES ('admin@gmail.com', '$2a$04$CHxMEXL8vezb4FCk9BoHMu4isGPn.6Md.8GQfbwyGDF5UESazaPKq', 'admin', 'admin','admin', NOW(), NOW());
\ No newline at end of file
+CREATE TABLE mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
+
+CREATE TABLE password_histories (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX password_histories_user_id_created_at_idx ON password_histories (user_id, created_at DESC);
+
+CREATE TABLE login_lockouts (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    failed_attempts INT NOT NULL,
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    cleared_at TIMESTAMP WITH TIME ZONE,
+    cleared_by UUID
+);
+
+CREATE INDEX login_lockouts_user_id_locked_at_idx ON login_lockouts (user_id, locked_at DESC);
+
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
+
+CREATE TABLE identities (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX identities_user_id_idx ON identities (user_id);
+
+CREATE TABLE passkeys (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- credential_id is the base64url encoded WebAuthn credential id, public_key its COSE encoded key
+    credential_id TEXT NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX passkeys_user_id_idx ON passkeys (user_id);
+
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    name TEXT NOT NULL,
+    -- secret_hash is empty for public clients, which must use PKCE without a secret
+    secret_hash TEXT NOT NULL DEFAULT '',
+    redirect_uris TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- channel-service uses the client credentials grant to call chat-service, the secret is channel-service-dev-secret
+INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, scopes)
+VALUES ('0199a3c1-5a3e-7c2b-9f10-3d6f1e2a4b01', 'channel-service', 'a9f3f710ad4c33e92f20b6e7ddf3a6e0089a6e9c8070db8ec0005ed23124aac2', '', 'chat:threads:write');
+
+CREATE TABLE oauth_consents (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+-- security_events is the audit log of authentication events, rows are kept but pseudonymized when the user is deleted
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    type TEXT NOT NULL,
+    user_id UUID,
+    actor_id UUID,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX security_events_user_id_created_at_idx ON security_events (user_id, created_at DESC);
+CREATE INDEX security_events_type_created_at_idx ON security_events (type, created_at DESC);
+CREATE INDEX security_events_created_at_idx ON security_events (created_at DESC);
+
+CREATE FUNCTION security_events_append_only() RETURNS trigger AS $$
+BEGIN
+    -- the only change allowed is pseudonymize_security_events removing the personal data of a deleted user
+    IF TG_OP = 'UPDATE' AND current_setting('security_events.pseudonymize', true) = 'on' THEN
+        IF NEW.id = OLD.id AND NEW.type = OLD.type AND NEW.created_at = OLD.created_at
+            AND NEW.user_id IS NOT DISTINCT FROM OLD.user_id AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+            AND NEW.ip = '' AND NEW.user_agent = '' AND NEW.details <@ OLD.details THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+    RAISE EXCEPTION 'security_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER security_events_no_update_delete
+    BEFORE UPDATE OR DELETE ON security_events
+    FOR EACH ROW EXECUTE FUNCTION security_events_append_only();
+
+CREATE TRIGGER security_events_no_truncate
+    BEFORE TRUNCATE ON security_events
+    FOR EACH STATEMENT EXECUTE FUNCTION security_events_append_only();
+
+-- pseudonymize_security_events erase the ip, user agent, email and username of a deleted user from the audit log,
+-- the events themselves are kept
+CREATE FUNCTION pseudonymize_security_events(p_user_id UUID) RETURNS void AS $$
+BEGIN
+    PERFORM set_config('security_events.pseudonymize', 'on', true);
+    UPDATE security_events
+    SET ip = '', user_agent = '',
+        details = details - ARRAY[
+            'email', 'old_email', 'new_email',
+            'email_fingerprint', 'old_email_fingerprint', 'new_email_fingerprint',
+            'old_username', 'new_username'
+        ]
+    WHERE user_id = p_user_id;
+    -- the ip and user agent of the events the user performed on other accounts are theirs too
+    UPDATE security_events SET ip = '', user_agent = '' WHERE actor_id = p_user_id AND user_id IS DISTINCT FROM p_user_id;
+    PERFORM set_config('security_events.pseudonymize', 'off', true);
+END;
+$$ LANGUAGE plpgsql SECURITY DEFINER SET search_path = public;
+
+CREATE TABLE invites (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    code_hash TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    max_uses INT NOT NULL CHECK (max_uses > 0),
+    use_count INT NOT NULL DEFAULT 0 CHECK (use_count >= 0 AND use_count <= max_uses),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX invites_created_at_idx ON invites (created_at DESC);
+
+CREATE TABLE invite_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    invite_id UUID NOT NULL REFERENCES invites(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX invite_redemptions_invite_id_created_at_idx ON invite_redemptions (invite_id, created_at);
+
+CREATE TABLE email_changes (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email TEXT NOT NULL,
+    new_email TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX email_changes_user_id_status_idx ON email_changes (user_id, status);
+
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id);
+CREATE INDEX data_exports_expires_at_idx ON data_exports (expires_at) WHERE expires_at IS NOT NULL;